@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	router "github.com/vdhieu/tx-parser/internal/api"
+	"github.com/vdhieu/tx-parser/internal/api/grpcserver"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/outbox"
 	"github.com/vdhieu/tx-parser/internal/parser"
 	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
 	"github.com/vdhieu/tx-parser/pkg/logger"
 	"github.com/vdhieu/tx-parser/pkg/notification"
 	"github.com/vdhieu/tx-parser/pkg/rpc"
@@ -23,14 +34,52 @@ func main() {
 	defer logger.Sync()
 
 	// Initialize components
+	retention, err := retentionFromEnv()
+	if err != nil {
+		logger.GetLogger().Fatal("Invalid retention policy", zap.Error(err))
+	}
+	store, err := openStorage(os.Getenv("TX_PARSER_DATA_DIR"), storage.WithRetention(retention))
+	if err != nil {
+		logger.GetLogger().Fatal("Failed to open storage", zap.Error(err))
+	}
+	pruner := storage.NewPruner(store, time.Minute)
+	broker := stream.NewBroker()
 	p := parser.NewEthParser(
-		storage.NewMemoryStorage(),
+		store,
 		rpc.NewEthClient(),
-		notification.NewConsoleNotifier(),
+		broker,
 	)
+	// notifications are enqueued by the parser and delivered in the background
+	notifier, err := newNotifier()
+	if err != nil {
+		logger.GetLogger().Fatal("Failed to create notifier", zap.Error(err))
+	}
+	var outboxConfig outbox.Config
+	// the async notifier acknowledge the events to the dispatcher, which is only created afterwards.
+	// It does not deliver anything before the dispatcher gave it events
+	var dispatcher *outbox.Dispatcher
+	if workers := os.Getenv("TX_PARSER_NOTIFY_WORKERS"); workers != "" {
+		ack := func(event models.NotificationEvent, err error) { dispatcher.Acknowledge(event, err) }
+		if notifier, err = newAsyncNotifier(notifier, ack, workers); err != nil {
+			logger.GetLogger().Fatal("Failed to create async notifier", zap.Error(err))
+		}
+		outboxConfig.AwaitAck = true
+	}
+	dispatcher = outbox.NewDispatcher(store, notifier, outboxConfig)
+
+	authService := auth.NewService(store)
+	// the bootstrap key is used to create the first keys through the admin API
+	if adminKey := os.Getenv("TX_PARSER_ADMIN_KEY"); adminKey != "" {
+		if err := authService.Bootstrap(adminKey); err != nil {
+			logger.GetLogger().Fatal("Failed to bootstrap admin key", zap.Error(err))
+		}
+	}
+
+	// the limits are shared by the REST and gRPC APIs, 600 requests per minute unless the key has its own
+	limiter := auth.NewRateLimiter(600)
 
 	// Setup router
-	r := router.SetupRouter(p)
+	r := router.SetupRouter(p, authService, limiter, broker, pruner)
 
 	// Create server
 	srv := &http.Server{
@@ -45,6 +94,22 @@ func main() {
 		}
 	}()
 
+	// Start the gRPC API next to the REST one
+	grpcAddr := os.Getenv("TX_PARSER_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":5006"
+	}
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.GetLogger().Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	grpcServer := grpcserver.NewServer(p, authService, limiter, broker)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.GetLogger().Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -58,11 +123,218 @@ func main() {
 
 	// Stop the parser
 	p.Shutdown()
+	dispatcher.Shutdown()
+	// send what the notifier still batch and close its connections
+	if n, ok := notifier.(interface{ Shutdown() }); ok {
+		n.Shutdown()
+	}
+	pruner.Shutdown()
 
 	// Shutdown server
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.GetLogger().Error("Server forced to shutdown:", zap.Error(err))
 	}
 
+	// watch streams only end with the client, stop them once the timeout is reached
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		logger.GetLogger().Error("gRPC server forced to shutdown")
+		grpcServer.Stop()
+	}
+
+	if err := store.Close(); err != nil {
+		logger.GetLogger().Error("Failed to close storage", zap.Error(err))
+	}
+
 	logger.GetLogger().Info("Server exited properly")
 }
+
+// newNotifier route the notifications of each subscription to the channels configured by the environment:
+// the webhook of the subscriptions when a signing secret is set, emails when an SMTP server is set, Slack,
+// Discord, Kafka and NATS. Subscriptions without routes use every configured channel, or the console
+// when there is none
+func newNotifier() (notification.Notifier, error) {
+	channels := map[models.NotificationChannel]notification.Notifier{}
+	// close the channels already connected when a later one can not be created
+	fail := func(err error) (notification.Notifier, error) {
+		for _, n := range channels {
+			if s, ok := n.(interface{ Shutdown() }); ok {
+				s.Shutdown()
+			}
+		}
+		return nil, err
+	}
+
+	if webhookSecret := os.Getenv("TX_PARSER_WEBHOOK_SECRET"); webhookSecret != "" {
+		// a failed delivery is retried by the outbox dispatcher, retrying in the notifier would hold it back
+		webhookOpts := []notification.WebhookOption{notification.WithRetry(1, 0, 0)}
+		if os.Getenv("TX_PARSER_WEBHOOK_ALLOW_PRIVATE") == "true" {
+			webhookOpts = append(webhookOpts, notification.WithPrivateNetworks())
+		}
+		channels[models.ChannelWebhook] = notification.NewWebhookNotifier(webhookSecret, webhookOpts...)
+	}
+	if os.Getenv("TX_PARSER_SMTP_HOST") != "" {
+		config, err := smtpConfigFromEnv()
+		if err != nil {
+			return fail(err)
+		}
+		if channels[models.ChannelEmail], err = notification.NewSMTPNotifier(config); err != nil {
+			return fail(err)
+		}
+	}
+	var chatOpts []notification.ChatOption
+	if explorerURL := os.Getenv("TX_PARSER_EXPLORER_URL"); explorerURL != "" {
+		chatOpts = append(chatOpts, notification.WithExplorerURL(explorerURL))
+	}
+	if url := os.Getenv("TX_PARSER_SLACK_WEBHOOK_URL"); url != "" {
+		channels[models.ChannelSlack] = notification.NewSlackNotifier(url, chatOpts...)
+	}
+	if url := os.Getenv("TX_PARSER_DISCORD_WEBHOOK_URL"); url != "" {
+		channels[models.ChannelDiscord] = notification.NewDiscordNotifier(url, chatOpts...)
+	}
+	if brokers := os.Getenv("TX_PARSER_KAFKA_BROKERS"); brokers != "" {
+		n, err := notification.NewKafkaNotifier(notification.KafkaConfig{
+			Brokers:     strings.Split(brokers, ","),
+			Topic:       os.Getenv("TX_PARSER_KAFKA_TOPIC"),
+			PartitionBy: notification.PartitionKey(os.Getenv("TX_PARSER_KAFKA_PARTITION_BY")),
+		})
+		if err != nil {
+			return fail(err)
+		}
+		channels[models.ChannelKafka] = n
+	}
+	if url := os.Getenv("TX_PARSER_NATS_URL"); url != "" {
+		n, err := notification.NewNATSNotifier(notification.NATSConfig{
+			URL:       url,
+			Subject:   os.Getenv("TX_PARSER_NATS_SUBJECT"),
+			JetStream: os.Getenv("TX_PARSER_NATS_JETSTREAM") == "true",
+		})
+		if err != nil {
+			return fail(err)
+		}
+		channels[models.ChannelNATS] = n
+	}
+
+	var opts []notification.RoutingOption
+	if len(channels) > 0 {
+		// the console is only used by the subscriptions routing to it
+		var defaults []models.NotificationRoute
+		for _, channel := range models.NotificationChannels {
+			if _, ok := channels[channel]; ok {
+				defaults = append(defaults, models.NotificationRoute{Channel: channel})
+			}
+		}
+		opts = append(opts, notification.WithDefaultRoutes(defaults...))
+	}
+	channels[models.ChannelConsole] = notification.NewConsoleNotifier()
+
+	n, err := notification.NewRoutingNotifier(channels, opts...)
+	if err != nil {
+		return fail(err)
+	}
+	return n, nil
+}
+
+// newAsyncNotifier deliver the events with a pool of workers so the outbox does not wait for slow channels.
+// The outbox keep the queued events until a worker give them to ack, the failed ones are then retried
+// after a backoff and dead-lettered after the last attempt as with a synchronous notifier. The events left
+// when shutting down are delivered on next start
+func newAsyncNotifier(next notification.Notifier, ack func(models.NotificationEvent, error),
+	workers string) (notification.Notifier, error) {
+	config := notification.AsyncConfig{
+		Overflow:    notification.OverflowPolicy(os.Getenv("TX_PARSER_NOTIFY_OVERFLOW")),
+		OnDelivered: func(event models.NotificationEvent) { ack(event, nil) },
+		OnError: func(event models.NotificationEvent, err error) {
+			if errors.Is(err, notification.ErrNotifierClosed) {
+				// still queued in the outbox
+				return
+			}
+			ack(event, err)
+		},
+	}
+	var err error
+	if config.Workers, err = strconv.Atoi(workers); err != nil {
+		return nil, fmt.Errorf("invalid TX_PARSER_NOTIFY_WORKERS: %w", err)
+	}
+	if size := os.Getenv("TX_PARSER_NOTIFY_QUEUE_SIZE"); size != "" {
+		if config.QueueSize, err = strconv.Atoi(size); err != nil {
+			return nil, fmt.Errorf("invalid TX_PARSER_NOTIFY_QUEUE_SIZE: %w", err)
+		}
+	}
+	if config.Overflow == notification.OverflowSpill {
+		dataDir := os.Getenv("TX_PARSER_DATA_DIR")
+		if dataDir == "" {
+			return nil, errors.New("TX_PARSER_DATA_DIR is required to spill notifications")
+		}
+		config.SpillDir = filepath.Join(dataDir, "notify-spill")
+	}
+	return notification.NewAsyncNotifier(next, config)
+}
+
+// retentionFromEnv read the TX_PARSER_RETENTION_* variables, txns are kept 30 days, 10000 per address
+// and 1000000 in total unless overridden. 0 remove a limit
+func retentionFromEnv() (storage.RetentionPolicy, error) {
+	policy := storage.RetentionPolicy{
+		MaxAge:        30 * 24 * time.Hour,
+		MaxPerAddress: 10000,
+		MaxTotal:      1000000,
+	}
+	if maxAge := os.Getenv("TX_PARSER_RETENTION_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d < 0 {
+			return policy, fmt.Errorf("invalid TX_PARSER_RETENTION_MAX_AGE %q", maxAge)
+		}
+		policy.MaxAge = d
+	}
+	for name, limit := range map[string]*int{
+		"TX_PARSER_RETENTION_MAX_PER_ADDRESS": &policy.MaxPerAddress,
+		"TX_PARSER_RETENTION_MAX_TOTAL":       &policy.MaxTotal,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*limit = n
+	}
+	return policy, nil
+}
+
+// smtpConfigFromEnv read the TX_PARSER_SMTP_* variables
+func smtpConfigFromEnv() (notification.SMTPConfig, error) {
+	config := notification.SMTPConfig{
+		Host:     os.Getenv("TX_PARSER_SMTP_HOST"),
+		Username: os.Getenv("TX_PARSER_SMTP_USERNAME"),
+		Password: os.Getenv("TX_PARSER_SMTP_PASSWORD"),
+		From:     os.Getenv("TX_PARSER_SMTP_FROM"),
+		Security: notification.SMTPSecurity(os.Getenv("TX_PARSER_SMTP_SECURITY")),
+	}
+	if port := os.Getenv("TX_PARSER_SMTP_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return config, fmt.Errorf("invalid TX_PARSER_SMTP_PORT: %w", err)
+		}
+		config.Port = p
+	}
+	if recipients := os.Getenv("TX_PARSER_SMTP_RECIPIENTS"); recipients != "" {
+		config.DefaultRecipients = strings.Split(recipients, ",")
+	}
+	return config, nil
+}
+
+// openStorage open a durable storage when dataDir is set, otherwise data is lost on restart
+func openStorage(dataDir string, opts ...storage.Option) (storage.Storage, error) {
+	if dataDir == "" {
+		return storage.NewMemoryStorage(opts...), nil
+	}
+	return storage.OpenMemoryStorage(dataDir, opts...)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vdhieu/tx-parser/internal/storage"
+)
+
+const usage = `Usage: snapshot <command> [flags]
+
+Commands:
+  dump      write a snapshot of the storage to -file (stdout when empty)
+  restore   load a snapshot from -file (stdin when empty) into the storage
+
+The storage is the durable one in -data-dir, TX_PARSER_DATA_DIR by default.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd := os.Args[1]
+	if cmd != "dump" && cmd != "restore" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	file := fs.String("file", "", "snapshot file path")
+	dataDir := fs.String("data-dir", os.Getenv("TX_PARSER_DATA_DIR"), "data directory of the durable storage")
+	fs.Parse(os.Args[2:])
+	if *dataDir == "" {
+		fmt.Fprintln(os.Stderr, "-data-dir or TX_PARSER_DATA_DIR is required, the snapshot is dumped from or restored to the durable storage in it")
+		os.Exit(2)
+	}
+
+	s, err := storage.OpenMemoryStorage(*dataDir)
+	if err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			fmt.Fprintf(os.Stderr, "%v, stop the server before running snapshot\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "open storage failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cmd == "dump" {
+		err = dump(s, *file)
+	} else {
+		err = restore(s, *file)
+	}
+	// closing flush the restored data to the WAL of a durable storage
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+func dump(s storage.Storage, file string) error {
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return storage.Export(s, w)
+}
+
+func restore(s storage.Storage, file string) error {
+	var r io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return storage.Import(s, r)
+}
@@ -0,0 +1,171 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// unpaginatedListSize how many items a list without a page size is expected to hold
+const unpaginatedListSize = 10
+
+// fieldCosts cost of fields doing more than reading their parent, every other field cost 1
+var fieldCosts = map[string]int{
+	"Query.subscriptions":       2,
+	"Query.transactions":        5,
+	"Query.transaction":         5,
+	"Query.tokenTransfers":      5,
+	"Query.blocks":              20,
+	"Query.block":               20,
+	"Query.status":              2,
+	"Subscription.transactions": 5,
+}
+
+// analyze compute the cost and depth of the operation to run. A list field multiply the cost
+// of its selection by the page size it can return, so asking for nested pages is expensive
+func analyze(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]any) (int, int, error) {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				if operation != nil && operationName == "" {
+					return 0, 0, fmt.Errorf("operation name is required when the document has several operations")
+				}
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	a := &analyzer{fragments: fragments, variables: variables}
+	return a.selectionSet(schema.QueryType(), operation.SelectionSet, 1)
+}
+
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// selectionSet cost and max depth of set selected on parent at depth
+func (a *analyzer) selectionSet(parent *graphql.Object, set *ast.SelectionSet, depth int) (int, int, error) {
+	if set == nil {
+		return 0, depth - 1, nil
+	}
+	cost, maxDepth := 0, depth
+	for _, selection := range set.Selections {
+		var c, d int
+		var err error
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c, d, err = a.field(parent, selection, depth)
+		case *ast.InlineFragment:
+			c, d, err = a.selectionSet(parent, selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			fragment, ok := a.fragments[selection.Name.Value]
+			if !ok {
+				return 0, 0, fmt.Errorf("unknown fragment %q", selection.Name.Value)
+			}
+			c, d, err = a.selectionSet(parent, fragment.SelectionSet, depth)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		cost += c
+		maxDepth = max(maxDepth, d)
+	}
+	return cost, maxDepth, nil
+}
+
+func (a *analyzer) field(parent *graphql.Object, field *ast.Field, depth int) (int, int, error) {
+	name := field.Name.Value
+	def, ok := parent.Fields()[name]
+	if !ok {
+		// __typename and introspection fields
+		return 0, depth, nil
+	}
+
+	cost, ok := fieldCosts[parent.Name()+"."+name]
+	if !ok {
+		cost = 1
+	}
+
+	object, _ := namedType(def.Type).(*graphql.Object)
+	if object == nil {
+		return cost, depth, nil
+	}
+	childCost, childDepth, err := a.selectionSet(object, field.SelectionSet, depth+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cost + childCost*a.multiplier(parent, def, field), childDepth, nil
+}
+
+// multiplier how many times the selection of field can be resolved
+func (a *analyzer) multiplier(parent *graphql.Object, def *graphql.FieldDefinition, field *ast.Field) int {
+	var firstArg *graphql.Argument
+	for _, arg := range def.Args {
+		if arg.Name() == "first" {
+			firstArg = arg
+		}
+	}
+	if firstArg == nil {
+		// nodes of a connection were already counted with the page size of the connection
+		if _, ok := unwrap(def.Type).(*graphql.List); ok && !strings.HasSuffix(parent.Name(), "Connection") {
+			return unpaginatedListSize
+		}
+		return 1
+	}
+
+	first := defaultPageSize
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			first, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			first = intVariable(a.variables[value.Name.Value], defaultPageSize)
+		}
+	}
+	return max(min(first, maxPageSize), 1)
+}
+
+func intVariable(v any, fallback int) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return fallback
+}
+
+func unwrap(t graphql.Type) graphql.Type {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		return nonNull.OfType
+	}
+	return t
+}
+
+func namedType(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
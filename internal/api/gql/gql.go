@@ -0,0 +1,89 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	txparser "github.com/vdhieu/tx-parser/internal/parser"
+)
+
+const (
+	defaultMaxDepth = 8
+	defaultMaxCost  = 2000
+)
+
+// ErrQueryRejected returned when a query is invalid or over the limits, it is never executed
+var ErrQueryRejected = errors.New("query rejected")
+
+// Request a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string
+	OperationName string
+	Variables     map[string]any
+}
+
+// Executor run GraphQL queries against the parser
+type Executor interface {
+	// Execute run req on behalf of tenant, ErrQueryRejected is returned for malformed queries
+	// and queries over the depth or cost limits
+	Execute(ctx context.Context, tenant string, req Request) (*graphql.Result, error)
+}
+
+type executor struct {
+	schema   graphql.Schema
+	maxDepth int
+	maxCost  int
+}
+
+// NewExecutor create an executor resolving queries with p
+func NewExecutor(p txparser.Parser) (Executor, error) {
+	schema, err := newSchema(p)
+	if err != nil {
+		return nil, err
+	}
+	return &executor{
+		schema:   schema,
+		maxDepth: defaultMaxDepth,
+		maxCost:  defaultMaxCost,
+	}, nil
+}
+
+func (e *executor) Execute(ctx context.Context, tenant string, req Request) (*graphql.Result, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQueryRejected, err)
+	}
+	if result := graphql.ValidateDocument(&e.schema, doc, nil); !result.IsValid {
+		return nil, fmt.Errorf("%w: %s", ErrQueryRejected, result.Errors[0].Message)
+	}
+
+	cost, depth, err := analyze(e.schema, doc, req.OperationName, req.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQueryRejected, err)
+	}
+	if depth > e.maxDepth {
+		return nil, fmt.Errorf("%w: query depth %d is over the limit of %d", ErrQueryRejected, depth, e.maxDepth)
+	}
+	if cost > e.maxCost {
+		return nil, fmt.Errorf("%w: query cost %d is over the limit of %d", ErrQueryRejected, cost, e.maxCost)
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, tenantKey{}, tenant),
+	}), nil
+}
+
+func parse(query string) (*ast.Document, error) {
+	return parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+}
@@ -0,0 +1,267 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+func Test_executor_Execute(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	subs := []models.Subscription{
+		{Address: "0x1111111111111111111111111111111111111111", Tenant: "acme", Label: "hot wallet", Tags: []string{"ops"}, CreatedAt: createdAt},
+		{Address: "0x2222222222222222222222222222222222222222", Tenant: "acme", Tags: []string{}, CreatedAt: createdAt},
+	}
+	txs := []models.Transaction{
+		{Hash: "0xa", From: "0x1111111111111111111111111111111111111111", To: "0x2222222222222222222222222222222222222222", Value: "10", BlockNumber: "100", Timestamp: "1700000000"},
+		{Hash: "0xb", From: "0x3333333333333333333333333333333333333333", To: "0x1111111111111111111111111111111111111111", Value: "20", BlockNumber: "101", Timestamp: "1700000012"},
+	}
+
+	tests := []struct {
+		name      string
+		req       Request
+		setupMock func(m *mockParser.Parser)
+		maxDepth  int
+		want      string
+		wantErr   string
+		// wantFieldErr error returned by a resolver
+		wantFieldErr string
+	}{
+		{
+			name: "status",
+			req:  Request{Query: `{ status { tenant currentBlock subscriptionCount } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetCurrentBlock").Return(120)
+				m.On("ListSubscriptions", "acme").Return(subs)
+			},
+			want: `{"status":{"currentBlock":120,"subscriptionCount":2,"tenant":"acme"}}`,
+		},
+		{
+			name: "subscriptions page",
+			req:  Request{Query: `{ subscriptions(first: 1) { totalCount pageInfo { hasNextPage } nodes { address label tags createdAt } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("ListSubscriptions", "acme").Return(subs)
+			},
+			want: `{"subscriptions":{"nodes":[{"address":"0x1111111111111111111111111111111111111111","createdAt":"2024-01-02T03:04:05Z","label":"hot wallet","tags":["ops"]}],"pageInfo":{"hasNextPage":true},"totalCount":2}}`,
+		},
+		{
+			name: "transactions of an address with variables",
+			req: Request{
+				Query:     `query Txs($address: String!, $first: Int) { transactions(address: $address, first: $first, offset: 1) { totalCount nodes { hash blockNumber timestamp } } }`,
+				Variables: map[string]any{"address": "0x1111111111111111111111111111111111111111", "first": float64(1)},
+			},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionsPage", "acme", "0x1111111111111111111111111111111111111111", 1, 1).Return(txs[1:], 2, nil)
+			},
+			want: `{"transactions":{"nodes":[{"blockNumber":101,"hash":"0xb","timestamp":1700000012}],"totalCount":2}}`,
+		},
+		{
+			name: "newest transactions of a subscription",
+			req:  Request{Query: `{ subscription(address: "0x1111111111111111111111111111111111111111") { address transactions(first: 1, newestFirst: true) { nodes { hash } pageInfo { hasNextPage } } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", "acme", "0x1111111111111111111111111111111111111111").Return(subs[0], true)
+				m.On("GetTransactions", "acme", "0x1111111111111111111111111111111111111111").Return(txs)
+			},
+			want: `{"subscription":{"address":"0x1111111111111111111111111111111111111111","transactions":{"nodes":[{"hash":"0xb"}],"pageInfo":{"hasNextPage":true}}}}`,
+		},
+		{
+			name: "unknown subscription",
+			req:  Request{Query: `{ subscription(address: "0x9999999999999999999999999999999999999999") { address } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", "acme", "0x9999999999999999999999999999999999999999").Return(models.Subscription{}, false)
+			},
+			want: `{"subscription":null}`,
+		},
+		{
+			name: "transaction by hash",
+			req:  Request{Query: `{ transaction(hash: "0xa") { addresses confirmations transaction { from to value } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionByHash", "acme", "0xa").Return(models.TransactionLookup{
+					Transaction:   txs[0],
+					Addresses:     []string{"0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"},
+					Confirmations: 21,
+				}, true)
+			},
+			want: `{"transaction":{"addresses":["0x1111111111111111111111111111111111111111","0x2222222222222222222222222222222222222222"],"confirmations":21,"transaction":{"from":"0x1111111111111111111111111111111111111111","to":"0x2222222222222222222222222222222222222222","value":"10"}}}`,
+		},
+		{
+			name: "blocks newest first without duplicated txn",
+			req:  Request{Query: `{ blocks(first: 5) { totalCount nodes { number transactionCount transactions { hash } } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("ListSubscriptions", "acme").Return(subs)
+				m.On("GetTransactions", "acme", "0x1111111111111111111111111111111111111111").Return(txs)
+				m.On("GetTransactions", "acme", "0x2222222222222222222222222222222222222222").Return(txs[:1])
+			},
+			want: `{"blocks":{"nodes":[{"number":101,"transactionCount":1,"transactions":[{"hash":"0xb"}]},{"number":100,"transactionCount":1,"transactions":[{"hash":"0xa"}]}],"totalCount":2}}`,
+		},
+		{
+			name: "addresses are checksummed",
+			req:  Request{Query: `{ transaction(hash: "0xc") { addresses transaction { from } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionByHash", "acme", "0xc").Return(models.TransactionLookup{
+					Transaction: models.Transaction{Hash: "0xc", From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", BlockNumber: "1", Timestamp: "1"},
+					Addresses:   []string{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+				}, true)
+			},
+			want: `{"transaction":{"addresses":["0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"],"transaction":{"from":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}}}`,
+		},
+		{
+			name: "token transfers of an address",
+			req:  Request{Query: `{ tokenTransfers(address: "0x1111111111111111111111111111111111111111") { totalCount nodes { hash tokenTransfer { token from to amount } } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				transfer := models.Transaction{
+					Hash: "0xc", From: "0x1111111111111111111111111111111111111111", To: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", Value: "0",
+					BlockNumber: "102", Timestamp: "1700000024",
+					TokenTransfer: &models.TokenTransfer{
+						Token:  "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+						From:   "0x1111111111111111111111111111111111111111",
+						To:     "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+						Amount: "1000000",
+					},
+				}
+				m.On("GetTransactions", "acme", "0x1111111111111111111111111111111111111111").Return(append(txs, transfer))
+			},
+			want: `{"tokenTransfers":{"nodes":[{"hash":"0xc","tokenTransfer":{"amount":"1000000","from":"0x1111111111111111111111111111111111111111",` +
+				`"to":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed","token":"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"}}],"totalCount":1}}`,
+		},
+		{
+			name: "eth transfer has no token transfer",
+			req:  Request{Query: `{ transaction(hash: "0xa") { transaction { tokenTransfer { amount } } } }`},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionByHash", "acme", "0xa").Return(models.TransactionLookup{Transaction: txs[0]}, true)
+			},
+			want: `{"transaction":{"transaction":{"tokenTransfer":null}}}`,
+		},
+		{
+			name:         "empty page",
+			req:          Request{Query: `{ transactions(address: "0x1111111111111111111111111111111111111111", first: 0) { totalCount } }`},
+			want:         `null`,
+			wantFieldErr: "first must be at least 1",
+		},
+		{
+			name:         "negative offset",
+			req:          Request{Query: `{ subscriptions(offset: -1) { totalCount } }`},
+			want:         `null`,
+			wantFieldErr: "offset must not be negative",
+		},
+		{
+			name:         "invalid address argument",
+			req:          Request{Query: `{ subscription(address: "hello") { address } }`},
+			want:         `{"subscription":null}`,
+			wantFieldErr: "invalid address",
+		},
+		{
+			name:    "syntax error",
+			req:     Request{Query: `{ status {`},
+			wantErr: "query rejected",
+		},
+		{
+			name:    "unknown field",
+			req:     Request{Query: `{ secrets }`},
+			wantErr: `Cannot query field "secrets" on type "Query".`,
+		},
+		{
+			name:    "over the cost limit",
+			req:     Request{Query: `{ subscriptions(first: 100) { nodes { transactions(first: 100) { nodes { hash } } } } }`},
+			wantErr: "query cost 20602 is over the limit of 2000",
+		},
+		{
+			name:     "over the depth limit",
+			req:      Request{Query: `{ subscriptions(first: 1) { nodes { transactions(first: 1) { nodes { hash } } } } }`},
+			maxDepth: 4,
+			wantErr:  "query depth 5 is over the limit of 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockParser.NewParser(t)
+			if tt.setupMock != nil {
+				tt.setupMock(m)
+			}
+			e, err := NewExecutor(m)
+			require.NoError(t, err)
+			if tt.maxDepth > 0 {
+				e.(*executor).maxDepth = tt.maxDepth
+			}
+
+			result, err := e.Execute(context.Background(), "acme", tt.req)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, ErrQueryRejected)
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantFieldErr != "" {
+				require.Len(t, result.Errors, 1)
+				require.Contains(t, result.Errors[0].Message, tt.wantFieldErr)
+			} else {
+				require.Empty(t, result.Errors)
+			}
+			got, err := json.Marshal(result.Data)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_analyze(t *testing.T) {
+	schema, err := newSchema(mockParser.NewParser(t))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		wantCost  int
+		wantDepth int
+	}{
+		{
+			name:      "scalar field",
+			query:     `{ currentBlock }`,
+			wantCost:  1,
+			wantDepth: 1,
+		},
+		{
+			name:      "default page size",
+			query:     `{ subscriptions { totalCount nodes { address } } }`,
+			wantCost:  2 + 20*(1+1+1),
+			wantDepth: 3,
+		},
+		{
+			name:      "page size from variable",
+			query:     `query ($n: Int) { subscriptions(first: $n) { nodes { address } } }`,
+			variables: map[string]any{"n": float64(3)},
+			wantCost:  2 + 3*(1+1),
+			wantDepth: 3,
+		},
+		{
+			name:      "page size is capped",
+			query:     `{ blocks(first: 1000) { nodes { number } } }`,
+			wantCost:  20 + 100*(1+1),
+			wantDepth: 3,
+		},
+		{
+			name:      "unpaginated list in fragment",
+			query:     `{ block(number: 1) { ...B } } fragment B on Block { transactions { hash } }`,
+			wantCost:  20 + 1 + 10*1,
+			wantDepth: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse(tt.query)
+			require.NoError(t, err)
+			cost, depth, err := analyze(schema, doc, "", tt.variables)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantCost, cost)
+			require.Equal(t, tt.wantDepth, depth)
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/vdhieu/tx-parser/internal/models"
+	txparser "github.com/vdhieu/tx-parser/internal/parser"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type tenantKey struct{}
+
+// page one page of a list field
+type page struct {
+	total int
+	nodes any
+	next  bool
+}
+
+// block summary of the txn the tenant has in a block
+type block struct {
+	number       int64
+	transactions []models.Transaction
+}
+
+func tenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	if tenant == "" {
+		return models.DefaultTenant
+	}
+	return tenant
+}
+
+// pageArgs read first and offset, first is capped to maxPageSize
+func pageArgs(p graphql.ResolveParams) (int, int, error) {
+	first, _ := p.Args["first"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if first < 1 {
+		return 0, 0, errors.New("first must be at least 1")
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	if first > maxPageSize {
+		first = maxPageSize
+	}
+	return first, offset, nil
+}
+
+func paginate[T any](items []T, first, offset int) page {
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := min(offset+first, len(items))
+	return page{total: len(items), nodes: items[offset:end], next: end < len(items)}
+}
+
+var pageArgsConfig = graphql.FieldConfigArgument{
+	"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "page size, at most 100"},
+	"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+}
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(page).next, nil
+		}},
+	},
+})
+
+// connection a paginated list of node
+func connection(name string, node graphql.Type) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(page).total, nil
+			}},
+			"nodes": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node))), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(page).nodes, nil
+			}},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source, nil
+			}},
+		},
+	})
+}
+
+var tokenTransferType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "TokenTransfer",
+	Description: "an ERC-20 transfer or transferFrom call, addresses are EIP-55 checksummed",
+	Fields: graphql.Fields{
+		"token":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "token contract called"},
+		"from":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"to":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "amount in the smallest unit of the token as a decimal string"},
+	},
+})
+
+var transactionType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Transaction",
+	Description: "a transaction sent from or to a subscribed address",
+	Fields: graphql.Fields{
+		"hash": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"from": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return models.ChecksumAddress(p.Source.(models.Transaction).From), nil
+		}},
+		"to": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return models.ChecksumAddress(p.Source.(models.Transaction).To), nil
+		}},
+		"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "value in wei as a decimal string"},
+		"blockNumber": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return strconv.Atoi(p.Source.(models.Transaction).BlockNumber)
+		}},
+		"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "unix seconds", Resolve: func(p graphql.ResolveParams) (any, error) {
+			return strconv.Atoi(p.Source.(models.Transaction).Timestamp)
+		}},
+		"tokenTransfer": &graphql.Field{
+			Type:        tokenTransferType,
+			Description: "set when the transaction is an ERC-20 transfer or transferFrom call",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				transfer := p.Source.(models.Transaction).Checksummed().TokenTransfer
+				if transfer == nil {
+					return nil, nil
+				}
+				return *transfer, nil
+			},
+		},
+	},
+})
+
+var transactionConnectionType = connection("TransactionConnection", transactionType)
+
+var transactionLookupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TransactionLookup",
+	Fields: graphql.Fields{
+		"transaction": &graphql.Field{Type: graphql.NewNonNull(transactionType), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(models.TransactionLookup).Transaction, nil
+		}},
+		"addresses": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "subscribed addresses involved in the transaction",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return models.ChecksumAddresses(p.Source.(models.TransactionLookup).Addresses), nil
+			},
+		},
+		"confirmations": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var blockType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Block",
+	Description: "a parsed block with the transactions of the tenant in it",
+	Fields: graphql.Fields{
+		"number": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(block).number, nil
+		}},
+		"transactionCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return len(p.Source.(block).transactions), nil
+		}},
+		"transactions": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(block).transactions, nil
+		}},
+	},
+})
+
+func newSchema(parser txparser.Parser) (graphql.Schema, error) {
+	// transactions page of a subscribed address, newest first when asked
+	transactionsPage := func(tenant, address string, first, offset int, newestFirst bool) (page, error) {
+		if !newestFirst {
+			txs, total, err := parser.GetTransactionsPage(tenant, address, offset, first)
+			if err != nil {
+				return page{}, err
+			}
+			return page{total: total, nodes: txs, next: offset+len(txs) < total}, nil
+		}
+		txs := parser.GetTransactions(tenant, address)
+		reversed := make([]models.Transaction, len(txs))
+		for i, tx := range txs {
+			reversed[len(txs)-1-i] = tx
+		}
+		return paginate(reversed, first, offset), nil
+	}
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "EIP-55 checksummed", Resolve: func(p graphql.ResolveParams) (any, error) {
+				return models.ChecksumAddress(p.Source.(models.Subscription).Address), nil
+			}},
+			"label":      &graphql.Field{Type: graphql.String},
+			"owner":      &graphql.Field{Type: graphql.String},
+			"tags":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"startBlock": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "RFC 3339", Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(models.Subscription).CreatedAt.Format(time.RFC3339), nil
+			}},
+			"muted": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(models.Subscription).Notifications.Muted, nil
+			}},
+			"transactions": &graphql.Field{
+				Type: graphql.NewNonNull(transactionConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"first":       pageArgsConfig["first"],
+					"offset":      pageArgsConfig["offset"],
+					"newestFirst": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, offset, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					sub := p.Source.(models.Subscription)
+					newestFirst, _ := p.Args["newestFirst"].(bool)
+					return transactionsPage(sub.Tenant, sub.Address, first, offset, newestFirst)
+				},
+			},
+		},
+	})
+
+	// blocks of the tenant txn, newest first
+	blocks := func(tenant string) []block {
+		seen := make(map[string]bool)
+		byNumber := make(map[int64]*block)
+		for _, sub := range parser.ListSubscriptions(tenant) {
+			for _, tx := range parser.GetTransactions(tenant, sub.Address) {
+				if seen[tx.Hash] {
+					continue
+				}
+				seen[tx.Hash] = true
+				number, err := strconv.ParseInt(tx.BlockNumber, 10, 64)
+				if err != nil {
+					continue
+				}
+				if byNumber[number] == nil {
+					byNumber[number] = &block{number: number}
+				}
+				byNumber[number].transactions = append(byNumber[number].transactions, tx)
+			}
+		}
+		result := make([]block, 0, len(byNumber))
+		for _, b := range byNumber {
+			result = append(result, *b)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].number > result[j].number })
+		return result
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+					Name: "Status",
+					Fields: graphql.Fields{
+						"tenant":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+						"currentBlock":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+						"subscriptionCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+					},
+				})),
+				Description: "parser status as seen by the tenant",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					tenant := tenantFrom(p.Context)
+					return map[string]any{
+						"tenant":            tenant,
+						"currentBlock":      parser.GetCurrentBlock(),
+						"subscriptionCount": len(parser.ListSubscriptions(tenant)),
+					}, nil
+				},
+			},
+			"currentBlock": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "last parsed block",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return parser.GetCurrentBlock(), nil
+				},
+			},
+			"subscriptions": &graphql.Field{
+				Type: graphql.NewNonNull(connection("SubscriptionConnection", subscriptionType)),
+				Args: pageArgsConfig,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, offset, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					return paginate(parser.ListSubscriptions(tenantFrom(p.Context)), first, offset), nil
+				},
+			},
+			"subscription": &graphql.Field{
+				Type: subscriptionType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					address, err := models.NormalizeAddress(p.Args["address"].(string))
+					if err != nil {
+						return nil, err
+					}
+					sub, ok := parser.GetSubscription(tenantFrom(p.Context), address)
+					if !ok {
+						return nil, nil
+					}
+					return sub, nil
+				},
+			},
+			"transactions": &graphql.Field{
+				Type: graphql.NewNonNull(transactionConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"address":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"first":       pageArgsConfig["first"],
+					"offset":      pageArgsConfig["offset"],
+					"newestFirst": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, offset, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					address, err := models.NormalizeAddress(p.Args["address"].(string))
+					if err != nil {
+						return nil, err
+					}
+					newestFirst, _ := p.Args["newestFirst"].(bool)
+					return transactionsPage(tenantFrom(p.Context), address, first, offset, newestFirst)
+				},
+			},
+			"tokenTransfers": &graphql.Field{
+				Type: graphql.NewNonNull(transactionConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"address":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"first":       pageArgsConfig["first"],
+					"offset":      pageArgsConfig["offset"],
+					"newestFirst": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Description: "transactions of a subscribed address which are ERC-20 transfers",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, offset, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					address, err := models.NormalizeAddress(p.Args["address"].(string))
+					if err != nil {
+						return nil, err
+					}
+					transfers := []models.Transaction{}
+					for _, tx := range parser.GetTransactions(tenantFrom(p.Context), address) {
+						if tx.TokenTransfer != nil {
+							transfers = append(transfers, tx)
+						}
+					}
+					if newestFirst, _ := p.Args["newestFirst"].(bool); newestFirst {
+						slices.Reverse(transfers)
+					}
+					return paginate(transfers, first, offset), nil
+				},
+			},
+			"transaction": &graphql.Field{
+				Type: transactionLookupType,
+				Args: graphql.FieldConfigArgument{
+					"hash": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					lookup, ok := parser.GetTransactionByHash(tenantFrom(p.Context), p.Args["hash"].(string))
+					if !ok {
+						return nil, nil
+					}
+					return lookup, nil
+				},
+			},
+			"blocks": &graphql.Field{
+				Type:        graphql.NewNonNull(connection("BlockConnection", blockType)),
+				Args:        pageArgsConfig,
+				Description: "blocks with transactions of the tenant, newest first",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, offset, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					return paginate(blocks(tenantFrom(p.Context)), first, offset), nil
+				},
+			},
+			"block": &graphql.Field{
+				Type: blockType,
+				Args: graphql.FieldConfigArgument{
+					"number": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					number := int64(p.Args["number"].(int))
+					for _, b := range blocks(tenantFrom(p.Context)) {
+						if b.number == number {
+							return b, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
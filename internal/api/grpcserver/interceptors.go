@@ -0,0 +1,131 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	txparserv1 "github.com/vdhieu/tx-parser/pkg/pb/txparser/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyMetadata = "x-api-key"
+	tenantMetadata = "x-tenant-id"
+)
+
+// methodScopes scope required by every method, methods missing here are rejected
+var methodScopes = map[string]string{
+	txparserv1.TxParserService_GetCurrentBlock_FullMethodName:   models.ScopeRead,
+	txparserv1.TxParserService_Subscribe_FullMethodName:         models.ScopeSubscribe,
+	txparserv1.TxParserService_Unsubscribe_FullMethodName:       models.ScopeSubscribe,
+	txparserv1.TxParserService_GetTransactions_FullMethodName:   models.ScopeRead,
+	txparserv1.TxParserService_WatchTransactions_FullMethodName: models.ScopeRead,
+}
+
+type tenantKey struct{}
+
+// tenantFromContext return the tenant the call is scoped to
+func tenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	return models.DefaultTenant
+}
+
+// guard authenticate, authorize and rate limit calls the same way as the REST API
+type guard struct {
+	auth    auth.Service
+	limiter auth.RateLimiter
+}
+
+func (g *guard) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := g.check(ctx, info.FullMethod, func(md metadata.MD) error {
+		return grpc.SetHeader(ctx, md)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *guard) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := g.check(ss.Context(), info.FullMethod, ss.SetHeader)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+}
+
+// check return the context of an authorized call, setHeader send the rate limit headers
+func (g *guard) check(ctx context.Context, method string, setHeader func(metadata.MD) error) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	rawKey := first(md, apiKeyMetadata)
+	if bearer, ok := strings.CutPrefix(first(md, "authorization"), "Bearer "); ok {
+		rawKey = strings.TrimSpace(bearer)
+	}
+	if rawKey == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing api key")
+	}
+
+	key, err := g.auth.Authenticate(rawKey)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidKey) {
+			logger.GetLogger().Error("Failed to authenticate api key", zap.Error(err))
+			return nil, status.Error(codes.Internal, "unable to authenticate")
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+
+	scope, ok := methodScopes[method]
+	if !ok || !key.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "api key is missing the %s scope", scope)
+	}
+
+	limit := g.limiter.Take(key)
+	header := metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(limit.Limit),
+		"ratelimit-remaining", strconv.Itoa(limit.Remaining),
+		"ratelimit-reset", strconv.Itoa(limit.Reset),
+	)
+	if !limit.Allowed {
+		header.Set("retry-after", strconv.Itoa(limit.Reset))
+	}
+	if err := setHeader(header); err != nil {
+		return nil, err
+	}
+	if !limit.Allowed {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	tenant := key.Tenant
+	if override := first(md, tenantMetadata); override != "" && key.HasScope(models.ScopeAdmin) {
+		tenant = override
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant), nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// tenantStream a server stream carrying the context of an authorized call
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,246 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	txparserv1 "github.com/vdhieu/tx-parser/pkg/pb/txparser/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type txParserServer struct {
+	txparserv1.UnimplementedTxParserServiceServer
+	parser parser.Parser
+	broker stream.Broker
+}
+
+// NewServer create a gRPC server exposing p, calls are authenticated and rate limited
+// like the REST API
+func NewServer(p parser.Parser, a auth.Service, limiter auth.RateLimiter, b stream.Broker) *grpc.Server {
+	g := &guard{auth: a, limiter: limiter}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(g.unary),
+		grpc.StreamInterceptor(g.stream),
+	)
+	txparserv1.RegisterTxParserServiceServer(srv, &txParserServer{parser: p, broker: b})
+	return srv
+}
+
+func (s *txParserServer) GetCurrentBlock(ctx context.Context, req *txparserv1.GetCurrentBlockRequest) (*txparserv1.GetCurrentBlockResponse, error) {
+	return &txparserv1.GetCurrentBlockResponse{Block: int64(s.parser.GetCurrentBlock())}, nil
+}
+
+func (s *txParserServer) Subscribe(ctx context.Context, req *txparserv1.SubscribeRequest) (*txparserv1.SubscribeResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	address, err := models.NormalizeAddress(req.GetAddress())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	in := models.SubscriptionInput{
+		Address:    address,
+		Label:      req.GetLabel(),
+		Owner:      req.GetOwner(),
+		Tags:       req.GetTags(),
+		StartBlock: req.GetStartBlock(),
+	}
+	if n := req.GetNotifications(); n != nil {
+		in.Notifications = toNotificationInput(n)
+	}
+	sub, err := in.Subscription(tenantFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !s.parser.Subscribe(sub) {
+		return nil, status.Error(codes.Internal, "unable to subscribe")
+	}
+	return &txparserv1.SubscribeResponse{}, nil
+}
+
+func (s *txParserServer) Unsubscribe(ctx context.Context, req *txparserv1.UnsubscribeRequest) (*txparserv1.UnsubscribeResponse, error) {
+	address, err := models.NormalizeAddress(req.GetAddress())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !s.parser.Unsubscribe(tenantFromContext(ctx), address) {
+		return nil, status.Error(codes.NotFound, "subscription not found")
+	}
+	return &txparserv1.UnsubscribeResponse{}, nil
+}
+
+func (s *txParserServer) GetTransactions(ctx context.Context, req *txparserv1.GetTransactionsRequest) (*txparserv1.GetTransactionsResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	address, err := models.NormalizeAddress(req.GetAddress())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	txs, total, err := s.parser.GetTransactionsPage(tenantFromContext(ctx), address, int(req.GetOffset()), int(req.GetLimit()))
+	switch {
+	case errors.Is(err, parser.ErrNotSubscribed):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrInvalidPage):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "unable to get transactions")
+	}
+
+	resp := &txparserv1.GetTransactionsResponse{
+		Transactions: make([]*txparserv1.Transaction, 0, len(txs)),
+		Total:        int32(total),
+	}
+	for _, tx := range txs {
+		resp.Transactions = append(resp.Transactions, toTransaction(tx))
+	}
+	return resp, nil
+}
+
+func (s *txParserServer) WatchTransactions(req *txparserv1.WatchTransactionsRequest, srv txparserv1.TxParserService_WatchTransactionsServer) error {
+	ctx := srv.Context()
+	tenant := tenantFromContext(ctx)
+
+	addresses := make(map[string]bool, len(req.GetAddresses()))
+	for _, raw := range req.GetAddresses() {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		addr, err := models.NormalizeAddress(raw)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s: %v", strings.TrimSpace(raw), err)
+		}
+		if _, found := s.parser.GetSubscription(tenant, addr); !found {
+			return status.Errorf(codes.NotFound, "address %s is not subscribed", models.ChecksumAddress(addr))
+		}
+		addresses[addr] = true
+	}
+	if len(addresses) == 0 {
+		return status.Error(codes.InvalidArgument, "addresses are required")
+	}
+
+	// subscribe before reading the history so no txn is missed in between
+	events, unsubscribe := s.broker.Subscribe()
+	defer unsubscribe()
+
+	replayed := make(map[string]bool)
+	if req.GetLastEventId() != "" {
+		history := make(map[string][]models.Transaction, len(addresses))
+		for addr := range addresses {
+			history[addr] = s.parser.GetTransactions(tenant, addr)
+		}
+		replay, err := stream.Replay(history, req.GetLastEventId())
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		for _, event := range replay {
+			replayed[event.Transaction.Hash] = true
+			if err := srv.Send(toWatchResponse(event)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "consumer too slow, resume with last_event_id")
+			}
+			event, ok = stream.Filter(event, addresses)
+			if !ok || (event.Type == stream.EventTransaction && replayed[event.Transaction.Hash]) {
+				continue
+			}
+			resp := toWatchResponse(event)
+			if resp == nil {
+				continue
+			}
+			if err := srv.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toTransaction(tx models.Transaction) *txparserv1.Transaction {
+	blockNumber, _ := strconv.ParseInt(tx.BlockNumber, 10, 64)
+	timestamp, _ := strconv.ParseInt(tx.Timestamp, 10, 64)
+	return &txparserv1.Transaction{
+		Hash:        tx.Hash,
+		From:        models.ChecksumAddress(tx.From),
+		To:          models.ChecksumAddress(tx.To),
+		Value:       tx.Value,
+		BlockNumber: blockNumber,
+		Timestamp:   timestamp,
+	}
+}
+
+// toWatchResponse convert a stream event, nil for events not sent to watchers
+func toWatchResponse(event stream.Event) *txparserv1.WatchTransactionsResponse {
+	switch event.Type {
+	case stream.EventTransaction:
+		return &txparserv1.WatchTransactionsResponse{
+			Event: &txparserv1.WatchTransactionsResponse_Transaction{Transaction: &txparserv1.TransactionEvent{
+				Id:          stream.EventID(event.Transaction),
+				Addresses:   models.ChecksumAddresses(event.Addresses),
+				Transaction: toTransaction(event.Transaction),
+			}},
+		}
+	case stream.EventReorg:
+		return &txparserv1.WatchTransactionsResponse{
+			Event: &txparserv1.WatchTransactionsResponse_Reorg{Reorg: &txparserv1.ReorgEvent{
+				BlockNumber: event.Reorg.BlockNumber,
+				OldHash:     event.Reorg.OldHash,
+				NewHash:     event.Reorg.NewHash,
+			}},
+		}
+	}
+	return nil
+}
+
+func toNotificationInput(n *txparserv1.NotificationPreferences) *models.NotificationInput {
+	in := &models.NotificationInput{
+		Muted:      n.GetMuted(),
+		WebhookURL: n.GetWebhookUrl(),
+		Emails:     n.GetEmails(),
+	}
+	for _, route := range n.GetRoutes() {
+		r := models.NotificationRoute{Channel: models.NotificationChannel(route.GetChannel())}
+		for _, eventType := range route.GetEventTypes() {
+			r.EventTypes = append(r.EventTypes, models.NotificationEventType(eventType))
+		}
+		in.Routes = append(in.Routes, r)
+	}
+	if d := n.GetDigest(); d != nil {
+		in.Digest = &models.DigestInput{Mode: models.DigestMode(d.GetMode()), IntervalMinutes: int(d.GetIntervalMinutes()), DailyAt: d.GetDailyAt()}
+	}
+	if t := n.GetThrottle(); t != nil {
+		in.Throttle = &models.ThrottleInput{Limit: int(t.GetLimit()), PeriodMinutes: int(t.GetPeriodMinutes())}
+	}
+	for _, rule := range n.GetRules() {
+		r := models.AlertRule{Name: rule.GetName()}
+		for _, c := range rule.GetConditions() {
+			r.Conditions = append(r.Conditions, models.AlertCondition{
+				Type:      models.AlertConditionType(c.GetType()),
+				Value:     c.GetValue(),
+				Contracts: c.GetContracts(),
+				Method:    c.GetMethod(),
+			})
+		}
+		in.Rules = append(in.Rules, r)
+	}
+	return in
+}
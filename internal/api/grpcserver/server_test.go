@@ -0,0 +1,239 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+	txparserv1 "github.com/vdhieu/tx-parser/pkg/pb/txparser/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	addrA = "0x1231231231231231231231231231231231231231"
+	addrB = "0x4564564564564564564564564564564564564564"
+	addrC = "0x9999999999999999999999999999999999999999"
+)
+
+type testEnv struct {
+	client txparserv1.TxParserServiceClient
+	parser *mockParser.Parser
+	broker stream.Broker
+	keys   map[string]string
+}
+
+// newTestEnv serve the gRPC API in memory, keys hold a raw key per scope
+func newTestEnv(t *testing.T, rateLimit int) *testEnv {
+	t.Helper()
+	authService := auth.NewService(storage.NewMemoryStorage())
+	keys := make(map[string]string)
+	for _, scope := range []string{models.ScopeRead, models.ScopeSubscribe, models.ScopeAdmin} {
+		raw, _, err := authService.CreateKey(models.APIKey{Tenant: "payments", Scopes: []string{scope}, RateLimit: rateLimit})
+		require.NoError(t, err)
+		keys[scope] = raw
+	}
+
+	p := mockParser.NewParser(t)
+	broker := stream.NewBroker()
+	srv := NewServer(p, authService, auth.NewRateLimiter(rateLimit), broker)
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &testEnv{client: txparserv1.NewTxParserServiceClient(conn), parser: p, broker: broker, keys: keys}
+}
+
+func (e *testEnv) ctx(scope string, pairs ...string) context.Context {
+	pairs = append(pairs, "authorization", "Bearer "+e.keys[scope])
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+func TestServer_auth(t *testing.T) {
+	env := newTestEnv(t, 1)
+	env.parser.On("GetCurrentBlock").Return(100)
+
+	_, err := env.client.GetCurrentBlock(context.Background(), &txparserv1.GetCurrentBlockRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-api-key", "txp_unknown"))
+	_, err = env.client.GetCurrentBlock(ctx, &txparserv1.GetCurrentBlockRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = env.client.Unsubscribe(env.ctx(models.ScopeRead), &txparserv1.UnsubscribeRequest{Address: addrA})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	var header metadata.MD
+	resp, err := env.client.GetCurrentBlock(env.ctx(models.ScopeRead), &txparserv1.GetCurrentBlockRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), resp.GetBlock())
+	require.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+	require.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	// the key allow a single request per minute
+	_, err = env.client.GetCurrentBlock(env.ctx(models.ScopeRead), &txparserv1.GetCurrentBlockRequest{}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"60"}, header.Get("retry-after"))
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	env := newTestEnv(t, 60)
+	env.parser.On("Subscribe", mock.MatchedBy(func(sub models.Subscription) bool {
+		return sub.Address == addrA && sub.Tenant == "payments" && sub.Label == "cold wallet" &&
+			sub.Notifications.Emails[0] == "ops@example.com" &&
+			sub.Notifications.Routes[0].Channel == models.ChannelEmail &&
+			sub.Notifications.Routes[0].EventTypes[0] == models.EventConfirmed &&
+			sub.Notifications.Digest == models.DigestPreferences{Mode: models.DigestDaily, DailyAt: 7 * time.Hour} &&
+			sub.Notifications.Throttle == models.Throttle{Limit: 5, Period: 10 * time.Minute} &&
+			sub.Notifications.Rules[0].Name == "usdc" &&
+			sub.Notifications.Rules[0].Conditions[0].Contracts[0] == "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	})).Return(true)
+	// admin keys may act on behalf of another tenant
+	env.parser.On("Subscribe", mock.MatchedBy(func(sub models.Subscription) bool {
+		return sub.Tenant == "treasury"
+	})).Return(false)
+
+	_, err := env.client.Subscribe(env.ctx(models.ScopeSubscribe, "x-tenant-id", "ignored"), &txparserv1.SubscribeRequest{
+		Address: addrA,
+		Label:   "cold wallet",
+		Notifications: &txparserv1.NotificationPreferences{
+			Emails:   []string{"ops@example.com"},
+			Routes:   []*txparserv1.NotificationRoute{{Channel: "email", EventTypes: []string{"confirmed"}}},
+			Digest:   &txparserv1.Digest{Mode: "daily", DailyAt: "07:00"},
+			Throttle: &txparserv1.Throttle{Limit: 5, PeriodMinutes: 10},
+			Rules: []*txparserv1.AlertRule{{Name: "usdc", Conditions: []*txparserv1.AlertCondition{
+				{Type: "contract", Contracts: []string{"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"}},
+			}}},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeSubscribe), &txparserv1.SubscribeRequest{
+		Address: addrA,
+		Notifications: &txparserv1.NotificationPreferences{Rules: []*txparserv1.AlertRule{
+			{Name: "gas", Conditions: []*txparserv1.AlertCondition{{Type: "gas_above"}}},
+		}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeSubscribe), &txparserv1.SubscribeRequest{
+		Address:       addrA,
+		Notifications: &txparserv1.NotificationPreferences{Digest: &txparserv1.Digest{Mode: "daily", DailyAt: "7am"}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeSubscribe), &txparserv1.SubscribeRequest{
+		Address:       addrA,
+		Notifications: &txparserv1.NotificationPreferences{Routes: []*txparserv1.NotificationRoute{{Channel: "pager"}}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeAdmin, "x-tenant-id", "treasury"), &txparserv1.SubscribeRequest{Address: addrA})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeSubscribe), &txparserv1.SubscribeRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = env.client.Subscribe(env.ctx(models.ScopeSubscribe), &txparserv1.SubscribeRequest{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "invalid address checksum")
+}
+
+func TestServer_Unsubscribe(t *testing.T) {
+	env := newTestEnv(t, 60)
+	env.parser.On("Unsubscribe", "payments", addrA).Return(true)
+	env.parser.On("Unsubscribe", "payments", addrB).Return(false)
+
+	_, err := env.client.Unsubscribe(env.ctx(models.ScopeSubscribe), &txparserv1.UnsubscribeRequest{Address: addrA})
+	require.NoError(t, err)
+	_, err = env.client.Unsubscribe(env.ctx(models.ScopeSubscribe), &txparserv1.UnsubscribeRequest{Address: addrB})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_GetTransactions(t *testing.T) {
+	env := newTestEnv(t, 60)
+	tx := models.Transaction{Hash: "0xabc", From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", To: addrB, Value: "1000", BlockNumber: "101", Timestamp: "1700000000"}
+	env.parser.On("GetTransactionsPage", "payments", addrA, 10, 1).Return([]models.Transaction{tx}, 11, nil)
+	env.parser.On("GetTransactionsPage", "payments", addrB, 0, 0).Return(nil, 0, parser.ErrNotSubscribed)
+	env.parser.On("GetTransactionsPage", "payments", addrA, -1, 0).Return(nil, 0, storage.ErrInvalidPage)
+
+	resp, err := env.client.GetTransactions(env.ctx(models.ScopeRead), &txparserv1.GetTransactionsRequest{Address: addrA, Offset: 10, Limit: 1})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&txparserv1.GetTransactionsResponse{
+		Transactions: []*txparserv1.Transaction{{
+			Hash: "0xabc", From: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", To: addrB, Value: "1000", BlockNumber: 101, Timestamp: 1700000000,
+		}},
+		Total: 11,
+	}, resp))
+
+	_, err = env.client.GetTransactions(env.ctx(models.ScopeRead), &txparserv1.GetTransactionsRequest{Address: addrB})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = env.client.GetTransactions(env.ctx(models.ScopeRead), &txparserv1.GetTransactionsRequest{Address: addrA, Offset: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = env.client.GetTransactions(env.ctx(models.ScopeRead), &txparserv1.GetTransactionsRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_WatchTransactions(t *testing.T) {
+	env := newTestEnv(t, 60)
+	tx100 := models.Transaction{Hash: "0x100", From: addrA, BlockNumber: "100"}
+	tx101 := models.Transaction{Hash: "0x101", From: addrA, BlockNumber: "101"}
+	tx102 := models.Transaction{Hash: "0x102", To: addrA, BlockNumber: "102"}
+	env.parser.On("GetSubscription", "payments", addrA).Return(models.Subscription{Address: addrA}, true)
+	env.parser.On("GetSubscription", "payments", addrB).Return(models.Subscription{}, false)
+	env.parser.On("GetTransactions", "payments", addrA).Return([]models.Transaction{tx100, tx101})
+
+	notSubscribed, err := env.client.WatchTransactions(env.ctx(models.ScopeRead), &txparserv1.WatchTransactionsRequest{Addresses: []string{addrB}})
+	require.NoError(t, err)
+	_, err = notSubscribed.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(env.ctx(models.ScopeRead), 5*time.Second)
+	defer cancel()
+	watch, err := env.client.WatchTransactions(ctx, &txparserv1.WatchTransactionsRequest{
+		Addresses:   []string{"0X1231231231231231231231231231231231231231"},
+		LastEventId: stream.EventID(tx100),
+	})
+	require.NoError(t, err)
+
+	// the missed txn is replayed first
+	resp, err := watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, stream.EventID(tx101), resp.GetTransaction().GetId())
+	require.Equal(t, "0x101", resp.GetTransaction().GetTransaction().GetHash())
+
+	// the server subscribed to the broker before replaying, live events are not lost
+	env.broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx101, Addresses: []string{addrA}})
+	env.broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx102, Addresses: []string{addrC, addrA}})
+	env.broker.Publish(stream.Event{Type: stream.EventReorg, Reorg: models.Reorg{BlockNumber: 102, OldHash: "0xa", NewHash: "0xb"}})
+
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, []string{addrA}, resp.GetTransaction().GetAddresses())
+	require.Equal(t, "0x102", resp.GetTransaction().GetTransaction().GetHash())
+
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&txparserv1.ReorgEvent{BlockNumber: 102, OldHash: "0xa", NewHash: "0xb"}, resp.GetReorg()))
+}
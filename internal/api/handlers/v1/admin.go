@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+)
+
+// PrunerMetricsSource the counters of the storage pruner, implemented by *storage.Pruner
+type PrunerMetricsSource interface {
+	Metrics() storage.PrunerMetrics
+}
+
+type AdminHandler struct {
+	auth   auth.Service
+	pruner PrunerMetricsSource
+}
+
+func NewAdminHandler(a auth.Service, pruner PrunerMetricsSource) *AdminHandler {
+	return &AdminHandler{auth: a, pruner: pruner}
+}
+
+// CreateAPIKey create a key, the raw key is only returned in this response
+func (h *AdminHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, CreateAPIKeyResponse{Error: "scopes are required"})
+		return
+	}
+
+	raw, key, err := h.auth.CreateKey(models.APIKey{
+		Name:      req.Name,
+		Tenant:    req.Tenant,
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, CreateAPIKeyResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, CreateAPIKeyResponse{Error: "unable to create api key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{Data: &CreateAPIKeyData{
+		APIKeyData: toAPIKeyData(key),
+		Key:        raw,
+	}})
+}
+
+func (h *AdminHandler) ListAPIKeys(c *gin.Context) {
+	keys := h.auth.ListKeys()
+	data := make([]APIKeyData, 0, len(keys))
+	for _, key := range keys {
+		data = append(data, toAPIKeyData(key))
+	}
+	c.JSON(http.StatusOK, APIKeysResponse{Data: data})
+}
+
+func (h *AdminHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.auth.RevokeKey(c.Param("id")); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, RevokeAPIKeyResponse{Error: "api key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, RevokeAPIKeyResponse{Error: "unable to revoke api key"})
+		return
+	}
+	c.JSON(http.StatusOK, RevokeAPIKeyResponse{Message: "api key revoked"})
+}
+
+func toAPIKeyData(key models.APIKey) APIKeyData {
+	return APIKeyData{
+		ID:        key.ID,
+		Name:      key.Name,
+		Tenant:    key.Tenant,
+		Scopes:    key.Scopes,
+		RateLimit: key.RateLimit,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// PrunerMetrics return the runs of the storage pruner and the txns it evicted since the server started
+func (h *AdminHandler) PrunerMetrics(c *gin.Context) {
+	metrics := h.pruner.Metrics()
+	c.JSON(http.StatusOK, PrunerMetricsResponse{Data: PrunerMetricsData{
+		Runs:                metrics.Runs,
+		Errors:              metrics.Errors,
+		EvictedByAge:        metrics.Evicted.ByAge,
+		EvictedByAddressCap: metrics.Evicted.ByAddressCap,
+		EvictedByTotalCap:   metrics.Evicted.ByTotalCap,
+	}})
+}
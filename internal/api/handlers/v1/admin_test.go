@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	mockAuth "github.com/vdhieu/tx-parser/mocks/internal_/auth"
+)
+
+var sampleAPIKey = models.APIKey{
+	ID:        "abcd",
+	Name:      "ci",
+	Tenant:    "payments",
+	Hash:      "hash",
+	Scopes:    []string{models.ScopeRead},
+	RateLimit: 60,
+	CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+var sampleAPIKeyData = APIKeyData{
+	ID:        "abcd",
+	Name:      "ci",
+	Tenant:    "payments",
+	Scopes:    []string{models.ScopeRead},
+	RateLimit: 60,
+	CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestAdminHandler_CreateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		setupMock  func(m *mockAuth.Service)
+		wantStatus int
+		wantBody   CreateAPIKeyResponse
+	}{
+		{
+			name: "key created",
+			body: `{"name":"ci","tenant":"payments","scopes":["read"],"rate_limit":60}`,
+			setupMock: func(m *mockAuth.Service) {
+				m.On("CreateKey", models.APIKey{
+					Name:      "ci",
+					Tenant:    "payments",
+					Scopes:    []string{models.ScopeRead},
+					RateLimit: 60,
+				}).Return("txp_raw", sampleAPIKey, nil)
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   CreateAPIKeyResponse{Data: &CreateAPIKeyData{APIKeyData: sampleAPIKeyData, Key: "txp_raw"}},
+		},
+		{
+			name:       "missing scopes",
+			body:       `{"name":"ci"}`,
+			setupMock:  func(m *mockAuth.Service) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   CreateAPIKeyResponse{Error: "scopes are required"},
+		},
+		{
+			name: "invalid scope",
+			body: `{"scopes":["root"]}`,
+			setupMock: func(m *mockAuth.Service) {
+				m.On("CreateKey", models.APIKey{Scopes: []string{"root"}}).Return("", models.APIKey{}, auth.ErrInvalidScope)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   CreateAPIKeyResponse{Error: auth.ErrInvalidScope.Error()},
+		},
+		{
+			name: "storage error",
+			body: `{"scopes":["read"]}`,
+			setupMock: func(m *mockAuth.Service) {
+				m.On("CreateKey", models.APIKey{Scopes: []string{"read"}}).Return("", models.APIKey{}, errors.New("boom"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   CreateAPIKeyResponse{Error: "unable to create api key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockAuth.NewService(t)
+			tt.setupMock(m)
+			h := NewAdminHandler(m, nil)
+
+			router := gin.New()
+			router.POST("/admin/keys", h.CreateAPIKey)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/keys", strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			var got CreateAPIKeyResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantBody, got)
+		})
+	}
+}
+
+func TestAdminHandler_ListAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := mockAuth.NewService(t)
+	m.On("ListKeys").Return([]models.APIKey{sampleAPIKey})
+	h := NewAdminHandler(m, nil)
+
+	router := gin.New()
+	router.GET("/admin/keys", h.ListAPIKeys)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/keys", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	// the hash is never exposed
+	require.NotContains(t, w.Body.String(), "hash")
+	var got APIKeysResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, APIKeysResponse{Data: []APIKeyData{sampleAPIKeyData}}, got)
+}
+
+func TestAdminHandler_RevokeAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "revoked", wantStatus: http.StatusOK},
+		{name: "not found", err: storage.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "storage error", err: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockAuth.NewService(t)
+			m.On("RevokeKey", "abcd").Return(tt.err)
+			h := NewAdminHandler(m, nil)
+
+			router := gin.New()
+			router.DELETE("/admin/keys/:id", h.RevokeAPIKey)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/keys/abcd", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+type stubPruner storage.PrunerMetrics
+
+func (s stubPruner) Metrics() storage.PrunerMetrics {
+	return storage.PrunerMetrics(s)
+}
+
+func TestAdminHandler_PrunerMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAdminHandler(mockAuth.NewService(t), stubPruner{
+		Runs:    12,
+		Errors:  1,
+		Evicted: storage.PruneStats{ByAge: 30, ByAddressCap: 5, ByTotalCap: 2},
+	})
+
+	router := gin.New()
+	router.GET("/admin/metrics/pruner", h.PrunerMetrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/metrics/pruner", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var got PrunerMetricsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, PrunerMetricsResponse{Data: PrunerMetricsData{
+		Runs:                12,
+		Errors:              1,
+		EvictedByAge:        30,
+		EvictedByAddressCap: 5,
+		EvictedByTotalCap:   2,
+	}}, got)
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+)
+
+const (
+	maxBulkSubscriptions = 10000
+	maxBulkBodySize      = 10 << 20
+)
+
+// bulkCSVColumns columns accepted in CSV uploads, only address is required
+var bulkCSVColumns = map[string]bool{
+	"address":     true,
+	"label":       true,
+	"owner":       true,
+	"tags":        true,
+	"start_block": true,
+	"webhook_url": true,
+	"emails":      true,
+	"muted":       true,
+}
+
+// BulkSubscribe subscribe many addresses in one request from a JSON array or a CSV file,
+// sent as body or as the file field of a multipart upload. The valid subscriptions are
+// stored atomically and every item get its own result
+func (h *ParserHandler) BulkSubscribe(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkBodySize)
+
+	items, itemErrs, err := readBulkSubscriptions(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedBulkFormat) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, BulkSubscribeResponse{Error: err.Error()})
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, BulkSubscribeResponse{Error: "no subscriptions"})
+		return
+	}
+	if len(items) > maxBulkSubscriptions {
+		c.JSON(http.StatusBadRequest, BulkSubscribeResponse{
+			Error: fmt.Sprintf("at most %d subscriptions per request", maxBulkSubscriptions),
+		})
+		return
+	}
+
+	tenant := middleware.GetTenant(c)
+	subs := make([]models.Subscription, 0, len(items))
+	indexes := make([]int, 0, len(items))
+	if itemErrs == nil {
+		itemErrs = make(map[int]error)
+	}
+	for i, item := range items {
+		if itemErrs[i] != nil {
+			continue
+		}
+		sub, err := toSubscription(item, tenant)
+		if err != nil {
+			itemErrs[i] = err
+			continue
+		}
+		subs = append(subs, sub)
+		indexes = append(indexes, i)
+	}
+
+	results, err := h.parser.BulkSubscribe(subs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, BulkSubscribeResponse{Error: "unable to subscribe"})
+		return
+	}
+
+	data := make([]BulkSubscribeResult, len(items))
+	for i, item := range items {
+		data[i] = BulkSubscribeResult{Index: i, Address: item.Address, Status: parser.BulkInvalid}
+		if itemErrs[i] != nil {
+			data[i].Error = itemErrs[i].Error()
+		}
+	}
+	for i, result := range results {
+		item := &data[indexes[i]]
+		item.Status = result.Status
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+			continue
+		}
+		item.Address = models.ChecksumAddress(result.Address)
+	}
+
+	var summary BulkSubscribeSummary
+	for _, item := range data {
+		switch item.Status {
+		case parser.BulkSubscribed:
+			summary.Subscribed++
+		case parser.BulkAlreadySubscribed:
+			summary.AlreadySubscribed++
+		case parser.BulkDuplicate:
+			summary.Duplicate++
+		default:
+			summary.Invalid++
+		}
+	}
+	c.JSON(http.StatusOK, BulkSubscribeResponse{Summary: &summary, Data: data})
+}
+
+var errUnsupportedBulkFormat = errors.New("expected a JSON array, a CSV body or a multipart upload with a file field")
+
+// readBulkSubscriptions decode the request, itemErrs hold the items which could not be parsed by index
+func readBulkSubscriptions(c *gin.Context) ([]SubscribeRequest, map[int]error, error) {
+	switch c.ContentType() {
+	case gin.MIMEJSON:
+		items, err := readBulkJSON(c.Request.Body)
+		return items, nil, err
+	case "text/csv":
+		return readBulkCSV(c.Request.Body)
+	case gin.MIMEMultipartPOSTForm:
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("file is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, nil, errors.New("unable to read file")
+		}
+		defer file.Close()
+		if strings.HasSuffix(strings.ToLower(header.Filename), ".json") {
+			items, err := readBulkJSON(file)
+			return items, nil, err
+		}
+		return readBulkCSV(file)
+	}
+	return nil, nil, errUnsupportedBulkFormat
+}
+
+func readBulkJSON(r io.Reader) ([]SubscribeRequest, error) {
+	var items []SubscribeRequest
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, errors.New("body must be a JSON array of subscriptions")
+	}
+	return items, nil
+}
+
+// readBulkCSV read a CSV with a header row, tags and emails are separated by semicolons
+func readBulkCSV(r io.Reader) ([]SubscribeRequest, map[int]error, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !bulkCSVColumns[name] {
+			return nil, nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, nil, errors.New("CSV address column is required")
+	}
+
+	var items []SubscribeRequest
+	itemErrs := make(map[int]error)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(items) == maxBulkSubscriptions {
+			// keep counting without storing so the size error is returned
+			items = append(items, SubscribeRequest{})
+			continue
+		}
+		item, err := parseCSVRecord(columns, record)
+		if err != nil {
+			itemErrs[len(items)] = err
+		}
+		items = append(items, item)
+	}
+	return items, itemErrs, nil
+}
+
+func parseCSVRecord(columns map[string]int, record []string) (SubscribeRequest, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	list := func(name string) []string {
+		var values []string
+		for _, v := range strings.Split(field(name), ";") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	item := SubscribeRequest{
+		Address: field("address"),
+		Label:   field("label"),
+		Owner:   field("owner"),
+		Tags:    list("tags"),
+	}
+	if v := field("start_block"); v != "" {
+		startBlock, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return item, fmt.Errorf("invalid start_block %q", v)
+		}
+		item.StartBlock = startBlock
+	}
+	webhookURL, emails, muted := field("webhook_url"), list("emails"), field("muted")
+	if webhookURL != "" || len(emails) > 0 || muted != "" {
+		item.Notifications = &NotificationPreferencesData{WebhookURL: webhookURL, Emails: emails}
+		if muted != "" {
+			m, err := strconv.ParseBool(muted)
+			if err != nil {
+				return item, fmt.Errorf("invalid muted %q", muted)
+			}
+			item.Notifications.Muted = m
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+func TestParserHandler_BulkSubscribe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		addr    = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		addrSum = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+		other   = "0x1111111111111111111111111111111111111111"
+	)
+
+	multipartBody := func(filename, content string) (string, string) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, err := w.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return body.String(), w.FormDataContentType()
+	}
+	csvUpload, csvUploadType := multipartBody("watchlist.csv", "address\n"+other+"\n")
+	jsonUpload, jsonUploadType := multipartBody("watchlist.json", `[{"address":"`+other+`"}]`)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		setupMock   func(m *mockParser.Parser)
+		wantStatus  int
+		wantBody    BulkSubscribeResponse
+	}{
+		{
+			name:        "json array",
+			contentType: "application/json",
+			body:        `[{"address":"` + addrSum + `","label":"deposit 1"},{"address":"` + other + `"},{"address":"hello"}]`,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{
+					{Address: addrSum, Tenant: models.DefaultTenant, Label: "deposit 1"},
+					{Address: other, Tenant: models.DefaultTenant},
+					{Address: "hello", Tenant: models.DefaultTenant},
+				}).Return([]parser.BulkSubscribeResult{
+					{Address: addr, Status: parser.BulkSubscribed},
+					{Address: other, Status: parser.BulkAlreadySubscribed},
+					{Address: "hello", Status: parser.BulkInvalid, Err: models.ErrInvalidAddress},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: BulkSubscribeResponse{
+				Summary: &BulkSubscribeSummary{Subscribed: 1, AlreadySubscribed: 1, Invalid: 1},
+				Data: []BulkSubscribeResult{
+					{Index: 0, Address: addrSum, Status: parser.BulkSubscribed},
+					{Index: 1, Address: other, Status: parser.BulkAlreadySubscribed},
+					{Index: 2, Address: "hello", Status: parser.BulkInvalid, Error: models.ErrInvalidAddress.Error()},
+				},
+			},
+		},
+		{
+			name:        "json array with unknown route",
+			contentType: "application/json",
+			body: `[{"address":"` + other + `","notifications":{"routes":[{"channel":"kafka","event_types":["confirmed"]}]}},` +
+				`{"address":"` + addr + `","notifications":{"routes":[{"channel":"pager"}]}}]`,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{{
+					Address: other,
+					Tenant:  models.DefaultTenant,
+					Notifications: models.NotificationPreferences{Routes: []models.NotificationRoute{
+						{Channel: models.ChannelKafka, EventTypes: []models.NotificationEventType{models.EventConfirmed}},
+					}},
+				}}).Return([]parser.BulkSubscribeResult{{Address: other, Status: parser.BulkSubscribed}}, nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantBody: BulkSubscribeResponse{
+				Summary: &BulkSubscribeSummary{Subscribed: 1, Invalid: 1},
+				Data: []BulkSubscribeResult{
+					{Index: 0, Address: other, Status: parser.BulkSubscribed},
+					{Index: 1, Address: addr, Status: parser.BulkInvalid, Error: `unknown notification channel "pager"`},
+				},
+			},
+		},
+		{
+			name:        "csv body with unparsable rows",
+			contentType: "text/csv",
+			body: "Address, label, tags, start_block, emails, muted\n" +
+				addr + `, cold, "a;b", 100, ops@example.com, true` + "\n" +
+				other + ", , , soon, , \n" +
+				other + ", , , , , \n",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{
+					{
+						Address:       addr,
+						Tenant:        models.DefaultTenant,
+						Label:         "cold",
+						Tags:          []string{"a", "b"},
+						StartBlock:    100,
+						Notifications: models.NotificationPreferences{Muted: true, Emails: []string{"ops@example.com"}},
+					},
+					{Address: other, Tenant: models.DefaultTenant},
+				}).Return([]parser.BulkSubscribeResult{
+					{Address: addr, Status: parser.BulkSubscribed},
+					{Address: other, Status: parser.BulkSubscribed},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: BulkSubscribeResponse{
+				Summary: &BulkSubscribeSummary{Subscribed: 2, Invalid: 1},
+				Data: []BulkSubscribeResult{
+					{Index: 0, Address: addrSum, Status: parser.BulkSubscribed},
+					{Index: 1, Address: other, Status: parser.BulkInvalid, Error: `invalid start_block "soon"`},
+					{Index: 2, Address: other, Status: parser.BulkSubscribed},
+				},
+			},
+		},
+		{
+			name:        "csv upload",
+			contentType: csvUploadType,
+			body:        csvUpload,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{{Address: other, Tenant: models.DefaultTenant}}).
+					Return([]parser.BulkSubscribeResult{{Address: other, Status: parser.BulkDuplicate}}, nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantBody: BulkSubscribeResponse{
+				Summary: &BulkSubscribeSummary{Duplicate: 1},
+				Data:    []BulkSubscribeResult{{Index: 0, Address: other, Status: parser.BulkDuplicate}},
+			},
+		},
+		{
+			name:        "json upload",
+			contentType: jsonUploadType,
+			body:        jsonUpload,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{{Address: other, Tenant: models.DefaultTenant}}).
+					Return([]parser.BulkSubscribeResult{{Address: other, Status: parser.BulkSubscribed}}, nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantBody: BulkSubscribeResponse{
+				Summary: &BulkSubscribeSummary{Subscribed: 1},
+				Data:    []BulkSubscribeResult{{Index: 0, Address: other, Status: parser.BulkSubscribed}},
+			},
+		},
+		{
+			name:        "storage failure",
+			contentType: "application/json",
+			body:        `[{"address":"` + addr + `","label":"fails"}]`,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("BulkSubscribe", []models.Subscription{{Address: addr, Tenant: models.DefaultTenant, Label: "fails"}}).
+					Return(nil, errors.New("disk full"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   BulkSubscribeResponse{Error: "unable to subscribe"},
+		},
+		{
+			name:        "empty",
+			contentType: "application/json",
+			body:        `[]`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    BulkSubscribeResponse{Error: "no subscriptions"},
+		},
+		{
+			name:        "not an array",
+			contentType: "application/json",
+			body:        `{"address":"` + addr + `"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    BulkSubscribeResponse{Error: "body must be a JSON array of subscriptions"},
+		},
+		{
+			name:        "too many",
+			contentType: "text/csv",
+			body:        "address\n" + strings.Repeat(addr+"\n", maxBulkSubscriptions+1),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    BulkSubscribeResponse{Error: "at most 10000 subscriptions per request"},
+		},
+		{
+			name:        "unknown csv column",
+			contentType: "text/csv",
+			body:        "address,color\n" + addr + ",red\n",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    BulkSubscribeResponse{Error: `unknown CSV column "color"`},
+		},
+		{
+			name:        "missing address column",
+			contentType: "text/csv",
+			body:        "label\ncold\n",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    BulkSubscribeResponse{Error: "CSV address column is required"},
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "application/xml",
+			body:        `<address/>`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    BulkSubscribeResponse{Error: errUnsupportedBulkFormat.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockParser.NewParser(t)
+			if tt.setupMock != nil {
+				tt.setupMock(m)
+			}
+			h := NewParserHandler(m)
+
+			router := gin.New()
+			router.POST("/subscriptions/bulk", h.BulkSubscribe)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/subscriptions/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			var got BulkSubscribeResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantBody, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+)
+
+// ListDeadLetters list the notifications of the tenant which could not be delivered
+func (h *ParserHandler) ListDeadLetters(c *gin.Context) {
+	events := h.parser.ListDeadLetters(middleware.GetTenant(c))
+	data := make([]NotificationEventData, 0, len(events))
+	for _, event := range events {
+		data = append(data, toNotificationEventData(event))
+	}
+	c.JSON(http.StatusOK, NotificationEventsResponse{Data: data})
+}
+
+// ReplayDeadLetter send a dead-lettered notification again with a fresh set of attempts
+func (h *ParserHandler) ReplayDeadLetter(c *gin.Context) {
+	event, err := h.parser.ReplayDeadLetter(middleware.GetTenant(c), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, parser.ErrEventNotFound):
+			c.JSON(http.StatusNotFound, NotificationEventResponse{Error: err.Error()})
+		case errors.Is(err, parser.ErrEventNotDead):
+			c.JSON(http.StatusConflict, NotificationEventResponse{Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, NotificationEventResponse{Error: "unable to replay event"})
+		}
+		return
+	}
+
+	data := toNotificationEventData(event)
+	c.JSON(http.StatusOK, NotificationEventResponse{Data: &data})
+}
+
+func toNotificationEventData(event models.OutboxEvent) NotificationEventData {
+	return NotificationEventData{
+		ID:            event.ID,
+		Status:        string(event.Status),
+		Attempts:      event.Attempts,
+		LastError:     event.LastError,
+		CreatedAt:     event.CreatedAt,
+		NextAttemptAt: event.NextAttemptAt,
+		Event:         notification.NewEventPayload(event.Event),
+	}
+}
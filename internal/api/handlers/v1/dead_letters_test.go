@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+)
+
+var sampleDeadLetter = models.OutboxEvent{
+	ID:     "evt_1",
+	Status: models.OutboxDead,
+	Event: models.NotificationEvent{
+		ID:           "evt_1",
+		Version:      models.NotificationEventVersion,
+		Type:         models.EventOutgoing,
+		Chain:        models.ChainEthereum,
+		Address:      sampleSubscription.Address,
+		Subscription: sampleSubscription,
+		Transaction: models.Transaction{Hash: "0xabc", From: sampleSubscription.Address,
+			To: "0x1111111111111111111111111111111111111111", Value: "1", BlockNumber: "101", Timestamp: "1704164645"},
+		Confirmations: 1,
+		OccurredAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	},
+	Attempts:      10,
+	LastError:     "unexpected status 500",
+	CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	NextAttemptAt: time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC),
+}
+
+var sampleDeadLetterData = NotificationEventData{
+	ID:            "evt_1",
+	Status:        "dead",
+	Attempts:      10,
+	LastError:     "unexpected status 500",
+	CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	NextAttemptAt: time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC),
+	Event: notification.EventPayload{
+		Version: "1",
+		ID:      "evt_1",
+		Type:    "outgoing",
+		Chain:   "ethereum",
+		Address: sampleSubscriptionData.Address,
+		Subscription: notification.EventSubscription{
+			Address: sampleSubscriptionData.Address,
+			Tenant:  models.DefaultTenant,
+			Label:   "cold wallet",
+			Owner:   "treasury",
+			Tags:    []string{"cold"},
+		},
+		Transaction: notification.EventTransaction{Hash: "0xabc", From: sampleSubscriptionData.Address,
+			To: "0x1111111111111111111111111111111111111111", Value: "1", BlockNumber: "101", Timestamp: "1704164645"},
+		Confirmations: 1,
+		OccurredAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	},
+}
+
+func TestParserHandler_ListDeadLetters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := mockParser.NewParser(t)
+	m.On("ListDeadLetters", models.DefaultTenant).Return([]models.OutboxEvent{sampleDeadLetter})
+
+	router := gin.New()
+	router.GET("/notifications/dead-letters", NewParserHandler(m).ListDeadLetters)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notifications/dead-letters", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var got NotificationEventsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, NotificationEventsResponse{Data: []NotificationEventData{sampleDeadLetterData}}, got)
+}
+
+func TestParserHandler_ReplayDeadLetter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	replayed := sampleDeadLetter
+	replayed.Status, replayed.Attempts = models.OutboxPending, 0
+	replayedData := sampleDeadLetterData
+	replayedData.Status, replayedData.Attempts = "pending", 0
+
+	tests := []struct {
+		name       string
+		id         string
+		event      models.OutboxEvent
+		err        error
+		wantStatus int
+		wantBody   NotificationEventResponse
+	}{
+		{
+			name:       "replayed",
+			id:         "evt_1",
+			event:      replayed,
+			wantStatus: http.StatusOK,
+			wantBody:   NotificationEventResponse{Data: &replayedData},
+		},
+		{
+			name:       "unknown",
+			id:         "evt_2",
+			err:        parser.ErrEventNotFound,
+			wantStatus: http.StatusNotFound,
+			wantBody:   NotificationEventResponse{Error: "event not found"},
+		},
+		{
+			name:       "still pending",
+			id:         "evt_3",
+			err:        parser.ErrEventNotDead,
+			wantStatus: http.StatusConflict,
+			wantBody:   NotificationEventResponse{Error: "event is not dead-lettered"},
+		},
+		{
+			name:       "storage failure",
+			id:         "evt_4",
+			err:        errors.New("disk full"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   NotificationEventResponse{Error: "unable to replay event"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockParser.NewParser(t)
+			m.On("ReplayDeadLetter", models.DefaultTenant, tt.id).Return(tt.event, tt.err)
+
+			router := gin.New()
+			router.POST("/notifications/dead-letters/:id/replay", NewParserHandler(m).ReplayDeadLetter)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notifications/dead-letters/"+tt.id+"/replay", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			var got NotificationEventResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantBody, got)
+		})
+	}
+}
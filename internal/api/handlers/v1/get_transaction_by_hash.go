@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func (h *ParserHandler) GetTransactionByHash(c *gin.Context) {
+	hash := c.Param("hash")
+
+	lookup, found := h.parser.GetTransactionByHash(middleware.GetTenant(c), hash)
+	if !found {
+		c.JSON(http.StatusNotFound, TransactionLookupResponse{Error: "transaction not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, TransactionLookupResponse{
+		Data: &TransactionLookupData{
+			Transaction:   lookup.Transaction.Checksummed(),
+			Addresses:     models.ChecksumAddresses(lookup.Addresses),
+			Confirmations: lookup.Confirmations,
+		},
+	})
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+func TestParserHandler_GetTransactionByHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockEthParser := mockParser.NewParser(t)
+	lookup := models.TransactionLookup{
+		Transaction: models.Transaction{
+			Hash:        "0xabc",
+			From:        "0x1111111111111111111111111111111111111111",
+			To:          "0x2222222222222222222222222222222222222222",
+			Value:       "1000",
+			BlockNumber: "100",
+		},
+		Addresses:     []string{"0x1111111111111111111111111111111111111111"},
+		Confirmations: 3,
+	}
+
+	tests := []struct {
+		name       string
+		hash       string
+		setupMock  func(m *mockParser.Parser)
+		wantStatus int
+		wantBody   *TransactionLookupResponse
+	}{
+		{
+			name: "transaction found",
+			hash: "0xabc",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionByHash", models.DefaultTenant, "0xabc").Return(lookup, true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &TransactionLookupResponse{
+				Data: &TransactionLookupData{
+					Transaction:   lookup.Transaction,
+					Addresses:     lookup.Addresses,
+					Confirmations: 3,
+				},
+			},
+		},
+		{
+			name: "transaction not found",
+			hash: "0xdef",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactionByHash", models.DefaultTenant, "0xdef").Return(models.TransactionLookup{}, false)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: &TransactionLookupResponse{
+				Error: "transaction not found",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMock != nil {
+				tt.setupMock(mockEthParser)
+			}
+
+			h := &ParserHandler{
+				parser: mockEthParser,
+			}
+
+			router := gin.New()
+			router.GET("/transactions/:hash", h.GetTransactionByHash)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/transactions/"+tt.hash, nil)
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantBody != nil {
+				var got TransactionLookupResponse
+				err := json.Unmarshal(w.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
 )
 
 func (h *ParserHandler) GetTransactions(c *gin.Context) {
@@ -13,8 +15,14 @@ func (h *ParserHandler) GetTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions := h.parser.GetTransactions(address)
+	address, err := models.NormalizeAddress(address)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TransactionsResponse{Error: err.Error()})
+		return
+	}
+
+	transactions := h.parser.GetTransactions(middleware.GetTenant(c), address)
 	c.JSON(http.StatusOK, TransactionsResponse{
-		Data: transactions,
+		Data: checksumTransactions(transactions),
 	})
 }
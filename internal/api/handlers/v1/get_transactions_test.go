@@ -18,8 +18,8 @@ func TestParserHandler_GetTransactions(t *testing.T) {
 	sampleTxs := []models.Transaction{
 		{
 			Hash:  "0xabc123",
-			From:  "0x111",
-			To:    "0x222",
+			From:  "0x1111111111111111111111111111111111111111",
+			To:    "0x2222222222222222222222222222222222222222",
 			Value: "1000000000000000000",
 		},
 	}
@@ -33,15 +33,38 @@ func TestParserHandler_GetTransactions(t *testing.T) {
 	}{
 		{
 			name:    "successful get transactions",
-			address: "0x1234",
+			address: "0x1234123412341234123412341234123412341234",
 			setupMock: func(m *mockParser.Parser) {
-				m.On("GetTransactions", "0x1234").Return(sampleTxs, nil)
+				m.On("GetTransactions", models.DefaultTenant, "0x1234123412341234123412341234123412341234").Return(sampleTxs, nil)
 			},
 			wantStatus: http.StatusOK,
 			wantBody: &TransactionsResponse{
 				Data: sampleTxs,
 			},
 		},
+		{
+			name:    "addresses are checksummed",
+			address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetTransactions", models.DefaultTenant, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed").Return([]models.Transaction{
+					{Hash: "0xdef", From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", To: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"},
+				})
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &TransactionsResponse{
+				Data: []models.Transaction{
+					{Hash: "0xdef", From: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", To: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+				},
+			},
+		},
+		{
+			name:       "malformed address",
+			address:    "foo",
+			wantStatus: http.StatusBadRequest,
+			wantBody: &TransactionsResponse{
+				Error: models.ErrInvalidAddress.Error(),
+			},
+		},
 		{
 			name:    "invalid address",
 			address: "",
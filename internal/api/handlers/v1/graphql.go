@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/gql"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+)
+
+type GraphQLHandler struct {
+	executor gql.Executor
+}
+
+func NewGraphQLHandler(e gql.Executor) *GraphQLHandler {
+	return &GraphQLHandler{executor: e}
+}
+
+// Query run a GraphQL query sent as JSON body or, for GET, in the query string
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				graphQLError(c, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		graphQLError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Query == "" {
+		graphQLError(c, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	result, err := h.executor.Execute(c.Request.Context(), middleware.GetTenant(c), gql.Request{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+	})
+	if err != nil {
+		if errors.Is(err, gql.ErrQueryRejected) {
+			graphQLError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		graphQLError(c, http.StatusInternalServerError, "unable to execute query")
+		return
+	}
+
+	// resolver errors are part of the result, as for any GraphQL server
+	c.JSON(http.StatusOK, result)
+}
+
+func graphQLError(c *gin.Context, status int, message string) {
+	c.JSON(status, GraphQLErrorResponse{Errors: []GraphQLError{{Message: message}}})
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/api/gql"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	mockGql "github.com/vdhieu/tx-parser/mocks/internal_/api/gql"
+)
+
+func TestGraphQLHandler_Query(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	query := `{ currentBlock }`
+	result := &graphql.Result{Data: map[string]any{"currentBlock": 12}}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		setupMock  func(m *mockGql.Executor)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:   "post query",
+			method: http.MethodPost,
+			body:   `{"query":"{ currentBlock }","operationName":"","variables":{"n":1}}`,
+			setupMock: func(m *mockGql.Executor) {
+				m.On("Execute", mock.Anything, "acme", gql.Request{Query: query, Variables: map[string]any{"n": float64(1)}}).Return(result, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"currentBlock":12}}`,
+		},
+		{
+			name:   "get query",
+			method: http.MethodGet,
+			target: "?query=" + url.QueryEscape(query) + "&operationName=Op&variables=" + url.QueryEscape(`{"a":"b"}`),
+			setupMock: func(m *mockGql.Executor) {
+				m.On("Execute", mock.Anything, "acme", gql.Request{Query: query, OperationName: "Op", Variables: map[string]any{"a": "b"}}).Return(result, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"currentBlock":12}}`,
+		},
+		{
+			name:       "invalid variables",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape(query) + "&variables=nope",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"variables must be a JSON object"}]}`,
+		},
+		{
+			name:       "missing query",
+			method:     http.MethodPost,
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"query is required"}]}`,
+		},
+		{
+			name:   "rejected query",
+			method: http.MethodPost,
+			body:   `{"query":"{ currentBlock }"}`,
+			setupMock: func(m *mockGql.Executor) {
+				m.On("Execute", mock.Anything, "acme", gql.Request{Query: query}).
+					Return(nil, fmt.Errorf("%w: query cost 3000 is over the limit of 2000", gql.ErrQueryRejected))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"query rejected: query cost 3000 is over the limit of 2000"}]}`,
+		},
+		{
+			name:   "execution failure",
+			method: http.MethodPost,
+			body:   `{"query":"{ currentBlock }"}`,
+			setupMock: func(m *mockGql.Executor) {
+				m.On("Execute", mock.Anything, "acme", gql.Request{Query: query}).Return(nil, errors.New("boom"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"errors":[{"message":"unable to execute query"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockGql.NewExecutor(t)
+			if tt.setupMock != nil {
+				tt.setupMock(m)
+			}
+			h := NewGraphQLHandler(m)
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) { middleware.SetTenant(c, "acme") })
+			router.Handle(tt.method, "/graphql", h.Query)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/graphql"+tt.target, strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
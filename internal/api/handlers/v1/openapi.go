@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/openapi"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+)
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>tx-parser API</title>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/api/v1/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+type OpenAPIHandler struct {
+	document *openapi.Document
+}
+
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{document: OpenAPIDocument()}
+}
+
+// Spec serve the OpenAPI document
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	c.JSON(http.StatusOK, h.document)
+}
+
+// EventSchema serve the JSON schema of the notification events
+func (h *OpenAPIHandler) EventSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", notification.EventSchema)
+}
+
+// Docs serve a page rendering the OpenAPI document
+func (h *OpenAPIHandler) Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// OpenAPIDocument describe every v1 route, a route added to the router must be documented here
+func OpenAPIDocument() *openapi.Document {
+	d := openapi.New(openapi.Info{
+		Title:       "tx-parser API",
+		Description: "Ethereum transaction parser: subscribe addresses and query or stream their transactions.",
+		Version:     "1.0.0",
+	})
+	d.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"bearerAuth":   {Type: "http", Scheme: "bearer", Description: "API key sent as a bearer token"},
+		"apiKeyHeader": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		"apiKeyQuery": {
+			Type:        "apiKey",
+			In:          "query",
+			Name:        "api_key",
+			Description: "only accepted by the WebSocket endpoint, browsers can not set headers on the handshake",
+		},
+	}
+	d.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}, {"apiKeyHeader": {}}}
+
+	errorSchema := d.Schema(ErrorResponse{})
+	authenticated := func(op *openapi.Operation, scope string) *openapi.Operation {
+		if op.Description != "" {
+			op.Description += "\n\n"
+		}
+		op.Description += "Requires the `" + scope + "` scope."
+		op.Responses["401"] = jsonResponse("missing or invalid api key", errorSchema)
+		op.Responses["403"] = jsonResponse("api key is missing the "+scope+" scope", errorSchema)
+		op.Responses["429"] = openapi.Response{
+			Description: "rate limit of the api key exceeded",
+			Headers: map[string]openapi.Header{
+				"Retry-After": {Description: "seconds before a request is allowed again", Schema: d.Schema(0)},
+			},
+			Content: jsonContent(errorSchema),
+		}
+		return op
+	}
+	addressParam := openapi.Parameter{Name: "address", In: "path", Required: true, Schema: d.Schema("")}
+
+	d.Add(http.MethodGet, "/api/v1/block/current", authenticated(&openapi.Operation{
+		OperationID: "getCurrentBlock",
+		Summary:     "Last processed block",
+		Tags:        []string{"blocks"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("last processed block", d.Schema(BlockResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodPost, "/api/v1/subscribe", authenticated(&openapi.Operation{
+		OperationID: "subscribe",
+		Summary:     "Subscribe an address",
+		Description: "Start observing the txns of an address for the tenant of the key.",
+		Tags:        []string{"subscriptions"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(d.Schema(SubscribeRequest{}))},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("address subscribed", d.Schema(SubscribeResponse{})),
+			"400": jsonResponse("address is missing", d.Schema(SubscribeResponse{})),
+			"500": jsonResponse("unable to subscribe", d.Schema(SubscribeResponse{})),
+		},
+	}, models.ScopeSubscribe))
+
+	d.Add(http.MethodPost, "/api/v1/subscriptions/bulk", authenticated(&openapi.Operation{
+		OperationID: "bulkSubscribe",
+		Summary:     "Subscribe many addresses",
+		Description: "Accept a JSON array of subscriptions, or a CSV with a header row and the columns address, label, owner, " +
+			"tags, start_block, webhook_url, emails and muted (lists separated by semicolons), sent as body or as the " +
+			"file field of a multipart upload. At most 10000 subscriptions per request. Invalid items are reported " +
+			"and the others are stored atomically.",
+		Tags: []string{"subscriptions"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/json": {Schema: d.Schema([]SubscribeRequest{})},
+			"text/csv":         {Schema: d.Schema("")},
+			"multipart/form-data": {Schema: &openapi.Schema{
+				Type:       "object",
+				Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+				Required:   []string{"file"},
+			}},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("result of every subscription, by position in the request", d.Schema(BulkSubscribeResponse{})),
+			"400": jsonResponse("malformed body, empty or too many subscriptions", d.Schema(BulkSubscribeResponse{})),
+			"415": jsonResponse("unsupported content type", d.Schema(BulkSubscribeResponse{})),
+			"500": jsonResponse("unable to subscribe, nothing was stored", d.Schema(BulkSubscribeResponse{})),
+		},
+	}, models.ScopeSubscribe))
+
+	d.Add(http.MethodGet, "/api/v1/transactions", authenticated(&openapi.Operation{
+		OperationID: "getTransactions",
+		Summary:     "Transactions of an address",
+		Tags:        []string{"transactions"},
+		Parameters: []openapi.Parameter{
+			{Name: "address", In: "query", Required: true, Schema: d.Schema("")},
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("inbound and outbound txns of the address", d.Schema(TransactionsResponse{})),
+			"400": jsonResponse("address is missing", d.Schema(TransactionsResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodGet, "/api/v1/transactions/:hash", authenticated(&openapi.Operation{
+		OperationID: "getTransactionByHash",
+		Summary:     "Transaction by hash",
+		Description: "Return the txn, the subscribed addresses it involves and its number of confirmations.",
+		Tags:        []string{"transactions"},
+		Parameters: []openapi.Parameter{
+			{Name: "hash", In: "path", Required: true, Schema: d.Schema("")},
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("the txn", d.Schema(TransactionLookupResponse{})),
+			"404": jsonResponse("no subscribed address has the txn", d.Schema(TransactionLookupResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodGet, "/api/v1/subscriptions", authenticated(&openapi.Operation{
+		OperationID: "listSubscriptions",
+		Summary:     "Subscriptions of the tenant",
+		Tags:        []string{"subscriptions"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("subscriptions", d.Schema(SubscriptionsResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodGet, "/api/v1/subscriptions/:address", authenticated(&openapi.Operation{
+		OperationID: "getSubscription",
+		Summary:     "Subscription of an address",
+		Tags:        []string{"subscriptions"},
+		Parameters:  []openapi.Parameter{addressParam},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("the subscription", d.Schema(SubscriptionResponse{})),
+			"404": jsonResponse("address is not subscribed", d.Schema(SubscriptionResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodDelete, "/api/v1/subscriptions/:address", authenticated(&openapi.Operation{
+		OperationID: "unsubscribe",
+		Summary:     "Unsubscribe an address",
+		Tags:        []string{"subscriptions"},
+		Parameters:  []openapi.Parameter{addressParam},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("address unsubscribed", d.Schema(SubscribeResponse{})),
+			"404": jsonResponse("address is not subscribed", d.Schema(SubscribeResponse{})),
+		},
+	}, models.ScopeSubscribe))
+
+	d.Add(http.MethodPut, "/api/v1/subscriptions/:address/rules", authenticated(&openapi.Operation{
+		OperationID: "setAlertRules",
+		Summary:     "Replace the alert rules of a subscription",
+		Description: "A subscription with rules is only notified of the txns raising an alert, a rule raise one " +
+			"when every of its conditions match the txn.",
+		Tags:        []string{"subscriptions"},
+		Parameters:  []openapi.Parameter{addressParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(d.Schema(AlertRulesRequest{}))},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("the updated subscription", d.Schema(SubscriptionResponse{})),
+			"400": jsonResponse("invalid address or rules", d.Schema(SubscriptionResponse{})),
+			"404": jsonResponse("address is not subscribed", d.Schema(SubscriptionResponse{})),
+			"500": jsonResponse("unable to update the rules", d.Schema(SubscriptionResponse{})),
+		},
+	}, models.ScopeSubscribe))
+
+	d.Add(http.MethodGet, "/api/v1/notifications/dead-letters", authenticated(&openapi.Operation{
+		OperationID: "listDeadLetters",
+		Summary:     "Undelivered notifications",
+		Description: "Notifications are retried with an exponential backoff, the ones which failed every attempt " +
+			"are kept here until they are replayed.",
+		Tags: []string{"notifications"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("dead-lettered notifications, oldest first", d.Schema(NotificationEventsResponse{})),
+		},
+	}, models.ScopeRead))
+
+	d.Add(http.MethodPost, "/api/v1/notifications/dead-letters/:id/replay", authenticated(&openapi.Operation{
+		OperationID: "replayDeadLetter",
+		Summary:     "Replay an undelivered notification",
+		Description: "Move the notification back to the outbox, it is delivered again with a fresh set of attempts.",
+		Tags:        []string{"notifications"},
+		Parameters:  []openapi.Parameter{{Name: "id", In: "path", Required: true, Schema: d.Schema("")}},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("notification scheduled for delivery", d.Schema(NotificationEventResponse{})),
+			"404": jsonResponse("unknown notification", d.Schema(NotificationEventResponse{})),
+			"409": jsonResponse("notification is not dead-lettered", d.Schema(NotificationEventResponse{})),
+			"500": jsonResponse("unable to replay", d.Schema(NotificationEventResponse{})),
+		},
+	}, models.ScopeSubscribe))
+
+	d.Add(http.MethodGet, "/api/v1/stream", authenticated(&openapi.Operation{
+		OperationID: "streamTransactions",
+		Summary:     "Stream transactions as Server-Sent Events",
+		Description: "Push `transaction` events (data is a StreamTransactionData) of the requested addresses and " +
+			"`reorg` events (data is a StreamReorgData). A client reconnecting with Last-Event-ID first " +
+			"receive the stored txns it missed. A heartbeat comment is sent every 15 seconds.",
+		Tags: []string{"stream"},
+		Parameters: []openapi.Parameter{
+			{Name: "address", In: "query", Required: true, Description: "subscribed addresses, repeated or comma separated", Schema: d.Schema([]string{})},
+			{Name: "last_event_id", In: "query", Description: "same as the Last-Event-ID header", Schema: d.Schema("")},
+			{Name: "Last-Event-ID", In: "header", Description: "id of the last event received", Schema: d.Schema("")},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "event stream",
+				Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: d.Schema("")}},
+			},
+			"400": jsonResponse("address missing or not subscribed, or invalid last event id", d.Schema(StreamErrorResponse{})),
+		},
+	}, models.ScopeRead))
+	// only referenced from the description of the stream
+	d.Schema(StreamTransactionData{})
+	d.Schema(StreamReorgData{})
+
+	graphQLResponses := map[string]openapi.Response{
+		"200": jsonResponse("result of the query, resolver errors are listed in errors", &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"data":   {Type: "object", Nullable: true},
+				"errors": d.Schema([]GraphQLError{}),
+			},
+		}),
+		"400": jsonResponse("malformed query, or query over the depth or cost limits", d.Schema(GraphQLErrorResponse{})),
+		"500": jsonResponse("unable to execute query", d.Schema(GraphQLErrorResponse{})),
+	}
+	// /graphql is an alias of /api/v1/graphql for GraphQL clients
+	for _, route := range []struct{ path, operationID string }{
+		{"/api/v1/graphql", "graphql"},
+		{"/graphql", "graphqlAlias"},
+	} {
+		d.Add(http.MethodGet, route.path, authenticated(&openapi.Operation{
+			OperationID: route.operationID + "Get",
+			Summary:     "Run a GraphQL query",
+			Tags:        []string{"graphql"},
+			Parameters: []openapi.Parameter{
+				{Name: "query", In: "query", Required: true, Schema: d.Schema("")},
+				{Name: "operationName", In: "query", Schema: d.Schema("")},
+				{Name: "variables", In: "query", Description: "JSON object", Schema: d.Schema("")},
+			},
+			Responses: graphQLResponses,
+		}, models.ScopeRead))
+		d.Add(http.MethodPost, route.path, authenticated(&openapi.Operation{
+			OperationID: route.operationID + "Post",
+			Summary:     "Run a GraphQL query",
+			Tags:        []string{"graphql"},
+			RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(d.Schema(GraphQLRequest{}))},
+			Responses:   graphQLResponses,
+		}, models.ScopeRead))
+	}
+
+	d.Add(http.MethodGet, "/api/v1/ws", authenticated(&openapi.Operation{
+		OperationID: "websocket",
+		Summary:     "WebSocket of live transactions",
+		Description: "Upgrade to a WebSocket. Clients send WSClientMessage to subscribe, unsubscribe or ping " +
+			"and receive WSServerMessage: acks, errors, txns, new heads, confirmations and reorgs. " +
+			"A client which does not keep up is closed with code 1008.",
+		Tags:     []string{"stream"},
+		Security: []openapi.SecurityRequirement{{"bearerAuth": {}}, {"apiKeyHeader": {}}, {"apiKeyQuery": {}}},
+		Responses: map[string]openapi.Response{
+			"101": {Description: "switching to the WebSocket protocol"},
+		},
+	}, models.ScopeRead))
+	d.Schema(WSClientMessage{})
+	d.Schema(WSServerMessage{})
+
+	d.Add(http.MethodPost, "/api/v1/admin/keys", authenticated(&openapi.Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Create an api key",
+		Description: "The raw key is only returned in this response.",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(d.Schema(CreateAPIKeyRequest{}))},
+		Responses: map[string]openapi.Response{
+			"201": jsonResponse("key created", d.Schema(CreateAPIKeyResponse{})),
+			"400": jsonResponse("scopes missing or invalid", d.Schema(CreateAPIKeyResponse{})),
+			"500": jsonResponse("unable to create api key", d.Schema(CreateAPIKeyResponse{})),
+		},
+	}, models.ScopeAdmin))
+
+	d.Add(http.MethodGet, "/api/v1/admin/keys", authenticated(&openapi.Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "List api keys",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("keys, without their raw value", d.Schema(APIKeysResponse{})),
+		},
+	}, models.ScopeAdmin))
+
+	d.Add(http.MethodDelete, "/api/v1/admin/keys/:id", authenticated(&openapi.Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an api key",
+		Tags:        []string{"admin"},
+		Parameters: []openapi.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: d.Schema("")},
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("key revoked", d.Schema(RevokeAPIKeyResponse{})),
+			"404": jsonResponse("api key not found", d.Schema(RevokeAPIKeyResponse{})),
+			"500": jsonResponse("unable to revoke api key", d.Schema(RevokeAPIKeyResponse{})),
+		},
+	}, models.ScopeAdmin))
+
+	d.Add(http.MethodGet, "/api/v1/admin/metrics/pruner", authenticated(&openapi.Operation{
+		OperationID: "prunerMetrics",
+		Summary:     "Counters of the storage pruner",
+		Description: "Runs, failed runs and txns evicted per retention rule since the server started.",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("pruner counters", d.Schema(PrunerMetricsResponse{})),
+		},
+	}, models.ScopeAdmin))
+
+	public := []openapi.SecurityRequirement{{}}
+	d.Add(http.MethodGet, "/api/v1/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPIDocument",
+		Summary:     "This OpenAPI document",
+		Tags:        []string{"docs"},
+		Security:    public,
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("OpenAPI document", &openapi.Schema{Type: "object"}),
+		},
+	})
+	d.Add(http.MethodGet, "/api/v1/notifications/schema.json", &openapi.Operation{
+		OperationID: "getNotificationEventSchema",
+		Summary:     "JSON schema of the events sent by every notifier",
+		Tags:        []string{"docs"},
+		Security:    public,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "JSON schema, draft 2020-12",
+				Content:     map[string]openapi.MediaType{"application/schema+json": {Schema: &openapi.Schema{Type: "object"}}},
+			},
+		},
+	})
+	d.Add(http.MethodGet, "/api/v1/docs", &openapi.Operation{
+		OperationID: "getDocs",
+		Summary:     "Browsable documentation of the API",
+		Tags:        []string{"docs"},
+		Security:    public,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "HTML page rendering this document",
+				Content:     map[string]openapi.MediaType{"text/html": {Schema: d.Schema("")}},
+			},
+		},
+	})
+
+	return d
+}
+
+func jsonContent(s *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: s}}
+}
+
+func jsonResponse(description string, s *openapi.Schema) openapi.Response {
+	return openapi.Response{Description: description, Content: jsonContent(s)}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/api/openapi"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAPIHandler()
+	router := gin.New()
+	router.GET("/openapi.json", h.Spec)
+	router.GET("/docs", h.Docs)
+	router.GET("/schema.json", h.EventSchema)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got openapi.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, openapi.Version, got.OpenAPI)
+	require.NotNil(t, got.Paths["/api/v1/transactions/{hash}"]["get"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	require.Contains(t, w.Body.String(), `spec-url="/api/v1/openapi.json"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schema.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, string(notification.EventSchema), w.Body.String())
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	d := OpenAPIDocument()
+
+	operationIDs := make(map[string]bool)
+	for path, item := range d.Paths {
+		for method, op := range item {
+			require.NotEmpty(t, op.Summary, "%s %s", method, path)
+			require.NotEmpty(t, op.Responses, "%s %s", method, path)
+			require.False(t, operationIDs[op.OperationID], "duplicated operation id %s", op.OperationID)
+			operationIDs[op.OperationID] = true
+		}
+	}
+
+	// every reference must point to a schema of the document
+	raw, err := json.Marshal(d)
+	require.NoError(t, err)
+	for _, part := range strings.Split(string(raw), `"$ref":"#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		require.NotNil(t, d.Components.Schemas[name], "missing schema %s", name)
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/stream"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+type StreamHandler struct {
+	parser    parser.Parser
+	broker    stream.Broker
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(p parser.Parser, b stream.Broker) *StreamHandler {
+	return &StreamHandler{
+		parser:    p,
+		broker:    b,
+		heartbeat: defaultHeartbeatInterval,
+	}
+}
+
+// Stream push the txns of the requested addresses and the reorgs as Server-Sent Events.
+// A client reconnecting with Last-Event-ID first receive the stored txns it missed
+func (h *StreamHandler) Stream(c *gin.Context) {
+	tenant := middleware.GetTenant(c)
+	addresses, err := h.streamAddresses(tenant, c.QueryArray("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, StreamErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// subscribe before reading the history so no txn is missed in between
+	events, unsubscribe := h.broker.Subscribe()
+	defer unsubscribe()
+
+	var replay []stream.Event
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID != "" {
+		history := make(map[string][]models.Transaction, len(addresses))
+		for addr := range addresses {
+			history[addr] = h.parser.GetTransactions(tenant, addr)
+		}
+		replay, err = stream.Replay(history, lastEventID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, StreamErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	replayed := make(map[string]bool, len(replay))
+	for _, event := range replay {
+		replayed[event.Transaction.Hash] = true
+		if err := writeEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			// the client was too slow, it reconnect and resume from the history
+			if !ok {
+				return
+			}
+			event, ok = stream.Filter(event, addresses)
+			if !ok || (event.Type == stream.EventTransaction && replayed[event.Transaction.Hash]) {
+				continue
+			}
+			if err := writeEvent(c.Writer, event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamAddresses return the requested addresses, they must be subscribed by tenant.
+// Addresses can be repeated or comma separated
+func (h *StreamHandler) streamAddresses(tenant string, params []string) (map[string]bool, error) {
+	addresses := make(map[string]bool)
+	for _, param := range params {
+		for _, raw := range strings.Split(param, ",") {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			addr, err := models.NormalizeAddress(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.TrimSpace(raw), err)
+			}
+			if _, found := h.parser.GetSubscription(tenant, addr); !found {
+				return nil, fmt.Errorf("address %s is not subscribed", models.ChecksumAddress(addr))
+			}
+			addresses[addr] = true
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("address is required")
+	}
+	return addresses, nil
+}
+
+func writeEvent(w gin.ResponseWriter, event stream.Event) error {
+	var (
+		id   string
+		data any
+	)
+	switch event.Type {
+	case stream.EventTransaction:
+		id = stream.EventID(event.Transaction)
+		data = StreamTransactionData{
+			Addresses:   models.ChecksumAddresses(event.Addresses),
+			Transaction: event.Transaction.Checksummed(),
+		}
+	case stream.EventReorg:
+		// reorgs are not stored so they do not move the resume position
+		data = StreamReorgData{
+			BlockNumber: event.Reorg.BlockNumber,
+			OldHash:     event.Reorg.OldHash,
+			NewHash:     event.Reorg.NewHash,
+		}
+	default:
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+	return err
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent read the next event of an SSE stream, heartbeats are returned as a comment event
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return e
+		case strings.HasPrefix(line, ":"):
+			e.event = "comment"
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamHandler_Stream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tx100 := models.Transaction{Hash: "0x100", From: "0x1231231231231231231231231231231231231231", To: "0x4564564564564564564564564564564564564564", BlockNumber: "100"}
+	tx101 := models.Transaction{Hash: "0x101", From: "0x1231231231231231231231231231231231231231", To: "0x7897897897897897897897897897897897897897", BlockNumber: "101"}
+	tx102 := models.Transaction{Hash: "0x102", From: "0x7897897897897897897897897897897897897897", To: "0x1231231231231231231231231231231231231231", BlockNumber: "102"}
+
+	m := mockParser.NewParser(t)
+	m.On("GetSubscription", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return(models.Subscription{Address: "0x1231231231231231231231231231231231231231"}, true)
+	m.On("GetTransactions", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return([]models.Transaction{tx100, tx101})
+
+	broker := stream.NewBroker()
+	h := &StreamHandler{parser: m, broker: broker, heartbeat: 50 * time.Millisecond}
+	router := gin.New()
+	router.GET("/stream", h.Stream)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream?address=0X1231231231231231231231231231231231231231", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", stream.EventID(tx100))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	// the txn missed since the last event is replayed from the history
+	got := readEvent(t, r)
+	require.Equal(t, stream.EventID(tx101), got.id)
+	require.Equal(t, stream.EventTransaction, got.event)
+	var data StreamTransactionData
+	require.NoError(t, json.Unmarshal([]byte(got.data), &data))
+	require.Equal(t, StreamTransactionData{Addresses: []string{"0x1231231231231231231231231231231231231231"}, Transaction: tx101}, data)
+
+	// live events, the replayed txn and the txns of other addresses are skipped
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx101, Addresses: []string{"0x1231231231231231231231231231231231231231"}})
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: models.Transaction{Hash: "0xother"}, Addresses: []string{"0x9999999999999999999999999999999999999999"}})
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx102, Addresses: []string{"0x7897897897897897897897897897897897897897", "0x1231231231231231231231231231231231231231"}})
+	broker.Publish(stream.Event{Type: stream.EventReorg, Reorg: models.Reorg{BlockNumber: 102, OldHash: "0xa", NewHash: "0xb"}})
+
+	got = readEvent(t, r)
+	for got.event == "comment" {
+		got = readEvent(t, r)
+	}
+	require.Equal(t, stream.EventID(tx102), got.id)
+	require.NoError(t, json.Unmarshal([]byte(got.data), &data))
+	require.Equal(t, StreamTransactionData{Addresses: []string{"0x1231231231231231231231231231231231231231"}, Transaction: tx102}, data)
+
+	got = readEvent(t, r)
+	for got.event == "comment" {
+		got = readEvent(t, r)
+	}
+	require.Equal(t, sseEvent{
+		event: stream.EventReorg,
+		data:  `{"block_number":102,"old_hash":"0xa","new_hash":"0xb"}`,
+	}, got)
+
+	// heartbeats keep the connection alive
+	require.Equal(t, sseEvent{event: "comment"}, readEvent(t, r))
+}
+
+func TestStreamHandler_Stream_badRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		query     string
+		header    string
+		setupMock func(m *mockParser.Parser)
+		wantError string
+	}{
+		{
+			name:      "missing address",
+			query:     "",
+			setupMock: func(m *mockParser.Parser) {},
+			wantError: "address is required",
+		},
+		{
+			name:  "address not subscribed",
+			query: "?address=0x1231231231231231231231231231231231231231,0x4564564564564564564564564564564564564564",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return(models.Subscription{Address: "0x1231231231231231231231231231231231231231"}, true)
+				m.On("GetSubscription", models.DefaultTenant, "0x4564564564564564564564564564564564564564").Return(models.Subscription{}, false)
+			},
+			wantError: "address 0x4564564564564564564564564564564564564564 is not subscribed",
+		},
+		{
+			name:   "invalid last event id",
+			query:  "?address=0x1231231231231231231231231231231231231231",
+			header: "invalid",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return(models.Subscription{Address: "0x1231231231231231231231231231231231231231"}, true)
+				m.On("GetTransactions", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return(nil)
+			},
+			wantError: `invalid event id "invalid"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockParser.NewParser(t)
+			tt.setupMock(m)
+			h := NewStreamHandler(m, stream.NewBroker())
+
+			router := gin.New()
+			router.GET("/stream", h.Stream)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/stream"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			var got StreamErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantError, got.Error)
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
 )
 
 func (h *ParserHandler) Subscribe(c *gin.Context) {
@@ -14,7 +16,19 @@ func (h *ParserHandler) Subscribe(c *gin.Context) {
 		return
 	}
 
-	success := h.parser.Subscribe(req.Address)
+	address, err := models.NormalizeAddress(req.Address)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscribeResponse{Error: err.Error()})
+		return
+	}
+
+	req.Address = address
+	sub, err := toSubscription(req, middleware.GetTenant(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscribeResponse{Error: err.Error()})
+		return
+	}
+	success := h.parser.Subscribe(sub)
 	if success {
 		c.JSON(http.StatusOK, SubscribeResponse{Message: "successfully subscribed"})
 		return
@@ -22,3 +36,60 @@ func (h *ParserHandler) Subscribe(c *gin.Context) {
 
 	c.JSON(http.StatusInternalServerError, SubscribeResponse{Message: "unable to subscribe"})
 }
+
+// toSubscription the subscription requested by req, an error is returned for invalid notification preferences
+func toSubscription(req SubscribeRequest, tenant string) (models.Subscription, error) {
+	in := models.SubscriptionInput{
+		Address:    req.Address,
+		Label:      req.Label,
+		Owner:      req.Owner,
+		Tags:       req.Tags,
+		StartBlock: req.StartBlock,
+	}
+	if req.Notifications != nil {
+		in.Notifications = toNotificationInput(*req.Notifications)
+	}
+	return in.Subscription(tenant)
+}
+
+func toNotificationInput(data NotificationPreferencesData) *models.NotificationInput {
+	in := &models.NotificationInput{
+		Muted:      data.Muted,
+		WebhookURL: data.WebhookURL,
+		Emails:     data.Emails,
+		Routes:     toRoutes(data.Routes),
+		Rules:      toAlertRules(data.Rules),
+	}
+	if data.Digest != nil {
+		in.Digest = &models.DigestInput{Mode: data.Digest.Mode, IntervalMinutes: data.Digest.IntervalMinutes, DailyAt: data.Digest.DailyAt}
+	}
+	if data.Throttle != nil {
+		in.Throttle = &models.ThrottleInput{Limit: data.Throttle.Limit, PeriodMinutes: data.Throttle.PeriodMinutes}
+	}
+	return in
+}
+
+func toRoutes(data []NotificationRouteData) []models.NotificationRoute {
+	if data == nil {
+		return nil
+	}
+	routes := make([]models.NotificationRoute, len(data))
+	for i, route := range data {
+		routes[i] = models.NotificationRoute{Channel: route.Channel, EventTypes: route.EventTypes}
+	}
+	return routes
+}
+
+func toAlertRules(data []AlertRuleData) []models.AlertRule {
+	if data == nil {
+		return nil
+	}
+	rules := make([]models.AlertRule, len(data))
+	for i, rule := range data {
+		rules[i] = models.AlertRule{Name: rule.Name, Conditions: make([]models.AlertCondition, len(rule.Conditions))}
+		for j, c := range rule.Conditions {
+			rules[i].Conditions[j] = models.AlertCondition{Type: c.Type, Value: c.Value, Contracts: c.Contracts, Method: c.Method}
+		}
+	}
+	return rules
+}
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
 	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
 )
 
@@ -26,29 +28,192 @@ func TestParserHandler_Subscribe(t *testing.T) {
 		{
 			name: "successful subscription",
 			reqBody: SubscribeRequest{
-				Address: "0x1234",
+				Address: "0x1234123412341234123412341234123412341234",
 			},
 			setupMock: func(m *mockParser.Parser) {
-				m.On("Subscribe", "0x1234").Return(true)
+				m.On("Subscribe", models.Subscription{Address: "0x1234123412341234123412341234123412341234", Tenant: models.DefaultTenant}).Return(true)
 			},
 			wantStatus: http.StatusOK,
 			wantBody: &SubscribeResponse{
 				Message: "successfully subscribed",
 			},
 		},
+		{
+			name: "successful subscription with metadata",
+			reqBody: SubscribeRequest{
+				Address:    "0x5678567856785678567856785678567856785678",
+				Label:      "cold wallet",
+				Owner:      "treasury",
+				Tags:       []string{"cold"},
+				StartBlock: 100,
+				Notifications: &NotificationPreferencesData{
+					WebhookURL: "https://example.com/hook",
+				},
+			},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("Subscribe", models.Subscription{
+					Address:    "0x5678567856785678567856785678567856785678",
+					Tenant:     models.DefaultTenant,
+					Label:      "cold wallet",
+					Owner:      "treasury",
+					Tags:       []string{"cold"},
+					StartBlock: 100,
+					Notifications: models.NotificationPreferences{
+						WebhookURL: "https://example.com/hook",
+					},
+				}).Return(true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &SubscribeResponse{
+				Message: "successfully subscribed",
+			},
+		},
+		{
+			name: "successful subscription with routes",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341235",
+				Notifications: &NotificationPreferencesData{
+					Routes: []NotificationRouteData{
+						{Channel: models.ChannelWebhook},
+						{Channel: models.ChannelEmail, EventTypes: []models.NotificationEventType{models.EventReorged}},
+					},
+				},
+			},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("Subscribe", models.Subscription{
+					Address: "0x1234123412341234123412341234123412341235",
+					Tenant:  models.DefaultTenant,
+					Notifications: models.NotificationPreferences{
+						Routes: []models.NotificationRoute{
+							{Channel: models.ChannelWebhook},
+							{Channel: models.ChannelEmail, EventTypes: []models.NotificationEventType{models.EventReorged}},
+						},
+					},
+				}).Return(true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &SubscribeResponse{
+				Message: "successfully subscribed",
+			},
+		},
+		{
+			name: "successful subscription with digest and throttle",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341235",
+				Notifications: &NotificationPreferencesData{
+					Digest:   &DigestData{Mode: models.DigestDaily, DailyAt: "18:45"},
+					Throttle: &ThrottleData{Limit: 10, PeriodMinutes: 60},
+				},
+			},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("Subscribe", models.Subscription{
+					Address: "0x1234123412341234123412341234123412341235",
+					Tenant:  models.DefaultTenant,
+					Notifications: models.NotificationPreferences{
+						Digest:   models.DigestPreferences{Mode: models.DigestDaily, DailyAt: 18*time.Hour + 45*time.Minute},
+						Throttle: models.Throttle{Limit: 10, Period: time.Hour},
+					},
+				}).Return(true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &SubscribeResponse{
+				Message: "successfully subscribed",
+			},
+		},
+		{
+			name: "invalid digest time",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341234",
+				Notifications: &NotificationPreferencesData{
+					Digest: &DigestData{Mode: models.DigestDaily, DailyAt: "6pm"},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: "digest daily_at must be formatted as HH:MM",
+			},
+		},
+		{
+			name: "digest interval too short",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341234",
+				Notifications: &NotificationPreferencesData{
+					Digest: &DigestData{Mode: models.DigestInterval},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: "digest interval must be at least a minute",
+			},
+		},
+		{
+			name: "unknown route channel",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341234",
+				Notifications: &NotificationPreferencesData{
+					Routes: []NotificationRouteData{{Channel: "pager"}},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: `unknown notification channel "pager"`,
+			},
+		},
+		{
+			name: "unknown route event type",
+			reqBody: SubscribeRequest{
+				Address: "0x1234123412341234123412341234123412341234",
+				Notifications: &NotificationPreferencesData{
+					Routes: []NotificationRouteData{{Channel: models.ChannelEmail, EventTypes: []models.NotificationEventType{"pending"}}},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: `unknown notification event type "pending"`,
+			},
+		},
 		{
 			name: "unable to subscribe address",
 			reqBody: SubscribeRequest{
-				Address: "0x12345",
+				Address: "0x1234512345123451234512345123451234512345",
 			},
 			setupMock: func(m *mockParser.Parser) {
-				m.On("Subscribe", "0x12345").Return(false)
+				m.On("Subscribe", models.Subscription{Address: "0x1234512345123451234512345123451234512345", Tenant: models.DefaultTenant}).Return(false)
 			},
 			wantStatus: http.StatusInternalServerError,
 			wantBody: &SubscribeResponse{
 				Message: "unable to subscribe",
 			},
 		},
+		{
+			name: "checksummed address is stored lower cased",
+			reqBody: SubscribeRequest{
+				Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+			},
+			setupMock: func(m *mockParser.Parser) {
+				m.On("Subscribe", models.Subscription{Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", Tenant: models.DefaultTenant}).Return(true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &SubscribeResponse{
+				Message: "successfully subscribed",
+			},
+		},
+		{
+			name:       "invalid address",
+			reqBody:    SubscribeRequest{Address: "hello"},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: models.ErrInvalidAddress.Error(),
+			},
+		},
+		{
+			name:       "invalid checksum",
+			reqBody:    SubscribeRequest{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"},
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscribeResponse{
+				Error: models.ErrInvalidChecksum.Error(),
+			},
+		},
 		{
 			name:       "invalid request - missing address",
 			reqBody:    SubscribeRequest{},
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+)
+
+func (h *ParserHandler) ListSubscriptions(c *gin.Context) {
+	subs := h.parser.ListSubscriptions(middleware.GetTenant(c))
+	data := make([]SubscriptionData, 0, len(subs))
+	for _, sub := range subs {
+		data = append(data, toSubscriptionData(sub))
+	}
+	c.JSON(http.StatusOK, SubscriptionsResponse{Data: data})
+}
+
+func (h *ParserHandler) GetSubscription(c *gin.Context) {
+	address, err := models.NormalizeAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscriptionResponse{Error: err.Error()})
+		return
+	}
+
+	sub, found := h.parser.GetSubscription(middleware.GetTenant(c), address)
+	if !found {
+		c.JSON(http.StatusNotFound, SubscriptionResponse{Error: "subscription not found"})
+		return
+	}
+
+	data := toSubscriptionData(sub)
+	c.JSON(http.StatusOK, SubscriptionResponse{Data: &data})
+}
+
+// SetAlertRules replace the alert rules of a subscription
+func (h *ParserHandler) SetAlertRules(c *gin.Context) {
+	address, err := models.NormalizeAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscriptionResponse{Error: err.Error()})
+		return
+	}
+	var req AlertRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, SubscriptionResponse{Error: "invalid alert rules"})
+		return
+	}
+	prefs, err := models.NotificationInput{Rules: toAlertRules(req.Rules)}.Preferences()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscriptionResponse{Error: err.Error()})
+		return
+	}
+
+	sub, err := h.parser.SetAlertRules(middleware.GetTenant(c), address, prefs.Rules)
+	if err != nil {
+		if errors.Is(err, parser.ErrNotSubscribed) {
+			c.JSON(http.StatusNotFound, SubscriptionResponse{Error: "subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, SubscriptionResponse{Error: "unable to update alert rules"})
+		return
+	}
+	data := toSubscriptionData(sub)
+	c.JSON(http.StatusOK, SubscriptionResponse{Data: &data})
+}
+
+func (h *ParserHandler) Unsubscribe(c *gin.Context) {
+	address, err := models.NormalizeAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, SubscribeResponse{Error: err.Error()})
+		return
+	}
+
+	if !h.parser.Unsubscribe(middleware.GetTenant(c), address) {
+		c.JSON(http.StatusNotFound, SubscribeResponse{Error: "subscription not found"})
+		return
+	}
+	c.JSON(http.StatusOK, SubscribeResponse{Message: "successfully unsubscribed"})
+}
+
+func toSubscriptionData(sub models.Subscription) SubscriptionData {
+	return SubscriptionData{
+		Address:    models.ChecksumAddress(sub.Address),
+		Tenant:     sub.Tenant,
+		Label:      sub.Label,
+		Owner:      sub.Owner,
+		Tags:       sub.Tags,
+		CreatedAt:  sub.CreatedAt,
+		StartBlock: sub.StartBlock,
+		Notifications: NotificationPreferencesData{
+			Muted:      sub.Notifications.Muted,
+			WebhookURL: sub.Notifications.WebhookURL,
+			Emails:     sub.Notifications.Emails,
+			Routes:     toRouteData(sub.Notifications.Routes),
+			Digest:     toDigestData(sub.Notifications.Digest),
+			Throttle:   toThrottleData(sub.Notifications.Throttle),
+			Rules:      toAlertRuleData(sub.Notifications.Rules),
+		},
+	}
+}
+
+func toAlertRuleData(rules []models.AlertRule) []AlertRuleData {
+	if rules == nil {
+		return nil
+	}
+	data := make([]AlertRuleData, len(rules))
+	for i, rule := range rules {
+		data[i] = AlertRuleData{Name: rule.Name, Conditions: make([]AlertConditionData, len(rule.Conditions))}
+		for j, c := range rule.Conditions {
+			condition := AlertConditionData{Type: c.Type, Value: c.Value, Method: c.Method}
+			for _, contract := range c.Contracts {
+				condition.Contracts = append(condition.Contracts, models.ChecksumAddress(contract))
+			}
+			data[i].Conditions[j] = condition
+		}
+	}
+	return data
+}
+
+func toDigestData(digest models.DigestPreferences) *DigestData {
+	if !digest.Batched() {
+		return nil
+	}
+	data := &DigestData{Mode: digest.Mode}
+	switch digest.Mode {
+	case models.DigestInterval:
+		data.IntervalMinutes = int(digest.Interval / time.Minute)
+	case models.DigestDaily:
+		data.DailyAt = fmt.Sprintf("%02d:%02d", int(digest.DailyAt/time.Hour), int(digest.DailyAt%time.Hour/time.Minute))
+	}
+	return data
+}
+
+func toThrottleData(throttle models.Throttle) *ThrottleData {
+	if throttle.Limit <= 0 {
+		return nil
+	}
+	return &ThrottleData{Limit: throttle.Limit, PeriodMinutes: int(throttle.Period / time.Minute)}
+}
+
+func toRouteData(routes []models.NotificationRoute) []NotificationRouteData {
+	if routes == nil {
+		return nil
+	}
+	data := make([]NotificationRouteData, len(routes))
+	for i, route := range routes {
+		data[i] = NotificationRouteData{Channel: route.Channel, EventTypes: route.EventTypes}
+	}
+	return data
+}
+
+// checksumTransactions copy of txs with checksummed addresses, API responses only use checksummed addresses
+func checksumTransactions(txs []models.Transaction) []models.Transaction {
+	if txs == nil {
+		return nil
+	}
+	result := make([]models.Transaction, len(txs))
+	for i, tx := range txs {
+		result[i] = tx.Checksummed()
+	}
+	return result
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+var sampleSubscription = models.Subscription{
+	Address:    "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+	Tenant:     models.DefaultTenant,
+	Label:      "cold wallet",
+	Owner:      "treasury",
+	Tags:       []string{"cold"},
+	CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	StartBlock: 100,
+	Notifications: models.NotificationPreferences{
+		Emails:   []string{"ops@example.com"},
+		Digest:   models.DigestPreferences{Mode: models.DigestDaily, DailyAt: 9*time.Hour + 30*time.Minute},
+		Throttle: models.Throttle{Limit: 20, Period: time.Hour},
+		Rules: []models.AlertRule{{Name: "usdc transfer", Conditions: []models.AlertCondition{
+			{Type: models.AlertContract, Contracts: []string{"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"}, Method: "0xa9059cbb"},
+		}}},
+	},
+}
+
+var sampleSubscriptionData = SubscriptionData{
+	Address:    "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	Tenant:     models.DefaultTenant,
+	Label:      "cold wallet",
+	Owner:      "treasury",
+	Tags:       []string{"cold"},
+	CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	StartBlock: 100,
+	Notifications: NotificationPreferencesData{
+		Emails:   []string{"ops@example.com"},
+		Digest:   &DigestData{Mode: models.DigestDaily, DailyAt: "09:30"},
+		Throttle: &ThrottleData{Limit: 20, PeriodMinutes: 60},
+		Rules: []AlertRuleData{{Name: "usdc transfer", Conditions: []AlertConditionData{
+			{Type: models.AlertContract, Contracts: []string{"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"}, Method: "0xa9059cbb"},
+		}}},
+	},
+}
+
+func TestParserHandler_ListSubscriptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockEthParser := mockParser.NewParser(t)
+	mockEthParser.On("ListSubscriptions", models.DefaultTenant).Return([]models.Subscription{sampleSubscription})
+
+	h := &ParserHandler{
+		parser: mockEthParser,
+	}
+
+	router := gin.New()
+	router.GET("/subscriptions", h.ListSubscriptions)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var got SubscriptionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, SubscriptionsResponse{Data: []SubscriptionData{sampleSubscriptionData}}, got)
+}
+
+func TestParserHandler_GetSubscription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockEthParser := mockParser.NewParser(t)
+
+	tests := []struct {
+		name       string
+		address    string
+		setupMock  func(m *mockParser.Parser)
+		wantStatus int
+		wantBody   *SubscriptionResponse
+	}{
+		{
+			name:    "subscription found",
+			address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", models.DefaultTenant, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed").Return(sampleSubscription, true)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &SubscriptionResponse{
+				Data: &sampleSubscriptionData,
+			},
+		},
+		{
+			name:    "subscription not found",
+			address: "0x5678567856785678567856785678567856785678",
+			setupMock: func(m *mockParser.Parser) {
+				m.On("GetSubscription", models.DefaultTenant, "0x5678567856785678567856785678567856785678").Return(models.Subscription{}, false)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: &SubscriptionResponse{
+				Error: "subscription not found",
+			},
+		},
+		{
+			name:       "invalid address",
+			address:    "hello",
+			wantStatus: http.StatusBadRequest,
+			wantBody: &SubscriptionResponse{
+				Error: models.ErrInvalidAddress.Error(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMock != nil {
+				tt.setupMock(mockEthParser)
+			}
+
+			h := &ParserHandler{
+				parser: mockEthParser,
+			}
+
+			router := gin.New()
+			router.GET("/subscriptions/:address", h.GetSubscription)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions/"+tt.address, nil)
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantBody != nil {
+				var got SubscriptionResponse
+				err := json.Unmarshal(w.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestParserHandler_SetAlertRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockEthParser := mockParser.NewParser(t)
+
+	whale := []AlertRuleData{{Name: "whale", Conditions: []AlertConditionData{{Type: models.AlertValueAbove, Value: "1000000000000000000"}}}}
+	tests := []struct {
+		name       string
+		address    string
+		body       string
+		setupMock  func(m *mockParser.Parser)
+		wantStatus int
+		wantBody   SubscriptionResponse
+	}{
+		{
+			name:    "rules replaced",
+			address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+			body:    `{"rules":[{"name":"whale","conditions":[{"type":"value_above","value":"1000000000000000000"}]}]}`,
+			setupMock: func(m *mockParser.Parser) {
+				rules := []models.AlertRule{{Name: "whale", Conditions: []models.AlertCondition{{Type: models.AlertValueAbove, Value: "1000000000000000000"}}}}
+				sub := sampleSubscription
+				sub.Notifications.Rules = rules
+				m.On("SetAlertRules", models.DefaultTenant, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", rules).Return(sub, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: func() SubscriptionResponse {
+				data := sampleSubscriptionData
+				data.Notifications.Rules = whale
+				return SubscriptionResponse{Data: &data}
+			}(),
+		},
+		{
+			name:    "contracts normalized",
+			address: "0x1234123412341234123412341234123412341234",
+			body:    `{"rules":[{"name":"usdc","conditions":[{"type":"contract","contracts":["0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"],"method":"0xA9059CBB"}]}]}`,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("SetAlertRules", models.DefaultTenant, "0x1234123412341234123412341234123412341234", []models.AlertRule{{
+					Name: "usdc",
+					Conditions: []models.AlertCondition{
+						{Type: models.AlertContract, Contracts: []string{"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"}, Method: "0xa9059cbb"},
+					},
+				}}).Return(models.Subscription{}, parser.ErrNotSubscribed)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   SubscriptionResponse{Error: "subscription not found"},
+		},
+		{
+			name:       "invalid rule",
+			address:    "0x1234123412341234123412341234123412341234",
+			body:       `{"rules":[{"name":"gas","conditions":[{"type":"gas_above"}]}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   SubscriptionResponse{Error: `alert rule "gas": unknown alert condition "gas_above"`},
+		},
+		{
+			name:       "invalid body",
+			address:    "0x1234123412341234123412341234123412341234",
+			body:       `{"rules":{}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   SubscriptionResponse{Error: "invalid alert rules"},
+		},
+		{
+			name:       "invalid address",
+			address:    "hello",
+			body:       `{"rules":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   SubscriptionResponse{Error: models.ErrInvalidAddress.Error()},
+		},
+		{
+			name:    "storage failure",
+			address: "0x5678567856785678567856785678567856785678",
+			body:    `{"rules":[]}`,
+			setupMock: func(m *mockParser.Parser) {
+				m.On("SetAlertRules", models.DefaultTenant, "0x5678567856785678567856785678567856785678", []models.AlertRule{}).
+					Return(models.Subscription{}, errors.New("disk full"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   SubscriptionResponse{Error: "unable to update alert rules"},
+		},
+	}
+
+	h := &ParserHandler{
+		parser: mockEthParser,
+	}
+	router := gin.New()
+	router.PUT("/subscriptions/:address/rules", h.SetAlertRules)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMock != nil {
+				tt.setupMock(mockEthParser)
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/subscriptions/"+tt.address+"/rules", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			var got SubscriptionResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantBody, got)
+		})
+	}
+}
+
+func TestParserHandler_Unsubscribe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockEthParser := mockParser.NewParser(t)
+	mockEthParser.On("Unsubscribe", models.DefaultTenant, "0x1234123412341234123412341234123412341234").Return(true)
+	mockEthParser.On("Unsubscribe", models.DefaultTenant, "0x5678567856785678567856785678567856785678").Return(false)
+
+	h := &ParserHandler{
+		parser: mockEthParser,
+	}
+
+	router := gin.New()
+	router.DELETE("/subscriptions/:address", h.Unsubscribe)
+
+	tests := []struct {
+		name       string
+		address    string
+		wantStatus int
+		wantBody   SubscribeResponse
+	}{
+		{
+			name:       "unsubscribed",
+			address:    "0x1234123412341234123412341234123412341234",
+			wantStatus: http.StatusOK,
+			wantBody:   SubscribeResponse{Message: "successfully unsubscribed"},
+		},
+		{
+			name:       "subscription not found",
+			address:    "0x5678567856785678567856785678567856785678",
+			wantStatus: http.StatusNotFound,
+			wantBody:   SubscribeResponse{Error: "subscription not found"},
+		},
+		{
+			name:       "invalid checksum",
+			address:    "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   SubscribeResponse{Error: models.ErrInvalidChecksum.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/subscriptions/"+tt.address, nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			var got SubscribeResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, tt.wantBody, got)
+		})
+	}
+}
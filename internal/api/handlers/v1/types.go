@@ -1,13 +1,101 @@
 package handler
 
-import "github.com/vdhieu/tx-parser/internal/models"
+import (
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+)
 
 type BlockResponse struct {
 	Block int `json:"block"`
 }
 
 type SubscribeRequest struct {
-	Address string `json:"address" binding:"required"`
+	Address       string                       `json:"address" binding:"required"`
+	Label         string                       `json:"label"`
+	Owner         string                       `json:"owner"`
+	Tags          []string                     `json:"tags"`
+	StartBlock    int64                        `json:"start_block"`
+	Notifications *NotificationPreferencesData `json:"notifications"`
+}
+
+type NotificationPreferencesData struct {
+	Muted      bool     `json:"muted"`
+	WebhookURL string   `json:"webhook_url,omitempty"`
+	Emails     []string `json:"emails,omitempty"`
+	// Routes channels the events are sent to, the server default routes when empty
+	Routes []NotificationRouteData `json:"routes,omitempty"`
+	// Digest batch the events in summaries, every event is sent on its own when nil
+	Digest *DigestData `json:"digest,omitempty"`
+	// Throttle limit the notifications sent, the events over the limit are sent in a digest
+	Throttle *ThrottleData `json:"throttle,omitempty"`
+	// Rules only the txns raising an alert are notified when set
+	Rules []AlertRuleData `json:"rules,omitempty"`
+}
+
+type AlertRuleData struct {
+	// Name unique per subscription, the events list the rules which raised them
+	Name string `json:"name"`
+	// Conditions every one of them must match the txn
+	Conditions []AlertConditionData `json:"conditions"`
+}
+
+type AlertConditionData struct {
+	// Type value_above, new_counterparty, contract, failed or nonce_gap
+	Type models.AlertConditionType `json:"type"`
+	// Value threshold of value_above in wei, base 10
+	Value string `json:"value,omitempty"`
+	// Contracts called, for contract
+	Contracts []string `json:"contracts,omitempty"`
+	// Method 4 bytes selector of the call, for contract. Any call when empty
+	Method string `json:"method,omitempty"`
+}
+
+type AlertRulesRequest struct {
+	// Rules replace the rules of the subscription, an empty list notify every txn again
+	Rules []AlertRuleData `json:"rules"`
+}
+
+type DigestData struct {
+	Mode models.DigestMode `json:"mode"`
+	// IntervalMinutes length of the windows of the interval mode
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// DailyAt UTC time of the daily digest, HH:MM
+	DailyAt string `json:"daily_at,omitempty"`
+}
+
+type ThrottleData struct {
+	// Limit notifications sent per period
+	Limit         int `json:"limit"`
+	PeriodMinutes int `json:"period_minutes"`
+}
+
+type NotificationRouteData struct {
+	Channel models.NotificationChannel `json:"channel"`
+	// EventTypes only these events take the route, every type when empty
+	EventTypes []models.NotificationEventType `json:"event_types,omitempty"`
+}
+
+type SubscriptionData struct {
+	Address       string                      `json:"address"`
+	Tenant        string                      `json:"tenant"`
+	Label         string                      `json:"label,omitempty"`
+	Owner         string                      `json:"owner,omitempty"`
+	Tags          []string                    `json:"tags,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	StartBlock    int64                       `json:"start_block"`
+	Notifications NotificationPreferencesData `json:"notifications"`
+}
+
+type SubscriptionResponse struct {
+	Error string            `json:"error,omitempty"`
+	Data  *SubscriptionData `json:"data,omitempty"`
+}
+
+type SubscriptionsResponse struct {
+	Error string             `json:"error,omitempty"`
+	Data  []SubscriptionData `json:"data"`
 }
 
 type SubscribeResponse struct {
@@ -19,3 +107,174 @@ type TransactionsResponse struct {
 	Error string               `json:"error,omitempty"`
 	Data  []models.Transaction `json:"data"`
 }
+
+type TransactionLookupData struct {
+	Transaction   models.Transaction `json:"transaction"`
+	Addresses     []string           `json:"addresses"`
+	Confirmations int64              `json:"confirmations"`
+}
+
+type TransactionLookupResponse struct {
+	Error string                 `json:"error,omitempty"`
+	Data  *TransactionLookupData `json:"data,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name"`
+	Tenant    string   `json:"tenant"`
+	Scopes    []string `json:"scopes" binding:"required"`
+	RateLimit int      `json:"rate_limit"`
+}
+
+type APIKeyData struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Tenant    string     `json:"tenant"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyData struct {
+	APIKeyData
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyResponse struct {
+	Error string            `json:"error,omitempty"`
+	Data  *CreateAPIKeyData `json:"data,omitempty"`
+}
+
+type APIKeysResponse struct {
+	Error string       `json:"error,omitempty"`
+	Data  []APIKeyData `json:"data"`
+}
+
+type RevokeAPIKeyResponse struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type PrunerMetricsData struct {
+	Runs                int64 `json:"runs"`
+	Errors              int64 `json:"errors"`
+	EvictedByAge        int   `json:"evicted_by_age"`
+	EvictedByAddressCap int   `json:"evicted_by_address_cap"`
+	EvictedByTotalCap   int   `json:"evicted_by_total_cap"`
+}
+
+type PrunerMetricsResponse struct {
+	Data PrunerMetricsData `json:"data"`
+}
+
+type StreamTransactionData struct {
+	Addresses   []string           `json:"addresses"`
+	Transaction models.Transaction `json:"transaction"`
+}
+
+type StreamReorgData struct {
+	BlockNumber int64  `json:"block_number"`
+	OldHash     string `json:"old_hash"`
+	NewHash     string `json:"new_hash"`
+}
+
+type StreamErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// WSClientMessage message sent by WebSocket clients
+type WSClientMessage struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	// Confirmations number of confirmation events wanted for the txns of Addresses
+	Confirmations int64 `json:"confirmations,omitempty"`
+}
+
+// WSServerMessage message sent to WebSocket clients, the fields set depend on Type
+type WSServerMessage struct {
+	Type          string              `json:"type"`
+	ID            string              `json:"id,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	Addresses     []string            `json:"addresses,omitempty"`
+	Transaction   *models.Transaction `json:"transaction,omitempty"`
+	Block         *WSBlockData        `json:"block,omitempty"`
+	Hash          string              `json:"hash,omitempty"`
+	BlockNumber   int64               `json:"block_number,omitempty"`
+	Confirmations int64               `json:"confirmations,omitempty"`
+	Reorg         *StreamReorgData    `json:"reorg,omitempty"`
+}
+
+type WSBlockData struct {
+	Number    int64  `json:"number"`
+	Hash      string `json:"hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// GraphQLRequest body of a GraphQL POST request
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+// GraphQLErrorResponse returned for queries rejected before execution
+type GraphQLErrorResponse struct {
+	Errors []GraphQLError `json:"errors"`
+}
+
+// ErrorResponse returned by the middlewares when a request is rejected
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// BulkSubscribeResult outcome of one subscription of a bulk request
+type BulkSubscribeResult struct {
+	// Index position of the subscription in the request, CSV data rows start at 0
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	// Status one of subscribed, already_subscribed, duplicate or invalid
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkSubscribeSummary struct {
+	Subscribed        int `json:"subscribed"`
+	AlreadySubscribed int `json:"already_subscribed"`
+	Duplicate         int `json:"duplicate"`
+	Invalid           int `json:"invalid"`
+}
+
+type BulkSubscribeResponse struct {
+	Error   string                `json:"error,omitempty"`
+	Summary *BulkSubscribeSummary `json:"summary,omitempty"`
+	Data    []BulkSubscribeResult `json:"data,omitempty"`
+}
+
+// NotificationEventData a notification of the outbox
+type NotificationEventData struct {
+	ID string `json:"id"`
+	// Status pending or dead
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// Event as sent by the notifiers
+	Event notification.EventPayload `json:"event"`
+}
+
+type NotificationEventsResponse struct {
+	Error string                  `json:"error,omitempty"`
+	Data  []NotificationEventData `json:"data"`
+}
+
+type NotificationEventResponse struct {
+	Error string                 `json:"error,omitempty"`
+	Data  *NotificationEventData `json:"data,omitempty"`
+}
@@ -0,0 +1,356 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// client messages
+	wsSubscribe   = "subscribe"
+	wsUnsubscribe = "unsubscribe"
+	wsPing        = "ping"
+
+	// server messages, events use the stream event types
+	wsAck          = "ack"
+	wsError        = "error"
+	wsPong         = "pong"
+	wsConfirmation = "confirmation"
+
+	defaultConfirmations = 12
+	maxConfirmations     = 128
+
+	wsSendBuffer     = 64
+	wsMaxMessageSize = 4096
+	wsWriteTimeout   = 10 * time.Second
+	wsPingInterval   = 30 * time.Second
+)
+
+type WebSocketHandler struct {
+	parser       parser.Parser
+	broker       stream.Broker
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	sendBuffer   int
+}
+
+func NewWebSocketHandler(p parser.Parser, b stream.Broker) *WebSocketHandler {
+	return &WebSocketHandler{
+		parser: p,
+		broker: b,
+		upgrader: websocket.Upgrader{
+			// requests are authenticated by API key, not cookies, so any origin is fine
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		pingInterval: wsPingInterval,
+		sendBuffer:   wsSendBuffer,
+	}
+}
+
+// pendingConfirmation a txn sent to the client which still get confirmation events
+type pendingConfirmation struct {
+	blockNumber int64
+	target      int64
+	addresses   []string
+}
+
+// wsSession state of a connection, the maps are only used by the goroutine running Serve
+type wsSession struct {
+	conn   *websocket.Conn
+	tenant string
+	send   chan WSServerMessage
+	head   int64
+	// watched address to the confirmations wanted for its txns
+	watched map[string]int64
+	// pending txn hash to its confirmation state
+	pending map[string]pendingConfirmation
+}
+
+// Serve upgrade the request to a WebSocket. Clients subscribe and unsubscribe to addresses
+// on the fly and receive their txns, new heads, confirmations and reorgs
+func (h *WebSocketHandler) Serve(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader already replied with an error
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.broker.Subscribe()
+	defer unsubscribe()
+
+	s := &wsSession{
+		conn:    conn,
+		tenant:  middleware.GetTenant(c),
+		send:    make(chan WSServerMessage, h.sendBuffer),
+		head:    int64(h.parser.GetCurrentBlock()),
+		watched: make(map[string]int64),
+		pending: make(map[string]pendingConfirmation),
+	}
+
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		h.writeLoop(s)
+	}()
+	defer func() {
+		close(done)
+		close(s.send)
+		<-writerDone
+	}()
+
+	incoming := make(chan WSClientMessage)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		h.readLoop(s, incoming, done)
+	}()
+
+	for {
+		ok := true
+		select {
+		case <-readerDone:
+			return
+		case msg := <-incoming:
+			ok = h.handleClientMessage(s, msg)
+		case event, open := <-events:
+			if !open {
+				ok = false
+				break
+			}
+			ok = h.handleEvent(s, event)
+		}
+		// every message is queued, a client which can not keep up is disconnected
+		// and should resume from the REST API
+		if !ok {
+			logger.GetLogger().Info("Closing slow WebSocket consumer", zap.String("tenant", s.tenant))
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "slow consumer"),
+				time.Now().Add(wsWriteTimeout))
+			return
+		}
+	}
+}
+
+func (h *WebSocketHandler) readLoop(s *wsSession, incoming chan<- WSClientMessage, done <-chan struct{}) {
+	s.conn.SetReadLimit(wsMaxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	})
+	for {
+		var msg WSClientMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		select {
+		case incoming <- msg:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (h *WebSocketHandler) writeLoop(s *wsSession) {
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg, open := <-s.send:
+			if !open {
+				return
+			}
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := s.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// enqueue queue msg to the client, false when the client is too slow
+func (s *wsSession) enqueue(msg WSServerMessage) bool {
+	select {
+	case s.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *WebSocketHandler) handleClientMessage(s *wsSession, msg WSClientMessage) bool {
+	switch msg.Type {
+	case wsPing:
+		return s.enqueue(WSServerMessage{Type: wsPong, ID: msg.ID})
+	case wsSubscribe:
+		addresses, err := h.subscribeAddresses(s.tenant, msg.Addresses)
+		if err != nil {
+			return s.enqueue(WSServerMessage{Type: wsError, ID: msg.ID, Error: err.Error()})
+		}
+		confirmations := msg.Confirmations
+		if confirmations <= 0 {
+			confirmations = defaultConfirmations
+		}
+		if confirmations > maxConfirmations {
+			return s.enqueue(WSServerMessage{
+				Type:  wsError,
+				ID:    msg.ID,
+				Error: fmt.Sprintf("confirmations must be at most %d", maxConfirmations),
+			})
+		}
+		for _, addr := range addresses {
+			s.watched[addr] = confirmations
+		}
+		return s.enqueue(WSServerMessage{Type: wsAck, ID: msg.ID, Addresses: models.ChecksumAddresses(addresses)})
+	case wsUnsubscribe:
+		addresses, err := normalizeAddresses(msg.Addresses)
+		if err != nil {
+			return s.enqueue(WSServerMessage{Type: wsError, ID: msg.ID, Error: err.Error()})
+		}
+		for _, addr := range addresses {
+			delete(s.watched, addr)
+		}
+		return s.enqueue(WSServerMessage{Type: wsAck, ID: msg.ID, Addresses: models.ChecksumAddresses(addresses)})
+	default:
+		return s.enqueue(WSServerMessage{Type: wsError, ID: msg.ID, Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+	}
+}
+
+func (h *WebSocketHandler) handleEvent(s *wsSession, event stream.Event) bool {
+	switch event.Type {
+	case stream.EventTransaction:
+		var (
+			addresses []string
+			target    int64
+		)
+		for _, addr := range event.Addresses {
+			if confirmations, ok := s.watched[addr]; ok {
+				addresses = append(addresses, addr)
+				target = max(target, confirmations)
+			}
+		}
+		if len(addresses) == 0 {
+			return true
+		}
+		tx := event.Transaction.Checksummed()
+		blockNumber, _ := strconv.ParseInt(tx.BlockNumber, 10, 64)
+		s.pending[tx.Hash] = pendingConfirmation{blockNumber: blockNumber, target: target, addresses: addresses}
+		return s.enqueue(WSServerMessage{
+			Type:        stream.EventTransaction,
+			Addresses:   models.ChecksumAddresses(addresses),
+			Transaction: &tx,
+		})
+
+	case stream.EventNewHead:
+		s.head = event.Head.Number
+		if !s.enqueue(WSServerMessage{Type: stream.EventNewHead, Block: &WSBlockData{
+			Number:    event.Head.Number,
+			Hash:      event.Head.Hash,
+			Timestamp: event.Head.Timestamp,
+		}}) {
+			return false
+		}
+		return h.sendConfirmations(s)
+
+	case stream.EventReorg:
+		// the txns of the replaced block are not confirmed anymore
+		for hash, p := range s.pending {
+			if p.blockNumber >= event.Reorg.BlockNumber {
+				delete(s.pending, hash)
+			}
+		}
+		return s.enqueue(WSServerMessage{Type: stream.EventReorg, Reorg: &StreamReorgData{
+			BlockNumber: event.Reorg.BlockNumber,
+			OldHash:     event.Reorg.OldHash,
+			NewHash:     event.Reorg.NewHash,
+		}})
+	}
+	return true
+}
+
+// sendConfirmations send the confirmations of the pending txns at the current head
+func (h *WebSocketHandler) sendConfirmations(s *wsSession) bool {
+	for hash, p := range s.pending {
+		if s.head < p.blockNumber {
+			continue
+		}
+		watched := false
+		for _, addr := range p.addresses {
+			if _, ok := s.watched[addr]; ok {
+				watched = true
+				break
+			}
+		}
+		confirmations := s.head - p.blockNumber + 1
+		if !watched || confirmations >= p.target {
+			delete(s.pending, hash)
+		}
+		if !watched {
+			continue
+		}
+		if !s.enqueue(WSServerMessage{
+			Type:          wsConfirmation,
+			Hash:          hash,
+			BlockNumber:   p.blockNumber,
+			Confirmations: confirmations,
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// subscribeAddresses return the normalized addresses, they must be subscribed by tenant
+func (h *WebSocketHandler) subscribeAddresses(tenant string, addresses []string) ([]string, error) {
+	addresses, err := normalizeAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("addresses are required")
+	}
+	for _, addr := range addresses {
+		if _, found := h.parser.GetSubscription(tenant, addr); !found {
+			return nil, fmt.Errorf("address %s is not subscribed", models.ChecksumAddress(addr))
+		}
+	}
+	return addresses, nil
+}
+
+// normalizeAddresses validate addresses and return them lower cased without duplicates
+func normalizeAddresses(addresses []string) ([]string, error) {
+	seen := make(map[string]bool, len(addresses))
+	normalized := make([]string, 0, len(addresses))
+	for _, raw := range addresses {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		addr, err := models.NormalizeAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(raw), err)
+		}
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		normalized = append(normalized, addr)
+	}
+	return normalized, nil
+}
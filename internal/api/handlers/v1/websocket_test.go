@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
+)
+
+func dialWebSocket(t *testing.T, h *WebSocketHandler) *websocket.Conn {
+	t.Helper()
+	router := gin.New()
+	router.GET("/ws", h.Serve)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) WSServerMessage {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var msg WSServerMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestWebSocketHandler_Serve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := mockParser.NewParser(t)
+	m.On("GetCurrentBlock").Return(100)
+	m.On("GetSubscription", models.DefaultTenant, "0x1231231231231231231231231231231231231231").Return(models.Subscription{Address: "0x1231231231231231231231231231231231231231"}, true)
+	m.On("GetSubscription", models.DefaultTenant, "0x4564564564564564564564564564564564564564").Return(models.Subscription{}, false)
+
+	broker := stream.NewBroker()
+	conn := dialWebSocket(t, NewWebSocketHandler(m, broker))
+
+	// only addresses subscribed by the tenant can be watched
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "subscribe", ID: "1", Addresses: []string{"0x4564564564564564564564564564564564564564"}}))
+	require.Equal(t, WSServerMessage{Type: "error", ID: "1", Error: "address 0x4564564564564564564564564564564564564564 is not subscribed"}, readMessage(t, conn))
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "subscribe", ID: "2", Addresses: []string{"0X1231231231231231231231231231231231231231"}, Confirmations: 2}))
+	require.Equal(t, WSServerMessage{Type: "ack", ID: "2", Addresses: []string{"0x1231231231231231231231231231231231231231"}}, readMessage(t, conn))
+
+	tx := models.Transaction{Hash: "0xabc", From: "0x1231231231231231231231231231231231231231", To: "0x7897897897897897897897897897897897897897", BlockNumber: "101"}
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: models.Transaction{Hash: "0xother"}, Addresses: []string{"0x9999999999999999999999999999999999999999"}})
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx, Addresses: []string{"0x1231231231231231231231231231231231231231", "0x7897897897897897897897897897897897897897"}})
+	require.Equal(t, WSServerMessage{Type: "transaction", Addresses: []string{"0x1231231231231231231231231231231231231231"}, Transaction: &tx}, readMessage(t, conn))
+
+	// the txn is confirmed by the following heads until the requested confirmations
+	for _, block := range []int64{101, 102, 103} {
+		broker.Publish(stream.Event{Type: stream.EventNewHead, Head: models.BlockHead{Number: block, Hash: "0xhead"}})
+		require.Equal(t, WSServerMessage{Type: "new_head", Block: &WSBlockData{Number: block, Hash: "0xhead"}}, readMessage(t, conn))
+		if block <= 102 {
+			require.Equal(t, WSServerMessage{Type: "confirmation", Hash: "0xabc", BlockNumber: 101, Confirmations: block - 100}, readMessage(t, conn))
+		}
+	}
+
+	broker.Publish(stream.Event{Type: stream.EventReorg, Reorg: models.Reorg{BlockNumber: 103, OldHash: "0xa", NewHash: "0xb"}})
+	require.Equal(t, WSServerMessage{Type: "reorg", Reorg: &StreamReorgData{BlockNumber: 103, OldHash: "0xa", NewHash: "0xb"}}, readMessage(t, conn))
+
+	// unwatched addresses are not sent anymore
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "unsubscribe", ID: "3", Addresses: []string{"0x1231231231231231231231231231231231231231"}}))
+	require.Equal(t, WSServerMessage{Type: "ack", ID: "3", Addresses: []string{"0x1231231231231231231231231231231231231231"}}, readMessage(t, conn))
+	broker.Publish(stream.Event{Type: stream.EventTransaction, Transaction: tx, Addresses: []string{"0x1231231231231231231231231231231231231231"}})
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "ping", ID: "4"}))
+	require.Equal(t, WSServerMessage{Type: "pong", ID: "4"}, readMessage(t, conn))
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "watch", ID: "5"}))
+	require.Equal(t, WSServerMessage{Type: "error", ID: "5", Error: `unknown message type "watch"`}, readMessage(t, conn))
+}
+
+func TestWebSocketHandler_handleEvent_slowConsumer(t *testing.T) {
+	h := NewWebSocketHandler(mockParser.NewParser(t), stream.NewBroker())
+	s := &wsSession{
+		send:    make(chan WSServerMessage, 1),
+		watched: make(map[string]int64),
+		pending: make(map[string]pendingConfirmation),
+	}
+
+	// the client does not read, the queue is full after the first message
+	require.True(t, h.handleEvent(s, stream.Event{Type: stream.EventNewHead, Head: models.BlockHead{Number: 101}}))
+	require.False(t, h.handleEvent(s, stream.Event{Type: stream.EventNewHead, Head: models.BlockHead{Number: 102}}))
+}
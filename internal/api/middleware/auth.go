@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyHeader alternative to the Authorization header
+	APIKeyHeader = "X-API-Key"
+
+	apiKeyKey = "api_key"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Auth authenticate the request with the key sent as `Authorization: Bearer <key>` or
+// in the X-API-Key header. The request is scoped to the tenant of the key, admin keys
+// may act on behalf of another tenant with the X-Tenant-ID header
+func Auth(service auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(APIKeyHeader)
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			rawKey = strings.TrimSpace(bearer)
+		}
+		if rawKey == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse{Error: "missing api key"})
+			return
+		}
+
+		key, err := service.Authenticate(rawKey)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidKey) {
+				logger.GetLogger().Error("Failed to authenticate api key", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse{Error: "unable to authenticate"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse{Error: "invalid api key"})
+			return
+		}
+
+		c.Set(apiKeyKey, key)
+		SetTenant(c, key.Tenant)
+		if tenant := c.GetHeader(TenantHeader); tenant != "" && key.HasScope(models.ScopeAdmin) {
+			SetTenant(c, tenant)
+		}
+		c.Next()
+	}
+}
+
+// APIKeyFromQuery use the api_key query parameter as the key of the request when no header
+// is set, browsers can not set headers on WebSocket handshakes. It must run before Auth
+func APIKeyFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.Query("api_key"); key != "" && c.GetHeader(APIKeyHeader) == "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set(APIKeyHeader, key)
+		}
+		c.Next()
+	}
+}
+
+// RequireScope reject requests whose key does not grant scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetAPIKey(c)
+		if !ok || !key.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse{Error: "api key is missing the " + scope + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetAPIKey return the key the request was authenticated with
+func GetAPIKey(c *gin.Context) (models.APIKey, bool) {
+	v, ok := c.Get(apiKeyKey)
+	if !ok {
+		return models.APIKey{}, false
+	}
+	key, ok := v.(models.APIKey)
+	return key, ok
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	mockAuth "github.com/vdhieu/tx-parser/mocks/internal_/auth"
+)
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	readKey := models.APIKey{ID: "1", Tenant: "payments", Scopes: []string{models.ScopeRead}}
+	adminKey := models.APIKey{ID: "2", Tenant: models.DefaultTenant, Scopes: []string{models.ScopeAdmin}}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		setupMock  func(m *mockAuth.Service)
+		wantStatus int
+		wantTenant string
+	}{
+		{
+			name:       "missing key",
+			setupMock:  func(m *mockAuth.Service) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "invalid key",
+			headers: map[string]string{APIKeyHeader: "txp_bad"},
+			setupMock: func(m *mockAuth.Service) {
+				m.On("Authenticate", "txp_bad").Return(models.APIKey{}, auth.ErrInvalidKey)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "storage error",
+			headers: map[string]string{APIKeyHeader: "txp_read"},
+			setupMock: func(m *mockAuth.Service) {
+				m.On("Authenticate", "txp_read").Return(models.APIKey{}, errors.New("boom"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:    "bearer key scoped to its tenant",
+			headers: map[string]string{"Authorization": "Bearer txp_read", TenantHeader: "other"},
+			setupMock: func(m *mockAuth.Service) {
+				m.On("Authenticate", "txp_read").Return(readKey, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantTenant: "payments",
+		},
+		{
+			name:    "admin key act on behalf of a tenant",
+			headers: map[string]string{APIKeyHeader: "txp_admin", TenantHeader: "other"},
+			setupMock: func(m *mockAuth.Service) {
+				m.On("Authenticate", "txp_admin").Return(adminKey, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantTenant: "other",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockAuth.NewService(t)
+			tt.setupMock(m)
+
+			var gotTenant string
+			router := gin.New()
+			router.Use(Auth(m))
+			router.GET("/", func(c *gin.Context) {
+				gotTenant = GetTenant(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantTenant, gotTenant)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		key        *models.APIKey
+		wantStatus int
+	}{
+		{
+			name:       "unauthenticated",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing scope",
+			key:        &models.APIKey{Scopes: []string{models.ScopeRead}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "scope granted",
+			key:        &models.APIKey{Scopes: []string{models.ScopeSubscribe}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "admin grant every scope",
+			key:        &models.APIKey{Scopes: []string{models.ScopeAdmin}},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.key != nil {
+					c.Set(apiKeyKey, *tt.key)
+				}
+			})
+			router.POST("/", RequireScope(models.ScopeSubscribe), func(c *gin.Context) {})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestAPIKeyFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "key from query",
+			url:  "/?api_key=txp_query",
+			want: "txp_query",
+		},
+		{
+			name:    "header take precedence",
+			url:     "/?api_key=txp_query",
+			headers: map[string]string{APIKeyHeader: "txp_header"},
+			want:    "txp_header",
+		},
+		{
+			name:    "authorization take precedence",
+			url:     "/?api_key=txp_query",
+			headers: map[string]string{"Authorization": "Bearer txp_bearer"},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			router := gin.New()
+			router.GET("/", APIKeyFromQuery(), func(c *gin.Context) {
+				got = c.GetHeader(APIKeyHeader)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redacted = "REDACTED"
+
+// Logger the access log of gin with the api_key query parameter redacted, WebSocket clients
+// send their key in the query
+func Logger(out io.Writer) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				redactQuery(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactQuery replace the api key in the query of path, the query is dropped when it can not be parsed
+func redactQuery(path string) string {
+	p, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return p
+	}
+	if !query.Has("api_key") {
+		return path
+	}
+	query.Set("api_key", redacted)
+	return p + "?" + query.Encode()
+}
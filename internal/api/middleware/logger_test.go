@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		notWant string
+	}{
+		{
+			name: "no query",
+			url:  "/ws",
+			want: `"/ws"`,
+		},
+		{
+			name:    "api key redacted",
+			url:     "/ws?api_key=txp_secret&watch=0xabc",
+			want:    `"/ws?api_key=REDACTED&watch=0xabc"`,
+			notWant: "txp_secret",
+		},
+		{
+			name: "other query kept",
+			url:  "/ws?watch=0xabc",
+			want: `"/ws?watch=0xabc"`,
+		},
+		{
+			name:    "invalid query dropped",
+			url:     "/ws?api_key=txp_secret&bad=%zz",
+			want:    `"/ws"`,
+			notWant: "txp_secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			router := gin.New()
+			router.Use(Logger(&out))
+			router.GET("/ws", func(c *gin.Context) {})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			require.Contains(t, out.String(), tt.want)
+			if tt.notWant != "" {
+				require.NotContains(t, out.String(), tt.notWant)
+			}
+		})
+	}
+}
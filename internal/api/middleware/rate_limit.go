@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/auth"
+)
+
+// RateLimit limit every API key with limiter, it must run after Auth
+func RateLimit(limiter auth.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetAPIKey(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		status := limiter.Take(key)
+		c.Header("RateLimit-Limit", strconv.Itoa(status.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(status.Reset))
+		if !status.Allowed {
+			c.Header("Retry-After", strconv.Itoa(status.Reset))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse{Error: "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
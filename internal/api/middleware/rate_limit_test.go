@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
+	mockAuth "github.com/vdhieu/tx-parser/mocks/internal_/auth"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key := models.APIKey{ID: "a"}
+	tests := []struct {
+		name        string
+		status      auth.RateLimitStatus
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "allowed",
+			status:     auth.RateLimitStatus{Allowed: true, Limit: 2, Remaining: 1, Reset: 30},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit":     "2",
+				"RateLimit-Remaining": "1",
+				"RateLimit-Reset":     "30",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:       "rejected",
+			status:     auth.RateLimitStatus{Limit: 2, Reset: 30},
+			wantStatus: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit":     "2",
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "30",
+				"Retry-After":         "30",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := mockAuth.NewRateLimiter(t)
+			limiter.On("Take", key).Return(tt.status)
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set(apiKeyKey, key) }, RateLimit(limiter))
+			router.GET("/", func(c *gin.Context) {})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			for k, v := range tt.wantHeaders {
+				require.Equal(t, v, w.Header().Get(k), k)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+const (
+	// TenantHeader header used by admin keys to act on behalf of another tenant
+	TenantHeader = "X-Tenant-ID"
+
+	tenantKey = "tenant"
+)
+
+// SetTenant scope the rest of the request to tenant
+func SetTenant(c *gin.Context, tenant string) {
+	c.Set(tenantKey, tenant)
+}
+
+// GetTenant return the tenant the request is scoped to
+func GetTenant(c *gin.Context) string {
+	if tenant := c.GetString(tenantKey); tenant != "" {
+		return tenant
+	}
+	return models.DefaultTenant
+}
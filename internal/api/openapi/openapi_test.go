@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type node struct {
+	base
+	Name     string            `json:"name,omitempty"`
+	Created  time.Time         `json:"created_at"`
+	Parent   *node             `json:"parent,omitempty"`
+	Children []node            `json:"children"`
+	Labels   map[string]string `json:"labels"`
+	Count    int64             `json:"count"`
+	Hidden   string            `json:"-"`
+	Untagged bool
+	private  string
+}
+
+func TestDocument_Schema(t *testing.T) {
+	d := New(Info{Title: "test", Version: "1"})
+
+	require.Equal(t, &Schema{Ref: "#/components/schemas/node"}, d.Schema(node{}))
+	require.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/node"}}, d.Schema([]node{}))
+	require.Equal(t, &Schema{Type: "string", Nullable: true}, d.Schema(new(string)))
+
+	ref := &Schema{Ref: "#/components/schemas/node"}
+	require.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":         {Type: "string"},
+			"name":       {Type: "string"},
+			"created_at": {Type: "string", Format: "date-time"},
+			"parent":     ref,
+			"children":   {Type: "array", Items: ref},
+			"labels":     {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"count":      {Type: "integer", Format: "int64"},
+			"Untagged":   {Type: "boolean"},
+		},
+		Required: []string{"id", "created_at", "children", "labels", "count", "Untagged"},
+	}, d.Components.Schemas["node"])
+	require.Len(t, d.Components.Schemas, 1)
+}
+
+func TestDocument_Add(t *testing.T) {
+	d := New(Info{Title: "test", Version: "1"})
+	op := &Operation{OperationID: "getTransaction"}
+	d.Add("GET", "/api/v1/transactions/:hash", op)
+
+	require.Contains(t, d.Paths, "/api/v1/transactions/{hash}")
+	require.Same(t, op, d.Operation("GET", "/api/v1/transactions/:hash"))
+	require.Same(t, op, d.Paths["/api/v1/transactions/{hash}"]["get"])
+	require.Nil(t, d.Operation("POST", "/api/v1/transactions/:hash"))
+	require.Nil(t, d.Operation("GET", "/api/v1/transactions"))
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/block/current", "/api/v1/block/current"},
+		{"/api/v1/subscriptions/:address", "/api/v1/subscriptions/{address}"},
+		{"/files/*path", "/files/{path}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			require.Equal(t, tt.want, Path(tt.path))
+		})
+	}
+}
@@ -2,20 +2,80 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/vdhieu/tx-parser/internal/api/gql"
 	handler "github.com/vdhieu/tx-parser/internal/api/handlers/v1"
+	"github.com/vdhieu/tx-parser/internal/api/middleware"
+	"github.com/vdhieu/tx-parser/internal/auth"
+	"github.com/vdhieu/tx-parser/internal/models"
 	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/stream"
 )
 
-func SetupRouter(p parser.Parser) *gin.Engine {
-	r := gin.Default()
+func SetupRouter(p parser.Parser, a auth.Service, limiter auth.RateLimiter, b stream.Broker,
+	pruner handler.PrunerMetricsSource) *gin.Engine {
+	// the access log redact the api key of WebSocket handshakes
+	r := gin.New()
+	r.Use(middleware.Logger(gin.DefaultWriter), gin.Recovery())
 	h := handler.NewParserHandler(p)
+	sh := handler.NewStreamHandler(p, b)
+	wsh := handler.NewWebSocketHandler(p, b)
+	admin := handler.NewAdminHandler(a, pruner)
+
+	executor, err := gql.NewExecutor(p)
+	if err != nil {
+		// the schema is static, failing to build it is a bug
+		panic(err)
+	}
+	gh := handler.NewGraphQLHandler(executor)
+	docs := handler.NewOpenAPIHandler()
+
+	read := middleware.RequireScope(models.ScopeRead)
+	subscribe := middleware.RequireScope(models.ScopeSubscribe)
+
+	authenticate := middleware.Auth(a)
+	rateLimit := middleware.RateLimit(limiter)
+
+	// the contract is public, everything else need an api key
+	r.GET("/api/v1/openapi.json", docs.Spec)
+	r.GET("/api/v1/notifications/schema.json", docs.EventSchema)
+	r.GET("/api/v1/docs", docs.Docs)
 
 	v1 := r.Group("/api/v1")
+	v1.Use(authenticate, rateLimit)
+	{
+		v1.GET("/block/current", read, h.GetCurrentBlock)
+		v1.POST("/subscribe", subscribe, h.Subscribe)
+		v1.POST("/subscriptions/bulk", subscribe, h.BulkSubscribe)
+		v1.GET("/transactions", read, h.GetTransactions)
+		v1.GET("/transactions/:hash", read, h.GetTransactionByHash)
+		v1.GET("/subscriptions", read, h.ListSubscriptions)
+		v1.GET("/subscriptions/:address", read, h.GetSubscription)
+		v1.DELETE("/subscriptions/:address", subscribe, h.Unsubscribe)
+		v1.PUT("/subscriptions/:address/rules", subscribe, h.SetAlertRules)
+		v1.GET("/notifications/dead-letters", read, h.ListDeadLetters)
+		v1.POST("/notifications/dead-letters/:id/replay", subscribe, h.ReplayDeadLetter)
+		v1.GET("/stream", read, sh.Stream)
+		v1.GET("/graphql", read, gh.Query)
+		v1.POST("/graphql", read, gh.Query)
+	}
+
+	adminGroup := v1.Group("/admin", middleware.RequireScope(models.ScopeAdmin))
+	{
+		adminGroup.POST("/keys", admin.CreateAPIKey)
+		adminGroup.GET("/keys", admin.ListAPIKeys)
+		adminGroup.DELETE("/keys/:id", admin.RevokeAPIKey)
+		adminGroup.GET("/metrics/pruner", admin.PrunerMetrics)
+	}
+
+	// the conventional path of GraphQL clients, with the same auth as /api/v1/graphql
+	graphql := r.Group("/graphql", authenticate, rateLimit, read)
 	{
-		v1.GET("/block/current", h.GetCurrentBlock)
-		v1.POST("/subscribe", h.Subscribe)
-		v1.GET("/transactions", h.GetTransactions)
+		graphql.GET("", gh.Query)
+		graphql.POST("", gh.Query)
 	}
 
+	// browsers can only send the key of a WebSocket in the query
+	r.GET("/api/v1/ws", middleware.APIKeyFromQuery(), authenticate, rateLimit, read, wsh.Serve)
+
 	return r
 }
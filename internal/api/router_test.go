@@ -1,15 +1,26 @@
 package router
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	handler "github.com/vdhieu/tx-parser/internal/api/handlers/v1"
+	"github.com/vdhieu/tx-parser/internal/auth"
 	"github.com/vdhieu/tx-parser/internal/parser"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
+	mockAuth "github.com/vdhieu/tx-parser/mocks/internal_/auth"
 	mockParser "github.com/vdhieu/tx-parser/mocks/internal_/parser"
 )
 
 func TestSetupRouter(t *testing.T) {
 	type args struct {
 		p parser.Parser
+		a auth.Service
+		l auth.RateLimiter
+		b stream.Broker
 	}
 
 	tests := []struct {
@@ -21,6 +32,9 @@ func TestSetupRouter(t *testing.T) {
 			name: "successful router setup",
 			args: args{
 				p: mockParser.NewParser(t),
+				a: mockAuth.NewService(t),
+				l: auth.NewRateLimiter(60),
+				b: stream.NewBroker(),
 			},
 			wantErr: false,
 		},
@@ -32,12 +46,33 @@ func TestSetupRouter(t *testing.T) {
 	}{
 		{"GET", "/api/v1/block/current"},
 		{"POST", "/api/v1/subscribe"},
+		{"POST", "/api/v1/subscriptions/bulk"},
 		{"GET", "/api/v1/transactions"},
+		{"GET", "/api/v1/transactions/:hash"},
+		{"GET", "/api/v1/subscriptions"},
+		{"GET", "/api/v1/subscriptions/:address"},
+		{"DELETE", "/api/v1/subscriptions/:address"},
+		{"PUT", "/api/v1/subscriptions/:address/rules"},
+		{"GET", "/api/v1/notifications/dead-letters"},
+		{"POST", "/api/v1/notifications/dead-letters/:id/replay"},
+		{"GET", "/api/v1/stream"},
+		{"GET", "/api/v1/graphql"},
+		{"POST", "/api/v1/graphql"},
+		{"GET", "/graphql"},
+		{"POST", "/graphql"},
+		{"GET", "/api/v1/ws"},
+		{"POST", "/api/v1/admin/keys"},
+		{"GET", "/api/v1/admin/keys"},
+		{"DELETE", "/api/v1/admin/keys/:id"},
+		{"GET", "/api/v1/admin/metrics/pruner"},
+		{"GET", "/api/v1/openapi.json"},
+		{"GET", "/api/v1/notifications/schema.json"},
+		{"GET", "/api/v1/docs"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			router := SetupRouter(tt.args.p)
+			router := SetupRouter(tt.args.p, tt.args.a, tt.args.l, tt.args.b, new(storage.Pruner))
 			if router == nil {
 				t.Error("SetupRouter() returned nil router")
 			}
@@ -64,3 +99,31 @@ func TestSetupRouter(t *testing.T) {
 		})
 	}
 }
+
+func TestSetupRouter_documented(t *testing.T) {
+	router := SetupRouter(mockParser.NewParser(t), mockAuth.NewService(t), auth.NewRateLimiter(60), stream.NewBroker(), new(storage.Pruner))
+	document := handler.OpenAPIDocument()
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+		require.NotNil(t, document.Operation(route.Method, route.Path),
+			"route %s %s is not documented in handler.OpenAPIDocument", route.Method, route.Path)
+	}
+
+	documented := 0
+	for _, item := range document.Paths {
+		documented += len(item)
+	}
+	require.Equal(t, len(routes), documented, "documented operations without a route")
+}
+
+func TestSetupRouter_graphqlAlias(t *testing.T) {
+	router := SetupRouter(mockParser.NewParser(t), mockAuth.NewService(t), auth.NewRateLimiter(60), stream.NewBroker(), new(storage.Pruner))
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/graphql?query=%7Bstatus%7BcurrentBlock%7D%7D", nil))
+		require.Equal(t, http.StatusUnauthorized, w.Code, method)
+	}
+}
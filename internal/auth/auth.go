@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+var (
+	// ErrInvalidKey returned when the key is unknown or revoked
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrInvalidScope returned when creating a key with an unknown scope
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// Service manage API keys and authenticate requests
+type Service interface {
+	// Authenticate return the key matching the raw key sent by a client
+	Authenticate(rawKey string) (models.APIKey, error)
+	// CreateKey generate a new key, the raw key is only returned here and never stored
+	CreateKey(key models.APIKey) (string, models.APIKey, error)
+	// RevokeKey revoke a key by id
+	RevokeKey(id string) error
+	// ListKeys return all keys, revoked ones included
+	ListKeys() []models.APIKey
+	// Bootstrap make sure rawKey is a valid admin key of the default tenant,
+	// it allows creating the first keys through the API
+	Bootstrap(rawKey string) error
+}
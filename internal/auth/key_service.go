@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+)
+
+const (
+	keyPrefix      = "txp_"
+	bootstrapKeyID = "bootstrap"
+)
+
+var validScopes = map[string]bool{
+	models.ScopeRead:      true,
+	models.ScopeSubscribe: true,
+	models.ScopeAdmin:     true,
+}
+
+type keyService struct {
+	storage storage.Storage
+	now     func() time.Time
+}
+
+// NewService create an API key service backed by storage
+func NewService(storage storage.Storage) Service {
+	return &keyService{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+func (s *keyService) Authenticate(rawKey string) (models.APIKey, error) {
+	if rawKey == "" {
+		return models.APIKey{}, ErrInvalidKey
+	}
+	key, err := s.storage.GetAPIKeyByHash(HashKey(rawKey))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.APIKey{}, ErrInvalidKey
+		}
+		return models.APIKey{}, err
+	}
+	if key.Revoked() {
+		return models.APIKey{}, ErrInvalidKey
+	}
+	return key, nil
+}
+
+func (s *keyService) CreateKey(key models.APIKey) (string, models.APIKey, error) {
+	if len(key.Scopes) == 0 {
+		return "", models.APIKey{}, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range key.Scopes {
+		if !validScopes[scope] {
+			return "", models.APIKey{}, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	rawKey, err := randomHex(32)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	rawKey = keyPrefix + rawKey
+	key.ID, err = randomHex(8)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	if key.Tenant == "" {
+		key.Tenant = models.DefaultTenant
+	}
+	key.Hash = HashKey(rawKey)
+	key.CreatedAt = s.now().UTC()
+	key.RevokedAt = nil
+
+	if err := s.storage.SaveAPIKey(key); err != nil {
+		return "", models.APIKey{}, err
+	}
+	return rawKey, key, nil
+}
+
+func (s *keyService) RevokeKey(id string) error {
+	return s.storage.RevokeAPIKey(id, s.now().UTC())
+}
+
+func (s *keyService) ListKeys() []models.APIKey {
+	return s.storage.ListAPIKeys()
+}
+
+func (s *keyService) Bootstrap(rawKey string) error {
+	hash := HashKey(rawKey)
+	if key, err := s.storage.GetAPIKeyByHash(hash); err == nil && !key.Revoked() {
+		return nil
+	}
+	return s.storage.SaveAPIKey(models.APIKey{
+		ID:        bootstrapKeyID,
+		Name:      "bootstrap admin key",
+		Tenant:    models.DefaultTenant,
+		Hash:      hash,
+		Scopes:    []string{models.ScopeAdmin},
+		CreatedAt: s.now().UTC(),
+	})
+}
+
+// HashKey return the hex encoded sha256 of a raw key, as stored in storage
+func HashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+)
+
+func TestNewService(t *testing.T) {
+	got := NewService(storage.NewMemoryStorage())
+	require.NotNil(t, got)
+}
+
+func Test_keyService_CreateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     models.APIKey
+		wantErr error
+	}{
+		{
+			name: "create read key",
+			key:  models.APIKey{Name: "ci", Tenant: "payments", Scopes: []string{models.ScopeRead}, RateLimit: 10},
+		},
+		{
+			name:    "missing scope",
+			key:     models.APIKey{Name: "ci"},
+			wantErr: ErrInvalidScope,
+		},
+		{
+			name:    "unknown scope",
+			key:     models.APIKey{Name: "ci", Scopes: []string{"root"}},
+			wantErr: ErrInvalidScope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStorage()
+			s := &keyService{storage: store, now: func() time.Time { return time.Unix(1700000000, 0) }}
+
+			raw, key, err := s.CreateKey(tt.key)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, store.ListAPIKeys())
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, key.ID)
+			require.Equal(t, tt.key.Tenant, key.Tenant)
+			// only the hash is stored
+			require.NotContains(t, key.Hash, raw)
+			require.Equal(t, HashKey(raw), key.Hash)
+			require.Equal(t, []models.APIKey{key}, store.ListAPIKeys())
+		})
+	}
+}
+
+func Test_keyService_Authenticate(t *testing.T) {
+	s := NewService(storage.NewMemoryStorage())
+	raw, key, err := s.CreateKey(models.APIKey{Scopes: []string{models.ScopeRead}})
+	require.NoError(t, err)
+	require.Equal(t, models.DefaultTenant, key.Tenant)
+
+	got, err := s.Authenticate(raw)
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+
+	_, err = s.Authenticate("txp_unknown")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = s.Authenticate("")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	require.NoError(t, s.RevokeKey(key.ID))
+	_, err = s.Authenticate(raw)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	require.ErrorIs(t, s.RevokeKey("unknown"), storage.ErrNotFound)
+}
+
+func Test_keyService_Bootstrap(t *testing.T) {
+	s := NewService(storage.NewMemoryStorage())
+	require.NoError(t, s.Bootstrap("secret"))
+	// bootstrapping twice keep a single key
+	require.NoError(t, s.Bootstrap("secret"))
+	require.Len(t, s.ListKeys(), 1)
+
+	key, err := s.Authenticate("secret")
+	require.NoError(t, err)
+	require.True(t, key.HasScope(models.ScopeAdmin))
+}
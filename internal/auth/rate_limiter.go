@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// RateLimitStatus outcome of a request against the limit of a key
+type RateLimitStatus struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset seconds until the next request is allowed when rejected,
+	// otherwise until the limit is fully available again
+	Reset int
+}
+
+// RateLimiter limit the number of requests per minute of every API key,
+// it is shared by every API so a key has a single budget
+type RateLimiter interface {
+	// Take consume a request from the budget of key
+	Take(key models.APIKey) RateLimitStatus
+}
+
+// bucket token bucket refilled continuously at rate tokens per second
+type bucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	updated  time.Time
+}
+
+type rateLimiter struct {
+	defaultLimit int
+	buckets      map[string]*bucket
+	mu           sync.Mutex
+	now          func() time.Time
+}
+
+// NewRateLimiter create a token bucket rate limiter, keys without their own RateLimit
+// get defaultPerMinute requests per minute
+func NewRateLimiter(defaultPerMinute int) RateLimiter {
+	return &rateLimiter{
+		defaultLimit: defaultPerMinute,
+		buckets:      make(map[string]*bucket),
+		now:          time.Now,
+	}
+}
+
+func (l *rateLimiter) Take(key models.APIKey) RateLimitStatus {
+	limit := key.RateLimit
+	if limit <= 0 {
+		limit = l.defaultLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key.ID]
+	if !ok || b.capacity != float64(limit) {
+		b = &bucket{tokens: float64(limit), capacity: float64(limit), rate: float64(limit) / 60, updated: now}
+		l.buckets[key.ID] = b
+	}
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return RateLimitStatus{Limit: limit, Reset: int(math.Ceil((1 - b.tokens) / b.rate))}
+	}
+	b.tokens--
+	return RateLimitStatus{
+		Allowed:   true,
+		Limit:     limit,
+		Remaining: int(b.tokens),
+		Reset:     int(math.Ceil((b.capacity - b.tokens) / b.rate)),
+	}
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	got := NewRateLimiter(60)
+	require.NotNil(t, got)
+}
+
+func Test_rateLimiter_Take(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := &rateLimiter{
+		defaultLimit: 2,
+		buckets:      make(map[string]*bucket),
+		now:          func() time.Time { return now },
+	}
+
+	// default limit
+	key := models.APIKey{ID: "a"}
+	require.Equal(t, RateLimitStatus{Allowed: true, Limit: 2, Remaining: 1, Reset: 30}, l.Take(key))
+	require.Equal(t, RateLimitStatus{Allowed: true, Limit: 2, Remaining: 0, Reset: 60}, l.Take(key))
+	require.Equal(t, RateLimitStatus{Limit: 2, Reset: 30}, l.Take(key))
+
+	// buckets are per key and honour the limit of the key
+	other := models.APIKey{ID: "b", RateLimit: 1}
+	require.Equal(t, RateLimitStatus{Allowed: true, Limit: 1, Remaining: 0, Reset: 60}, l.Take(other))
+	require.False(t, l.Take(other).Allowed)
+
+	// tokens are refilled over time
+	now = now.Add(30 * time.Second)
+	require.True(t, l.Take(key).Allowed)
+	require.False(t, l.Take(key).Allowed)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// API key scopes
+const (
+	ScopeRead      = "read"
+	ScopeSubscribe = "subscribe"
+	ScopeAdmin     = "admin"
+)
+
+// APIKey credential of an API user, only the hash of the key is stored
+type APIKey struct {
+	ID     string
+	Name   string
+	Tenant string
+	// Hash hex encoded sha256 of the raw key
+	Hash   string
+	Scopes []string
+	// RateLimit allowed requests per minute, zero means the server default
+	RateLimit int
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// HasScope whether the key was granted scope, admin keys have every scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked whether the key can not be used anymore
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
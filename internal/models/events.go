@@ -0,0 +1,94 @@
+package models
+
+import "github.com/vdhieu/tx-parser/pkg/events"
+
+// The notification events and the subscription, transaction and alert types they carry are defined
+// in pkg/events so the notifiers of pkg/notification do not depend on internal packages
+
+type (
+	NotificationEvent     = events.NotificationEvent
+	NotificationEventType = events.NotificationEventType
+	Digest                = events.Digest
+	Transaction           = events.Transaction
+	TokenTransfer         = events.TokenTransfer
+	Reorg                 = events.Reorg
+
+	Subscription            = events.Subscription
+	NotificationPreferences = events.NotificationPreferences
+	NotificationChannel     = events.NotificationChannel
+	NotificationRoute       = events.NotificationRoute
+	DigestMode              = events.DigestMode
+	DigestPreferences       = events.DigestPreferences
+	Throttle                = events.Throttle
+
+	AlertRule          = events.AlertRule
+	AlertCondition     = events.AlertCondition
+	AlertConditionType = events.AlertConditionType
+	AlertFacts         = events.AlertFacts
+)
+
+const (
+	NotificationEventVersion = events.NotificationEventVersion
+	ChainEthereum            = events.ChainEthereum
+	DefaultTenant            = events.DefaultTenant
+
+	EventIncoming      = events.EventIncoming
+	EventOutgoing      = events.EventOutgoing
+	EventTokenTransfer = events.EventTokenTransfer
+	EventReorged       = events.EventReorged
+	EventConfirmed     = events.EventConfirmed
+	EventDigest        = events.EventDigest
+
+	ChannelWebhook = events.ChannelWebhook
+	ChannelEmail   = events.ChannelEmail
+	ChannelSlack   = events.ChannelSlack
+	ChannelDiscord = events.ChannelDiscord
+	ChannelKafka   = events.ChannelKafka
+	ChannelNATS    = events.ChannelNATS
+	ChannelConsole = events.ChannelConsole
+
+	DigestImmediate = events.DigestImmediate
+	DigestInterval  = events.DigestInterval
+	DigestDaily     = events.DigestDaily
+	DigestThrottled = events.DigestThrottled
+
+	AlertValueAbove      = events.AlertValueAbove
+	AlertNewCounterparty = events.AlertNewCounterparty
+	AlertContract        = events.AlertContract
+	AlertFailed          = events.AlertFailed
+	AlertNonceGap        = events.AlertNonceGap
+)
+
+var (
+	NotificationEventTypes = events.NotificationEventTypes
+	NotificationChannels   = events.NotificationChannels
+	AlertConditionTypes    = events.AlertConditionTypes
+
+	ErrInvalidAddress  = events.ErrInvalidAddress
+	ErrInvalidChecksum = events.ErrInvalidChecksum
+)
+
+// NormalizeAddress see events.NormalizeAddress
+func NormalizeAddress(addr string) (string, error) {
+	return events.NormalizeAddress(addr)
+}
+
+// ChecksumAddress see events.ChecksumAddress
+func ChecksumAddress(addr string) string {
+	return events.ChecksumAddress(addr)
+}
+
+// ChecksumAddresses see events.ChecksumAddresses
+func ChecksumAddresses(addrs []string) []string {
+	return events.ChecksumAddresses(addrs)
+}
+
+// MatchAlertRules see events.MatchAlertRules
+func MatchAlertRules(rules []AlertRule, facts AlertFacts) []string {
+	return events.MatchAlertRules(rules, facts)
+}
+
+// AlertRulesUse see events.AlertRulesUse
+func AlertRulesUse(rules []AlertRule, conditionType AlertConditionType) bool {
+	return events.AlertRulesUse(rules, conditionType)
+}
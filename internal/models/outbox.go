@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// OutboxStatus where an outbox event is in its delivery
+type OutboxStatus string
+
+const (
+	// OutboxPending waiting for its next delivery attempt
+	OutboxPending OutboxStatus = "pending"
+	// OutboxQueued handed to a notifier delivering it in the background, kept until the delivery is acknowledged
+	OutboxQueued OutboxStatus = "queued"
+	// OutboxDead every attempt failed, the event wait in the dead-letter queue to be replayed
+	OutboxDead OutboxStatus = "dead"
+)
+
+// OutboxEvent a notification event stored with the txn it is about and delivered asynchronously,
+// it is removed from the outbox once delivered
+type OutboxEvent struct {
+	// ID same as the id of Event
+	ID     string
+	Status OutboxStatus
+	Event  NotificationEvent
+	// Attempts failed delivery attempts so far
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+}
+
+// Tenant the tenant owning the subscription the event was created for
+func (e OutboxEvent) Tenant() string {
+	return e.Event.Tenant()
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SubscriptionInput a subscription as the API transports receive it, the REST and gRPC handlers
+// both turn it into a Subscription with Subscription so they accept the same requests
+type SubscriptionInput struct {
+	Address    string
+	Label      string
+	Owner      string
+	Tags       []string
+	StartBlock int64
+	// Notifications default preferences when nil
+	Notifications *NotificationInput
+}
+
+// NotificationInput notification preferences as received, durations in minutes
+type NotificationInput struct {
+	Muted      bool
+	WebhookURL string
+	Emails     []string
+	Routes     []NotificationRoute
+	Digest     *DigestInput
+	Throttle   *ThrottleInput
+	Rules      []AlertRule
+}
+
+type DigestInput struct {
+	Mode            DigestMode
+	IntervalMinutes int
+	// DailyAt UTC time of the daily digest, HH:MM
+	DailyAt string
+}
+
+type ThrottleInput struct {
+	Limit         int
+	PeriodMinutes int
+}
+
+// Subscription the subscription of tenant requested by in, an error is returned for invalid notification
+// preferences. The address is kept as given, callers normalize it when they need to
+func (in SubscriptionInput) Subscription(tenant string) (Subscription, error) {
+	sub := Subscription{
+		Address:    in.Address,
+		Tenant:     tenant,
+		Label:      in.Label,
+		Owner:      in.Owner,
+		Tags:       in.Tags,
+		StartBlock: in.StartBlock,
+	}
+	if in.Notifications == nil {
+		return sub, nil
+	}
+	prefs, err := in.Notifications.Preferences()
+	if err != nil {
+		return Subscription{}, err
+	}
+	sub.Notifications = prefs
+	return sub, nil
+}
+
+// Preferences the validated preferences requested by in, the addresses and selectors of the rules are lower cased
+func (in NotificationInput) Preferences() (NotificationPreferences, error) {
+	prefs := NotificationPreferences{
+		Muted:      in.Muted,
+		WebhookURL: in.WebhookURL,
+		Emails:     in.Emails,
+		Routes:     in.Routes,
+		Rules:      normalizeAlertRules(in.Rules),
+	}
+	if in.Throttle != nil {
+		prefs.Throttle = Throttle{Limit: in.Throttle.Limit, Period: time.Duration(in.Throttle.PeriodMinutes) * time.Minute}
+	}
+	if in.Digest != nil {
+		prefs.Digest = DigestPreferences{Mode: in.Digest.Mode, Interval: time.Duration(in.Digest.IntervalMinutes) * time.Minute}
+		if in.Digest.DailyAt != "" {
+			at, err := time.Parse("15:04", in.Digest.DailyAt)
+			if err != nil {
+				return NotificationPreferences{}, errors.New("digest daily_at must be formatted as HH:MM")
+			}
+			prefs.Digest.DailyAt = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+		}
+	}
+	if err := prefs.Validate(); err != nil {
+		return NotificationPreferences{}, err
+	}
+	return prefs, nil
+}
+
+// normalizeAlertRules copy rules with normalized contracts and methods,
+// invalid addresses are kept as given for the validation to report them
+func normalizeAlertRules(rules []AlertRule) []AlertRule {
+	if rules == nil {
+		return nil
+	}
+	normalized := make([]AlertRule, len(rules))
+	for i, rule := range rules {
+		normalized[i] = AlertRule{Name: rule.Name, Conditions: make([]AlertCondition, len(rule.Conditions))}
+		for j, c := range rule.Conditions {
+			condition := AlertCondition{Type: c.Type, Value: c.Value, Method: strings.ToLower(c.Method)}
+			for _, contract := range c.Contracts {
+				if address, err := NormalizeAddress(contract); err == nil {
+					contract = address
+				}
+				condition.Contracts = append(condition.Contracts, contract)
+			}
+			normalized[i].Conditions[j] = condition
+		}
+	}
+	return normalized
+}
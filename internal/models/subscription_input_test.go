@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testContract = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+
+func TestSubscriptionInput_Subscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      SubscriptionInput
+		want    Subscription
+		wantErr string
+	}{
+		{
+			name: "default preferences",
+			in:   SubscriptionInput{Address: "0x123", Label: "cold wallet", StartBlock: 100},
+			want: Subscription{Address: "0x123", Tenant: "acme", Label: "cold wallet", StartBlock: 100},
+		},
+		{
+			name: "durations and rules",
+			in: SubscriptionInput{Address: "0x123", Notifications: &NotificationInput{
+				Digest:   &DigestInput{Mode: DigestDaily, DailyAt: "18:45"},
+				Throttle: &ThrottleInput{Limit: 10, PeriodMinutes: 60},
+				Rules: []AlertRule{{Name: "usdc", Conditions: []AlertCondition{
+					{Type: AlertContract, Contracts: []string{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}, Method: "0xA9059CBB"},
+				}}},
+			}},
+			want: Subscription{Address: "0x123", Tenant: "acme", Notifications: NotificationPreferences{
+				Digest:   DigestPreferences{Mode: DigestDaily, DailyAt: 18*time.Hour + 45*time.Minute},
+				Throttle: Throttle{Limit: 10, Period: time.Hour},
+				Rules: []AlertRule{{Name: "usdc", Conditions: []AlertCondition{
+					{Type: AlertContract, Contracts: []string{testContract}, Method: "0xa9059cbb"},
+				}}},
+			}},
+		},
+		{
+			name:    "invalid daily time",
+			in:      SubscriptionInput{Notifications: &NotificationInput{Digest: &DigestInput{Mode: DigestDaily, DailyAt: "6pm"}}},
+			wantErr: "digest daily_at must be formatted as HH:MM",
+		},
+		{
+			name: "invalid contract",
+			in: SubscriptionInput{Notifications: &NotificationInput{Rules: []AlertRule{
+				{Name: "a", Conditions: []AlertCondition{{Type: AlertContract, Contracts: []string{"0x123"}}}},
+			}}},
+			wantErr: `alert rule "a": contract condition 0x123: invalid address, expected 0x followed by 40 hex characters`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.Subscription("acme")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
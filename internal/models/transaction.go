@@ -1,10 +1,15 @@
 package models
 
-type Transaction struct {
-	Hash        string
-	From        string
-	To          string
-	Value       string
-	BlockNumber string
-	Timestamp   string
+// TransactionLookup a txn found by hash together with the subscribed addresses it involves
+type TransactionLookup struct {
+	Transaction   Transaction
+	Addresses     []string
+	Confirmations int64
+}
+
+// BlockHead a processed block
+type BlockHead struct {
+	Number    int64
+	Hash      string
+	Timestamp int64
 }
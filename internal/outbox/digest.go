@@ -0,0 +1,119 @@
+package outbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// digestTopSize txns listed in a digest
+const digestTopSize = 5
+
+// newDigestEvent summarize the events of a subscription, oldest first, in a single digest event
+func newDigestEvent(events []models.OutboxEvent, mode models.DigestMode, now time.Time) models.NotificationEvent {
+	last := events[len(events)-1].Event
+	digest := &models.Digest{
+		Mode:   mode,
+		From:   events[0].Event.CreatedAt,
+		To:     last.CreatedAt,
+		Count:  len(events),
+		Counts: make(map[models.NotificationEventType]int),
+	}
+	totalIn, totalOut := new(big.Int), new(big.Int)
+	type valued struct {
+		txn   models.Transaction
+		value *big.Int
+	}
+	var txns []valued
+	for _, e := range events {
+		event := e.Event
+		digest.Counts[event.Type]++
+		digest.EventIDs = append(digest.EventIDs, event.ID)
+		if event.CreatedAt.Before(digest.From) {
+			digest.From = event.CreatedAt
+		}
+		if event.CreatedAt.After(digest.To) {
+			digest.To = event.CreatedAt
+		}
+
+		value, ok := new(big.Int).SetString(event.Transaction.Value, 10)
+		if !ok {
+			value = new(big.Int)
+		}
+		switch event.Type {
+		case models.EventIncoming:
+			totalIn.Add(totalIn, value)
+		case models.EventOutgoing:
+			totalOut.Add(totalOut, value)
+		case models.EventTokenTransfer:
+		default:
+			// reorged and confirmed events are about txns already counted
+			continue
+		}
+		if !slices.ContainsFunc(txns, func(v valued) bool { return v.txn.Hash == event.Transaction.Hash }) {
+			txns = append(txns, valued{txn: event.Transaction, value: value})
+		}
+	}
+	slices.SortStableFunc(txns, func(a, b valued) int { return b.value.Cmp(a.value) })
+	for _, v := range txns[:min(len(txns), digestTopSize)] {
+		digest.Top = append(digest.Top, v.txn)
+	}
+	digest.TotalIn, digest.TotalOut = totalIn.String(), totalOut.String()
+
+	return models.NotificationEvent{
+		ID:           digestEventID(digest.EventIDs),
+		Version:      models.NotificationEventVersion,
+		Type:         models.EventDigest,
+		Chain:        last.Chain,
+		Address:      last.Address,
+		Subscription: last.Subscription,
+		Digest:       digest,
+		OccurredAt:   last.OccurredAt,
+		CreatedAt:    now,
+	}
+}
+
+// digestEventID derived from the events of the digest, so a retried digest keep its id
+func digestEventID(eventIDs []string) string {
+	sum := sha256.Sum256([]byte(string(models.EventDigest) + "/" + strings.Join(eventIDs, "/")))
+	return "evt_" + hex.EncodeToString(sum[:16])
+}
+
+// throttle count the notifications sent per subscription
+type throttle struct {
+	sent map[string][]time.Time
+}
+
+func newThrottle() *throttle {
+	return &throttle{sent: make(map[string][]time.Time)}
+}
+
+// remaining notifications the subscription may send at now, and when the next one is allowed once none is left
+func (t *throttle) remaining(key string, limit models.Throttle, now time.Time) (int, time.Time) {
+	if limit.Limit <= 0 {
+		delete(t.sent, key)
+		return math.MaxInt, time.Time{}
+	}
+	sent := slices.DeleteFunc(t.sent[key], func(at time.Time) bool { return !at.After(now.Add(-limit.Period)) })
+	if len(sent) == 0 {
+		delete(t.sent, key)
+	} else {
+		t.sent[key] = sent
+	}
+	if left := limit.Limit - len(sent); left > 0 {
+		return left, time.Time{}
+	}
+	return 0, sent[len(sent)-limit.Limit].Add(limit.Period)
+}
+
+func (t *throttle) record(key string, limit models.Throttle, now time.Time) {
+	if limit.Limit > 0 {
+		t.sent[key] = append(t.sent[key], now)
+	}
+}
@@ -0,0 +1,370 @@
+// Package outbox deliver the notification events stored by the parser in the storage outbox.
+// Events are written in the same storage commit as their txn and removed only once the notifier
+// accepted them, so every event is delivered at least once even across restarts
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"github.com/vdhieu/tx-parser/pkg/notification"
+	"go.uber.org/zap"
+)
+
+// Config of a dispatcher, zero values use the defaults
+type Config struct {
+	// Interval between two polls of the outbox, 1s by default
+	Interval time.Duration
+	// BatchSize maximum events delivered per poll, 100 by default
+	BatchSize int
+	// MaxAttempts failed deliveries after which an event is dead-lettered, 10 by default
+	MaxAttempts int
+	// InitialBackoff wait before the first retry, doubled after every failure up to MaxBackoff.
+	// 5s and 1h by default
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AwaitAck the notifier deliver the events after Notify returned, as an async notifier do. The events
+	// it accepted stay in the outbox as queued until they are settled with Dispatcher.Acknowledge
+	AwaitAck bool
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 10
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 5 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Hour
+	}
+	return c
+}
+
+// DispatcherMetrics cumulative counters of a dispatcher since it started
+type DispatcherMetrics struct {
+	Delivered int64
+	// Queued events accepted by a notifier which acknowledge them later, see Config.AwaitAck.
+	// They are counted as delivered once acknowledged
+	Queued       int64
+	Failed       int64
+	DeadLettered int64
+	// Digests notifications summarizing several events
+	Digests int64
+	// Throttled events postponed because their subscription reached its throttle limit
+	Throttled int64
+	// Errors storage errors while updating the outbox
+	Errors int64
+}
+
+// Dispatcher periodically deliver the pending events of the outbox with notifier
+type Dispatcher struct {
+	storage  storage.Storage
+	notifier notification.Notifier
+	config   Config
+	log      *zap.Logger
+	now      func() time.Time
+	// ctx canceled on shutdown to abort the delivery in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+	// throttle only used by the dispatching goroutine
+	throttle *throttle
+
+	mu      sync.Mutex
+	metrics DispatcherMetrics
+}
+
+// NewDispatcher create new dispatcher and start its background process
+func NewDispatcher(storage storage.Storage, notifier notification.Notifier, config Config) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		storage:  storage,
+		notifier: notifier,
+		config:   config.withDefaults(),
+		log:      logger.GetLogger().With(zap.String("component", "outbox")),
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		throttle: newThrottle(),
+	}
+
+	if d.config.AwaitAck {
+		d.requeue()
+	}
+
+	d.log.Info("Starting outbox dispatcher",
+		zap.Duration("interval", d.config.Interval),
+		zap.Int("max_attempts", d.config.MaxAttempts))
+	go d.run()
+
+	return d
+}
+
+// Shutdown stop the dispatcher and abort the event being delivered,
+// the events left are delivered after the next start
+func (d *Dispatcher) Shutdown() {
+	d.log.Info("Shutting down outbox dispatcher")
+	close(d.stop)
+	d.cancel()
+	<-d.done
+}
+
+// Metrics return a copy of the dispatcher counters
+func (d *Dispatcher) Metrics() DispatcherMetrics {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.metrics
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.dispatchOnce()
+		}
+	}
+}
+
+// dispatchOnce deliver the due pending events, oldest first. The events of a subscription due together
+// are sent in a digest when the subscription batch them or is over its throttle limit. BatchSize cap
+// the events delivered one by one, a digest or throttled subscription get all its due events at once
+// so they are summarized in a single notification
+func (d *Dispatcher) dispatchOnce() {
+	now := d.now()
+	var keys []string
+	groups := make(map[string][]models.OutboxEvent)
+	due := 0
+	for _, event := range d.storage.ListOutboxEvents(models.OutboxPending) {
+		if event.NextAttemptAt.After(now) {
+			continue
+		}
+		key := event.Tenant() + "/" + event.Event.Address
+		_, started := groups[key]
+		if due >= d.config.BatchSize && !(started && aggregated(event)) {
+			continue
+		}
+		if !started {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], event)
+		due++
+	}
+
+	for _, key := range keys {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+		d.dispatchSubscription(key, groups[key], now)
+	}
+}
+
+// aggregated whether the subscription of event may summarize its events in a digest
+func aggregated(event models.OutboxEvent) bool {
+	prefs := event.Event.Subscription.Notifications
+	return prefs.Digest.Batched() || prefs.Throttle.Limit > 0
+}
+
+// dispatchSubscription deliver the due events of a subscription, within its throttle limit
+func (d *Dispatcher) dispatchSubscription(key string, events []models.OutboxEvent, now time.Time) {
+	prefs := events[len(events)-1].Event.Subscription.Notifications
+	left, next := d.throttle.remaining(key, prefs.Throttle, now)
+	if left == 0 {
+		d.postpone(events, next)
+		return
+	}
+	if prefs.Digest.Batched() {
+		d.throttle.record(key, prefs.Throttle, now)
+		d.deliver(events, newDigestEvent(events, prefs.Digest.Mode, now))
+		return
+	}
+
+	// the events over the limit are sent together with the last notification allowed
+	single := len(events)
+	if single > left {
+		single = left - 1
+	}
+	for _, event := range events[:single] {
+		d.throttle.record(key, prefs.Throttle, now)
+		d.deliver([]models.OutboxEvent{event}, event.Event)
+	}
+	if rest := events[single:]; len(rest) > 0 {
+		d.throttle.record(key, prefs.Throttle, now)
+		d.deliver(rest, newDigestEvent(rest, models.DigestThrottled, now))
+	}
+}
+
+// deliver notify event, which is the only event of events or their digest
+func (d *Dispatcher) deliver(events []models.OutboxEvent, event models.NotificationEvent) {
+	log := d.log.With(zap.String("event_id", event.ID),
+		zap.String("type", string(event.Type)),
+		zap.String("address", event.Address))
+	if d.config.AwaitAck {
+		// queued before Notify since the notifier may acknowledge them before it returns,
+		// they are kept until then and a crash meanwhile deliver them again
+		for _, e := range events {
+			e.Status = models.OutboxQueued
+			d.save(e, log)
+		}
+	}
+	err := d.notifier.Notify(d.ctx, event)
+	if err == nil {
+		d.count(func(m *DispatcherMetrics) {
+			if d.config.AwaitAck {
+				m.Queued += int64(len(events))
+			} else {
+				m.Delivered += int64(len(events))
+			}
+			if event.Digest != nil {
+				m.Digests++
+			}
+		})
+		if d.config.AwaitAck {
+			return
+		}
+		// a crash before the delete deliver the event again, receivers must be idempotent
+		for _, e := range events {
+			if err := d.storage.DeleteOutboxEvent(e.ID); err != nil {
+				d.count(func(m *DispatcherMetrics) { m.Errors++ })
+				log.Error("Failed to remove delivered event", zap.String("outbox_event_id", e.ID), zap.Error(err))
+			}
+		}
+		return
+	}
+	if errors.Is(err, context.Canceled) && d.ctx.Err() != nil {
+		// aborted by shutdown, not a failure of the receiver
+		log.Info("Notification aborted by shutdown")
+		if d.config.AwaitAck {
+			for _, e := range events {
+				d.save(e, log)
+			}
+		}
+		return
+	}
+	// events were not queued by the notifier, they are pending again
+	for _, e := range events {
+		d.fail(e, err)
+	}
+}
+
+// Acknowledge settle the queued events of a notification delivered in the background, see Config.AwaitAck.
+// They leave the outbox when err is nil, otherwise they are retried after a backoff or dead-lettered
+// as any failed delivery. A digest settle every event it batched
+func (d *Dispatcher) Acknowledge(event models.NotificationEvent, err error) {
+	ids := []string{event.ID}
+	if event.Digest != nil {
+		ids = event.Digest.EventIDs
+	}
+	for _, id := range ids {
+		log := d.log.With(zap.String("event_id", id))
+		if err == nil {
+			if err := d.storage.DeleteOutboxEvent(id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				d.count(func(m *DispatcherMetrics) { m.Errors++ })
+				log.Error("Failed to remove delivered event", zap.Error(err))
+				continue
+			}
+			d.count(func(m *DispatcherMetrics) { m.Delivered++ })
+			continue
+		}
+		queued, getErr := d.storage.GetOutboxEvent(id)
+		if getErr != nil {
+			if !errors.Is(getErr, storage.ErrNotFound) {
+				d.count(func(m *DispatcherMetrics) { m.Errors++ })
+				log.Error("Failed to get queued event", zap.Error(getErr))
+			}
+			continue
+		}
+		if queued.Status != models.OutboxQueued {
+			// already settled
+			continue
+		}
+		queued.Status = models.OutboxPending
+		d.fail(queued, err)
+	}
+}
+
+// requeue the events queued by a previous run, they were not acknowledged before it stopped
+func (d *Dispatcher) requeue() {
+	for _, event := range d.storage.ListOutboxEvents(models.OutboxQueued) {
+		event.Status = models.OutboxPending
+		d.save(event, d.log.With(zap.String("event_id", event.ID)))
+	}
+}
+
+// fail record a failed delivery of event, it is retried after a backoff or dead-lettered
+func (d *Dispatcher) fail(event models.OutboxEvent, err error) {
+	log := d.log.With(zap.String("event_id", event.ID),
+		zap.String("type", string(event.Event.Type)),
+		zap.String("address", event.Event.Address))
+	event.Attempts++
+	event.LastError = err.Error()
+	if event.Attempts >= d.config.MaxAttempts {
+		event.Status = models.OutboxDead
+		d.count(func(m *DispatcherMetrics) { m.DeadLettered++ })
+		log.Error("Notification dead-lettered", zap.Int("attempts", event.Attempts), zap.Error(err))
+	} else {
+		event.NextAttemptAt = d.now().Add(d.backoff(event.Attempts))
+		d.count(func(m *DispatcherMetrics) { m.Failed++ })
+		log.Warn("Notification failed, will retry",
+			zap.Int("attempts", event.Attempts),
+			zap.Time("next_attempt_at", event.NextAttemptAt),
+			zap.Error(err))
+	}
+	d.save(event, log)
+}
+
+// postpone events of a throttled subscription until it may send again, they are then sent in a digest
+func (d *Dispatcher) postpone(events []models.OutboxEvent, until time.Time) {
+	d.count(func(m *DispatcherMetrics) { m.Throttled += int64(len(events)) })
+	for _, event := range events {
+		event.NextAttemptAt = until
+		d.save(event, d.log.With(zap.String("event_id", event.ID)))
+	}
+}
+
+func (d *Dispatcher) save(event models.OutboxEvent, log *zap.Logger) {
+	if err := d.storage.SaveOutboxEvent(event); err != nil {
+		d.count(func(m *DispatcherMetrics) { m.Errors++ })
+		log.Error("Failed to save event", zap.Error(err))
+	}
+}
+
+// backoff wait before the attempt following the given number of failures
+func (d *Dispatcher) backoff(failures int) time.Duration {
+	backoff := d.config.InitialBackoff
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (d *Dispatcher) count(update func(*DispatcherMetrics)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	update(&d.metrics)
+}
@@ -0,0 +1,385 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+	mockNoti "github.com/vdhieu/tx-parser/mocks/pkg/notification"
+	"go.uber.org/zap"
+)
+
+func newTestDispatcher(t *testing.T, config Config) (*Dispatcher, storage.Storage, *mockNoti.Notifier, *time.Time) {
+	store := storage.NewMemoryStorage()
+	require.NoError(t, store.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a"}))
+	notifier := mockNoti.NewNotifier(t)
+	now := time.Unix(1700000000, 0).UTC()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d := &Dispatcher{
+		storage:  store,
+		notifier: notifier,
+		config:   config.withDefaults(),
+		log:      zap.NewNop(),
+		now:      func() time.Time { return now },
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		throttle: newThrottle(),
+	}
+	return d, store, notifier, &now
+}
+
+func testEvent(id string, createdAt time.Time) models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:     id,
+		Status: models.OutboxPending,
+		Event: models.NotificationEvent{
+			ID:           id,
+			Version:      models.NotificationEventVersion,
+			Type:         models.EventIncoming,
+			Chain:        models.ChainEthereum,
+			Address:      "0x123",
+			Subscription: models.Subscription{Address: "0x123", Tenant: "a"},
+			Transaction:  models.Transaction{Hash: "0x" + id},
+			CreatedAt:    createdAt,
+		},
+		CreatedAt:     createdAt,
+		NextAttemptAt: createdAt,
+	}
+}
+
+func TestDispatcher_dispatchOnce(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute})
+	first, second := testEvent("1", *now), testEvent("2", now.Add(time.Second))
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, []models.OutboxEvent{first, second}))
+
+	notifier.On("Notify", mock.Anything, first.Event).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, second.Event).Return(errors.New("unexpected status 500"))
+
+	// the delivered event leave the outbox, the failed one is retried after the backoff
+	*now = now.Add(time.Second)
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Delivered: 1, Failed: 1}, d.Metrics())
+	_, err := store.GetOutboxEvent("1")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	got, err := store.GetOutboxEvent("2")
+	require.NoError(t, err)
+	require.Equal(t, 1, got.Attempts)
+	require.Equal(t, "unexpected status 500", got.LastError)
+	require.Equal(t, now.Add(time.Second), got.NextAttemptAt)
+
+	// not due yet
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Delivered: 1, Failed: 1}, d.Metrics())
+
+	*now = now.Add(time.Second)
+	d.dispatchOnce()
+	got, err = store.GetOutboxEvent("2")
+	require.NoError(t, err)
+	require.Equal(t, 2, got.Attempts)
+	require.Equal(t, now.Add(2*time.Second), got.NextAttemptAt)
+
+	// the last attempt move the event to the dead-letter queue
+	*now = now.Add(2 * time.Second)
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Delivered: 1, Failed: 2, DeadLettered: 1}, d.Metrics())
+	require.Empty(t, store.ListOutboxEvents(models.OutboxPending))
+	dead := store.ListOutboxEvents(models.OutboxDead)
+	require.Len(t, dead, 1)
+	require.Equal(t, 3, dead[0].Attempts)
+
+	// dead events are not retried anymore
+	*now = now.Add(time.Hour)
+	d.dispatchOnce()
+	notifier.AssertNumberOfCalls(t, "Notify", 4)
+}
+
+func TestDispatcher_dispatchOnce_batch(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{BatchSize: 2})
+	events := []models.OutboxEvent{testEvent("1", *now), testEvent("2", *now), testEvent("3", *now)}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+	notifier.On("Notify", mock.Anything, events[0].Event).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, events[1].Event).Return(nil).Once()
+
+	d.dispatchOnce()
+	require.Equal(t, []models.OutboxEvent{events[2]}, store.ListOutboxEvents(""))
+}
+
+func TestDispatcher_dispatchOnce_batchDigest(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{BatchSize: 2})
+	prefs := models.NotificationPreferences{Digest: models.DigestPreferences{Mode: models.DigestDaily}}
+	var events []models.OutboxEvent
+	for i := range 5 {
+		events = append(events, valueEvent(fmt.Sprint(i), *now, models.EventIncoming, fmt.Sprint("0x", i), "1", prefs))
+	}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+
+	// the digest is not split by the batch size
+	notifier.On("Notify", mock.Anything, mock.MatchedBy(func(e models.NotificationEvent) bool {
+		return e.Type == models.EventDigest && e.Digest.Count == 5
+	})).Return(nil).Once()
+	d.dispatchOnce()
+	require.Empty(t, store.ListOutboxEvents(""))
+}
+
+// valueEvent an event of eventType about a txn moving value wei, for a subscription notified with prefs
+func valueEvent(id string, createdAt time.Time, eventType models.NotificationEventType, hash, value string,
+	prefs models.NotificationPreferences) models.OutboxEvent {
+	event := testEvent(id, createdAt)
+	event.Event.Type = eventType
+	event.Event.Transaction = models.Transaction{Hash: hash, Value: value}
+	event.Event.Subscription.Notifications = prefs
+	return event
+}
+
+func TestDispatcher_dispatchOnce_digest(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{})
+	prefs := models.NotificationPreferences{Digest: models.DigestPreferences{Mode: models.DigestInterval, Interval: time.Minute}}
+	events := []models.OutboxEvent{
+		valueEvent("1", *now, models.EventIncoming, "0xa", "1000000000000000000", prefs),
+		valueEvent("2", now.Add(time.Second), models.EventOutgoing, "0xb", "500000000000000000", prefs),
+		valueEvent("3", now.Add(2*time.Second), models.EventIncoming, "0xc", "2000000000000000000", prefs),
+		valueEvent("4", now.Add(3*time.Second), models.EventConfirmed, "0xa", "1000000000000000000", prefs),
+	}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+
+	var got models.NotificationEvent
+	notifier.On("Notify", mock.Anything, mock.Anything).Return(nil).Once().
+		Run(func(args mock.Arguments) { got = args.Get(1).(models.NotificationEvent) })
+
+	*now = now.Add(time.Minute)
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Delivered: 4, Digests: 1}, d.Metrics())
+	require.Empty(t, store.ListOutboxEvents(""))
+
+	require.Equal(t, models.EventDigest, got.Type)
+	require.Equal(t, digestEventID([]string{"1", "2", "3", "4"}), got.ID)
+	require.Equal(t, "0x123", got.Address)
+	require.Equal(t, *now, got.CreatedAt)
+	require.Equal(t, &models.Digest{
+		Mode:  models.DigestInterval,
+		From:  events[0].CreatedAt,
+		To:    events[3].CreatedAt,
+		Count: 4,
+		Counts: map[models.NotificationEventType]int{
+			models.EventIncoming: 2, models.EventOutgoing: 1, models.EventConfirmed: 1,
+		},
+		TotalIn:  "3000000000000000000",
+		TotalOut: "500000000000000000",
+		Top: []models.Transaction{
+			{Hash: "0xc", Value: "2000000000000000000"},
+			{Hash: "0xa", Value: "1000000000000000000"},
+			{Hash: "0xb", Value: "500000000000000000"},
+		},
+		EventIDs: []string{"1", "2", "3", "4"},
+	}, got.Digest)
+}
+
+func TestDispatcher_dispatchOnce_throttle(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{})
+	prefs := models.NotificationPreferences{Throttle: models.Throttle{Limit: 2, Period: time.Hour}}
+	var events []models.OutboxEvent
+	for i := range 4 {
+		events = append(events, valueEvent(fmt.Sprint(i), *now, models.EventIncoming, fmt.Sprint("0x", i), "1", prefs))
+	}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+
+	// the first event is sent on its own, the others together as the last notification allowed
+	notifier.On("Notify", mock.Anything, events[0].Event).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, mock.MatchedBy(func(e models.NotificationEvent) bool {
+		return e.Type == models.EventDigest && e.Digest.Mode == models.DigestThrottled && e.Digest.Count == 3
+	})).Return(nil).Once()
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Delivered: 4, Digests: 1}, d.Metrics())
+
+	// over the limit the events wait for the period to end
+	later := []models.OutboxEvent{
+		valueEvent("4", *now, models.EventIncoming, "0x4", "1", prefs),
+		valueEvent("5", *now, models.EventIncoming, "0x5", "1", prefs),
+	}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, later))
+	*now = now.Add(time.Minute)
+	d.dispatchOnce()
+	require.Equal(t, int64(2), d.Metrics().Throttled)
+	got, err := store.GetOutboxEvent("4")
+	require.NoError(t, err)
+	require.Equal(t, now.Add(59*time.Minute), got.NextAttemptAt)
+	require.Equal(t, 0, got.Attempts)
+
+	notifier.On("Notify", mock.Anything, later[0].Event).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, later[1].Event).Return(nil).Once()
+	*now = now.Add(59 * time.Minute)
+	d.dispatchOnce()
+	require.Empty(t, store.ListOutboxEvents(""))
+}
+
+func TestDispatcher_deliver_shutdown(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{})
+	event := testEvent("1", *now)
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, []models.OutboxEvent{event}))
+	notifier.On("Notify", mock.Anything, event.Event).Return(context.Canceled).Once()
+
+	// an attempt aborted by shutdown is not counted
+	d.cancel()
+	d.deliver([]models.OutboxEvent{event}, event.Event)
+	require.Equal(t, DispatcherMetrics{}, d.Metrics())
+	got, err := store.GetOutboxEvent("1")
+	require.NoError(t, err)
+	require.Equal(t, event, got)
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := &Dispatcher{config: Config{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}.withDefaults()}
+	got := make([]time.Duration, 0, 6)
+	for failures := 1; failures <= 6; failures++ {
+		got = append(got, d.backoff(failures))
+	}
+	require.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	}, got)
+}
+
+func TestNewDispatcher(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	require.NoError(t, store.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a"}))
+	event := testEvent("1", time.Now())
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, []models.OutboxEvent{event}))
+
+	notifier := mockNoti.NewNotifier(t)
+	delivered := make(chan struct{})
+	notifier.On("Notify", mock.Anything, event.Event).Return(nil).Once().
+		Run(func(_ mock.Arguments) { close(delivered) })
+
+	d := NewDispatcher(store, notifier, Config{Interval: 10 * time.Millisecond})
+	<-delivered
+	d.Shutdown()
+	require.Equal(t, int64(1), d.Metrics().Delivered)
+	require.Empty(t, store.ListOutboxEvents(""))
+}
+
+func TestDispatcher_deliver_awaitAck(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{AwaitAck: true})
+	events := []models.OutboxEvent{testEvent("1", *now), testEvent("2", *now)}
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+	notifier.On("Notify", mock.Anything, mock.Anything).Return(nil).Twice()
+
+	// the queued events are kept until acknowledged and not dispatched again
+	d.dispatchOnce()
+	d.dispatchOnce()
+	require.Equal(t, DispatcherMetrics{Queued: 2}, d.Metrics())
+	require.Len(t, store.ListOutboxEvents(models.OutboxQueued), 2)
+
+	// a restart deliver the events left queued
+	d.requeue()
+	require.Len(t, store.ListOutboxEvents(models.OutboxPending), 2)
+}
+
+func TestDispatcher_deliver_awaitAckBeforeReturn(t *testing.T) {
+	d, store, notifier, now := newTestDispatcher(t, Config{AwaitAck: true})
+	delivered, failed := testEvent("1", *now), testEvent("2", *now)
+	require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, []models.OutboxEvent{delivered, failed}))
+
+	// a worker may acknowledge the event before Notify returned
+	notifier.On("Notify", mock.Anything, delivered.Event).Return(nil).Once().
+		Run(func(args mock.Arguments) { d.Acknowledge(args.Get(1).(models.NotificationEvent), nil) })
+	notifier.On("Notify", mock.Anything, failed.Event).Return(errors.New("queue full")).Once()
+	d.dispatchOnce()
+
+	require.Equal(t, DispatcherMetrics{Delivered: 1, Queued: 1, Failed: 1}, d.Metrics())
+	events := store.ListOutboxEvents("")
+	require.Len(t, events, 1)
+	require.Equal(t, models.OutboxPending, events[0].Status)
+	require.Equal(t, "2", events[0].ID)
+}
+
+func TestDispatcher_Acknowledge(t *testing.T) {
+	errDown := errors.New("down")
+	digest := models.NotificationEvent{ID: "digest_1", Digest: &models.Digest{EventIDs: []string{"2", "3"}}}
+	tests := []struct {
+		name  string
+		event func(now time.Time) models.NotificationEvent
+		err   error
+		// want status of the events left by id
+		want        map[string]models.OutboxStatus
+		wantMetrics DispatcherMetrics
+	}{
+		{
+			name:        "delivered",
+			event:       func(now time.Time) models.NotificationEvent { return testEvent("1", now).Event },
+			want:        map[string]models.OutboxStatus{"2": models.OutboxQueued, "3": models.OutboxQueued, "4": models.OutboxPending},
+			wantMetrics: DispatcherMetrics{Delivered: 1},
+		},
+		{
+			name:        "delivered digest",
+			event:       func(time.Time) models.NotificationEvent { return digest },
+			want:        map[string]models.OutboxStatus{"1": models.OutboxQueued, "4": models.OutboxPending},
+			wantMetrics: DispatcherMetrics{Delivered: 2},
+		},
+		{
+			name:  "failed is retried",
+			event: func(now time.Time) models.NotificationEvent { return testEvent("1", now).Event },
+			err:   errDown,
+			want: map[string]models.OutboxStatus{
+				"1": models.OutboxPending, "2": models.OutboxQueued, "3": models.OutboxQueued, "4": models.OutboxPending,
+			},
+			wantMetrics: DispatcherMetrics{Failed: 1},
+		},
+		{
+			name:  "failed digest over max attempts",
+			event: func(time.Time) models.NotificationEvent { return digest },
+			err:   errDown,
+			want: map[string]models.OutboxStatus{
+				"1": models.OutboxQueued, "2": models.OutboxDead, "3": models.OutboxDead, "4": models.OutboxPending,
+			},
+			wantMetrics: DispatcherMetrics{DeadLettered: 2},
+		},
+		{
+			name:  "already settled",
+			event: func(now time.Time) models.NotificationEvent { return testEvent("4", now).Event },
+			err:   errDown,
+			want: map[string]models.OutboxStatus{
+				"1": models.OutboxQueued, "2": models.OutboxQueued, "3": models.OutboxQueued, "4": models.OutboxPending,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, store, _, now := newTestDispatcher(t, Config{MaxAttempts: 2, InitialBackoff: time.Second})
+			var events []models.OutboxEvent
+			for _, id := range []string{"1", "2", "3", "4"} {
+				event := testEvent(id, *now)
+				if id != "4" {
+					event.Status = models.OutboxQueued
+				}
+				if id == "2" || id == "3" {
+					event.Attempts = 1
+				}
+				events = append(events, event)
+			}
+			require.NoError(t, store.SaveTransactionsWithEvents("0x123", nil, events))
+
+			d.Acknowledge(tt.event(*now), tt.err)
+			got := map[string]models.OutboxStatus{}
+			for _, event := range store.ListOutboxEvents("") {
+				got[event.ID] = event.Status
+				if event.LastError != "" {
+					require.Equal(t, "down", event.LastError)
+				}
+				if event.ID == "1" && event.Status == models.OutboxPending {
+					require.Equal(t, 1, event.Attempts)
+					require.Equal(t, now.Add(time.Second), event.NextAttemptAt)
+				}
+			}
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantMetrics, d.Metrics())
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// alertState what the parser remember between blocks to evaluate the alert rules,
+// the zero value is ready to use. It is only accessed by the block processing
+type alertState struct {
+	// nonces last nonce seen in an outgoing txn of a subscribed address
+	nonces map[string]int64
+}
+
+// observeNonce record the nonce of an outgoing txn of address and return how many nonces it skipped.
+// The last nonce of an address not seen yet is restored from its stored txns, the first txn of an
+// address never skip any
+func (s *alertState) observeNonce(address string, nonce int64, stored []models.Transaction) int64 {
+	if s.nonces == nil {
+		s.nonces = make(map[string]int64)
+	}
+	last, seen := s.nonces[address]
+	if !seen {
+		last, seen = lastNonce(address, stored)
+	}
+	if !seen || nonce > last {
+		s.nonces[address] = nonce
+	} else {
+		s.nonces[address] = last
+	}
+	if seen && nonce > last+1 {
+		return nonce - last - 1
+	}
+	return 0
+}
+
+// lastNonce the highest nonce of the txns sent by address, false when it sent none
+func lastNonce(address string, txs []models.Transaction) (int64, bool) {
+	var last int64
+	seen := false
+	for _, tx := range txs {
+		if strings.EqualFold(tx.From, address) && (!seen || tx.Nonce > last) {
+			last, seen = tx.Nonce, true
+		}
+	}
+	return last, seen
+}
+
+// newCounterparty whether none of the txns of address involve counterparty
+func newCounterparty(counterparty string, txs []models.Transaction) bool {
+	if counterparty == "" {
+		return false
+	}
+	for _, tx := range txs {
+		if strings.EqualFold(tx.From, counterparty) || strings.EqualFold(tx.To, counterparty) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func Test_alertState_observeNonce(t *testing.T) {
+	var s alertState
+	require.Zero(t, s.observeNonce("0x123", 5, nil))
+	require.Zero(t, s.observeNonce("0x123", 6, nil))
+	require.Equal(t, int64(3), s.observeNonce("0x123", 10, nil))
+	// a replacement of an older nonce is not a gap
+	require.Zero(t, s.observeNonce("0x123", 9, nil))
+	require.Zero(t, s.observeNonce("0x123", 11, nil))
+	require.Zero(t, s.observeNonce("0x456", 40, nil))
+
+	// the last nonce is restored from the txns sent by the address
+	stored := []models.Transaction{
+		{From: "0x789", To: "0xaaa", Nonce: 3},
+		{From: "0xbbb", To: "0x789", Nonce: 90},
+		{From: "0x789", To: "0xaaa", Nonce: 4},
+	}
+	require.Equal(t, int64(2), s.observeNonce("0x789", 7, stored))
+	require.Zero(t, s.observeNonce("0x789", 8, nil))
+}
+
+func Test_newCounterparty(t *testing.T) {
+	txs := []models.Transaction{{From: "0x123", To: "0xaaa"}, {From: "0xBBB", To: "0x123"}}
+	require.False(t, newCounterparty("0xaaa", txs))
+	require.False(t, newCounterparty("0xbbb", txs))
+	require.True(t, newCounterparty("0xccc", txs))
+	// contract creations have no counterparty
+	require.False(t, newCounterparty("", txs))
+}
@@ -1,37 +1,53 @@
 package parser
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/vdhieu/tx-parser/internal/models"
 	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
 	"github.com/vdhieu/tx-parser/pkg/logger"
-	"github.com/vdhieu/tx-parser/pkg/notification"
 	"github.com/vdhieu/tx-parser/pkg/rpc"
 	"go.uber.org/zap"
 )
 
+const (
+	// confirmationDepth confirmations after which a confirmed event is sent for a txn
+	confirmationDepth = 12
+
+	// selectors of the ERC-20 transfer(address,uint256) and transferFrom(address,address,uint256) calls
+	erc20TransferSelector     = "0xa9059cbb"
+	erc20TransferFromSelector = "0x23b872dd"
+)
+
 type ethParser struct {
-	storage  storage.Storage
-	client   rpc.Client
-	log      *zap.Logger
-	notifier notification.Notifier
-	running  bool
+	storage storage.Storage
+	client  rpc.Client
+	log     *zap.Logger
+	broker  stream.Broker
+	running bool
+
+	alerts alertState
 }
 
-// NewEthParser create new parser instance and start a background process to process eth blocks
-func NewEthParser(storage storage.Storage, client rpc.Client, notifier notification.Notifier) Parser {
+// NewEthParser create new parser instance and start a background process to process eth blocks,
+// matched txns and reorgs are published to broker for live consumers. Notifications are enqueued
+// in the storage outbox together with their txn and delivered by an outbox.Dispatcher
+func NewEthParser(storage storage.Storage, client rpc.Client, broker stream.Broker) Parser {
 	log := logger.GetLogger()
 	p := &ethParser{
-		storage:  storage,
-		client:   client,
-		notifier: notifier,
-		log:      log.With(zap.String("parser", "eth")),
+		storage: storage,
+		client:  client,
+		broker:  broker,
+		log:     log.With(zap.String("parser", "eth")),
 	}
-
 	p.log.Info("Starting ETH parser background process")
 	p.running = true
 	go p.processBlocks()
@@ -52,29 +68,244 @@ func (p *ethParser) GetCurrentBlock() int {
 }
 
 // Subscribe subscribe address to event notification
-func (p *ethParser) Subscribe(address string) bool {
-	err := p.storage.AddSubscriber(strings.ToLower(address))
+func (p *ethParser) Subscribe(sub models.Subscription) bool {
+	address, err := models.NormalizeAddress(sub.Address)
 	if err != nil {
+		p.log.Warn("Invalid subscriber address",
+			zap.String("address", sub.Address),
+			zap.Error(err))
+		return false
+	}
+	sub.Address = address
+	if sub.Tenant == "" {
+		sub.Tenant = models.DefaultTenant
+	}
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now().UTC()
+	}
+
+	if err := p.storage.AddSubscriber(sub); err != nil {
 		p.log.Error("Failed to add subscriber",
-			zap.String("address", address),
+			zap.String("address", sub.Address),
 			zap.Error(err))
 		return false
 	}
-	p.log.Info("New subscriber added", zap.String("address", address))
+	p.log.Info("New subscriber added",
+		zap.String("address", sub.Address),
+		zap.String("tenant", sub.Tenant),
+		zap.String("owner", sub.Owner))
+
+	return true
+}
+
+// BulkSubscribe validate subs and store the new ones in a single batch
+func (p *ethParser) BulkSubscribe(subs []models.Subscription) ([]BulkSubscribeResult, error) {
+	results := make([]BulkSubscribeResult, len(subs))
+	batch := make([]models.Subscription, 0, len(subs))
+	seen := make(map[string]bool, len(subs))
+	now := time.Now().UTC()
+	for i, sub := range subs {
+		address, err := models.NormalizeAddress(sub.Address)
+		if err != nil {
+			results[i] = BulkSubscribeResult{Address: sub.Address, Status: BulkInvalid, Err: err}
+			continue
+		}
+		sub.Address = address
+		if sub.Tenant == "" {
+			sub.Tenant = models.DefaultTenant
+		}
+		if sub.CreatedAt.IsZero() {
+			sub.CreatedAt = now
+		}
+
+		results[i].Address = address
+		key := sub.Tenant + "/" + address
+		if seen[key] {
+			results[i].Status = BulkDuplicate
+			continue
+		}
+		seen[key] = true
+		if _, err := p.storage.GetSubscription(sub.Tenant, address); err == nil {
+			results[i].Status = BulkAlreadySubscribed
+			continue
+		}
+		results[i].Status = BulkSubscribed
+		batch = append(batch, sub)
+	}
+
+	if err := p.storage.AddSubscribers(batch); err != nil {
+		p.log.Error("Failed to add subscribers",
+			zap.Int("count", len(batch)),
+			zap.Error(err))
+		return nil, err
+	}
+	p.log.Info("New subscribers added",
+		zap.Int("count", len(batch)),
+		zap.Int("requested", len(subs)))
+	return results, nil
+}
 
+// Unsubscribe stop observing address for tenant
+func (p *ethParser) Unsubscribe(tenant, address string) bool {
+	address = strings.ToLower(address)
+	if err := p.storage.RemoveSubscriber(tenant, address); err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			p.log.Error("Failed to remove subscriber",
+				zap.String("address", address),
+				zap.Error(err))
+		}
+		return false
+	}
+	p.log.Info("Subscriber removed",
+		zap.String("address", address),
+		zap.String("tenant", tenant))
 	return true
 }
 
-// GetTransactions return all txn parsed filter by address
-func (p *ethParser) GetTransactions(address string) []models.Transaction {
-	txs, err := p.storage.GetTransactions(strings.ToLower(address))
+// GetSubscription return the subscription of an address in tenant
+func (p *ethParser) GetSubscription(tenant, address string) (models.Subscription, bool) {
+	sub, err := p.storage.GetSubscription(tenant, strings.ToLower(address))
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			p.log.Error("Failed to get subscription",
+				zap.String("address", address),
+				zap.Error(err))
+		}
+		return models.Subscription{}, false
+	}
+	return sub, true
+}
+
+// SetAlertRules replace the alert rules of the subscription of address in tenant
+func (p *ethParser) SetAlertRules(tenant, address string, rules []models.AlertRule) (models.Subscription, error) {
+	address = strings.ToLower(address)
+	sub, err := p.storage.GetSubscription(tenant, address)
+	if err == nil {
+		sub.Notifications.Rules = rules
+		err = p.storage.UpdateSubscription(sub)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.Subscription{}, ErrNotSubscribed
+		}
+		p.log.Error("Failed to set alert rules",
+			zap.String("address", address),
+			zap.Error(err))
+		return models.Subscription{}, err
+	}
+	p.log.Info("Alert rules updated",
+		zap.String("address", address),
+		zap.String("tenant", tenant),
+		zap.Int("rules", len(rules)))
+	return sub, nil
+}
+
+// ListSubscriptions return all subscriptions of tenant
+func (p *ethParser) ListSubscriptions(tenant string) []models.Subscription {
+	return p.storage.ListSubscriptions(tenant)
+}
+
+// GetTransactions return all txn parsed filter by address, only if tenant subscribed the address
+func (p *ethParser) GetTransactions(tenant, address string) []models.Transaction {
+	address = strings.ToLower(address)
+	if _, err := p.storage.GetSubscription(tenant, address); err != nil {
+		return nil
+	}
+
+	txs, err := p.storage.GetTransactions(address)
 	if err != nil {
 		p.log.Error("Failed to add subscriber",
 			zap.String("address", address),
 			zap.Error(err))
 		return nil
 	}
-	return txs
+	return publicTransactions(txs)
+}
+
+// GetTransactionsPage return a page of the txns of address, only if tenant subscribed the address
+func (p *ethParser) GetTransactionsPage(tenant, address string, offset, limit int) ([]models.Transaction, int, error) {
+	address = strings.ToLower(address)
+	if _, err := p.storage.GetSubscription(tenant, address); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, 0, ErrNotSubscribed
+		}
+		return nil, 0, err
+	}
+	txs, total, err := p.storage.GetTransactionsPage(address, offset, limit)
+	return publicTransactions(txs), total, err
+}
+
+// GetTransactionByHash find a parsed txn and the addresses subscribed by tenant it involves
+func (p *ethParser) GetTransactionByHash(tenant, hash string) (models.TransactionLookup, bool) {
+	tx, holders, err := p.storage.GetTransactionByHash(hash)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			p.log.Error("Failed to get transaction by hash",
+				zap.String("hash", hash),
+				zap.Error(err))
+		}
+		return models.TransactionLookup{}, false
+	}
+
+	// hide the txn and the addresses of other tenants
+	addresses := make([]string, 0, len(holders))
+	for _, addr := range holders {
+		if _, err := p.storage.GetSubscription(tenant, addr); err == nil {
+			addresses = append(addresses, addr)
+		}
+	}
+	if len(addresses) == 0 {
+		return models.TransactionLookup{}, false
+	}
+
+	lookup := models.TransactionLookup{
+		Transaction: tx.Public(),
+		Addresses:   addresses,
+	}
+	current, _ := p.storage.GetCurrentBlock()
+	if blockNumber, err := strconv.ParseInt(tx.BlockNumber, 10, 64); err == nil && current >= blockNumber {
+		lookup.Confirmations = current - blockNumber + 1
+	}
+	return lookup, true
+}
+
+// ListDeadLetters return the notifications of tenant which could not be delivered
+func (p *ethParser) ListDeadLetters(tenant string) []models.OutboxEvent {
+	events := make([]models.OutboxEvent, 0)
+	for _, event := range p.storage.ListOutboxEvents(models.OutboxDead) {
+		if event.Tenant() == tenant {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// ReplayDeadLetter move a dead-lettered notification of tenant back to the outbox for a new round of attempts
+func (p *ethParser) ReplayDeadLetter(tenant, id string) (models.OutboxEvent, error) {
+	event, err := p.storage.GetOutboxEvent(id)
+	if err != nil || event.Tenant() != tenant {
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return models.OutboxEvent{}, err
+		}
+		return models.OutboxEvent{}, ErrEventNotFound
+	}
+	if event.Status != models.OutboxDead {
+		return models.OutboxEvent{}, ErrEventNotDead
+	}
+
+	event.Status = models.OutboxPending
+	event.Attempts = 0
+	event.NextAttemptAt = time.Now().UTC()
+	if err := p.storage.SaveOutboxEvent(event); err != nil {
+		p.log.Error("Failed to replay dead letter",
+			zap.String("event_id", id),
+			zap.Error(err))
+		return models.OutboxEvent{}, err
+	}
+	p.log.Info("Dead letter replayed",
+		zap.String("event_id", id),
+		zap.String("tenant", tenant))
+	return event, nil
 }
 
 func (p *ethParser) processBlocks() {
@@ -103,6 +334,8 @@ func (p *ethParser) processBlocks() {
 			zap.Int64("from_block", lastProcessed),
 			zap.Int64("to_block", currentBlock))
 
+		// blocks processed again after a reorg do not confirm any block again
+		highest := lastProcessed
 		for blockNum := lastProcessed + 1; blockNum <= currentBlock; blockNum++ {
 			block, err := p.client.GetBlockByNumber(blockNum)
 			if err != nil {
@@ -112,8 +345,35 @@ func (p *ethParser) processBlocks() {
 				continue
 			}
 
+			ancestor, reorged, err := p.detectReorg(block)
+			if err != nil {
+				log.Error("Failed to check reorg",
+					zap.Int64("block_number", blockNum),
+					zap.Error(err))
+				break
+			}
+			if reorged {
+				// the new chain is processed from the last common block
+				blockNum = ancestor
+				continue
+			}
+
+			head := models.BlockHead{Number: block.Number, Hash: block.Hash, Timestamp: block.Timestamp}
+			if err := p.storage.SaveBlockHead(head); err != nil {
+				log.Error("Failed to save block head",
+					zap.Int64("block_number", blockNum),
+					zap.Error(err))
+			}
 			p.storage.SetCurrentBlock(blockNum)
 			p.processTransactions(block)
+			if blockNum > highest {
+				p.enqueueConfirmed(blockNum)
+				highest = blockNum
+			}
+			p.publish(stream.Event{
+				Type: stream.EventNewHead,
+				Head: head,
+			})
 			log.Debug("Processed block", zap.Int64("block_number", blockNum))
 		}
 	}
@@ -127,31 +387,41 @@ func (p *ethParser) processTransactions(block rpc.Block) {
 		return
 	}
 
-	subscribers := p.storage.GetSubscribers()
-	if len(subscribers) == 0 {
+	subscriptions := p.storage.ListAllSubscriptions()
+	if len(subscriptions) == 0 {
 		return
 	}
 
-	subscriberMap := make(map[string]bool)
-	for _, addr := range subscribers {
-		subscriberMap[strings.ToLower(addr)] = true
+	// every tenant subscribing an address is matched in a single pass over the block
+	blockNumber := block.Number
+	subscriberMap := make(map[string][]models.Subscription)
+	for _, sub := range subscriptions {
+		// the subscription start after this block
+		if sub.StartBlock > blockNumber {
+			continue
+		}
+		addr := strings.ToLower(sub.Address)
+		subscriberMap[addr] = append(subscriberMap[addr], sub)
 	}
 
-	blockNumber := block.Number
 	matchedTxs := 0
 	for _, tx := range transactions {
 		fromAddr := strings.ToLower(tx.From)
 		toAddr := strings.ToLower(tx.To)
+		fromSubs, fromSubscribed := subscriberMap[fromAddr]
+		toSubs, toSubscribed := subscriberMap[toAddr]
 
-		if subscriberMap[fromAddr] || subscriberMap[toAddr] {
+		if fromSubscribed || toSubscribed {
 			matchedTxs++
 			transaction := models.Transaction{
-				Hash:        tx.Hash,
-				From:        fromAddr,
-				To:          tx.To,
-				Value:       strconv.FormatInt(tx.Value, 10),
-				BlockNumber: strconv.FormatInt(blockNumber, 10),
-				Timestamp:   strconv.FormatInt(block.Timestamp, 10),
+				Hash:          tx.Hash,
+				From:          fromAddr,
+				To:            tx.To,
+				Value:         strconv.FormatInt(tx.Value, 10),
+				BlockNumber:   strconv.FormatInt(blockNumber, 10),
+				Timestamp:     strconv.FormatInt(block.Timestamp, 10),
+				TokenTransfer: decodeTokenTransfer(tx),
+				Nonce:         tx.Nonce,
 			}
 
 			p.log.Debug("Found matching transaction",
@@ -159,24 +429,34 @@ func (p *ethParser) processTransactions(block rpc.Block) {
 				zap.String("from", tx.From),
 				zap.String("to", tx.To))
 
-			if subscriberMap[fromAddr] {
-				existing, _ := p.storage.GetTransactions(fromAddr)
-				err := p.storage.SaveTransactions(fromAddr, append(existing, transaction))
-				if err != nil {
-					p.log.Error(fmt.Sprintf("Unable to save txn for address %v", fromAddr), zap.Error(err))
-				}
+			facts := models.AlertFacts{Transaction: transaction, Input: tx.Input}
+			if fromSubscribed {
+				stored, _ := p.storage.GetTransactions(fromAddr)
+				facts.NonceGap = p.alerts.observeNonce(fromAddr, tx.Nonce, stored)
+			}
+			if checkFailed(fromSubs) || (toSubscribed && checkFailed(toSubs)) {
+				facts.Failed = p.txFailed(tx.Hash)
+			}
 
-				p.notifier.Notify(fromAddr, "found a new transactions", transaction)
+			var addresses []string
+			if fromSubscribed {
+				p.saveAndEnqueue(fromAddr, fromSubs, transaction, transactionEventType(fromAddr, tx), facts)
+				addresses = append(addresses, fromAddr)
 			}
 
-			if subscriberMap[toAddr] {
-				existing, _ := p.storage.GetTransactions(toAddr)
-				err := p.storage.SaveTransactions(toAddr, append(existing, transaction))
-				if err != nil {
-					p.log.Error(fmt.Sprintf("Unable to save txn for address %v", toAddr), zap.Error(err))
-				}
-				p.notifier.Notify(toAddr, "found a new transactions", transaction)
+			// a self transfer is saved and notified once
+			if toSubscribed && toAddr != fromAddr {
+				// the nonce is the one of the sender
+				facts.NonceGap = 0
+				p.saveAndEnqueue(toAddr, toSubs, transaction, transactionEventType(toAddr, tx), facts)
+				addresses = append(addresses, toAddr)
 			}
+
+			p.publish(stream.Event{
+				Type:        stream.EventTransaction,
+				Transaction: transaction,
+				Addresses:   addresses,
+			})
 		}
 	}
 
@@ -186,3 +466,287 @@ func (p *ethParser) processTransactions(block rpc.Block) {
 			zap.Int("matched_transactions", matchedTxs))
 	}
 }
+
+// saveAndEnqueue save the txn once for address and enqueue a notification for every tenant subscribing it,
+// the notifications are stored with the txn and delivered asynchronously. A subscription with alert rules
+// is only notified when the txn described by facts raise an alert, the alerts are saved with the txn
+func (p *ethParser) saveAndEnqueue(address string, subs []models.Subscription, transaction models.Transaction,
+	eventType models.NotificationEventType, facts models.AlertFacts) {
+	existing, _ := p.storage.GetTransactions(address)
+	counterparty := transaction.To
+	if strings.EqualFold(counterparty, address) {
+		counterparty = transaction.From
+	}
+	facts.NewCounterparty = newCounterparty(counterparty, existing)
+
+	events := make([]models.OutboxEvent, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Notifications.Muted {
+			continue
+		}
+		event := newNotificationEvent(eventType, address, sub, transaction)
+		event.Confirmations = 1
+		if rules := sub.Notifications.Rules; len(rules) > 0 {
+			event.Alerts = models.MatchAlertRules(rules, facts)
+			if len(event.Alerts) == 0 {
+				continue
+			}
+			if transaction.Alerts == nil {
+				transaction.Alerts = make(map[string][]string)
+			}
+			transaction.Alerts[sub.Tenant] = event.Alerts
+		}
+		events = append(events, newOutboxEvent(event))
+	}
+
+	err := p.storage.AppendTransactionsWithEvents(address, []models.Transaction{transaction}, events)
+	if err != nil {
+		p.log.Error(fmt.Sprintf("Unable to save txn for address %v", address), zap.Error(err))
+	}
+}
+
+// enqueueConfirmed enqueue a confirmed event for the saved txns of the block reaching
+// confirmationDepth once blockNumber is processed
+func (p *ethParser) enqueueConfirmed(blockNumber int64) {
+	confirmedBlock := blockNumber - confirmationDepth + 1
+	if confirmedBlock < 0 {
+		return
+	}
+	p.enqueueForBlock(confirmedBlock, models.EventConfirmed, func(event *models.NotificationEvent) {
+		event.Confirmations = confirmationDepth
+	})
+}
+
+// enqueueReorged enqueue a reorged event for the saved txns of the replaced block
+func (p *ethParser) enqueueReorged(reorg models.Reorg) {
+	p.enqueueForBlock(reorg.BlockNumber, models.EventReorged, func(event *models.NotificationEvent) {
+		event.Reorg = &reorg
+		// the id of the replaced block is part of the event id, the txn may be reorged again later
+		event.ID = notificationEventID(event.Type, event.Tenant(), event.Address, event.Transaction.Hash, reorg.OldHash)
+	})
+}
+
+// enqueueForBlock enqueue an event of eventType completed by build for every saved txn of blockNumber
+// and every tenant which was notified of it, the subscriptions with alert rules only for the txns
+// which raised an alert
+func (p *ethParser) enqueueForBlock(blockNumber int64, eventType models.NotificationEventType,
+	build func(*models.NotificationEvent)) {
+	number := strconv.FormatInt(blockNumber, 10)
+	subscriberMap := make(map[string][]models.Subscription)
+	for _, sub := range p.storage.ListAllSubscriptions() {
+		if sub.StartBlock > blockNumber || sub.Notifications.Muted {
+			continue
+		}
+		addr := strings.ToLower(sub.Address)
+		subscriberMap[addr] = append(subscriberMap[addr], sub)
+	}
+
+	for address, subs := range subscriberMap {
+		txs, err := p.storage.GetTransactions(address)
+		if err != nil {
+			continue
+		}
+		// txns are saved in block order, only the last ones can be in the block
+		for i := len(txs) - 1; i >= 0; i-- {
+			txBlock, err := strconv.ParseInt(txs[i].BlockNumber, 10, 64)
+			if err != nil || txBlock < blockNumber {
+				break
+			}
+			if txs[i].BlockNumber != number {
+				continue
+			}
+			for _, sub := range subs {
+				event := newNotificationEvent(eventType, address, sub, txs[i])
+				if len(sub.Notifications.Rules) > 0 {
+					alerts, raised := txs[i].Alerts[sub.Tenant]
+					if !raised {
+						continue
+					}
+					event.Alerts = alerts
+				}
+				build(&event)
+				if err := p.storage.SaveOutboxEvent(newOutboxEvent(event)); err != nil {
+					p.log.Error("Unable to enqueue notification",
+						zap.String("type", string(event.Type)),
+						zap.String("address", address),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func newNotificationEvent(eventType models.NotificationEventType, address string, sub models.Subscription,
+	transaction models.Transaction) models.NotificationEvent {
+	occurredAt := time.Time{}
+	if ts, err := strconv.ParseInt(transaction.Timestamp, 10, 64); err == nil {
+		occurredAt = time.Unix(ts, 0).UTC()
+	}
+	return models.NotificationEvent{
+		ID:           notificationEventID(eventType, sub.Tenant, address, transaction.Hash),
+		Version:      models.NotificationEventVersion,
+		Type:         eventType,
+		Chain:        models.ChainEthereum,
+		Address:      address,
+		Subscription: sub,
+		Transaction:  transaction.Public(),
+		OccurredAt:   occurredAt,
+		CreatedAt:    time.Now().UTC(),
+	}
+}
+
+// newOutboxEvent the event is due at once, or at the end of the digest window of its subscription
+func newOutboxEvent(event models.NotificationEvent) models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:            event.ID,
+		Status:        models.OutboxPending,
+		Event:         event,
+		CreatedAt:     event.CreatedAt,
+		NextAttemptAt: event.Subscription.Notifications.Digest.DeliverAt(event.CreatedAt),
+	}
+}
+
+// notificationEventID is derived from what the event is about, so parsing a block again replace
+// the events instead of notifying twice
+func notificationEventID(eventType models.NotificationEventType, parts ...string) string {
+	key := string(eventType)
+	for _, part := range parts {
+		key += "/" + strings.ToLower(part)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "evt_" + hex.EncodeToString(sum[:16])
+}
+
+// transactionEventType the event sent for a txn of address, token calls are told apart by their selector
+func transactionEventType(address string, tx rpc.Transaction) models.NotificationEventType {
+	input := strings.ToLower(tx.Input)
+	if strings.HasPrefix(input, erc20TransferSelector) || strings.HasPrefix(input, erc20TransferFromSelector) {
+		return models.EventTokenTransfer
+	}
+	if strings.EqualFold(tx.From, address) {
+		return models.EventOutgoing
+	}
+	return models.EventIncoming
+}
+
+// decodeTokenTransfer the ERC-20 transfer of tx, nil when it is not a well formed transfer or transferFrom call
+func decodeTokenTransfer(tx rpc.Transaction) *models.TokenTransfer {
+	input := strings.ToLower(tx.Input)
+	if len(input) < 10 {
+		return nil
+	}
+	selector, args := input[:10], input[10:]
+	// every argument is a 32 bytes word, addresses are in its last 20 bytes
+	word := func(i int) string { return args[i*64 : (i+1)*64] }
+
+	transfer := models.TokenTransfer{Token: strings.ToLower(tx.To), From: strings.ToLower(tx.From)}
+	amountWord := 1
+	switch {
+	case selector == erc20TransferSelector && len(args) >= 2*64:
+		transfer.To = "0x" + word(0)[24:]
+	case selector == erc20TransferFromSelector && len(args) >= 3*64:
+		transfer.From, transfer.To = "0x"+word(0)[24:], "0x"+word(1)[24:]
+		amountWord = 2
+	default:
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(word(amountWord), 16)
+	if !ok {
+		return nil
+	}
+	transfer.Amount = amount.String()
+	return &transfer
+}
+
+// checkFailed whether a rule of subs need to know if the txn failed
+func checkFailed(subs []models.Subscription) bool {
+	for _, sub := range subs {
+		if !sub.Notifications.Muted && models.AlertRulesUse(sub.Notifications.Rules, models.AlertFailed) {
+			return true
+		}
+	}
+	return false
+}
+
+// txFailed whether the txn was reverted, its receipt is only fetched for the rules checking it
+func (p *ethParser) txFailed(hash string) bool {
+	receipt, err := p.client.GetTransactionReceipt(hash)
+	if err != nil {
+		p.log.Warn("Unable to get txn receipt", zap.String("hash", hash), zap.Error(err))
+		return false
+	}
+	return receipt.Status == 0
+}
+
+// detectReorg check block build on the last processed block. When it does not, the processed blocks it
+// replace are walked back with the recent block heads until the last common block: the subscribers of their
+// txns are notified, the txns are removed and the current block is moved back to the last common block
+// which is returned, so the new chain is processed from there
+func (p *ethParser) detectReorg(block rpc.Block) (int64, bool, error) {
+	heads := p.storage.GetBlockHeads()
+	// gaps in the processed blocks can not be checked
+	if len(heads) == 0 || heads[len(heads)-1].Number != block.Number-1 || heads[len(heads)-1].Hash == block.ParentHash {
+		return 0, false, nil
+	}
+
+	var reorgs []models.Reorg
+	parentHash := block.ParentHash
+	ancestor := heads[0].Number - 1
+	for i := len(heads) - 1; i >= 0; i-- {
+		head := heads[i]
+		if head.Hash == parentHash {
+			ancestor = head.Number
+			break
+		}
+		reorgs = append(reorgs, models.Reorg{BlockNumber: head.Number, OldHash: head.Hash, NewHash: parentHash})
+		if i == 0 || heads[i-1].Number != head.Number-1 {
+			// the older blocks are not known, the reorg is assumed to start there
+			ancestor = head.Number - 1
+			break
+		}
+		canonical, err := p.client.GetBlockByNumber(head.Number)
+		if err != nil {
+			return 0, false, fmt.Errorf("get block %d: %w", head.Number, err)
+		}
+		parentHash = canonical.ParentHash
+	}
+
+	p.log.Warn("Detected chain reorg",
+		zap.Int64("block_number", ancestor+1),
+		zap.Int("depth", len(reorgs)),
+		zap.String("old_hash", reorgs[len(reorgs)-1].OldHash),
+		zap.String("new_hash", reorgs[len(reorgs)-1].NewHash))
+	for i := len(reorgs) - 1; i >= 0; i-- {
+		p.enqueueReorged(reorgs[i])
+		p.publish(stream.Event{
+			Type:  stream.EventReorg,
+			Reorg: reorgs[i],
+		})
+	}
+	// the txns of the replaced blocks are saved again if the new chain include them
+	if err := p.storage.RemoveTransactionsFrom(ancestor + 1); err != nil {
+		return 0, false, fmt.Errorf("remove replaced txns: %w", err)
+	}
+	if err := p.storage.SetCurrentBlock(ancestor); err != nil {
+		return 0, false, fmt.Errorf("set current block: %w", err)
+	}
+	return ancestor, true, nil
+}
+
+// publicTransactions copy txs without the alerts of the tenants
+func publicTransactions(txs []models.Transaction) []models.Transaction {
+	if txs == nil {
+		return nil
+	}
+	public := make([]models.Transaction, len(txs))
+	for i, tx := range txs {
+		public[i] = tx.Public()
+	}
+	return public
+}
+
+func (p *ethParser) publish(event stream.Event) {
+	if p.broker != nil {
+		p.broker.Publish(event)
+	}
+}
@@ -1,42 +1,41 @@
 package parser
 
 import (
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/vdhieu/tx-parser/internal/models"
 	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/stream"
 	mockStorage "github.com/vdhieu/tx-parser/mocks/internal_/storage"
-	mockNoti "github.com/vdhieu/tx-parser/mocks/pkg/notification"
 	mockClient "github.com/vdhieu/tx-parser/mocks/pkg/rpc"
-	"github.com/vdhieu/tx-parser/pkg/notification"
 	"github.com/vdhieu/tx-parser/pkg/rpc"
 	"go.uber.org/zap"
 )
 
-func setupMocks(t *testing.T) (*mockStorage.Storage, *mockClient.Client, *mockNoti.Notifier) {
+func setupMocks(t *testing.T) (*mockStorage.Storage, *mockClient.Client) {
 	mockStorage := mockStorage.NewStorage(t)
 	mockClient := mockClient.NewClient(t)
-	mockNotifier := mockNoti.NewNotifier(t)
-	return mockStorage, mockClient, mockNotifier
+	return mockStorage, mockClient
 }
 
 func TestNewEthParser(t *testing.T) {
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
-
-	got := NewEthParser(mockStorage, mockClient, mockNotifier)
+	mockStorage, mockClient := setupMocks(t)
+	got := NewEthParser(mockStorage, mockClient, stream.NewBroker())
 	require.NotNil(t, got)
 }
 
 func Test_ethParser_Shutdown(t *testing.T) {
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
+	mockStorage, mockClient := setupMocks(t)
 	type fields struct {
-		storage  storage.Storage
-		client   rpc.Client
-		log      *zap.Logger
-		notifier notification.Notifier
-		running  bool
+		storage storage.Storage
+		client  rpc.Client
+		log     *zap.Logger
+		running bool
 	}
 	tests := []struct {
 		name   string
@@ -45,22 +44,20 @@ func Test_ethParser_Shutdown(t *testing.T) {
 		{
 			name: "successful shutdown",
 			fields: fields{
-				storage:  mockStorage,
-				client:   mockClient,
-				log:      zap.NewNop(),
-				notifier: mockNotifier,
-				running:  true,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &ethParser{
-				storage:  tt.fields.storage,
-				client:   tt.fields.client,
-				log:      tt.fields.log,
-				notifier: tt.fields.notifier,
-				running:  tt.fields.running,
+				storage: tt.fields.storage,
+				client:  tt.fields.client,
+				log:     tt.fields.log,
+				running: tt.fields.running,
 			}
 			p.Shutdown()
 			require.False(t, p.running)
@@ -69,16 +66,15 @@ func Test_ethParser_Shutdown(t *testing.T) {
 }
 
 func Test_ethParser_GetCurrentBlock(t *testing.T) {
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
+	mockStorage, mockClient := setupMocks(t)
 
 	mockStorage.On("GetCurrentBlock").Return(int64(100), nil)
 
 	type fields struct {
-		storage  storage.Storage
-		client   rpc.Client
-		log      *zap.Logger
-		notifier notification.Notifier
-		running  bool
+		storage storage.Storage
+		client  rpc.Client
+		log     *zap.Logger
+		running bool
 	}
 
 	tests := []struct {
@@ -89,11 +85,10 @@ func Test_ethParser_GetCurrentBlock(t *testing.T) {
 		{
 			name: "get current block",
 			fields: fields{
-				storage:  mockStorage,
-				client:   mockClient,
-				log:      zap.NewNop(),
-				notifier: mockNotifier,
-				running:  true,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
 			},
 			want: 100,
 		},
@@ -101,11 +96,10 @@ func Test_ethParser_GetCurrentBlock(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &ethParser{
-				storage:  tt.fields.storage,
-				client:   tt.fields.client,
-				log:      tt.fields.log,
-				notifier: tt.fields.notifier,
-				running:  tt.fields.running,
+				storage: tt.fields.storage,
+				client:  tt.fields.client,
+				log:     tt.fields.log,
+				running: tt.fields.running,
 			}
 			got := p.GetCurrentBlock()
 			require.Equal(t, tt.want, got)
@@ -114,21 +108,22 @@ func Test_ethParser_GetCurrentBlock(t *testing.T) {
 }
 
 func Test_ethParser_Subscribe(t *testing.T) {
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
+	mockStorage, mockClient := setupMocks(t)
 
-	address := "0x123"
+	address := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
 
-	mockStorage.On("AddSubscriber", address).Return(nil)
+	mockStorage.On("AddSubscriber", mock.MatchedBy(func(sub models.Subscription) bool {
+		return sub.Address == address && sub.Tenant == models.DefaultTenant && sub.Label == "cold wallet" && !sub.CreatedAt.IsZero()
+	})).Return(nil)
 
 	type fields struct {
-		storage  storage.Storage
-		client   rpc.Client
-		log      *zap.Logger
-		notifier notification.Notifier
-		running  bool
+		storage storage.Storage
+		client  rpc.Client
+		log     *zap.Logger
+		running bool
 	}
 	type args struct {
-		address string
+		sub models.Subscription
 	}
 	tests := []struct {
 		name   string
@@ -139,28 +134,48 @@ func Test_ethParser_Subscribe(t *testing.T) {
 		{
 			name: "successful subscription",
 			fields: fields{
-				storage:  mockStorage,
-				client:   mockClient,
-				log:      zap.NewNop(),
-				notifier: mockNotifier,
-				running:  true,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
 			},
 			args: args{
-				address: address,
+				sub: models.Subscription{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Label: "cold wallet"},
 			},
 			want: true,
 		},
+		{
+			name: "invalid address",
+			fields: fields{
+				storage: mockStorage,
+				log:     zap.NewNop(),
+			},
+			args: args{
+				sub: models.Subscription{Address: "hello"},
+			},
+			want: false,
+		},
+		{
+			name: "invalid checksum",
+			fields: fields{
+				storage: mockStorage,
+				log:     zap.NewNop(),
+			},
+			args: args{
+				sub: models.Subscription{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &ethParser{
-				storage:  tt.fields.storage,
-				client:   tt.fields.client,
-				log:      tt.fields.log,
-				notifier: tt.fields.notifier,
-				running:  tt.fields.running,
+				storage: tt.fields.storage,
+				client:  tt.fields.client,
+				log:     tt.fields.log,
+				running: tt.fields.running,
 			}
-			got := p.Subscribe(tt.args.address)
+			got := p.Subscribe(tt.args.sub)
 			require.Equal(t, tt.want, got)
 		})
 	}
@@ -168,16 +183,16 @@ func Test_ethParser_Subscribe(t *testing.T) {
 
 func Test_ethParser_GetTransactions(t *testing.T) {
 	type fields struct {
-		storage  storage.Storage
-		client   rpc.Client
-		log      *zap.Logger
-		notifier notification.Notifier
-		running  bool
+		storage storage.Storage
+		client  rpc.Client
+		log     *zap.Logger
+		running bool
 	}
 	type args struct {
+		tenant  string
 		address string
 	}
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
+	mockStorage, mockClient := setupMocks(t)
 
 	address := "0x123"
 	expectedTxs := []models.Transaction{
@@ -190,6 +205,8 @@ func Test_ethParser_GetTransactions(t *testing.T) {
 		},
 	}
 
+	mockStorage.On("GetSubscription", "tenant-a", address).Return(models.Subscription{Address: address, Tenant: "tenant-a"}, nil)
+	mockStorage.On("GetSubscription", "tenant-b", address).Return(models.Subscription{}, storage.ErrNotFound)
 	mockStorage.On("GetTransactions", address).Return(expectedTxs, nil)
 
 	tests := []struct {
@@ -201,36 +218,280 @@ func Test_ethParser_GetTransactions(t *testing.T) {
 		{
 			name: "get transactions",
 			fields: fields{
-				storage:  mockStorage,
-				client:   mockClient,
-				log:      zap.NewNop(),
-				notifier: mockNotifier,
-				running:  true,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
 			},
 			args: args{
+				tenant:  "tenant-a",
 				address: address,
 			},
 			want: expectedTxs,
 		},
+		{
+			name: "address not subscribed by tenant",
+			fields: fields{
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
+			},
+			args: args{
+				tenant:  "tenant-b",
+				address: address,
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ethParser{
+				storage: tt.fields.storage,
+				client:  tt.fields.client,
+				log:     tt.fields.log,
+				running: tt.fields.running,
+			}
+			got := p.GetTransactions(tt.args.tenant, tt.args.address)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ethParser_GetSubscription(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
+
+	sub := models.Subscription{Address: "0x123", Label: "cold wallet"}
+	mockStorage.On("GetSubscription", models.DefaultTenant, "0x123").Return(sub, nil)
+	mockStorage.On("GetSubscription", models.DefaultTenant, "0x456").Return(models.Subscription{}, storage.ErrNotFound)
+
+	tests := []struct {
+		name      string
+		address   string
+		want      models.Subscription
+		wantFound bool
+	}{
+		{
+			name:      "subscribed address",
+			address:   "0X123",
+			want:      sub,
+			wantFound: true,
+		},
+		{
+			name:      "unknown address",
+			address:   "0x456",
+			want:      models.Subscription{},
+			wantFound: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ethParser{
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+			}
+			got, found := p.GetSubscription(models.DefaultTenant, tt.address)
+			require.Equal(t, tt.wantFound, found)
+			require.Equal(t, tt.want, got)
+		})
 	}
+}
+
+func Test_ethParser_Unsubscribe(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
+
+	mockStorage.On("RemoveSubscriber", "tenant-a", "0x123").Return(nil)
+	mockStorage.On("RemoveSubscriber", "tenant-a", "0x456").Return(storage.ErrNotFound)
+
+	p := &ethParser{
+		storage: mockStorage,
+		client:  mockClient,
+		log:     zap.NewNop(),
+	}
+	require.True(t, p.Unsubscribe("tenant-a", "0X123"))
+	require.False(t, p.Unsubscribe("tenant-a", "0x456"))
+}
+
+func Test_ethParser_SetAlertRules(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
 
+	rules := []models.AlertRule{{Name: "failed", Conditions: []models.AlertCondition{{Type: models.AlertFailed}}}}
+	sub := models.Subscription{Address: "0x123", Tenant: "tenant-a", Label: "hot wallet"}
+	updated := sub
+	updated.Notifications.Rules = rules
+	mockStorage.On("GetSubscription", "tenant-a", "0x123").Return(sub, nil)
+	mockStorage.On("UpdateSubscription", updated).Return(nil)
+	mockStorage.On("GetSubscription", "tenant-a", "0x456").Return(models.Subscription{}, storage.ErrNotFound)
+
+	p := &ethParser{
+		storage: mockStorage,
+		client:  mockClient,
+		log:     zap.NewNop(),
+	}
+	got, err := p.SetAlertRules("tenant-a", "0X123", rules)
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	_, err = p.SetAlertRules("tenant-a", "0x456", rules)
+	require.ErrorIs(t, err, ErrNotSubscribed)
+}
+
+func Test_ethParser_BulkSubscribe(t *testing.T) {
+	const (
+		existing = "0x1111111111111111111111111111111111111111"
+		fresh    = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	)
+	subs := []models.Subscription{
+		{Address: existing, Tenant: "tenant-a"},
+		{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Tenant: "tenant-a", Label: "deposit 1"},
+		{Address: "hello", Tenant: "tenant-a"},
+		{Address: fresh, Tenant: "tenant-a", Label: "deposit 1 again"},
+		{Address: fresh},
+	}
+	wantResults := []BulkSubscribeResult{
+		{Address: existing, Status: BulkAlreadySubscribed},
+		{Address: fresh, Status: BulkSubscribed},
+		{Address: "hello", Status: BulkInvalid, Err: models.ErrInvalidAddress},
+		{Address: fresh, Status: BulkDuplicate},
+		{Address: fresh, Status: BulkSubscribed},
+	}
+
+	tests := []struct {
+		name       string
+		storageErr error
+		want       []BulkSubscribeResult
+		wantErr    bool
+	}{
+		{name: "new subscriptions are stored in one batch", want: wantResults},
+		{name: "nothing stored", storageErr: errors.New("disk full"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage, _ := setupMocks(t)
+			mockStorage.On("GetSubscription", "tenant-a", existing).Return(models.Subscription{Address: existing}, nil)
+			mockStorage.On("GetSubscription", mock.Anything, fresh).Return(models.Subscription{}, storage.ErrNotFound)
+			mockStorage.On("AddSubscribers", mock.MatchedBy(func(batch []models.Subscription) bool {
+				return len(batch) == 2 &&
+					batch[0].Address == fresh && batch[0].Tenant == "tenant-a" && batch[0].Label == "deposit 1" &&
+					batch[1].Address == fresh && batch[1].Tenant == models.DefaultTenant && !batch[1].CreatedAt.IsZero()
+			})).Return(tt.storageErr)
+
+			p := &ethParser{storage: mockStorage, log: zap.NewNop()}
+			got, err := p.BulkSubscribe(subs)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ethParser_GetTransactionsPage(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
+
+	txs := []models.Transaction{{Hash: "0xabc"}}
+	mockStorage.On("GetSubscription", "tenant-a", "0x123").Return(models.Subscription{Address: "0x123"}, nil)
+	mockStorage.On("GetSubscription", "tenant-b", "0x123").Return(models.Subscription{}, storage.ErrNotFound)
+	mockStorage.On("GetTransactionsPage", "0x123", 10, 1).Return(txs, 11, nil)
+
+	p := &ethParser{
+		storage: mockStorage,
+		client:  mockClient,
+		log:     zap.NewNop(),
+	}
+	got, total, err := p.GetTransactionsPage("tenant-a", "0X123", 10, 1)
+	require.NoError(t, err)
+	require.Equal(t, txs, got)
+	require.Equal(t, 11, total)
+
+	_, _, err = p.GetTransactionsPage("tenant-b", "0x123", 0, 1)
+	require.ErrorIs(t, err, ErrNotSubscribed)
+}
+
+func Test_ethParser_ListSubscriptions(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
+
+	subs := []models.Subscription{{Address: "0x123"}, {Address: "0x456"}}
+	mockStorage.On("ListSubscriptions", "tenant-a").Return(subs)
+
+	p := &ethParser{
+		storage: mockStorage,
+		client:  mockClient,
+		log:     zap.NewNop(),
+	}
+	require.Equal(t, subs, p.ListSubscriptions("tenant-a"))
+}
+
+func Test_ethParser_GetTransactionByHash(t *testing.T) {
+	mockStorage, mockClient := setupMocks(t)
+
+	tx := models.Transaction{
+		Hash:        "0xabc",
+		From:        "0x123",
+		To:          "0x456",
+		Value:       "1000",
+		BlockNumber: "98",
+	}
+	mockStorage.On("GetTransactionByHash", "0xabc").Return(tx, []string{"0x123", "0x456"}, nil)
+	mockStorage.On("GetTransactionByHash", "0xdef").Return(models.Transaction{}, nil, storage.ErrNotFound)
+	mockStorage.On("GetSubscription", "tenant-a", "0x123").Return(models.Subscription{Address: "0x123"}, nil)
+	mockStorage.On("GetSubscription", "tenant-a", "0x456").Return(models.Subscription{}, storage.ErrNotFound)
+	mockStorage.On("GetSubscription", "tenant-b", mock.Anything).Return(models.Subscription{}, storage.ErrNotFound)
+	mockStorage.On("GetCurrentBlock").Return(int64(100), nil)
+
+	tests := []struct {
+		name      string
+		tenant    string
+		hash      string
+		want      models.TransactionLookup
+		wantFound bool
+	}{
+		{
+			name:   "found transaction with confirmations",
+			tenant: "tenant-a",
+			hash:   "0xabc",
+			want: models.TransactionLookup{
+				Transaction:   tx,
+				Addresses:     []string{"0x123"},
+				Confirmations: 3,
+			},
+			wantFound: true,
+		},
+		{
+			name:      "transaction of another tenant",
+			tenant:    "tenant-b",
+			hash:      "0xabc",
+			want:      models.TransactionLookup{},
+			wantFound: false,
+		},
+		{
+			name:      "unknown transaction",
+			tenant:    "tenant-a",
+			hash:      "0xdef",
+			want:      models.TransactionLookup{},
+			wantFound: false,
+		},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &ethParser{
-				storage:  tt.fields.storage,
-				client:   tt.fields.client,
-				log:      tt.fields.log,
-				notifier: tt.fields.notifier,
-				running:  tt.fields.running,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
 			}
-			got := p.GetTransactions(tt.args.address)
+			got, found := p.GetTransactionByHash(tt.tenant, tt.hash)
+			require.Equal(t, tt.wantFound, found)
 			require.Equal(t, tt.want, got)
 		})
 	}
 }
 
 func Test_ethParser_processTransactions(t *testing.T) {
-	mockStorage, mockClient, mockNotifier := setupMocks(t)
+	mockStorage, mockClient := setupMocks(t)
 	// Mock data
 	subscribedAddr := "0x123"
 	txHash := "0xabc"
@@ -256,17 +517,30 @@ func Test_ethParser_processTransactions(t *testing.T) {
 		Timestamp:   strconv.FormatInt(block.Timestamp, 10),
 	}
 	// Mock the necessary calls
-	mockStorage.On("GetSubscribers").Return([]string{subscribedAddr}, nil)
-	mockStorage.On("GetTransactions", subscribedAddr).Return([]models.Transaction{}, nil)
-	mockStorage.On("SaveTransactions", subscribedAddr, []models.Transaction{txn}).Return(nil)
-	mockNotifier.On("Notify", subscribedAddr, "found a new transactions", txn).Return(nil)
+	subA := models.Subscription{Address: subscribedAddr, Tenant: "tenant-a", Label: "hot wallet"}
+	subB := models.Subscription{Address: subscribedAddr, Tenant: "tenant-b", Label: "deposit"}
+	mockStorage.On("ListAllSubscriptions").Return([]models.Subscription{
+		subA,
+		subB,
+		// muted subscriptions are saved but not notified
+		{Address: subscribedAddr, Tenant: "tenant-c", Notifications: models.NotificationPreferences{Muted: true}},
+		// the subscription of 0x456 start after the block so its txn is ignored
+		{Address: "0x456", Tenant: "tenant-a", StartBlock: 102},
+	})
+	// the txn is saved once for all tenants, with a notification for every tenant not muted
+	// the stored txns are read to restore the last nonce of the sender then to find new counterparties,
+	// the txn is appended to them
+	var enqueued []models.OutboxEvent
+	mockStorage.On("GetTransactions", subscribedAddr).Return([]models.Transaction{}, nil).Twice()
+	mockStorage.On("AppendTransactionsWithEvents", subscribedAddr, []models.Transaction{txn}, mock.Anything).
+		Run(func(args mock.Arguments) { enqueued = args.Get(2).([]models.OutboxEvent) }).
+		Return(nil).Once()
 
 	type fields struct {
-		storage  storage.Storage
-		client   rpc.Client
-		log      *zap.Logger
-		notifier notification.Notifier
-		running  bool
+		storage storage.Storage
+		client  rpc.Client
+		log     *zap.Logger
+		running bool
 	}
 	type args struct {
 		block rpc.Block
@@ -279,11 +553,10 @@ func Test_ethParser_processTransactions(t *testing.T) {
 		{
 			name: "process transaction for subscribed address",
 			fields: fields{
-				storage:  mockStorage,
-				client:   mockClient,
-				log:      zap.NewNop(),
-				notifier: mockNotifier,
-				running:  true,
+				storage: mockStorage,
+				client:  mockClient,
+				log:     zap.NewNop(),
+				running: true,
 			},
 			args: args{
 				block: block,
@@ -293,15 +566,413 @@ func Test_ethParser_processTransactions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &ethParser{
-				storage:  tt.fields.storage,
-				client:   tt.fields.client,
-				log:      tt.fields.log,
-				notifier: tt.fields.notifier,
-				running:  tt.fields.running,
+				storage: tt.fields.storage,
+				client:  tt.fields.client,
+				log:     tt.fields.log,
+				broker:  stream.NewBroker(),
+				running: tt.fields.running,
 			}
+			events, unsubscribe := p.broker.Subscribe()
+			defer unsubscribe()
+
 			p.processTransactions(tt.args.block)
 			mockStorage.AssertExpectations(t)
-			mockNotifier.AssertExpectations(t)
+			require.Len(t, enqueued, 2)
+			for i, sub := range []models.Subscription{subA, subB} {
+				event := enqueued[i].Event
+				require.Equal(t, notificationEventID(models.EventOutgoing, sub.Tenant, subscribedAddr, txHash), enqueued[i].ID)
+				require.Equal(t, enqueued[i].ID, event.ID)
+				require.Equal(t, models.OutboxPending, enqueued[i].Status)
+				require.False(t, enqueued[i].NextAttemptAt.After(time.Now()))
+				require.Equal(t, models.NotificationEventVersion, event.Version)
+				require.Equal(t, models.EventOutgoing, event.Type)
+				require.Equal(t, models.ChainEthereum, event.Chain)
+				require.Equal(t, subscribedAddr, event.Address)
+				require.Equal(t, sub, event.Subscription)
+				require.Equal(t, txn, event.Transaction)
+				require.Equal(t, int64(1), event.Confirmations)
+				require.Equal(t, time.Unix(block.Timestamp, 0).UTC(), event.OccurredAt)
+			}
+			require.NotEqual(t, enqueued[0].ID, enqueued[1].ID)
+			require.Equal(t, stream.Event{
+				Type:        stream.EventTransaction,
+				Transaction: txn,
+				Addresses:   []string{subscribedAddr},
+			}, <-events)
+		})
+	}
+}
+
+func Test_ethParser_ListDeadLetters(t *testing.T) {
+	mockStorage, _ := setupMocks(t)
+
+	deadA := models.OutboxEvent{ID: "evt-1", Status: models.OutboxDead, Event: models.NotificationEvent{
+		Subscription: models.Subscription{Tenant: "tenant-a"},
+	}}
+	deadB := models.OutboxEvent{ID: "evt-2", Status: models.OutboxDead, Event: models.NotificationEvent{
+		Subscription: models.Subscription{Tenant: "tenant-b"},
+	}}
+	mockStorage.On("ListOutboxEvents", models.OutboxDead).Return([]models.OutboxEvent{deadA, deadB})
+
+	p := &ethParser{storage: mockStorage, log: zap.NewNop()}
+	require.Equal(t, []models.OutboxEvent{deadA}, p.ListDeadLetters("tenant-a"))
+	require.Equal(t, []models.OutboxEvent{}, p.ListDeadLetters("tenant-c"))
+}
+
+func Test_ethParser_ReplayDeadLetter(t *testing.T) {
+	event := func(id string, status models.OutboxStatus) models.OutboxEvent {
+		return models.OutboxEvent{
+			ID:        id,
+			Status:    status,
+			Attempts:  10,
+			LastError: "unexpected status 500",
+			Event: models.NotificationEvent{
+				Subscription: models.Subscription{Tenant: "tenant-a"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		tenant    string
+		id        string
+		setupMock func(m *mockStorage.Storage)
+		wantErr   error
+	}{
+		{
+			name:   "dead event is pending again",
+			tenant: "tenant-a",
+			id:     "evt-1",
+			setupMock: func(m *mockStorage.Storage) {
+				m.On("GetOutboxEvent", "evt-1").Return(event("evt-1", models.OutboxDead), nil)
+				m.On("SaveOutboxEvent", mock.MatchedBy(func(e models.OutboxEvent) bool {
+					return e.ID == "evt-1" && e.Status == models.OutboxPending && e.Attempts == 0 &&
+						e.LastError == "unexpected status 500" && !e.NextAttemptAt.IsZero()
+				})).Return(nil)
+			},
+		},
+		{
+			name:   "event of another tenant",
+			tenant: "tenant-b",
+			id:     "evt-1",
+			setupMock: func(m *mockStorage.Storage) {
+				m.On("GetOutboxEvent", "evt-1").Return(event("evt-1", models.OutboxDead), nil)
+			},
+			wantErr: ErrEventNotFound,
+		},
+		{
+			name:   "unknown event",
+			tenant: "tenant-a",
+			id:     "evt-2",
+			setupMock: func(m *mockStorage.Storage) {
+				m.On("GetOutboxEvent", "evt-2").Return(models.OutboxEvent{}, storage.ErrNotFound)
+			},
+			wantErr: ErrEventNotFound,
+		},
+		{
+			name:   "pending event",
+			tenant: "tenant-a",
+			id:     "evt-3",
+			setupMock: func(m *mockStorage.Storage) {
+				m.On("GetOutboxEvent", "evt-3").Return(event("evt-3", models.OutboxPending), nil)
+			},
+			wantErr: ErrEventNotDead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage, _ := setupMocks(t)
+			tt.setupMock(mockStorage)
+
+			p := &ethParser{storage: mockStorage, log: zap.NewNop()}
+			got, err := p.ReplayDeadLetter(tt.tenant, tt.id)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, models.OutboxPending, got.Status)
+			require.Zero(t, got.Attempts)
+		})
+	}
+}
+
+func Test_transactionEventType(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		tx      rpc.Transaction
+		want    models.NotificationEventType
+	}{
+		{name: "incoming", address: "0x456", tx: rpc.Transaction{From: "0x123", To: "0x456"}, want: models.EventIncoming},
+		{name: "outgoing", address: "0x123", tx: rpc.Transaction{From: "0x123", To: "0x456"}, want: models.EventOutgoing},
+		{name: "outgoing mixed case", address: "0xabc", tx: rpc.Transaction{From: "0xABC", To: "0x456"}, want: models.EventOutgoing},
+		{name: "transfer", address: "0x123", tx: rpc.Transaction{From: "0x123", To: "0x456", Input: "0xa9059cbb0000"}, want: models.EventTokenTransfer},
+		{name: "transferFrom", address: "0x456", tx: rpc.Transaction{From: "0x123", To: "0x456", Input: "0x23B872DD0000"}, want: models.EventTokenTransfer},
+		{name: "other call", address: "0x456", tx: rpc.Transaction{From: "0x123", To: "0x456", Input: "0x095ea7b3"}, want: models.EventIncoming},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, transactionEventType(tt.address, tt.tx))
+		})
+	}
+}
+
+func Test_decodeTokenTransfer(t *testing.T) {
+	const (
+		token     = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+		sender    = "0x1111111111111111111111111111111111111111"
+		recipient = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		toWord    = "0000000000000000000000005aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+		fromWord  = "0000000000000000000000001111111111111111111111111111111111111111"
+		// 1000000 = 0xf4240
+		amountWord = "00000000000000000000000000000000000000000000000000000000000f4240"
+	)
+	tests := []struct {
+		name string
+		tx   rpc.Transaction
+		want *models.TokenTransfer
+	}{
+		{
+			name: "transfer",
+			tx:   rpc.Transaction{From: sender, To: token, Input: "0xa9059cbb" + toWord + amountWord},
+			want: &models.TokenTransfer{Token: token, From: sender, To: recipient, Amount: "1000000"},
+		},
+		{
+			name: "transferFrom",
+			tx:   rpc.Transaction{From: "0x2222222222222222222222222222222222222222", To: token, Input: "0x23b872dd" + fromWord + toWord + amountWord},
+			want: &models.TokenTransfer{Token: token, From: sender, To: recipient, Amount: "1000000"},
+		},
+		{name: "truncated", tx: rpc.Transaction{From: sender, To: token, Input: "0xa9059cbb" + toWord}},
+		{name: "other call", tx: rpc.Transaction{From: sender, To: token, Input: "0x095ea7b3" + toWord + amountWord}},
+		{name: "eth transfer", tx: rpc.Transaction{From: sender, To: recipient, Input: "0x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, decodeTokenTransfer(tt.tx))
 		})
 	}
 }
+
+// newBlockStorage a storage with address subscribed by two tenants, one muted, and a txn
+// saved in blocks 100 and 101
+func newBlockStorage(t *testing.T, address string) storage.Storage {
+	store := storage.NewMemoryStorage()
+	require.NoError(t, store.AddSubscribers([]models.Subscription{
+		{Address: address, Tenant: "tenant-a"},
+		{Address: address, Tenant: "tenant-b", Notifications: models.NotificationPreferences{Muted: true}},
+	}))
+	require.NoError(t, store.SaveTransactions(address, []models.Transaction{
+		{Hash: "0x1", From: address, BlockNumber: "100", Timestamp: "1700000000"},
+		{Hash: "0x2", To: address, BlockNumber: "101", Timestamp: "1700000012"},
+	}))
+	return store
+}
+
+func Test_ethParser_enqueueConfirmed(t *testing.T) {
+	const address = "0x123"
+	store := newBlockStorage(t, address)
+	p := &ethParser{storage: store, log: zap.NewNop()}
+
+	// block 110 give 11 confirmations to block 100
+	p.enqueueConfirmed(110)
+	require.Empty(t, store.ListOutboxEvents(""))
+
+	p.enqueueConfirmed(111)
+	events := store.ListOutboxEvents("")
+	require.Len(t, events, 1)
+	event := events[0].Event
+	require.Equal(t, notificationEventID(models.EventConfirmed, "tenant-a", address, "0x1"), event.ID)
+	require.Equal(t, models.EventConfirmed, event.Type)
+	require.Equal(t, int64(confirmationDepth), event.Confirmations)
+	require.Equal(t, "0x1", event.Transaction.Hash)
+	require.Equal(t, time.Unix(1700000000, 0).UTC(), event.OccurredAt)
+
+	// processing the block again replace the event
+	p.enqueueConfirmed(111)
+	require.Len(t, store.ListOutboxEvents(""), 1)
+}
+
+func Test_ethParser_detectReorg(t *testing.T) {
+	const address = "0x123"
+	store := newBlockStorage(t, address)
+	for _, head := range []models.BlockHead{{Number: 99, Hash: "0xa99"}, {Number: 100, Hash: "0xa100"}, {Number: 101, Hash: "0xa101"}} {
+		require.NoError(t, store.SaveBlockHead(head))
+	}
+	require.NoError(t, store.SetCurrentBlock(101))
+	client := mockClient.NewClient(t)
+	p := &ethParser{
+		storage: store,
+		client:  client,
+		log:     zap.NewNop(),
+		broker:  stream.NewBroker(),
+	}
+	events, unsubscribe := p.broker.Subscribe()
+	defer unsubscribe()
+
+	_, reorged, err := p.detectReorg(rpc.Block{Number: 102, Hash: "0xa102", ParentHash: "0xa101"})
+	require.NoError(t, err)
+	require.False(t, reorged)
+	// gaps in the processed blocks can not be checked
+	_, reorged, err = p.detectReorg(rpc.Block{Number: 110, Hash: "0xa110", ParentHash: "0xa109"})
+	require.NoError(t, err)
+	require.False(t, reorged)
+	require.Empty(t, events)
+	require.Empty(t, store.ListOutboxEvents(""))
+
+	// block 102 build on another chain forking after block 99
+	client.On("GetBlockByNumber", int64(101)).Return(rpc.Block{Number: 101, Hash: "0xb101", ParentHash: "0xb100"}, nil).Once()
+	client.On("GetBlockByNumber", int64(100)).Return(rpc.Block{Number: 100, Hash: "0xb100", ParentHash: "0xa99"}, nil).Once()
+	ancestor, reorged, err := p.detectReorg(rpc.Block{Number: 102, Hash: "0xb102", ParentHash: "0xb101"})
+	require.NoError(t, err)
+	require.True(t, reorged)
+	require.Equal(t, int64(99), ancestor)
+	reorgs := []models.Reorg{
+		{BlockNumber: 100, OldHash: "0xa100", NewHash: "0xb100"},
+		{BlockNumber: 101, OldHash: "0xa101", NewHash: "0xb101"},
+	}
+	for _, reorg := range reorgs {
+		require.Equal(t, stream.Event{Type: stream.EventReorg, Reorg: reorg}, <-events)
+	}
+
+	// the unmuted subscriber of the txns in the replaced blocks is notified
+	got := map[string]*models.Reorg{}
+	for _, outboxEvent := range store.ListOutboxEvents("") {
+		event := outboxEvent.Event
+		require.Equal(t, models.EventReorged, event.Type)
+		require.Equal(t, "tenant-a", event.Tenant())
+		got[event.Transaction.Hash] = event.Reorg
+	}
+	require.Equal(t, map[string]*models.Reorg{"0x1": &reorgs[0], "0x2": &reorgs[1]}, got)
+
+	// the replaced txns are removed so they are never confirmed, the new chain is processed from block 100
+	txs, err := store.GetTransactions(address)
+	require.NoError(t, err)
+	require.Empty(t, txs)
+	current, err := store.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(99), current)
+	p.enqueueConfirmed(111)
+	require.Len(t, store.ListOutboxEvents(""), 2)
+}
+
+func Test_newOutboxEvent(t *testing.T) {
+	created := time.Date(2024, 1, 2, 10, 7, 30, 0, time.UTC)
+	event := models.NotificationEvent{ID: "evt_1", CreatedAt: created}
+	require.Equal(t, created, newOutboxEvent(event).NextAttemptAt)
+
+	// the events of a digest subscription wait for the end of the window
+	event.Subscription.Notifications.Digest = models.DigestPreferences{Mode: models.DigestInterval, Interval: time.Hour}
+	got := newOutboxEvent(event)
+	require.Equal(t, time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC), got.NextAttemptAt)
+	require.Equal(t, created, got.CreatedAt)
+}
+
+func Test_ethParser_processTransactions_alerts(t *testing.T) {
+	const address = "0x123"
+	store := storage.NewMemoryStorage()
+	rules := []models.AlertRule{
+		{Name: "large", Conditions: []models.AlertCondition{{Type: models.AlertValueAbove, Value: "100"}}},
+		{Name: "new payee", Conditions: []models.AlertCondition{{Type: models.AlertNewCounterparty}}},
+		{Name: "failed", Conditions: []models.AlertCondition{{Type: models.AlertFailed}}},
+		{Name: "nonce gap", Conditions: []models.AlertCondition{{Type: models.AlertNonceGap}}},
+	}
+	require.NoError(t, store.AddSubscribers([]models.Subscription{
+		{Address: address, Tenant: "tenant-a", Notifications: models.NotificationPreferences{Rules: rules}},
+		{Address: address, Tenant: "tenant-b"},
+	}))
+	require.NoError(t, store.SaveTransactions(address, []models.Transaction{
+		{Hash: "0x0", From: address, To: "0xaaa", Value: "1", BlockNumber: "99", Nonce: 3},
+	}))
+	client := mockClient.NewClient(t)
+	client.On("GetTransactionReceipt", "0x1").Return(rpc.TransactionReceipt{Status: 1}, nil)
+	client.On("GetTransactionReceipt", "0x2").Return(rpc.TransactionReceipt{Status: 1}, nil)
+	client.On("GetTransactionReceipt", "0x3").Return(rpc.TransactionReceipt{Status: 0}, nil)
+	p := &ethParser{storage: store, client: client, log: zap.NewNop(), broker: stream.NewBroker()}
+
+	p.processTransactions(rpc.Block{Number: 100, Transactions: []rpc.Transaction{
+		// a known counterparty, below the threshold
+		{Hash: "0x1", From: address, To: "0xaaa", Value: 10, Nonce: 4},
+		// a new counterparty above the threshold, nonces 5 and 6 were skipped
+		{Hash: "0x2", From: address, To: "0xbbb", Value: 500, Nonce: 7},
+		// reverted
+		{Hash: "0x3", From: address, To: "0xaaa", Value: 0, Nonce: 8},
+	}})
+
+	alerts := map[string][]string{}
+	for _, event := range store.ListOutboxEvents("") {
+		if event.Event.Tenant() == "tenant-a" {
+			alerts[event.Event.Transaction.Hash] = event.Event.Alerts
+		} else {
+			require.Empty(t, event.Event.Alerts)
+		}
+	}
+	require.Equal(t, map[string][]string{
+		"0x2": {"large", "new payee", "nonce gap"},
+		"0x3": {"failed"},
+	}, alerts)
+	require.Len(t, store.ListOutboxEvents(""), 5)
+
+	// only the txns which raised an alert are confirmed to tenant-a
+	for _, event := range store.ListOutboxEvents("") {
+		require.NoError(t, store.DeleteOutboxEvent(event.ID))
+	}
+	p.enqueueConfirmed(100 + confirmationDepth - 1)
+	confirmed := map[string][]string{}
+	for _, event := range store.ListOutboxEvents("") {
+		require.Equal(t, models.EventConfirmed, event.Event.Type)
+		confirmed[event.Event.Tenant()+" "+event.Event.Transaction.Hash] = event.Event.Alerts
+	}
+	require.Equal(t, map[string][]string{
+		"tenant-a 0x2": {"large", "new payee", "nonce gap"},
+		"tenant-a 0x3": {"failed"},
+		"tenant-b 0x1": nil,
+		"tenant-b 0x2": nil,
+		"tenant-b 0x3": nil,
+	}, confirmed)
+	// the alerts are kept with the saved txns but not returned to the tenants
+	for _, tx := range p.GetTransactions("tenant-a", address) {
+		require.Nil(t, tx.Alerts)
+	}
+}
+
+func Test_ethParser_processTransactions_alertsRestart(t *testing.T) {
+	const address = "0x123"
+	dir := t.TempDir()
+	store, err := storage.OpenMemoryStorage(dir)
+	require.NoError(t, err)
+	rules := []models.AlertRule{
+		{Name: "large", Conditions: []models.AlertCondition{{Type: models.AlertValueAbove, Value: "100"}}},
+		{Name: "nonce gap", Conditions: []models.AlertCondition{{Type: models.AlertNonceGap}}},
+	}
+	require.NoError(t, store.AddSubscriber(models.Subscription{
+		Address: address, Tenant: "tenant-a", Notifications: models.NotificationPreferences{Rules: rules},
+	}))
+	p := &ethParser{storage: store, log: zap.NewNop(), broker: stream.NewBroker()}
+	p.processTransactions(rpc.Block{Number: 100, Transactions: []rpc.Transaction{
+		{Hash: "0x1", From: address, To: "0xaaa", Value: 500, Nonce: 4},
+	}})
+	require.NoError(t, store.Close())
+
+	// the alerts and the nonces survive a restart
+	store, err = storage.OpenMemoryStorage(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, store.Close()) })
+	for _, event := range store.ListOutboxEvents("") {
+		require.NoError(t, store.DeleteOutboxEvent(event.ID))
+	}
+	p = &ethParser{storage: store, log: zap.NewNop(), broker: stream.NewBroker()}
+	p.processTransactions(rpc.Block{Number: 101, Transactions: []rpc.Transaction{
+		{Hash: "0x2", From: address, To: "0xaaa", Value: 1, Nonce: 6},
+	}})
+	p.enqueueConfirmed(100 + confirmationDepth - 1)
+
+	alerts := map[string][]string{}
+	for _, event := range store.ListOutboxEvents("") {
+		alerts[string(event.Event.Type)+" "+event.Event.Transaction.Hash] = event.Event.Alerts
+	}
+	require.Equal(t, map[string][]string{
+		"confirmed 0x1": {"large"},
+		"outgoing 0x2":  {"nonce gap"},
+	}, alerts)
+}
@@ -1,16 +1,66 @@
 package parser
 
 import (
+	"errors"
+
 	"github.com/vdhieu/tx-parser/internal/models"
 )
 
+var (
+	// ErrNotSubscribed returned when the tenant did not subscribe the address
+	ErrNotSubscribed = errors.New("address not subscribed")
+	// ErrEventNotFound returned when the tenant has no notification event with the given id
+	ErrEventNotFound = errors.New("event not found")
+	// ErrEventNotDead returned when replaying an event which is not in the dead-letter queue
+	ErrEventNotDead = errors.New("event is not dead-lettered")
+)
+
+// Statuses of the subscriptions of a bulk subscribe
+const (
+	BulkSubscribed        = "subscribed"
+	BulkAlreadySubscribed = "already_subscribed"
+	BulkDuplicate         = "duplicate"
+	BulkInvalid           = "invalid"
+)
+
+// BulkSubscribeResult outcome of one subscription of a bulk subscribe
+type BulkSubscribeResult struct {
+	// Address normalized address, as given when it is invalid
+	Address string
+	Status  string
+	// Err why an invalid subscription was rejected
+	Err error
+}
+
 type Parser interface {
 	// Shutdown stop the parser
 	Shutdown()
 	// GetCurrentBlock last parsed block
 	GetCurrentBlock() int
-	// Subscribe add address to observer
-	Subscribe(address string) bool
-	// GetTransactions list of inbound or outbound transactions for an address
-	GetTransactions(address string) []models.Transaction
+	// Subscribe add address to observer for the tenant of sub
+	Subscribe(sub models.Subscription) bool
+	// BulkSubscribe subscribe many addresses at once, invalid ones are skipped and the others are
+	// stored atomically. Results match subs by index, an error means nothing was stored
+	BulkSubscribe(subs []models.Subscription) ([]BulkSubscribeResult, error)
+	// Unsubscribe stop observing address for tenant, false when tenant did not subscribe it
+	Unsubscribe(tenant, address string) bool
+	// GetSubscription return the subscription of an address in tenant, false when not subscribed
+	GetSubscription(tenant, address string) (models.Subscription, bool)
+	// SetAlertRules replace the alert rules of the subscription of address in tenant and return it,
+	// ErrNotSubscribed when tenant did not subscribe the address
+	SetAlertRules(tenant, address string, rules []models.AlertRule) (models.Subscription, error)
+	// ListSubscriptions return all subscriptions of tenant
+	ListSubscriptions(tenant string) []models.Subscription
+	// GetTransactions list of inbound or outbound transactions for an address subscribed by tenant
+	GetTransactions(tenant, address string) []models.Transaction
+	// GetTransactionsPage return up to limit txns of an address subscribed by tenant starting at offset,
+	// together with the total count. ErrNotSubscribed when tenant did not subscribe the address
+	GetTransactionsPage(tenant, address string, offset, limit int) ([]models.Transaction, int, error)
+	// GetTransactionByHash find a parsed txn by hash, false when no address subscribed by tenant has it
+	GetTransactionByHash(tenant, hash string) (models.TransactionLookup, bool)
+	// ListDeadLetters return the notification events of tenant which exhausted their delivery attempts
+	ListDeadLetters(tenant string) []models.OutboxEvent
+	// ReplayDeadLetter schedule a dead-lettered event of tenant for delivery again,
+	// ErrEventNotFound when tenant has no such event and ErrEventNotDead when it is still pending
+	ReplayDeadLetter(tenant, id string) (models.OutboxEvent, error)
 }
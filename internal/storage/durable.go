@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSnapshotInterval = 10 * time.Minute
+
+	lockFileName = "LOCK"
+)
+
+// ErrLocked returned by OpenMemoryStorage when another process, such as a running server, already
+// opened the data dir
+var ErrLocked = errors.New("data dir is locked by another process")
+
+// OpenMemoryStorage create a memory storage persisted in dir: every mutation is appended
+// to a WAL and the WAL is periodically compacted into a snapshot. The state is restored
+// from the snapshot and the WAL when dir already holds data. dir is locked until Close, which
+// must be called on shutdown, ErrLocked is returned when it is already
+func OpenMemoryStorage(dir string, opts ...Option) (Storage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &memoryStorage{
+		subscribers:        make(map[string]map[string]models.Subscription),
+		transactions:       make(map[string][]models.Transaction),
+		hashIndex:          make(map[string]map[string]bool),
+		retentionOverrides: make(map[string]RetentionPolicy),
+		apiKeys:            make(map[string]models.APIKey),
+		outbox:             make(map[string]models.OutboxEvent),
+		snapshotInterval:   defaultSnapshotInterval,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+		lock:               lock,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.restore(dir); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	w, err := openWAL(dir)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	s.wal = w
+
+	go s.snapshotLoop(dir)
+
+	return s, nil
+}
+
+// restore load the last snapshot then replay the WALs written after it
+func (s *memoryStorage) restore(dir string) error {
+	log := logger.GetLogger().With(zap.String("storage", "memory"), zap.String("dir", dir))
+
+	f, err := os.Open(filepath.Join(dir, snapshotFileName))
+	switch {
+	case err == nil:
+		err = Import(s, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("load snapshot: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	// wal-prev.log exist only when the process stopped while compacting
+	for _, name := range []string{walPrevFileName, walFileName} {
+		applied, torn, err := replayWAL(filepath.Join(dir, name), s.applyWAL)
+		if err != nil {
+			return err
+		}
+		if torn {
+			log.Warn("Dropped torn write at the end of the WAL", zap.String("file", name))
+		}
+		if applied > 0 {
+			log.Info("Replayed WAL", zap.String("file", name), zap.Int("records", applied))
+		}
+	}
+	return nil
+}
+
+// applyWAL apply a replayed record, it is only called before the storage is shared
+func (s *memoryStorage) applyWAL(rec walRecord) error {
+	switch rec.Op {
+	case walOpAddSubscriber:
+		sub := models.Subscription{Address: rec.Address}
+		if rec.Subscription != nil {
+			sub = *rec.Subscription
+		}
+		s.putSubscription(sub)
+	case walOpUpdateSubscription:
+		if rec.Subscription == nil {
+			return errors.New("missing subscription")
+		}
+		s.putSubscription(*rec.Subscription)
+	case walOpAddSubscribers:
+		for _, sub := range rec.Subscriptions {
+			s.putSubscription(sub)
+		}
+	case walOpRemoveSubscriber:
+		s.deleteSubscription(rec.Tenant, rec.Address)
+	case walOpAppendTransactions:
+		s.setTransactions(rec.Address, dedupTransactions(append(s.transactions[rec.Address], rec.Transactions...)))
+		s.putOutboxEvents(rec.Events)
+	case walOpSaveTransactions:
+		s.setTransactions(rec.Address, rec.Transactions)
+		s.putOutboxEvents(rec.Events)
+	case walOpRemoveTransactions:
+		s.removeTransactionsFrom(rec.Block)
+	case walOpSetCurrentBlock:
+		s.currentBlock = rec.Block
+	case walOpSaveBlockHead:
+		if rec.Head == nil {
+			return errors.New("missing block head")
+		}
+		s.putBlockHead(*rec.Head)
+	case walOpSetRetentionOverride:
+		if rec.Retention == nil {
+			return errors.New("missing retention policy")
+		}
+		s.retentionOverrides[rec.Address] = rec.Retention.policy()
+	case walOpSaveAPIKey:
+		if rec.APIKey == nil {
+			return errors.New("missing api key")
+		}
+		s.putAPIKey(*rec.APIKey)
+	case walOpSaveOutboxEvent:
+		s.putOutboxEvents(rec.Events)
+	case walOpDeleteOutboxEvent:
+		delete(s.outbox, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+func (s *memoryStorage) snapshotLoop(dir string) {
+	defer close(s.done)
+	log := logger.GetLogger().With(zap.String("storage", "memory"), zap.String("dir", dir))
+	ticker := time.NewTicker(s.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.compact(dir); err != nil {
+				log.Error("Failed to compact WAL", zap.Error(err))
+			}
+		}
+	}
+}
+
+// compact write a snapshot of the current state and drop the WAL it covers. The WAL is
+// rotated first so writes continue while the snapshot is written, the records logged
+// meanwhile may also be in the snapshot which is fine since replaying them is idempotent
+func (s *memoryStorage) compact(dir string) error {
+	// a leftover wal-prev.log is not covered by any snapshot yet, rotating again would overwrite it
+	if _, err := os.Stat(filepath.Join(dir, walPrevFileName)); errors.Is(err, os.ErrNotExist) {
+		s.mu.Lock()
+		err := s.wal.rotate()
+		s.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("rotate wal: %w", err)
+		}
+	}
+
+	tmp := filepath.Join(dir, snapshotFileName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := Export(s, f); err != nil {
+		f.Close()
+		return fmt.Errorf("export snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, snapshotFileName)); err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(dir, walPrevFileName))
+}
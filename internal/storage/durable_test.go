@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func openTestStorage(t *testing.T, dir string) *memoryStorage {
+	s, err := OpenMemoryStorage(dir, WithSnapshotInterval(time.Hour))
+	require.NoError(t, err)
+	return s.(*memoryStorage)
+}
+
+func seedStorage(t *testing.T, s Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Label: "cold wallet", Tags: []string{"cold"}}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	require.NoError(t, s.SaveTransactions("0x123", txsAt(1)))
+	require.NoError(t, s.SaveTransactions("0x123", txsAt(1, 2, 3)))
+	require.NoError(t, s.SetRetentionOverride("0x456", RetentionPolicy{MaxPerAddress: 1}))
+	require.NoError(t, s.UpdateSubscription(models.Subscription{Address: "0x456", Owner: "treasury"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x999"}))
+	require.NoError(t, s.SaveTransactions("0x999", txsAt(4)))
+	require.NoError(t, s.RemoveSubscriber("", "0x999"))
+	require.NoError(t, s.AddSubscribers([]models.Subscription{{Address: "0x456"}, {Address: "0x789", Tenant: "b"}}))
+	require.NoError(t, s.SaveTransactionsWithEvents("0x456", txsAt(5), []models.OutboxEvent{seedEvent("evt-1"), seedEvent("evt-2")}))
+	require.NoError(t, s.DeleteOutboxEvent("evt-1"))
+	dead := seedEvent("evt-2")
+	dead.Status, dead.Attempts = models.OutboxDead, 5
+	require.NoError(t, s.SaveOutboxEvent(dead))
+	require.NoError(t, s.SaveBlockHead(models.BlockHead{Number: 41, Hash: "0x41"}))
+	require.NoError(t, s.SaveBlockHead(models.BlockHead{Number: 42, Hash: "0x42"}))
+	// a reorg replace the head of block 42
+	require.NoError(t, s.SaveBlockHead(models.BlockHead{Number: 42, Hash: "0x42b"}))
+	// a reorg also drop the txns of the replaced blocks
+	require.NoError(t, s.AppendTransactionsWithEvents("0x123", []models.Transaction{{Hash: "0x43", BlockNumber: "43"}}, nil))
+	require.NoError(t, s.RemoveTransactionsFrom(43))
+	require.NoError(t, s.SetCurrentBlock(42))
+}
+
+func seedEvent(id string) models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:     id,
+		Status: models.OutboxPending,
+		Event: models.NotificationEvent{
+			ID:           id,
+			Type:         models.EventIncoming,
+			Address:      "0x456",
+			Subscription: models.Subscription{Address: "0x456"},
+			Transaction:  txsAt(5)[0],
+		},
+		CreatedAt: time.Unix(5, 0).UTC(),
+	}
+}
+
+func requireSeeded(t *testing.T, s Storage) {
+	require.ElementsMatch(t, []string{"0x123", "0x456", "0x789"}, s.GetSubscribers())
+	require.Equal(t, []models.Subscription{{Address: "0x789", Tenant: "b"}}, s.ListSubscriptions("b"))
+	sub, err := s.GetSubscription("", "0x123")
+	require.NoError(t, err)
+	require.Equal(t, models.Subscription{Address: "0x123", Label: "cold wallet", Tags: []string{"cold"}}, sub)
+	sub, err = s.GetSubscription("", "0x456")
+	require.NoError(t, err)
+	require.Equal(t, "treasury", sub.Owner)
+	txs, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txsAt(1, 2, 3), txs)
+	require.Equal(t, map[string]RetentionPolicy{"0x456": {MaxPerAddress: 1}}, s.GetRetentionOverrides())
+	dead := seedEvent("evt-2")
+	dead.Status, dead.Attempts = models.OutboxDead, 5
+	require.Equal(t, []models.OutboxEvent{dead}, s.ListOutboxEvents(""))
+	require.Equal(t, []models.BlockHead{{Number: 41, Hash: "0x41"}, {Number: 42, Hash: "0x42b"}}, s.GetBlockHeads())
+	block, err := s.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(42), block)
+}
+
+func TestOpenMemoryStorage_ReplayWAL(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStorage(t, dir)
+	seedStorage(t, s)
+	require.NoError(t, s.Close())
+
+	reopened := openTestStorage(t, dir)
+	defer reopened.Close()
+	requireSeeded(t, reopened)
+}
+
+func TestOpenMemoryStorage_Locked(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStorage(t, dir)
+
+	// a second process, or a second open in the same one, can not write the same WAL
+	_, err := OpenMemoryStorage(dir)
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, s.Close())
+	reopened := openTestStorage(t, dir)
+	require.NoError(t, reopened.Close())
+}
+
+func TestOpenMemoryStorage_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStorage(t, dir)
+	seedStorage(t, s)
+	require.NoError(t, s.compact(dir))
+
+	// writes after the compaction only live in the new WAL
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x789"}))
+	require.NoError(t, s.Close())
+
+	_, err := os.Stat(filepath.Join(dir, walPrevFileName))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	reopened := openTestStorage(t, dir)
+	defer reopened.Close()
+	require.True(t, reopened.IsSubscribed("0x789"))
+	require.NoError(t, reopened.SaveTransactions("0x789", nil))
+	txs, err := reopened.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txsAt(1, 2, 3), txs)
+}
+
+func TestOpenMemoryStorage_PruneIsDurable(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenMemoryStorage(dir, WithRetention(RetentionPolicy{MaxPerAddress: 1}))
+	require.NoError(t, err)
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.SaveTransactions("0x123", txsAt(1, 2, 3)))
+	_, err = s.Prune(time.Unix(10, 0))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	reopened := openTestStorage(t, dir)
+	defer reopened.Close()
+	txs, err := reopened.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txsAt(1, 2, 3)[2:], txs)
+}
+
+func TestOpenMemoryStorage_TornBatch(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStorage(t, dir)
+	seedStorage(t, s)
+	require.NoError(t, s.Close())
+	walPath := filepath.Join(dir, walFileName)
+	before, err := os.Stat(walPath)
+	require.NoError(t, err)
+
+	s = openTestStorage(t, dir)
+	require.NoError(t, s.AddSubscribers([]models.Subscription{{Address: "0xaaa"}, {Address: "0xbbb"}}))
+	require.NoError(t, s.Close())
+	after, err := os.Stat(walPath)
+	require.NoError(t, err)
+
+	// a crash while writing the batch must not replay part of it
+	require.NoError(t, os.Truncate(walPath, before.Size()+(after.Size()-before.Size())/2))
+	reopened := openTestStorage(t, dir)
+	requireSeeded(t, reopened)
+	require.False(t, reopened.IsSubscribed("0xaaa"))
+	require.False(t, reopened.IsSubscribed("0xbbb"))
+	require.NoError(t, reopened.Close())
+}
+
+func TestOpenMemoryStorage_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStorage(t, dir)
+	seedStorage(t, s)
+	require.NoError(t, s.Close())
+
+	walPath := filepath.Join(dir, walFileName)
+	valid, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{name: "partial header", tail: []byte{0, 0}},
+		{name: "partial payload", tail: []byte{0, 0, 0, 10, 1, 2, 3, 4, '{'}},
+		{name: "bad checksum", tail: []byte{0, 0, 0, 2, 1, 2, 3, 4, '{', '}'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(walPath, append(append([]byte{}, valid...), tt.tail...), 0o644))
+
+			reopened := openTestStorage(t, dir)
+			requireSeeded(t, reopened)
+			// the torn tail is truncated so new records are readable after it
+			require.NoError(t, reopened.SetCurrentBlock(43))
+			require.NoError(t, reopened.Close())
+
+			reopened = openTestStorage(t, dir)
+			block, err := reopened.GetCurrentBlock()
+			require.NoError(t, err)
+			require.Equal(t, int64(43), block)
+			require.NoError(t, reopened.Close())
+
+			require.NoError(t, os.WriteFile(walPath, valid, 0o644))
+		})
+	}
+}
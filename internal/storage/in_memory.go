@@ -1,36 +1,166 @@
 package storage
 
 import (
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/vdhieu/tx-parser/internal/models"
 )
 
 type memoryStorage struct {
 	currentBlock int64
-	subscribers  map[string]bool
+	// blockHeads the RecentBlockHeads last processed blocks, sorted by number
+	blockHeads []models.BlockHead
+	// subscribers subscriptions keyed by address then tenant
+	subscribers  map[string]map[string]models.Subscription
 	transactions map[string][]models.Transaction
 	mu           sync.RWMutex
+
+	// hashIndex addresses holding a txn, keyed by lower case txn hash
+	hashIndex map[string]map[string]bool
+
+	retention          RetentionPolicy
+	retentionOverrides map[string]RetentionPolicy
+
+	// apiKeys keyed by id
+	apiKeys map[string]models.APIKey
+
+	// outbox notification events not delivered yet, keyed by id
+	outbox map[string]models.OutboxEvent
+
+	// durability, only set by OpenMemoryStorage
+	wal              *wal
+	lock             *os.File
+	snapshotInterval time.Duration
+	stop             chan struct{}
+	done             chan struct{}
+}
+
+func NewMemoryStorage(opts ...Option) Storage {
+	s := &memoryStorage{
+		subscribers:        make(map[string]map[string]models.Subscription),
+		transactions:       make(map[string][]models.Transaction),
+		hashIndex:          make(map[string]map[string]bool),
+		retentionOverrides: make(map[string]RetentionPolicy),
+		apiKeys:            make(map[string]models.APIKey),
+		outbox:             make(map[string]models.OutboxEvent),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *memoryStorage) AddSubscriber(sub models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub.Address][sub.Tenant]; ok {
+		return nil
+	}
+	if err := s.log(walRecord{Op: walOpAddSubscriber, Address: sub.Address, Subscription: &sub}); err != nil {
+		return err
+	}
+	s.putSubscription(sub)
+	return nil
+}
+
+func (s *memoryStorage) AddSubscribers(subs []models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := make([]models.Subscription, 0, len(subs))
+	seen := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		key := sub.Tenant + "/" + sub.Address
+		if _, ok := s.subscribers[sub.Address][sub.Tenant]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		added = append(added, sub)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := s.log(walRecord{Op: walOpAddSubscribers, Subscriptions: added}); err != nil {
+		return err
+	}
+	for _, sub := range added {
+		s.putSubscription(sub)
+	}
+	return nil
 }
 
-func NewMemoryStorage() Storage {
-	return &memoryStorage{
-		subscribers:  make(map[string]bool),
-		transactions: make(map[string][]models.Transaction),
+func (s *memoryStorage) UpdateSubscription(sub models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub.Address][sub.Tenant]; !ok {
+		return ErrNotFound
 	}
+	if err := s.log(walRecord{Op: walOpUpdateSubscription, Address: sub.Address, Subscription: &sub}); err != nil {
+		return err
+	}
+	s.putSubscription(sub)
+	return nil
 }
 
-func (s *memoryStorage) AddSubscriber(address string) error {
+func (s *memoryStorage) RemoveSubscriber(tenant, address string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.subscribers[address] = true
+	if _, ok := s.subscribers[address][tenant]; !ok {
+		return ErrNotFound
+	}
+	if err := s.log(walRecord{Op: walOpRemoveSubscriber, Address: address, Tenant: tenant}); err != nil {
+		return err
+	}
+	s.deleteSubscription(tenant, address)
 	return nil
 }
 
 func (s *memoryStorage) IsSubscribed(address string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.subscribers[address]
+	return len(s.subscribers[address]) > 0
+}
+
+func (s *memoryStorage) GetSubscription(tenant, address string) (models.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.subscribers[address][tenant]
+	if !ok {
+		return models.Subscription{}, ErrNotFound
+	}
+	return sub, nil
+}
+
+func (s *memoryStorage) ListSubscriptions(tenant string) []models.Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]models.Subscription, 0)
+	for _, tenants := range s.subscribers {
+		if sub, ok := tenants[tenant]; ok {
+			subs = append(subs, sub)
+		}
+	}
+	sortSubscriptions(subs)
+	return subs
+}
+
+func (s *memoryStorage) ListAllSubscriptions() []models.Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]models.Subscription, 0, len(s.subscribers))
+	for _, tenants := range s.subscribers {
+		for _, sub := range tenants {
+			subs = append(subs, sub)
+		}
+	}
+	sortSubscriptions(subs)
+	return subs
 }
 
 func (s *memoryStorage) GetSubscribers() []string {
@@ -40,16 +170,46 @@ func (s *memoryStorage) GetSubscribers() []string {
 	for addr := range s.subscribers {
 		addresses = append(addresses, addr)
 	}
+	sort.Strings(addresses)
 	return addresses
 }
 
 // SaveTransactions save txn for subscribed address, non-subscribber will not be saved
 func (s *memoryStorage) SaveTransactions(address string, txs []models.Transaction) error {
+	return s.SaveTransactionsWithEvents(address, txs, nil)
+}
+
+// SaveTransactionsWithEvents save txn and enqueue events in a single WAL record,
+// nothing is saved for a non-subscribber
+func (s *memoryStorage) SaveTransactionsWithEvents(address string, txs []models.Transaction, events []models.OutboxEvent) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.subscribers[address] {
-		s.transactions[address] = txs
+	return s.saveTransactionsWithEvents(address, txs, events)
+}
+
+func (s *memoryStorage) AppendTransactionsWithEvents(address string, txs []models.Transaction, events []models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.transactions[address]
+	return s.saveTransactionsWithEvents(address, append(stored[:len(stored):len(stored)], txs...), events)
+}
+
+// saveTransactionsWithEvents replace the txns of address and enqueue events, s.mu must be held
+func (s *memoryStorage) saveTransactionsWithEvents(address string, txs []models.Transaction, events []models.OutboxEvent) error {
+	if len(s.subscribers[address]) == 0 {
+		return nil
 	}
+
+	txs = dedupTransactions(txs)
+	rec := transactionsRecord(s.transactions[address], address, txs)
+	rec.Events = events
+	if rec.Op != walOpAppendTransactions || len(rec.Transactions) > 0 || len(events) > 0 {
+		if err := s.log(rec); err != nil {
+			return err
+		}
+	}
+	s.setTransactions(address, txs)
+	s.putOutboxEvents(events)
 	return nil
 }
 
@@ -59,9 +219,68 @@ func (s *memoryStorage) GetTransactions(address string) ([]models.Transaction, e
 	return s.transactions[address], nil
 }
 
+func (s *memoryStorage) GetTransactionsPage(address string, offset, limit int) ([]models.Transaction, int, error) {
+	if offset < 0 {
+		return nil, 0, ErrInvalidPage
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	txs := s.transactions[address]
+	total := len(txs)
+	if offset >= total {
+		return []models.Transaction{}, total, nil
+	}
+
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	page := make([]models.Transaction, end-offset)
+	copy(page, txs[offset:end])
+	return page, total, nil
+}
+
+func (s *memoryStorage) GetTransactionByHash(hash string) (models.Transaction, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	holders := s.hashIndex[strings.ToLower(hash)]
+	if len(holders) == 0 {
+		return models.Transaction{}, nil, ErrNotFound
+	}
+
+	var tx models.Transaction
+	addresses := make([]string, 0, len(holders))
+	for addr := range holders {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+	for _, stored := range s.transactions[addresses[0]] {
+		if strings.EqualFold(stored.Hash, hash) {
+			tx = stored
+			break
+		}
+	}
+	return tx, addresses, nil
+}
+
+// RemoveTransactionsFrom drop the txns included in block number or later from every address
+func (s *memoryStorage) RemoveTransactionsFrom(number int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log(walRecord{Op: walOpRemoveTransactions, Block: number}); err != nil {
+		return err
+	}
+	s.removeTransactionsFrom(number)
+	return nil
+}
+
 func (s *memoryStorage) SetCurrentBlock(blockNum int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.log(walRecord{Op: walOpSetCurrentBlock, Block: blockNum}); err != nil {
+		return err
+	}
 	s.currentBlock = blockNum
 	return nil
 }
@@ -71,3 +290,338 @@ func (s *memoryStorage) GetCurrentBlock() (int64, error) {
 	defer s.mu.RUnlock()
 	return s.currentBlock, nil
 }
+
+// SaveBlockHead record a processed block and drop the heads it replace or push out
+func (s *memoryStorage) SaveBlockHead(head models.BlockHead) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log(walRecord{Op: walOpSaveBlockHead, Head: &head}); err != nil {
+		return err
+	}
+	s.putBlockHead(head)
+	return nil
+}
+
+func (s *memoryStorage) GetBlockHeads() []models.BlockHead {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.blockHeads)
+}
+
+// SetRetentionOverride replace the default retention policy for a single address
+func (s *memoryStorage) SetRetentionOverride(address string, policy RetentionPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log(walRecord{
+		Op:        walOpSetRetentionOverride,
+		Address:   address,
+		Retention: toSnapshotRetention(policy),
+	}); err != nil {
+		return err
+	}
+	if s.retentionOverrides == nil {
+		s.retentionOverrides = make(map[string]RetentionPolicy)
+	}
+	s.retentionOverrides[address] = policy
+	return nil
+}
+
+func (s *memoryStorage) GetRetentionOverrides() map[string]RetentionPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	overrides := make(map[string]RetentionPolicy, len(s.retentionOverrides))
+	for addr, policy := range s.retentionOverrides {
+		overrides[addr] = policy
+	}
+	return overrides
+}
+
+// Prune evict txn which are not allowed by the retention policies anymore
+func (s *memoryStorage) Prune(now time.Time) (PruneStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats PruneStats
+	pruned := make(map[string][]models.Transaction)
+	for addr, txs := range s.transactions {
+		policy, ok := s.retentionOverrides[addr]
+		if !ok {
+			policy = s.retention
+		}
+		kept, addrStats := applyAddressRetention(txs, policy, now)
+		pruned[addr] = kept
+		stats.Add(addrStats)
+	}
+
+	if s.retention.MaxTotal > 0 {
+		for addr, n := range totalCapEvictions(pruned, s.retention.MaxTotal) {
+			pruned[addr] = pruned[addr][n:]
+			stats.ByTotalCap += n
+		}
+	}
+
+	for addr, kept := range pruned {
+		if len(kept) == len(s.transactions[addr]) {
+			continue
+		}
+		if err := s.log(walRecord{Op: walOpSaveTransactions, Address: addr, Transactions: kept}); err != nil {
+			return PruneStats{}, err
+		}
+		s.setTransactions(addr, kept)
+	}
+
+	return stats, nil
+}
+
+func (s *memoryStorage) SaveOutboxEvent(event models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := []models.OutboxEvent{event}
+	if err := s.log(walRecord{Op: walOpSaveOutboxEvent, Events: events}); err != nil {
+		return err
+	}
+	s.putOutboxEvents(events)
+	return nil
+}
+
+func (s *memoryStorage) GetOutboxEvent(id string) (models.OutboxEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	event, ok := s.outbox[id]
+	if !ok {
+		return models.OutboxEvent{}, ErrNotFound
+	}
+	return event, nil
+}
+
+func (s *memoryStorage) ListOutboxEvents(status models.OutboxStatus) []models.OutboxEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := make([]models.OutboxEvent, 0)
+	for _, event := range s.outbox {
+		if status == "" || event.Status == status {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+func (s *memoryStorage) DeleteOutboxEvent(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.outbox[id]; !ok {
+		return ErrNotFound
+	}
+	if err := s.log(walRecord{Op: walOpDeleteOutboxEvent, ID: id}); err != nil {
+		return err
+	}
+	delete(s.outbox, id)
+	return nil
+}
+
+func (s *memoryStorage) SaveAPIKey(key models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log(walRecord{Op: walOpSaveAPIKey, APIKey: &key}); err != nil {
+		return err
+	}
+	s.putAPIKey(key)
+	return nil
+}
+
+func (s *memoryStorage) GetAPIKeyByHash(hash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.apiKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, ErrNotFound
+}
+
+func (s *memoryStorage) ListAPIKeys() []models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]models.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (s *memoryStorage) RevokeAPIKey(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.RevokedAt = &at
+	if err := s.log(walRecord{Op: walOpSaveAPIKey, APIKey: &key}); err != nil {
+		return err
+	}
+	s.putAPIKey(key)
+	return nil
+}
+
+// Close stop the periodic snapshots and release the WAL and the data dir, it is a no-op for a
+// non durable storage
+func (s *memoryStorage) Close() error {
+	if s.wal == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	err := s.wal.close()
+	s.lock.Close()
+	return err
+}
+
+// putSubscription it must be called with the lock held
+func (s *memoryStorage) putSubscription(sub models.Subscription) {
+	if s.subscribers[sub.Address] == nil {
+		s.subscribers[sub.Address] = make(map[string]models.Subscription)
+	}
+	s.subscribers[sub.Address][sub.Tenant] = sub
+}
+
+// deleteSubscription remove the subscription of tenant, the txns of address are dropped with its last subscription.
+// It must be called with the lock held
+func (s *memoryStorage) deleteSubscription(tenant, address string) {
+	delete(s.subscribers[address], tenant)
+	if len(s.subscribers[address]) > 0 {
+		return
+	}
+	delete(s.subscribers, address)
+	s.setTransactions(address, nil)
+	delete(s.transactions, address)
+}
+
+// putBlockHead it must be called with the lock held
+func (s *memoryStorage) putBlockHead(head models.BlockHead) {
+	heads := slices.DeleteFunc(s.blockHeads, func(h models.BlockHead) bool {
+		return h.Number >= head.Number
+	})
+	heads = append(heads, head)
+	if len(heads) > RecentBlockHeads {
+		heads = slices.Delete(heads, 0, len(heads)-RecentBlockHeads)
+	}
+	s.blockHeads = heads
+}
+
+// putAPIKey it must be called with the lock held
+func (s *memoryStorage) putAPIKey(key models.APIKey) {
+	if s.apiKeys == nil {
+		s.apiKeys = make(map[string]models.APIKey)
+	}
+	s.apiKeys[key.ID] = key
+}
+
+// putOutboxEvents it must be called with the lock held
+func (s *memoryStorage) putOutboxEvents(events []models.OutboxEvent) {
+	if s.outbox == nil {
+		s.outbox = make(map[string]models.OutboxEvent)
+	}
+	for _, event := range events {
+		s.outbox[event.ID] = event
+	}
+}
+
+// setTransactions replace the txn of address and keep the hash index in sync,
+// it must be called with the lock held
+func (s *memoryStorage) setTransactions(address string, txs []models.Transaction) {
+	if s.hashIndex == nil {
+		s.hashIndex = make(map[string]map[string]bool)
+	}
+	for _, tx := range s.transactions[address] {
+		hash := strings.ToLower(tx.Hash)
+		delete(s.hashIndex[hash], address)
+		if len(s.hashIndex[hash]) == 0 {
+			delete(s.hashIndex, hash)
+		}
+	}
+	for _, tx := range txs {
+		hash := strings.ToLower(tx.Hash)
+		if s.hashIndex[hash] == nil {
+			s.hashIndex[hash] = make(map[string]bool)
+		}
+		s.hashIndex[hash][address] = true
+	}
+	s.transactions[address] = txs
+}
+
+// removeTransactionsFrom it must be called with the lock held
+func (s *memoryStorage) removeTransactionsFrom(number int64) {
+	for address, txs := range s.transactions {
+		kept := slices.DeleteFunc(slices.Clone(txs), func(tx models.Transaction) bool {
+			block, err := strconv.ParseInt(tx.BlockNumber, 10, 64)
+			return err == nil && block >= number
+		})
+		if len(kept) != len(txs) {
+			s.setTransactions(address, kept)
+		}
+	}
+}
+
+// log append rec to the WAL when the storage is durable, it must be called with the lock held
+// and before the mutation is applied so a failed write leaves the storage unchanged
+func (s *memoryStorage) log(rec walRecord) error {
+	if s.wal == nil {
+		return nil
+	}
+	return s.wal.append(rec)
+}
+
+// dedupTransactions drop txn with an already seen hash, keeping the first occurrence
+func dedupTransactions(txs []models.Transaction) []models.Transaction {
+	seen := make(map[string]bool, len(txs))
+	result := make([]models.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if seen[tx.Hash] {
+			continue
+		}
+		seen[tx.Hash] = true
+		result = append(result, tx)
+	}
+	return result
+}
+
+// transactionsRecord build the cheapest WAL record turning old into txs, appending
+// only the new txn when txs extend old since most saves add a few txn to a long list
+func transactionsRecord(old []models.Transaction, address string, txs []models.Transaction) walRecord {
+	if len(txs) >= len(old) {
+		isPrefix := true
+		for i := range old {
+			if !reflect.DeepEqual(old[i], txs[i]) {
+				isPrefix = false
+				break
+			}
+		}
+		if isPrefix {
+			return walRecord{Op: walOpAppendTransactions, Address: address, Transactions: txs[len(old):]}
+		}
+	}
+	return walRecord{Op: walOpSaveTransactions, Address: address, Transactions: txs}
+}
+
+func sortSubscriptions(subs []models.Subscription) {
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Address != subs[j].Address {
+			return subs[i].Address < subs[j].Address
+		}
+		return subs[i].Tenant < subs[j].Tenant
+	})
+}
@@ -0,0 +1,25 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/vdhieu/tx-parser/internal/storage"
+	"github.com/vdhieu/tx-parser/internal/storage/storagetest"
+)
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewMemoryStorage()
+	})
+}
+
+func TestDurableMemoryStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := storage.OpenMemoryStorage(t.TempDir())
+		if err != nil {
+			t.Fatalf("OpenMemoryStorage() error = %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
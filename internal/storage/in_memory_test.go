@@ -18,7 +18,7 @@ func TestNewMemoryStorage(t *testing.T) {
 func Test_memoryStorage_AddSubscriber(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -34,7 +34,7 @@ func Test_memoryStorage_AddSubscriber(t *testing.T) {
 		{
 			name: "add new subscriber",
 			fields: fields{
-				subscribers:  make(map[string]bool),
+				subscribers:  make(map[string]map[string]models.Subscription),
 				transactions: make(map[string][]models.Transaction),
 			},
 			args: args{
@@ -45,8 +45,8 @@ func Test_memoryStorage_AddSubscriber(t *testing.T) {
 		{
 			name: "add existing subscriber",
 			fields: fields{
-				subscribers: map[string]bool{
-					"0x123": true,
+				subscribers: map[string]map[string]models.Subscription{
+					"0x123": {"": {Address: "0x123"}},
 				},
 				transactions: make(map[string][]models.Transaction),
 			},
@@ -64,7 +64,7 @@ func Test_memoryStorage_AddSubscriber(t *testing.T) {
 				transactions: tt.fields.transactions,
 				mu:           tt.fields.mu,
 			}
-			if err := s.AddSubscriber(tt.args.address); (err != nil) != tt.wantErr {
+			if err := s.AddSubscriber(models.Subscription{Address: tt.args.address}); (err != nil) != tt.wantErr {
 				t.Errorf("memoryStorage.AddSubscriber() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -74,7 +74,7 @@ func Test_memoryStorage_AddSubscriber(t *testing.T) {
 func Test_memoryStorage_IsSubscribed(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -90,8 +90,8 @@ func Test_memoryStorage_IsSubscribed(t *testing.T) {
 		{
 			name: "check existing subscriber",
 			fields: fields{
-				subscribers: map[string]bool{
-					"0x123": true,
+				subscribers: map[string]map[string]models.Subscription{
+					"0x123": {"": {Address: "0x123"}},
 				},
 			},
 			args: args{
@@ -102,7 +102,7 @@ func Test_memoryStorage_IsSubscribed(t *testing.T) {
 		{
 			name: "check non-existing subscriber",
 			fields: fields{
-				subscribers: map[string]bool{},
+				subscribers: map[string]map[string]models.Subscription{},
 			},
 			args: args{
 				address: "0x123",
@@ -128,7 +128,7 @@ func Test_memoryStorage_IsSubscribed(t *testing.T) {
 func Test_memoryStorage_GetSubscribers(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -140,16 +140,16 @@ func Test_memoryStorage_GetSubscribers(t *testing.T) {
 		{
 			name: "get empty subscribers",
 			fields: fields{
-				subscribers: map[string]bool{},
+				subscribers: map[string]map[string]models.Subscription{},
 			},
 			want: []string{},
 		},
 		{
 			name: "get multiple subscribers",
 			fields: fields{
-				subscribers: map[string]bool{
-					"0x123": true,
-					"0x456": true,
+				subscribers: map[string]map[string]models.Subscription{
+					"0x123": {"": {Address: "0x123"}},
+					"0x456": {"": {Address: "0x456"}},
 				},
 			},
 			want: []string{"0x123", "0x456"},
@@ -173,7 +173,7 @@ func Test_memoryStorage_GetSubscribers(t *testing.T) {
 func Test_memoryStorage_SaveTransactions(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -190,8 +190,8 @@ func Test_memoryStorage_SaveTransactions(t *testing.T) {
 		{
 			name: "save transactions for subscribed address",
 			fields: fields{
-				subscribers: map[string]bool{
-					"0x123": true,
+				subscribers: map[string]map[string]models.Subscription{
+					"0x123": {"": {Address: "0x123"}},
 				},
 				transactions: make(map[string][]models.Transaction),
 			},
@@ -206,7 +206,7 @@ func Test_memoryStorage_SaveTransactions(t *testing.T) {
 		{
 			name: "save transactions for non-subscribed address",
 			fields: fields{
-				subscribers:  make(map[string]bool),
+				subscribers:  make(map[string]map[string]models.Subscription),
 				transactions: make(map[string][]models.Transaction),
 			},
 			args: args{
@@ -238,7 +238,7 @@ func Test_memoryStorage_GetTransactions(t *testing.T) {
 
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -255,8 +255,8 @@ func Test_memoryStorage_GetTransactions(t *testing.T) {
 		{
 			name: "get transactions for subscribed address",
 			fields: fields{
-				subscribers: map[string]bool{
-					"0x123": true,
+				subscribers: map[string]map[string]models.Subscription{
+					"0x123": {"": {Address: "0x123"}},
 				},
 				transactions: map[string][]models.Transaction{
 					"0x123": sampleTx,
@@ -271,7 +271,7 @@ func Test_memoryStorage_GetTransactions(t *testing.T) {
 		{
 			name: "get transactions for non-subscribed address",
 			fields: fields{
-				subscribers:  make(map[string]bool),
+				subscribers:  make(map[string]map[string]models.Subscription),
 				transactions: make(map[string][]models.Transaction),
 			},
 			args: args{
@@ -302,7 +302,7 @@ func Test_memoryStorage_GetTransactions(t *testing.T) {
 func Test_memoryStorage_SetCurrentBlock(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
@@ -344,7 +344,7 @@ func Test_memoryStorage_SetCurrentBlock(t *testing.T) {
 func Test_memoryStorage_GetCurrentBlock(t *testing.T) {
 	type fields struct {
 		currentBlock int64
-		subscribers  map[string]bool
+		subscribers  map[string]map[string]models.Subscription
 		transactions map[string][]models.Transaction
 		mu           sync.RWMutex
 	}
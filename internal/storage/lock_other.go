@@ -0,0 +1,14 @@
+//go:build !unix
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockDir only create the lock file, flock is not available on this platform so the
+// data dir is not protected against a second process
+func lockDir(dir string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+}
@@ -0,0 +1,20 @@
+package storage
+
+import "time"
+
+// Option configure a memory storage
+type Option func(*memoryStorage)
+
+// WithRetention set the default retention policy applied by Prune
+func WithRetention(policy RetentionPolicy) Option {
+	return func(s *memoryStorage) {
+		s.retention = policy
+	}
+}
+
+// WithSnapshotInterval set how often a durable storage compact its WAL into a snapshot
+func WithSnapshotInterval(interval time.Duration) Option {
+	return func(s *memoryStorage) {
+		s.snapshotInterval = interval
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PrunerMetrics cumulative counters of a pruner since it started
+type PrunerMetrics struct {
+	Runs    int64
+	Errors  int64
+	Evicted PruneStats
+}
+
+// Pruner periodically evict stored txn according to the storage retention policies
+type Pruner struct {
+	storage  Storage
+	interval time.Duration
+	log      *zap.Logger
+	now      func() time.Time
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu      sync.Mutex
+	metrics PrunerMetrics
+}
+
+// NewPruner create new pruner and start its background process
+func NewPruner(storage Storage, interval time.Duration) *Pruner {
+	p := &Pruner{
+		storage:  storage,
+		interval: interval,
+		log:      logger.GetLogger().With(zap.String("component", "pruner")),
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	p.log.Info("Starting storage pruner", zap.Duration("interval", interval))
+	go p.run()
+
+	return p
+}
+
+// Shutdown stop the pruner and wait for the running prune to finish
+func (p *Pruner) Shutdown() {
+	p.log.Info("Shutting down storage pruner")
+	close(p.stop)
+	<-p.done
+}
+
+// Metrics return a copy of the pruner counters
+func (p *Pruner) Metrics() PrunerMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.metrics
+}
+
+func (p *Pruner) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.pruneOnce()
+		}
+	}
+}
+
+func (p *Pruner) pruneOnce() {
+	stats, err := p.storage.Prune(p.now())
+
+	p.mu.Lock()
+	p.metrics.Runs++
+	if err != nil {
+		p.metrics.Errors++
+	}
+	p.metrics.Evicted.Add(stats)
+	p.mu.Unlock()
+
+	if err != nil {
+		p.log.Error("Failed to prune storage", zap.Error(err))
+		return
+	}
+	if stats.Total() > 0 {
+		p.log.Info("Pruned transactions",
+			zap.Int("by_age", stats.ByAge),
+			zap.Int("by_address_cap", stats.ByAddressCap),
+			zap.Int("by_total_cap", stats.ByTotalCap))
+	}
+}
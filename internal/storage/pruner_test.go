@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func TestNewPruner(t *testing.T) {
+	s := NewMemoryStorage(WithRetention(RetentionPolicy{MaxPerAddress: 1}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.SaveTransactions("0x123", txsAt(1, 2, 3)))
+
+	p := NewPruner(s, 10*time.Millisecond)
+	require.NotNil(t, p)
+
+	require.Eventually(t, func() bool {
+		return p.Metrics().Evicted.ByAddressCap == 2
+	}, time.Second, 5*time.Millisecond)
+	p.Shutdown()
+
+	metrics := p.Metrics()
+	require.Positive(t, metrics.Runs)
+	require.Zero(t, metrics.Errors)
+
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+}
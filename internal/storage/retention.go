@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// RetentionPolicy limit how many transactions are kept, a zero field means no limit
+type RetentionPolicy struct {
+	// MaxAge drop txn whose block timestamp is older than now - MaxAge
+	MaxAge time.Duration
+	// MaxPerAddress keep only the newest MaxPerAddress txn of each address
+	MaxPerAddress int
+	// MaxTotal keep only the newest MaxTotal txn across all addresses,
+	// it is a storage wide limit so it is ignored in per address overrides
+	MaxTotal int
+}
+
+// PruneStats number of evicted txn grouped by the rule which evicted them
+type PruneStats struct {
+	ByAge        int
+	ByAddressCap int
+	ByTotalCap   int
+}
+
+// Total number of evicted txn
+func (s PruneStats) Total() int {
+	return s.ByAge + s.ByAddressCap + s.ByTotalCap
+}
+
+// Add accumulate other stats into s
+func (s *PruneStats) Add(other PruneStats) {
+	s.ByAge += other.ByAge
+	s.ByAddressCap += other.ByAddressCap
+	s.ByTotalCap += other.ByTotalCap
+}
+
+// applyAddressRetention apply age and per address rules to txs, txs is expected in chronological order
+func applyAddressRetention(txs []models.Transaction, policy RetentionPolicy, now time.Time) ([]models.Transaction, PruneStats) {
+	var stats PruneStats
+	kept := txs
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge).Unix()
+		kept = make([]models.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			if ts, err := strconv.ParseInt(tx.Timestamp, 10, 64); err == nil && ts < cutoff {
+				stats.ByAge++
+				continue
+			}
+			kept = append(kept, tx)
+		}
+	}
+
+	if policy.MaxPerAddress > 0 && len(kept) > policy.MaxPerAddress {
+		stats.ByAddressCap = len(kept) - policy.MaxPerAddress
+		kept = kept[stats.ByAddressCap:]
+	}
+
+	return kept, stats
+}
+
+// totalCapEvictions return how many of the oldest txn each address has to drop
+// so that the total number of txn does not exceed maxTotal
+func totalCapEvictions(transactions map[string][]models.Transaction, maxTotal int) map[string]int {
+	type entry struct {
+		address   string
+		timestamp int64
+	}
+
+	var entries []entry
+	for addr, txs := range transactions {
+		for _, tx := range txs {
+			ts, _ := strconv.ParseInt(tx.Timestamp, 10, 64)
+			entries = append(entries, entry{address: addr, timestamp: ts})
+		}
+	}
+	if len(entries) <= maxTotal {
+		return nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp < entries[j].timestamp
+	})
+
+	evictions := make(map[string]int)
+	for _, e := range entries[:len(entries)-maxTotal] {
+		evictions[e.address]++
+	}
+	return evictions
+}
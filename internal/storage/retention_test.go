@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func txsAt(timestamps ...int64) []models.Transaction {
+	txs := make([]models.Transaction, len(timestamps))
+	for i, ts := range timestamps {
+		txs[i] = models.Transaction{
+			Hash:      "tx" + strconv.Itoa(i),
+			Timestamp: strconv.FormatInt(ts, 10),
+		}
+	}
+	return txs
+}
+
+func Test_applyAddressRetention(t *testing.T) {
+	now := time.Unix(1000, 0)
+	type args struct {
+		txs    []models.Transaction
+		policy RetentionPolicy
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      []models.Transaction
+		wantStats PruneStats
+	}{
+		{
+			name: "no limits",
+			args: args{
+				txs: txsAt(1, 2, 3),
+			},
+			want: txsAt(1, 2, 3),
+		},
+		{
+			name: "max age",
+			args: args{
+				txs:    txsAt(800, 900, 950),
+				policy: RetentionPolicy{MaxAge: 100 * time.Second},
+			},
+			want:      txsAt(800, 900, 950)[1:],
+			wantStats: PruneStats{ByAge: 1},
+		},
+		{
+			name: "max per address",
+			args: args{
+				txs:    txsAt(1, 2, 3, 4),
+				policy: RetentionPolicy{MaxPerAddress: 3},
+			},
+			want:      txsAt(1, 2, 3, 4)[1:],
+			wantStats: PruneStats{ByAddressCap: 1},
+		},
+		{
+			name: "unparsable timestamp is kept",
+			args: args{
+				txs:    []models.Transaction{{Hash: "tx0", Timestamp: "not-a-number"}},
+				policy: RetentionPolicy{MaxAge: time.Second},
+			},
+			want: []models.Transaction{{Hash: "tx0", Timestamp: "not-a-number"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := applyAddressRetention(tt.args.txs, tt.args.policy, now)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantStats, stats)
+		})
+	}
+}
+
+func Test_memoryStorage_Prune_MaxTotal(t *testing.T) {
+	s := NewMemoryStorage(WithRetention(RetentionPolicy{MaxTotal: 3}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	require.NoError(t, s.SaveTransactions("0x123", txsAt(1, 4, 6)))
+	require.NoError(t, s.SaveTransactions("0x456", txsAt(2, 3, 5)))
+
+	stats, err := s.Prune(time.Unix(10, 0))
+	require.NoError(t, err)
+	require.Equal(t, PruneStats{ByTotalCap: 3}, stats)
+
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txsAt(1, 4, 6)[1:], got)
+
+	got, err = s.GetTransactions("0x456")
+	require.NoError(t, err)
+	require.Equal(t, txsAt(2, 3, 5)[2:], got)
+}
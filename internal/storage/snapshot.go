@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// SnapshotVersion current version of the snapshot format written by Export,
+// version 2 added API keys, version 3 the notification outbox and version 4 the recent block hashes
+const SnapshotVersion = 4
+
+const (
+	recordHeader            = "header"
+	recordSubscriber        = "subscriber"
+	recordRetentionOverride = "retention_override"
+	recordTransactions      = "transactions"
+	recordAPIKey            = "api_key"
+	recordOutboxEvent       = "outbox_event"
+	recordBlockHead         = "block_head"
+	recordCursor            = "cursor"
+	recordFooter            = "footer"
+)
+
+var (
+	// ErrUnsupportedSnapshot returned when the snapshot version can not be read by this build
+	ErrUnsupportedSnapshot = errors.New("unsupported snapshot version")
+	// ErrCorruptedSnapshot returned when the snapshot is truncated or malformed
+	ErrCorruptedSnapshot = errors.New("corrupted snapshot")
+)
+
+// snapshotRecord is a single line of a snapshot, the snapshot is a stream of JSON lines
+// starting with a header and ending with a footer holding the number of records in between
+type snapshotRecord struct {
+	Type         string               `json:"type"`
+	Version      int                  `json:"version,omitempty"`
+	CreatedAt    int64                `json:"created_at,omitempty"`
+	Address      string               `json:"address,omitempty"`
+	Subscription *models.Subscription `json:"subscription,omitempty"`
+	Retention    *snapshotRetention   `json:"retention,omitempty"`
+	APIKey       *models.APIKey       `json:"api_key,omitempty"`
+	Transactions []models.Transaction `json:"transactions,omitempty"`
+	Event        *models.OutboxEvent  `json:"event,omitempty"`
+	Head         *models.BlockHead    `json:"head,omitempty"`
+	Block        int64                `json:"block,omitempty"`
+	Records      int                  `json:"records,omitempty"`
+}
+
+type snapshotRetention struct {
+	MaxAgeSeconds int64 `json:"max_age_seconds,omitempty"`
+	MaxPerAddress int   `json:"max_per_address,omitempty"`
+	MaxTotal      int   `json:"max_total,omitempty"`
+}
+
+func toSnapshotRetention(policy RetentionPolicy) *snapshotRetention {
+	return &snapshotRetention{
+		MaxAgeSeconds: int64(policy.MaxAge / time.Second),
+		MaxPerAddress: policy.MaxPerAddress,
+		MaxTotal:      policy.MaxTotal,
+	}
+}
+
+func (r *snapshotRetention) policy() RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:        time.Duration(r.MaxAgeSeconds) * time.Second,
+		MaxPerAddress: r.MaxPerAddress,
+		MaxTotal:      r.MaxTotal,
+	}
+}
+
+// Export stream the whole content of s to w, addresses are written one at a time
+// so the storage is never copied as a whole
+func Export(s Storage, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	records := 0
+	write := func(r snapshotRecord) error {
+		records++
+		return enc.Encode(r)
+	}
+
+	if err := enc.Encode(snapshotRecord{
+		Type:      recordHeader,
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().Unix(),
+	}); err != nil {
+		return err
+	}
+
+	subs := s.ListAllSubscriptions()
+	for i := range subs {
+		if err := write(snapshotRecord{Type: recordSubscriber, Address: subs[i].Address, Subscription: &subs[i]}); err != nil {
+			return err
+		}
+	}
+
+	overrides := s.GetRetentionOverrides()
+	overrideAddresses := make([]string, 0, len(overrides))
+	for addr := range overrides {
+		overrideAddresses = append(overrideAddresses, addr)
+	}
+	sort.Strings(overrideAddresses)
+	for _, addr := range overrideAddresses {
+		policy := overrides[addr]
+		if err := write(snapshotRecord{
+			Type:      recordRetentionOverride,
+			Address:   addr,
+			Retention: toSnapshotRetention(policy),
+		}); err != nil {
+			return err
+		}
+	}
+
+	keys := s.ListAPIKeys()
+	for i := range keys {
+		if err := write(snapshotRecord{Type: recordAPIKey, APIKey: &keys[i]}); err != nil {
+			return err
+		}
+	}
+
+	addresses := s.GetSubscribers()
+	sort.Strings(addresses)
+	for _, addr := range addresses {
+		txs, err := s.GetTransactions(addr)
+		if err != nil {
+			return fmt.Errorf("get transactions of %s: %w", addr, err)
+		}
+		if len(txs) == 0 {
+			continue
+		}
+		if err := write(snapshotRecord{Type: recordTransactions, Address: addr, Transactions: txs}); err != nil {
+			return err
+		}
+	}
+
+	// events are written after the txn they are about, the same order they are stored in
+	events := s.ListOutboxEvents("")
+	for i := range events {
+		if err := write(snapshotRecord{Type: recordOutboxEvent, Event: &events[i]}); err != nil {
+			return err
+		}
+	}
+
+	heads := s.GetBlockHeads()
+	for i := range heads {
+		if err := write(snapshotRecord{Type: recordBlockHead, Head: &heads[i]}); err != nil {
+			return err
+		}
+	}
+
+	block, err := s.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("get current block: %w", err)
+	}
+	if err := write(snapshotRecord{Type: recordCursor, Block: block}); err != nil {
+		return err
+	}
+
+	if err := enc.Encode(snapshotRecord{Type: recordFooter, Records: records}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import read a snapshot written by Export and load it into s,
+// records are applied as they are read so a truncated snapshot may be partially imported
+func Import(s Storage, r io.Reader) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header snapshotRecord
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("%w: read header: %v", ErrCorruptedSnapshot, err)
+	}
+	if header.Type != recordHeader {
+		return fmt.Errorf("%w: missing header", ErrCorruptedSnapshot)
+	}
+	// older versions are a subset of the current one
+	if header.Version < 1 || header.Version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshot, header.Version)
+	}
+
+	records := 0
+	for {
+		var rec snapshotRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%w: missing footer", ErrCorruptedSnapshot)
+			}
+			return fmt.Errorf("%w: %v", ErrCorruptedSnapshot, err)
+		}
+
+		var err error
+		switch rec.Type {
+		case recordSubscriber:
+			sub := models.Subscription{Address: rec.Address}
+			if rec.Subscription != nil {
+				sub = *rec.Subscription
+			}
+			err = s.AddSubscriber(sub)
+		case recordRetentionOverride:
+			if rec.Retention == nil {
+				return fmt.Errorf("%w: retention override without policy", ErrCorruptedSnapshot)
+			}
+			err = s.SetRetentionOverride(rec.Address, rec.Retention.policy())
+		case recordAPIKey:
+			if rec.APIKey == nil {
+				return fmt.Errorf("%w: api key record without key", ErrCorruptedSnapshot)
+			}
+			err = s.SaveAPIKey(*rec.APIKey)
+		case recordTransactions:
+			existing, getErr := s.GetTransactions(rec.Address)
+			if getErr != nil {
+				return getErr
+			}
+			err = s.SaveTransactions(rec.Address, append(existing, rec.Transactions...))
+		case recordOutboxEvent:
+			if rec.Event == nil {
+				return fmt.Errorf("%w: outbox event record without event", ErrCorruptedSnapshot)
+			}
+			err = s.SaveOutboxEvent(*rec.Event)
+		case recordBlockHead:
+			if rec.Head == nil {
+				return fmt.Errorf("%w: block head record without head", ErrCorruptedSnapshot)
+			}
+			err = s.SaveBlockHead(*rec.Head)
+		case recordCursor:
+			err = s.SetCurrentBlock(rec.Block)
+		case recordFooter:
+			if rec.Records != records {
+				return fmt.Errorf("%w: expected %d records, got %d", ErrCorruptedSnapshot, rec.Records, records)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%w: unknown record type %q", ErrCorruptedSnapshot, rec.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("import %s record: %w", rec.Type, err)
+		}
+		records++
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func TestExportImport(t *testing.T) {
+	src := NewMemoryStorage()
+	require.NoError(t, src.AddSubscriber(models.Subscription{Address: "0x123", Label: "cold wallet", Owner: "treasury"}))
+	require.NoError(t, src.AddSubscriber(models.Subscription{Address: "0x456"}))
+	require.NoError(t, src.AddSubscriber(models.Subscription{Address: "0x456", Tenant: "payments"}))
+	require.NoError(t, src.SaveTransactions("0x123", txsAt(1, 2, 3)))
+	require.NoError(t, src.SetRetentionOverride("0x456", RetentionPolicy{MaxAge: time.Hour, MaxPerAddress: 5}))
+	require.NoError(t, src.SaveAPIKey(models.APIKey{ID: "key-1", Hash: "hash-1", Scopes: []string{models.ScopeRead}}))
+	require.NoError(t, src.SaveTransactionsWithEvents("0x456", txsAt(4), []models.OutboxEvent{{
+		ID:     "evt-1",
+		Status: models.OutboxDead,
+		Event: models.NotificationEvent{
+			ID:           "evt-1",
+			Type:         models.EventReorged,
+			Address:      "0x456",
+			Subscription: models.Subscription{Address: "0x456"},
+			Transaction:  txsAt(4)[0],
+			Reorg:        &models.Reorg{BlockNumber: 4, OldHash: "0xa4", NewHash: "0xb4"},
+			OccurredAt:   time.Unix(4, 0).UTC(),
+		},
+		Attempts:  5,
+		LastError: "unexpected status 500",
+		CreatedAt: time.Unix(4, 0).UTC(),
+	}}))
+	require.NoError(t, src.SaveBlockHead(models.BlockHead{Number: 99, Hash: "0x99", Timestamp: 1188}))
+	require.NoError(t, src.SaveBlockHead(models.BlockHead{Number: 100, Hash: "0x100", Timestamp: 1200}))
+	require.NoError(t, src.SetCurrentBlock(100))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(src, &buf))
+
+	dst := NewMemoryStorage()
+	require.NoError(t, Import(dst, &buf))
+
+	require.Equal(t, src.ListAllSubscriptions(), dst.ListAllSubscriptions())
+	require.Equal(t, src.ListAPIKeys(), dst.ListAPIKeys())
+	require.Equal(t, src.GetRetentionOverrides(), dst.GetRetentionOverrides())
+	require.Len(t, dst.GetBlockHeads(), 2)
+	require.Equal(t, src.GetBlockHeads(), dst.GetBlockHeads())
+	require.Len(t, dst.ListOutboxEvents(""), 1)
+	require.Equal(t, src.ListOutboxEvents(""), dst.ListOutboxEvents(""))
+	for _, addr := range src.GetSubscribers() {
+		want, err := src.GetTransactions(addr)
+		require.NoError(t, err)
+		got, err := dst.GetTransactions(addr)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	block, err := dst.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), block)
+}
+
+func TestImport_Errors(t *testing.T) {
+	var valid bytes.Buffer
+	src := NewMemoryStorage()
+	require.NoError(t, src.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, Export(src, &valid))
+	lines := strings.SplitAfter(valid.String(), "\n")
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "empty input",
+			input:   "",
+			wantErr: ErrCorruptedSnapshot,
+		},
+		{
+			name:    "version 1 without api keys",
+			input:   `{"type":"header","version":1}` + "\n" + `{"type":"cursor","block":1}` + "\n" + `{"type":"footer","records":1}` + "\n",
+			wantErr: nil,
+		},
+		{
+			name:    "unsupported version",
+			input:   `{"type":"header","version":99}` + "\n",
+			wantErr: ErrUnsupportedSnapshot,
+		},
+		{
+			name:    "truncated snapshot",
+			input:   strings.Join(lines[:len(lines)-2], ""),
+			wantErr: ErrCorruptedSnapshot,
+		},
+		{
+			name:    "torn last line",
+			input:   valid.String()[:valid.Len()-5],
+			wantErr: ErrCorruptedSnapshot,
+		},
+		{
+			name:    "unknown record",
+			input:   lines[0] + `{"type":"unknown"}` + "\n",
+			wantErr: ErrCorruptedSnapshot,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Import(NewMemoryStorage(), strings.NewReader(tt.input))
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
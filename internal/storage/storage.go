@@ -1,15 +1,104 @@
 package storage
 
-import "github.com/vdhieu/tx-parser/internal/models"
+import (
+	"errors"
+	"time"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// RecentBlockHeads number of processed blocks kept with their hash
+const RecentBlockHeads = 64
+
+var (
+	// ErrInvalidPage returned when a page is requested with a negative offset
+	ErrInvalidPage = errors.New("invalid page: offset must not be negative")
+	// ErrNotFound returned when the requested record does not exist
+	ErrNotFound = errors.New("not found")
+)
 
 type Storage interface {
-	AddSubscriber(address string) error
+	// AddSubscriber store a new subscription, subscribing an address already subscribed
+	// by the same tenant is a no-op
+	AddSubscriber(sub models.Subscription) error
+	// AddSubscribers store several subscriptions at once, either all of them are stored or none.
+	// Subscriptions already stored are skipped as with AddSubscriber
+	AddSubscribers(subs []models.Subscription) error
+	// UpdateSubscription replace the stored subscription of sub.Address in sub.Tenant,
+	// ErrNotFound when not subscribed
+	UpdateSubscription(sub models.Subscription) error
+	// RemoveSubscriber delete the subscription of address in tenant, ErrNotFound when not subscribed.
+	// The txns of the address are dropped once no tenant subscribe it anymore
+	RemoveSubscriber(tenant, address string) error
+	// IsSubscribed whether any tenant subscribed address
 	IsSubscribed(address string) bool
+	// GetSubscribers return the addresses subscribed by any tenant, sorted
 	GetSubscribers() []string
+	// GetSubscription return the subscription of address in tenant, ErrNotFound when not subscribed
+	GetSubscription(tenant, address string) (models.Subscription, error)
+	// ListSubscriptions return the subscriptions of tenant sorted by address
+	ListSubscriptions(tenant string) []models.Subscription
+	// ListAllSubscriptions return the subscriptions of every tenant sorted by address then tenant
+	ListAllSubscriptions() []models.Subscription
+
+	// Transactions are stored once per address and shared by the tenants subscribing it,
+	// callers are responsible for checking the tenant subscribed the address before reading them
 
+	// SaveTransactions replace the stored txn list of a subscribed address,
+	// duplicated hashes are stored only once
 	SaveTransactions(address string, txs []models.Transaction) error
+	// SaveTransactionsWithEvents same as SaveTransactions and also enqueue events in the notification
+	// outbox, both are written at once so an event is stored if and only if its txn is
+	SaveTransactionsWithEvents(address string, txs []models.Transaction, events []models.OutboxEvent) error
+	// AppendTransactionsWithEvents add txs after the stored txns of a subscribed address and enqueue events,
+	// the stored list is read and written at once so a concurrent save or Prune is not undone.
+	// Hashes already stored are skipped
+	AppendTransactionsWithEvents(address string, txs []models.Transaction, events []models.OutboxEvent) error
 	GetTransactions(address string) ([]models.Transaction, error)
+	// GetTransactionsPage return up to limit txn starting at offset together with the total count,
+	// a non-positive limit return everything after offset
+	GetTransactionsPage(address string, offset, limit int) ([]models.Transaction, int, error)
+	// GetTransactionByHash return a stored txn and the subscribed addresses it was stored for,
+	// ErrNotFound is returned when no address has it
+	GetTransactionByHash(hash string) (models.Transaction, []string, error)
+	// RemoveTransactionsFrom drop the txns of every address included in block number or a later one,
+	// as when these blocks are replaced by a reorg
+	RemoveTransactionsFrom(number int64) error
 
 	SetCurrentBlock(blockNum int64) error
 	GetCurrentBlock() (int64, error)
+	// SaveBlockHead record a processed block, only the RecentBlockHeads last ones are kept.
+	// The heads from the same number on are replaced, as after a reorg
+	SaveBlockHead(head models.BlockHead) error
+	// GetBlockHeads return the recent processed blocks sorted by number
+	GetBlockHeads() []models.BlockHead
+
+	// SetRetentionOverride replace the default retention policy for a single address
+	SetRetentionOverride(address string, policy RetentionPolicy) error
+	// GetRetentionOverrides return all per address retention policies
+	GetRetentionOverrides() map[string]RetentionPolicy
+	// Prune evict txn which are not allowed by the retention policies anymore
+	Prune(now time.Time) (PruneStats, error)
+
+	// SaveOutboxEvent create or replace an outbox event
+	SaveOutboxEvent(event models.OutboxEvent) error
+	// GetOutboxEvent return an outbox event, ErrNotFound when unknown
+	GetOutboxEvent(id string) (models.OutboxEvent, error)
+	// ListOutboxEvents return the outbox events with status, all of them when status is empty,
+	// sorted by creation time
+	ListOutboxEvents(status models.OutboxStatus) []models.OutboxEvent
+	// DeleteOutboxEvent remove a delivered event, ErrNotFound when unknown
+	DeleteOutboxEvent(id string) error
+
+	// SaveAPIKey create or replace an API key
+	SaveAPIKey(key models.APIKey) error
+	// GetAPIKeyByHash return the API key with the given hash, ErrNotFound when unknown
+	GetAPIKeyByHash(hash string) (models.APIKey, error)
+	// ListAPIKeys return all API keys sorted by creation time, revoked ones included
+	ListAPIKeys() []models.APIKey
+	// RevokeAPIKey mark the key as revoked at the given time, ErrNotFound when unknown
+	RevokeAPIKey(id string, at time.Time) error
+
+	// Close flush and release the resources held by the storage
+	Close() error
 }
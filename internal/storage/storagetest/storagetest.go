@@ -0,0 +1,650 @@
+// Package storagetest provides a conformance suite that every storage.Storage
+// implementation must pass, so all backends share the same behavior.
+//
+// Usage from a backend test:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return NewMyStorage()
+//		})
+//	}
+package storagetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+	"github.com/vdhieu/tx-parser/internal/storage"
+)
+
+// Factory return a new empty storage, called once per test case
+type Factory func(t *testing.T) storage.Storage
+
+// Run execute the whole conformance suite against storages created by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{name: "subscribers", test: testSubscribers},
+		{name: "subscription metadata", test: testSubscriptionMetadata},
+		{name: "tenant isolation", test: testTenantIsolation},
+		{name: "add subscribers", test: testAddSubscribers},
+		{name: "update subscription", test: testUpdateSubscription},
+		{name: "remove subscriber", test: testRemoveSubscriber},
+		{name: "non-subscriber writes ignored", test: testNonSubscriberWritesIgnored},
+		{name: "save replaces transactions", test: testSaveReplacesTransactions},
+		{name: "transactions dedup", test: testTransactionsDedup},
+		{name: "append transactions", test: testAppendTransactions},
+		{name: "pagination", test: testPagination},
+		{name: "cursor", test: testCursor},
+		{name: "cursor monotonicity", test: testCursorMonotonicity},
+		{name: "block heads", test: testBlockHeads},
+		{name: "remove transactions", test: testRemoveTransactions},
+		{name: "concurrency stress", test: testConcurrencyStress},
+		{name: "retention override", test: testRetentionOverride},
+		{name: "transaction by hash", test: testTransactionByHash},
+		{name: "api keys", test: testAPIKeys},
+		{name: "outbox", test: testOutbox},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func sampleTransactions(address string, n int) []models.Transaction {
+	txs := make([]models.Transaction, n)
+	for i := range txs {
+		txs[i] = models.Transaction{
+			Hash:        fmt.Sprintf("0x%s%04d", address, i),
+			From:        address,
+			To:          "0xdead",
+			Value:       "1",
+			BlockNumber: fmt.Sprint(100 + i),
+			Timestamp:   fmt.Sprint(1700000000 + i),
+		}
+	}
+	return txs
+}
+
+func testSubscribers(t *testing.T, s storage.Storage) {
+	require.Empty(t, s.GetSubscribers())
+	require.False(t, s.IsSubscribed("0x123"))
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	// subscribing twice must be a no-op
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+
+	require.True(t, s.IsSubscribed("0x123"))
+	require.True(t, s.IsSubscribed("0x456"))
+	require.False(t, s.IsSubscribed("0x789"))
+	require.ElementsMatch(t, []string{"0x123", "0x456"}, s.GetSubscribers())
+}
+
+func testSubscriptionMetadata(t *testing.T, s storage.Storage) {
+	_, err := s.GetSubscription("treasury", "0x123")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	sub := models.Subscription{
+		Address:    "0x123",
+		Tenant:     "treasury",
+		Label:      "cold wallet",
+		Owner:      "treasury",
+		Tags:       []string{"cold", "eth"},
+		CreatedAt:  time.Unix(1700000000, 0).UTC(),
+		StartBlock: 100,
+		Notifications: models.NotificationPreferences{
+			WebhookURL: "https://example.com/hook",
+			Emails:     []string{"ops@example.com"},
+			Routes: []models.NotificationRoute{
+				{Channel: models.ChannelEmail, EventTypes: []models.NotificationEventType{models.EventConfirmed}},
+			},
+			Digest:   models.DigestPreferences{Mode: models.DigestInterval, Interval: 15 * time.Minute},
+			Throttle: models.Throttle{Limit: 100, Period: time.Hour},
+			Rules: []models.AlertRule{
+				{Name: "whale", Conditions: []models.AlertCondition{{Type: models.AlertValueAbove, Value: "1000000000000000000"}}},
+			},
+		},
+	}
+	require.NoError(t, s.AddSubscriber(sub))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x001", Tenant: "treasury", Label: "hot wallet"}))
+	// subscribing again must keep the original record
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "treasury", Label: "changed"}))
+
+	got, err := s.GetSubscription("treasury", "0x123")
+	require.NoError(t, err)
+	require.Equal(t, sub, got)
+
+	subs := s.ListSubscriptions("treasury")
+	require.Len(t, subs, 2)
+	require.Equal(t, "0x001", subs[0].Address)
+	require.Equal(t, sub, subs[1])
+}
+
+func testTenantIsolation(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a", Label: "a wallet"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "b", Label: "b wallet"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456", Tenant: "b"}))
+
+	// the address is shared but each tenant keep its own record
+	got, err := s.GetSubscription("a", "0x123")
+	require.NoError(t, err)
+	require.Equal(t, "a wallet", got.Label)
+	got, err = s.GetSubscription("b", "0x123")
+	require.NoError(t, err)
+	require.Equal(t, "b wallet", got.Label)
+
+	_, err = s.GetSubscription("a", "0x456")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.Len(t, s.ListSubscriptions("a"), 1)
+	require.Len(t, s.ListSubscriptions("b"), 2)
+	require.Empty(t, s.ListSubscriptions("c"))
+	require.Len(t, s.ListAllSubscriptions(), 3)
+
+	// txn are stored once per address whatever the number of tenants
+	require.ElementsMatch(t, []string{"0x123", "0x456"}, s.GetSubscribers())
+	txs := sampleTransactions("123", 2)
+	require.NoError(t, s.SaveTransactions("0x123", txs))
+	stored, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs, stored)
+}
+
+func testAddSubscribers(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscribers(nil))
+	require.Empty(t, s.GetSubscribers())
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a", Label: "first"}))
+	require.NoError(t, s.AddSubscribers([]models.Subscription{
+		// already stored, kept as is
+		{Address: "0x123", Tenant: "a", Label: "second"},
+		{Address: "0x123", Tenant: "b"},
+		{Address: "0x456", Tenant: "a", Tags: []string{"deposit"}},
+		// duplicated in the batch, the first one wins
+		{Address: "0x456", Tenant: "a", Tags: []string{"other"}},
+	}))
+
+	require.Equal(t, []string{"0x123", "0x456"}, s.GetSubscribers())
+	require.Equal(t, []models.Subscription{
+		{Address: "0x123", Tenant: "a", Label: "first"},
+		{Address: "0x456", Tenant: "a", Tags: []string{"deposit"}},
+	}, s.ListSubscriptions("a"))
+	require.Equal(t, []models.Subscription{{Address: "0x123", Tenant: "b"}}, s.ListSubscriptions("b"))
+}
+
+func testUpdateSubscription(t *testing.T, s storage.Storage) {
+	sub := models.Subscription{Address: "0x123", Tenant: "a", Label: "cold wallet"}
+	require.ErrorIs(t, s.UpdateSubscription(sub), storage.ErrNotFound)
+
+	require.NoError(t, s.AddSubscriber(sub))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "b"}))
+	sub.Notifications.Rules = []models.AlertRule{
+		{Name: "large", Conditions: []models.AlertCondition{{Type: models.AlertValueAbove, Value: "1000"}}},
+	}
+	require.NoError(t, s.UpdateSubscription(sub))
+	got, err := s.GetSubscription("a", "0x123")
+	require.NoError(t, err)
+	require.Equal(t, sub, got)
+
+	// the other tenants keep their own subscription
+	got, err = s.GetSubscription("b", "0x123")
+	require.NoError(t, err)
+	require.Empty(t, got.Notifications.Rules)
+}
+
+func testRemoveSubscriber(t *testing.T, s storage.Storage) {
+	require.ErrorIs(t, s.RemoveSubscriber("a", "0x123"), storage.ErrNotFound)
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "b"}))
+	txs := sampleTransactions("123", 2)
+	require.NoError(t, s.SaveTransactions("0x123", txs))
+
+	// the txns are kept while another tenant subscribe the address
+	require.NoError(t, s.RemoveSubscriber("a", "0x123"))
+	require.ErrorIs(t, s.RemoveSubscriber("a", "0x123"), storage.ErrNotFound)
+	_, err := s.GetSubscription("a", "0x123")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.True(t, s.IsSubscribed("0x123"))
+	stored, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs, stored)
+
+	require.NoError(t, s.RemoveSubscriber("b", "0x123"))
+	require.False(t, s.IsSubscribed("0x123"))
+	require.Empty(t, s.GetSubscribers())
+	require.Empty(t, s.ListAllSubscriptions())
+	stored, err = s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Empty(t, stored)
+	_, _, err = s.GetTransactionByHash(txs[0].Hash)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// subscribing again start from scratch
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "a"}))
+	stored, err = s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func testNonSubscriberWritesIgnored(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.SaveTransactions("0x123", sampleTransactions("123", 3)))
+
+	txs, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Empty(t, txs)
+
+	page, total, err := s.GetTransactionsPage("0x123", 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, page)
+	require.Zero(t, total)
+
+	// subscribing later must not reveal the ignored write
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	txs, err = s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Empty(t, txs)
+}
+
+func testSaveReplacesTransactions(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	first := sampleTransactions("123", 2)
+	require.NoError(t, s.SaveTransactions("0x123", first))
+
+	second := sampleTransactions("123", 5)
+	require.NoError(t, s.SaveTransactions("0x123", second))
+
+	txs, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, second, txs)
+
+	// txn of other addresses must not leak
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	txs, err = s.GetTransactions("0x456")
+	require.NoError(t, err)
+	require.Empty(t, txs)
+}
+
+func testTransactionsDedup(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	txs := sampleTransactions("123", 3)
+	withDuplicates := append(append([]models.Transaction{}, txs...), txs[1], txs[0])
+
+	require.NoError(t, s.SaveTransactions("0x123", withDuplicates))
+
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs, got)
+
+	_, total, err := s.GetTransactionsPage("0x123", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(txs), total)
+}
+
+func testAppendTransactions(t *testing.T, s storage.Storage) {
+	txs := sampleTransactions("123", 4)
+	event := models.OutboxEvent{
+		ID:     "evt-1",
+		Status: models.OutboxPending,
+		Event:  models.NotificationEvent{ID: "evt-1", Type: models.EventIncoming, Address: "0x123", Transaction: txs[2]},
+	}
+
+	// nothing is stored for a non-subscriber
+	require.NoError(t, s.AppendTransactionsWithEvents("0x123", txs[:1], []models.OutboxEvent{event}))
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Empty(t, got)
+	require.Empty(t, s.ListOutboxEvents(""))
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.AppendTransactionsWithEvents("0x123", txs[:2], nil))
+	// already stored hashes are skipped
+	require.NoError(t, s.AppendTransactionsWithEvents("0x123", []models.Transaction{txs[1], txs[2]}, []models.OutboxEvent{event}))
+	got, err = s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs[:3], got)
+	require.Equal(t, []models.OutboxEvent{event}, s.ListOutboxEvents(""))
+
+	// txns evicted in between are not brought back
+	require.NoError(t, s.SaveTransactions("0x123", txs[2:3]))
+	require.NoError(t, s.AppendTransactionsWithEvents("0x123", txs[3:], nil))
+	got, err = s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs[2:], got)
+}
+
+func testPagination(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	txs := sampleTransactions("123", 25)
+	require.NoError(t, s.SaveTransactions("0x123", txs))
+
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		want   []models.Transaction
+	}{
+		{name: "first page", offset: 0, limit: 10, want: txs[0:10]},
+		{name: "middle page", offset: 10, limit: 10, want: txs[10:20]},
+		{name: "last partial page", offset: 20, limit: 10, want: txs[20:25]},
+		{name: "offset past the end", offset: 30, limit: 10, want: []models.Transaction{}},
+		{name: "no limit", offset: 5, limit: 0, want: txs[5:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := s.GetTransactionsPage("0x123", tt.offset, tt.limit)
+			require.NoError(t, err)
+			require.Equal(t, 25, total)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, _, err := s.GetTransactionsPage("0x123", -1, 10)
+	require.ErrorIs(t, err, storage.ErrInvalidPage)
+
+	// mutating a returned page must not affect the storage
+	page, _, err := s.GetTransactionsPage("0x123", 0, 1)
+	require.NoError(t, err)
+	page[0].Hash = "mutated"
+	got, _, err := s.GetTransactionsPage("0x123", 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, txs[0].Hash, got[0].Hash)
+}
+
+func testCursor(t *testing.T, s storage.Storage) {
+	block, err := s.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Zero(t, block)
+
+	require.NoError(t, s.SetCurrentBlock(100))
+	block, err = s.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), block)
+
+	require.NoError(t, s.SetCurrentBlock(101))
+	block, err = s.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(101), block)
+}
+
+// testCursorMonotonicity make sure readers never observe the cursor going backwards
+// while a single writer is advancing it
+func testRemoveTransactions(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscribers([]models.Subscription{{Address: "0x123"}, {Address: "0x456"}}))
+	txs := sampleTransactions("0x123", 5)
+	require.NoError(t, s.SaveTransactions("0x123", txs))
+	require.NoError(t, s.SaveTransactions("0x456", sampleTransactions("0x456", 2)))
+
+	// the txns of block 103 and later are dropped from every address
+	require.NoError(t, s.RemoveTransactionsFrom(103))
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs[:3], got)
+	got, err = s.GetTransactions("0x456")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	_, _, err = s.GetTransactionByHash(txs[3].Hash)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testBlockHeads(t *testing.T, s storage.Storage) {
+	require.Empty(t, s.GetBlockHeads())
+
+	for n := int64(1); n <= storage.RecentBlockHeads+10; n++ {
+		require.NoError(t, s.SaveBlockHead(models.BlockHead{Number: n, Hash: fmt.Sprintf("0x%x", n), Timestamp: n * 12}))
+	}
+	heads := s.GetBlockHeads()
+	require.Len(t, heads, storage.RecentBlockHeads)
+	require.Equal(t, int64(11), heads[0].Number)
+	require.Equal(t, models.BlockHead{Number: storage.RecentBlockHeads + 10, Hash: fmt.Sprintf("0x%x", storage.RecentBlockHeads+10), Timestamp: (storage.RecentBlockHeads + 10) * 12}, heads[len(heads)-1])
+
+	// a reorg replace the heads from its block on
+	require.NoError(t, s.SaveBlockHead(models.BlockHead{Number: 70, Hash: "0xreorged"}))
+	heads = s.GetBlockHeads()
+	require.Equal(t, models.BlockHead{Number: 70, Hash: "0xreorged"}, heads[len(heads)-1])
+	require.Equal(t, int64(69), heads[len(heads)-2].Number)
+
+	// the returned heads are a copy
+	heads[0].Hash = "0xchanged"
+	require.Equal(t, "0xb", s.GetBlockHeads()[0].Hash)
+}
+
+func testCursorMonotonicity(t *testing.T, s storage.Storage) {
+	const lastBlock = 2000
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last int64
+			for {
+				block, err := s.GetCurrentBlock()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if block < last {
+					errs <- fmt.Errorf("cursor went backwards from %d to %d", last, block)
+					return
+				}
+				last = block
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	for block := int64(1); block <= lastBlock; block++ {
+		require.NoError(t, s.SetCurrentBlock(block))
+	}
+	close(done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	block, err := s.GetCurrentBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(lastBlock), block)
+}
+
+func testConcurrencyStress(t *testing.T, s storage.Storage) {
+	const workers = 16
+	const txPerWorker = 50
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			address := fmt.Sprintf("0x%d", w)
+			if err := s.AddSubscriber(models.Subscription{Address: address}); err != nil {
+				t.Errorf("AddSubscriber(%s) error = %v", address, err)
+				return
+			}
+			txs := sampleTransactions(fmt.Sprint(w), txPerWorker)
+			for i := 1; i <= txPerWorker; i++ {
+				if err := s.SaveTransactions(address, txs[:i]); err != nil {
+					t.Errorf("SaveTransactions(%s) error = %v", address, err)
+					return
+				}
+				if _, err := s.GetTransactions(address); err != nil {
+					t.Errorf("GetTransactions(%s) error = %v", address, err)
+					return
+				}
+				s.GetSubscribers()
+				s.IsSubscribed(address)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	require.Len(t, s.GetSubscribers(), workers)
+	for w := 0; w < workers; w++ {
+		address := fmt.Sprintf("0x%d", w)
+		txs, err := s.GetTransactions(address)
+		require.NoError(t, err)
+		require.Len(t, txs, txPerWorker)
+	}
+}
+
+func testRetentionOverride(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	txs := sampleTransactions("123", 5)
+	require.NoError(t, s.SaveTransactions("0x123", txs))
+	require.NoError(t, s.SaveTransactions("0x456", sampleTransactions("456", 5)))
+
+	require.NoError(t, s.SetRetentionOverride("0x123", storage.RetentionPolicy{MaxPerAddress: 2}))
+	// sample txn timestamps start at 1700000000, keep the last 2 of 0x456 by age
+	require.NoError(t, s.SetRetentionOverride("0x456", storage.RetentionPolicy{MaxAge: 2 * time.Second}))
+
+	stats, err := s.Prune(time.Unix(1700000005, 0))
+	require.NoError(t, err)
+	require.Equal(t, storage.PruneStats{ByAge: 3, ByAddressCap: 3}, stats)
+
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs[3:], got)
+
+	got, err = s.GetTransactions("0x456")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	// pruning again must be a no-op
+	stats, err = s.Prune(time.Unix(1700000005, 0))
+	require.NoError(t, err)
+	require.Zero(t, stats.Total())
+}
+
+func testTransactionByHash(t *testing.T, s storage.Storage) {
+	_, _, err := s.GetTransactionByHash("0xabc")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123"}))
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x456"}))
+	shared := models.Transaction{Hash: "0xABC", From: "0x123", To: "0x456", Value: "1", BlockNumber: "100", Timestamp: "1700000000"}
+	require.NoError(t, s.SaveTransactions("0x123", []models.Transaction{shared}))
+	require.NoError(t, s.SaveTransactions("0x456", append(sampleTransactions("456", 2), shared)))
+
+	// lookup is case insensitive
+	tx, addresses, err := s.GetTransactionByHash("0xabc")
+	require.NoError(t, err)
+	require.Equal(t, shared, tx)
+	require.ElementsMatch(t, []string{"0x123", "0x456"}, addresses)
+
+	// replacing the txn of an address must update the index
+	require.NoError(t, s.SaveTransactions("0x123", nil))
+	_, addresses, err = s.GetTransactionByHash("0xABC")
+	require.NoError(t, err)
+	require.Equal(t, []string{"0x456"}, addresses)
+
+	require.NoError(t, s.SaveTransactions("0x456", nil))
+	_, _, err = s.GetTransactionByHash("0xABC")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testAPIKeys(t *testing.T, s storage.Storage) {
+	require.Empty(t, s.ListAPIKeys())
+	_, err := s.GetAPIKeyByHash("unknown")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.ErrorIs(t, s.RevokeAPIKey("unknown", time.Now()), storage.ErrNotFound)
+
+	first := models.APIKey{
+		ID:        "key-1",
+		Name:      "ci",
+		Tenant:    "payments",
+		Hash:      "hash-1",
+		Scopes:    []string{models.ScopeRead},
+		RateLimit: 10,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+	}
+	second := models.APIKey{
+		ID:        "key-2",
+		Tenant:    "payments",
+		Hash:      "hash-2",
+		Scopes:    []string{models.ScopeAdmin},
+		CreatedAt: time.Unix(1700000100, 0).UTC(),
+	}
+	require.NoError(t, s.SaveAPIKey(second))
+	require.NoError(t, s.SaveAPIKey(first))
+
+	got, err := s.GetAPIKeyByHash("hash-1")
+	require.NoError(t, err)
+	require.Equal(t, first, got)
+	require.Equal(t, []models.APIKey{first, second}, s.ListAPIKeys())
+
+	revokedAt := time.Unix(1700000200, 0).UTC()
+	require.NoError(t, s.RevokeAPIKey("key-1", revokedAt))
+	got, err = s.GetAPIKeyByHash("hash-1")
+	require.NoError(t, err)
+	require.True(t, got.Revoked())
+	require.True(t, got.RevokedAt.Equal(revokedAt))
+}
+
+func testOutbox(t *testing.T, s storage.Storage) {
+	require.Empty(t, s.ListOutboxEvents(""))
+	_, err := s.GetOutboxEvent("unknown")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.ErrorIs(t, s.DeleteOutboxEvent("unknown"), storage.ErrNotFound)
+
+	txs := sampleTransactions("0x123", 2)
+	event := func(id string, tx models.Transaction, createdAt int64) models.OutboxEvent {
+		return models.OutboxEvent{
+			ID:     id,
+			Status: models.OutboxPending,
+			Event: models.NotificationEvent{
+				ID:           id,
+				Type:         models.EventIncoming,
+				Address:      "0x123",
+				Subscription: models.Subscription{Address: "0x123", Tenant: "payments"},
+				Transaction:  tx,
+			},
+			CreatedAt:     time.Unix(createdAt, 0).UTC(),
+			NextAttemptAt: time.Unix(createdAt, 0).UTC(),
+		}
+	}
+	first, second := event("evt-1", txs[0], 1700000000), event("evt-2", txs[1], 1700000100)
+
+	// events of a non-subscriber are dropped together with its txn
+	require.NoError(t, s.SaveTransactionsWithEvents("0x123", txs[:1], []models.OutboxEvent{first}))
+	require.Empty(t, s.ListOutboxEvents(""))
+
+	require.NoError(t, s.AddSubscriber(models.Subscription{Address: "0x123", Tenant: "payments"}))
+	require.NoError(t, s.SaveTransactionsWithEvents("0x123", txs[:1], []models.OutboxEvent{first}))
+	require.NoError(t, s.SaveTransactionsWithEvents("0x123", txs, []models.OutboxEvent{second}))
+	got, err := s.GetTransactions("0x123")
+	require.NoError(t, err)
+	require.Equal(t, txs, got)
+	require.Equal(t, []models.OutboxEvent{first, second}, s.ListOutboxEvents(""))
+
+	// a failed event is replaced in place
+	second.Attempts = 3
+	second.LastError = "unexpected status 500"
+	second.Status = models.OutboxDead
+	require.NoError(t, s.SaveOutboxEvent(second))
+	gotEvent, err := s.GetOutboxEvent("evt-2")
+	require.NoError(t, err)
+	require.Equal(t, second, gotEvent)
+	require.Equal(t, []models.OutboxEvent{first}, s.ListOutboxEvents(models.OutboxPending))
+	require.Equal(t, []models.OutboxEvent{second}, s.ListOutboxEvents(models.OutboxDead))
+
+	// undelivered events outlive the subscription they were created for
+	require.NoError(t, s.RemoveSubscriber("payments", "0x123"))
+	require.NoError(t, s.DeleteOutboxEvent("evt-1"))
+	require.Equal(t, []models.OutboxEvent{second}, s.ListOutboxEvents(""))
+}
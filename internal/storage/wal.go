@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+const (
+	walFileName      = "wal.log"
+	walPrevFileName  = "wal-prev.log"
+	snapshotFileName = "snapshot.jsonl"
+
+	// walHeaderSize length and crc32 of the payload, both uint32 big endian
+	walHeaderSize = 8
+	// walMaxRecordSize guard against allocating a garbage length read from a corrupted header
+	walMaxRecordSize = 256 << 20
+)
+
+const (
+	walOpAddSubscriber        = "add_subscriber"
+	walOpAddSubscribers       = "add_subscribers"
+	walOpUpdateSubscription   = "update_subscription"
+	walOpRemoveSubscriber     = "remove_subscriber"
+	walOpAppendTransactions   = "append_transactions"
+	walOpSaveTransactions     = "save_transactions"
+	walOpRemoveTransactions   = "remove_transactions"
+	walOpSetCurrentBlock      = "set_current_block"
+	walOpSaveBlockHead        = "save_block_head"
+	walOpSetRetentionOverride = "set_retention_override"
+	walOpSaveAPIKey           = "save_api_key"
+	walOpSaveOutboxEvent      = "save_outbox_event"
+	walOpDeleteOutboxEvent    = "delete_outbox_event"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walRecord a single mutation, every op must be safe to replay on top of a state
+// which already contains it because snapshots are taken while writes continue
+type walRecord struct {
+	Op           string               `json:"op"`
+	Address      string               `json:"address,omitempty"`
+	Tenant       string               `json:"tenant,omitempty"`
+	Subscription *models.Subscription `json:"subscription,omitempty"`
+	// Subscriptions of a batch, written as a single record so a batch is never partially replayed
+	Subscriptions []models.Subscription `json:"subscriptions,omitempty"`
+	Transactions  []models.Transaction  `json:"transactions,omitempty"`
+	Block         int64                 `json:"block,omitempty"`
+	Head          *models.BlockHead     `json:"head,omitempty"`
+	Retention     *snapshotRetention    `json:"retention,omitempty"`
+	APIKey        *models.APIKey        `json:"api_key,omitempty"`
+
+	// Events outbox events enqueued with Transactions or saved on their own
+	Events []models.OutboxEvent `json:"events,omitempty"`
+	// ID of the deleted outbox event
+	ID string `json:"id,omitempty"`
+}
+
+// wal append-only log, each record is framed as [length][crc32][json payload]
+type wal struct {
+	dir  string
+	file *os.File
+	mu   sync.Mutex
+}
+
+func openWAL(dir string) (*wal, error) {
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &wal{dir: dir, file: f}, nil
+}
+
+func (w *wal) append(rec walRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[walHeaderSize:], payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(frame); err != nil {
+		return fmt.Errorf("write wal: %w", err)
+	}
+	return w.file.Sync()
+}
+
+// rotate move the current log aside so it can be dropped once a snapshot covering it is written
+func (w *wal) rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	current := filepath.Join(w.dir, walFileName)
+	if err := os.Rename(current, filepath.Join(w.dir, walPrevFileName)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	return nil
+}
+
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// replayWAL apply every valid record of the log at path, a torn or corrupted tail
+// is truncated so the log can be appended again. It return the number of applied records
+// and whether a torn tail was dropped
+func replayWAL(path string, apply func(walRecord) error) (int, bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	applied := 0
+	header := make([]byte, walHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return applied, false, nil
+			}
+			break
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > walMaxRecordSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+		var rec walRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			break
+		}
+		if err := apply(rec); err != nil {
+			return applied, false, fmt.Errorf("replay %s: %w", rec.Op, err)
+		}
+		applied++
+		offset += int64(walHeaderSize + len(payload))
+	}
+
+	// everything after offset is a torn write
+	return applied, true, f.Truncate(offset)
+}
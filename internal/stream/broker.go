@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const defaultBufferSize = 256
+
+type broker struct {
+	subscribers map[int]chan Event
+	nextID      int
+	bufferSize  int
+	mu          sync.Mutex
+}
+
+// NewBroker create an in-process broker
+func NewBroker() Broker {
+	return &broker{
+		subscribers: make(map[int]chan Event),
+		bufferSize:  defaultBufferSize,
+	}
+}
+
+func (b *broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// a slow subscriber must not hold the parser back
+			logger.GetLogger().Warn("Dropping slow stream subscriber", zap.Int("subscriber", id))
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+func (b *broker) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, b.bufferSize)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[id]; ok {
+				delete(b.subscribers, id)
+				close(ch)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func TestNewBroker(t *testing.T) {
+	got := NewBroker()
+	require.NotNil(t, got)
+}
+
+func Test_broker_Publish(t *testing.T) {
+	b := NewBroker()
+	first, unsubscribeFirst := b.Subscribe()
+	second, unsubscribeSecond := b.Subscribe()
+	defer unsubscribeSecond()
+
+	event := Event{Type: EventTransaction, Transaction: models.Transaction{Hash: "0xabc"}, Addresses: []string{"0x123"}}
+	b.Publish(event)
+	require.Equal(t, event, <-first)
+	require.Equal(t, event, <-second)
+
+	// unsubscribed consumers are closed and not published to anymore
+	unsubscribeFirst()
+	unsubscribeFirst()
+	b.Publish(event)
+	_, ok := <-first
+	require.False(t, ok)
+	require.Equal(t, event, <-second)
+}
+
+func Test_broker_Publish_slowSubscriber(t *testing.T) {
+	b := &broker{subscribers: make(map[int]chan Event), bufferSize: 1}
+	slow, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	b.Publish(Event{Type: EventReorg})
+	b.Publish(Event{Type: EventReorg})
+
+	// the buffered event is still delivered then the channel is closed
+	require.Equal(t, Event{Type: EventReorg}, <-slow)
+	_, ok := <-slow
+	require.False(t, ok)
+}
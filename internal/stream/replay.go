@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+// EventID identify a transaction event so consumers can resume after it,
+// it is made of the block number and the hash of the txn
+func EventID(tx models.Transaction) string {
+	return tx.BlockNumber + ":" + tx.Hash
+}
+
+// ParseEventID split an id built by EventID
+func ParseEventID(id string) (int64, string, error) {
+	block, hash, ok := strings.Cut(id, ":")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid event id %q", id)
+	}
+	number, err := strconv.ParseInt(block, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid event id %q: %w", id, err)
+	}
+	return number, hash, nil
+}
+
+// Replay build the transaction events stored in history, address to its stored txns,
+// which come after the event lastEventID. Events are ordered by block and a txn involving
+// several addresses is replayed once
+func Replay(history map[string][]models.Transaction, lastEventID string) ([]Event, error) {
+	lastBlock, lastHash, err := ParseEventID(lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(history))
+	for addr := range history {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	// merge the histories first so the position of the last event is the same for every address
+	var events []Event
+	byHash := make(map[string]int)
+	for _, addr := range addresses {
+		for _, tx := range history[addr] {
+			if idx, ok := byHash[tx.Hash]; ok {
+				events[idx].Addresses = append(events[idx].Addresses, addr)
+				continue
+			}
+			byHash[tx.Hash] = len(events)
+			events = append(events, Event{
+				Type:        EventTransaction,
+				Transaction: tx,
+				Addresses:   []string{addr},
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return blockNumber(events[i].Transaction) < blockNumber(events[j].Transaction)
+	})
+
+	for i, event := range events {
+		if event.Transaction.Hash == lastHash {
+			return events[i+1:], nil
+		}
+	}
+	// the last event was pruned, resume from the next block
+	for i, event := range events {
+		if blockNumber(event.Transaction) > lastBlock {
+			return events[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// Filter narrow event to the given addresses, false when the event does not concern them.
+// Events other than transactions concern every consumer
+func Filter(event Event, addresses map[string]bool) (Event, bool) {
+	if event.Type != EventTransaction {
+		return event, true
+	}
+	matched := make([]string, 0, len(event.Addresses))
+	for _, addr := range event.Addresses {
+		if addresses[addr] {
+			matched = append(matched, addr)
+		}
+	}
+	if len(matched) == 0 {
+		return Event{}, false
+	}
+	event.Addresses = matched
+	return event, true
+}
+
+func blockNumber(tx models.Transaction) int64 {
+	number, _ := strconv.ParseInt(tx.BlockNumber, 10, 64)
+	return number
+}
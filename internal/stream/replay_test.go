@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+func TestParseEventID(t *testing.T) {
+	block, hash, err := ParseEventID(EventID(models.Transaction{BlockNumber: "101", Hash: "0xabc"}))
+	require.NoError(t, err)
+	require.Equal(t, int64(101), block)
+	require.Equal(t, "0xabc", hash)
+
+	_, _, err = ParseEventID("0xabc")
+	require.Error(t, err)
+	_, _, err = ParseEventID("block:0xabc")
+	require.Error(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	tx100 := models.Transaction{Hash: "0x100", From: "0x1", To: "0x2", BlockNumber: "100"}
+	tx101a := models.Transaction{Hash: "0x101a", From: "0x1", To: "0x3", BlockNumber: "101"}
+	tx101b := models.Transaction{Hash: "0x101b", From: "0x1", To: "0x2", BlockNumber: "101"}
+	tx102 := models.Transaction{Hash: "0x102", From: "0x2", To: "0x3", BlockNumber: "102"}
+	history := map[string][]models.Transaction{
+		"0x1": {tx100, tx101a, tx101b},
+		"0x2": {tx100, tx101b, tx102},
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []Event
+		wantErr     bool
+	}{
+		{
+			name:        "resume after a known txn",
+			lastEventID: EventID(tx101a),
+			want: []Event{
+				{Type: EventTransaction, Transaction: tx101b, Addresses: []string{"0x1", "0x2"}},
+				{Type: EventTransaction, Transaction: tx102, Addresses: []string{"0x2"}},
+			},
+		},
+		{
+			name:        "resume after a pruned txn",
+			lastEventID: "100:0xpruned",
+			want: []Event{
+				{Type: EventTransaction, Transaction: tx101a, Addresses: []string{"0x1"}},
+				{Type: EventTransaction, Transaction: tx101b, Addresses: []string{"0x1", "0x2"}},
+				{Type: EventTransaction, Transaction: tx102, Addresses: []string{"0x2"}},
+			},
+		},
+		{
+			name:        "up to date",
+			lastEventID: EventID(tx102),
+			want:        []Event{},
+		},
+		{
+			name:        "invalid id",
+			lastEventID: "invalid",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Replay(history, tt.lastEventID)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	event := Event{Type: EventTransaction, Addresses: []string{"0x1", "0x2"}}
+
+	got, ok := Filter(event, map[string]bool{"0x2": true})
+	require.True(t, ok)
+	require.Equal(t, []string{"0x2"}, got.Addresses)
+
+	_, ok = Filter(event, map[string]bool{"0x3": true})
+	require.False(t, ok)
+
+	_, ok = Filter(Event{Type: EventReorg}, map[string]bool{"0x3": true})
+	require.True(t, ok)
+}
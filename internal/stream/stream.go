@@ -0,0 +1,37 @@
+package stream
+
+import (
+	"github.com/vdhieu/tx-parser/internal/models"
+)
+
+const (
+	// EventTransaction a txn matched one or more subscribed addresses
+	EventTransaction = "transaction"
+	// EventReorg a processed block was replaced, its txns may not be canonical anymore
+	EventReorg = "reorg"
+	// EventNewHead a block was processed, every txn of the block was published before it
+	EventNewHead = "new_head"
+)
+
+// Event published by the parser to live consumers
+type Event struct {
+	Type string
+	// Transaction and Addresses are set for EventTransaction,
+	// Addresses are the subscribed addresses the txn involves
+	Transaction models.Transaction
+	Addresses   []string
+	// Reorg is set for EventReorg
+	Reorg models.Reorg
+	// Head is set for EventNewHead
+	Head models.BlockHead
+}
+
+// Broker fan out parser events to live consumers
+type Broker interface {
+	// Publish send event to every subscriber, it never block
+	Publish(event Event)
+	// Subscribe return a channel receiving every event published from now on and a func
+	// to unsubscribe. The channel is closed when the subscriber is too slow to keep up,
+	// the consumer should then resume from the stored history
+	Subscribe() (<-chan Event, func())
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrInvalidAddress returned for strings which are not 0x followed by 40 hex characters
+	ErrInvalidAddress = errors.New("invalid address, expected 0x followed by 40 hex characters")
+	// ErrInvalidChecksum returned for mixed case addresses not matching their EIP-55 checksum
+	ErrInvalidChecksum = errors.New("invalid address checksum")
+)
+
+// NormalizeAddress validate addr and return it in lower case, the form addresses are stored in.
+// All lower or all upper case addresses are accepted as is, mixed case ones must be EIP-55 checksummed
+func NormalizeAddress(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if len(addr) != 42 || (addr[:2] != "0x" && addr[:2] != "0X") {
+		return "", ErrInvalidAddress
+	}
+	if _, err := hex.DecodeString(addr[2:]); err != nil {
+		return "", ErrInvalidAddress
+	}
+
+	hexPart := addr[2:]
+	lower := strings.ToLower(hexPart)
+	if hexPart != lower && hexPart != strings.ToUpper(hexPart) && ChecksumAddress(addr) != "0x"+hexPart {
+		return "", ErrInvalidChecksum
+	}
+	return "0x" + lower, nil
+}
+
+// ChecksumAddress return addr in its EIP-55 mixed case form, addr is returned unchanged when it is not an address
+func ChecksumAddress(addr string) string {
+	if len(addr) != 42 || (addr[:2] != "0x" && addr[:2] != "0X") {
+		return addr
+	}
+	lower := strings.ToLower(addr[2:])
+	if _, err := hex.DecodeString(lower); err != nil {
+		return addr
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hash.Sum(nil)
+
+	result := []byte(lower)
+	for i, c := range result {
+		// a letter is upper cased when the matching nibble of the hash is 8 or more
+		nibble := digest[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if c >= 'a' && nibble&0xf >= 8 {
+			result[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(result)
+}
+
+// ChecksumAddresses ChecksumAddress every address of addrs
+func ChecksumAddresses(addrs []string) []string {
+	if addrs == nil {
+		return nil
+	}
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		result[i] = ChecksumAddress(addr)
+	}
+	return result
+}
+
+// Checksummed copy of tx with its from and to addresses checksummed
+func (tx Transaction) Checksummed() Transaction {
+	tx.From = ChecksumAddress(tx.From)
+	tx.To = ChecksumAddress(tx.To)
+	if tx.TokenTransfer != nil {
+		tx.TokenTransfer = &TokenTransfer{
+			Token:  ChecksumAddress(tx.TokenTransfer.Token),
+			From:   ChecksumAddress(tx.TokenTransfer.From),
+			To:     ChecksumAddress(tx.TokenTransfer.To),
+			Amount: tx.TokenTransfer.Amount,
+		}
+	}
+	return tx
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checksummed addresses from the EIP-55 specification
+var eip55Addresses = []string{
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+}
+
+func TestChecksumAddress(t *testing.T) {
+	for _, addr := range eip55Addresses {
+		require.Equal(t, addr, ChecksumAddress(strings.ToLower(addr)))
+		require.Equal(t, addr, ChecksumAddress("0x"+strings.ToUpper(addr[2:])))
+	}
+	require.Equal(t, "", ChecksumAddress(""))
+	require.Equal(t, "hello", ChecksumAddress("hello"))
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		want    string
+		wantErr error
+	}{
+		{name: "checksummed", addr: eip55Addresses[0], want: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{name: "lower case", addr: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", want: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{name: "upper case", addr: "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", want: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{name: "surrounding spaces", addr: " " + eip55Addresses[1] + " ", want: strings.ToLower(eip55Addresses[1])},
+		{name: "bad checksum", addr: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", wantErr: ErrInvalidChecksum},
+		{name: "not hex", addr: "hello", wantErr: ErrInvalidAddress},
+		{name: "missing prefix", addr: "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00", wantErr: ErrInvalidAddress},
+		{name: "too short", addr: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", wantErr: ErrInvalidAddress},
+		{name: "invalid character", addr: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz", wantErr: ErrInvalidAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAddress(tt.addr)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
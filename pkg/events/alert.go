@@ -0,0 +1,174 @@
+package events
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// AlertConditionType what an alert condition check on a txn
+type AlertConditionType string
+
+const (
+	// AlertValueAbove the txn move more than Value wei
+	AlertValueAbove AlertConditionType = "value_above"
+	// AlertNewCounterparty no txn stored for the address involve the other party of the txn
+	AlertNewCounterparty AlertConditionType = "new_counterparty"
+	// AlertContract the txn call one of Contracts, with the Method selector when set
+	AlertContract AlertConditionType = "contract"
+	// AlertFailed the txn was reverted
+	AlertFailed AlertConditionType = "failed"
+	// AlertNonceGap the address skipped nonces since its last outgoing txn
+	AlertNonceGap AlertConditionType = "nonce_gap"
+)
+
+// AlertConditionTypes every condition type, in the order they are documented
+var AlertConditionTypes = []AlertConditionType{
+	AlertValueAbove, AlertNewCounterparty, AlertContract, AlertFailed, AlertNonceGap,
+}
+
+// AlertRule raise an alert for the txns matching every condition. A subscription with rules is only
+// notified of the txns raising an alert
+type AlertRule struct {
+	// Name unique per subscription, the events tell the rules which raised them by name
+	Name       string
+	Conditions []AlertCondition
+}
+
+// AlertCondition a single check of a rule, the fields used depend on Type
+type AlertCondition struct {
+	Type AlertConditionType
+	// Value threshold of AlertValueAbove in wei, base 10
+	Value string
+	// Contracts addresses of AlertContract
+	Contracts []string
+	// Method 4 bytes selector of AlertContract, any call when empty
+	Method string
+}
+
+// AlertFacts what the rules are evaluated on for a txn of the subscribed address
+type AlertFacts struct {
+	Transaction Transaction
+	// Input call data of the txn
+	Input string
+	// NewCounterparty no txn stored for the address before involve the other party
+	NewCounterparty bool
+	// Failed only known when a rule check it, see AlertRulesUse
+	Failed bool
+	// NonceGap nonces skipped by an outgoing txn since the previous one seen
+	NonceGap int64
+}
+
+// Match whether every condition of the rule match facts
+func (r AlertRule) Match(facts AlertFacts) bool {
+	for _, c := range r.Conditions {
+		if !c.Match(facts) {
+			return false
+		}
+	}
+	return len(r.Conditions) > 0
+}
+
+// Match whether the condition match facts
+func (c AlertCondition) Match(facts AlertFacts) bool {
+	switch c.Type {
+	case AlertValueAbove:
+		value, ok := new(big.Int).SetString(facts.Transaction.Value, 10)
+		threshold, valid := new(big.Int).SetString(c.Value, 10)
+		return ok && valid && value.Cmp(threshold) > 0
+	case AlertNewCounterparty:
+		return facts.NewCounterparty
+	case AlertContract:
+		if !slices.ContainsFunc(c.Contracts, func(contract string) bool {
+			return strings.EqualFold(contract, facts.Transaction.To)
+		}) {
+			return false
+		}
+		return c.Method == "" || strings.HasPrefix(strings.ToLower(facts.Input), strings.ToLower(c.Method))
+	case AlertFailed:
+		return facts.Failed
+	case AlertNonceGap:
+		return facts.NonceGap > 0
+	}
+	return false
+}
+
+// Validate check the condition has what its type need
+func (c AlertCondition) Validate() error {
+	switch c.Type {
+	case AlertValueAbove:
+		if value, ok := new(big.Int).SetString(c.Value, 10); !ok || value.Sign() < 0 {
+			return fmt.Errorf("%s condition need a value in wei", c.Type)
+		}
+	case AlertContract:
+		if len(c.Contracts) == 0 {
+			return fmt.Errorf("%s condition need contracts", c.Type)
+		}
+		for _, contract := range c.Contracts {
+			if _, err := NormalizeAddress(contract); err != nil {
+				return fmt.Errorf("%s condition %s: %w", c.Type, contract, err)
+			}
+		}
+		if c.Method != "" {
+			if len(c.Method) != 10 || !strings.HasPrefix(c.Method, "0x") {
+				return fmt.Errorf("%s condition method must be a 4 bytes selector", c.Type)
+			}
+			if _, err := hex.DecodeString(c.Method[2:]); err != nil {
+				return fmt.Errorf("%s condition method must be a 4 bytes selector", c.Type)
+			}
+		}
+	case AlertNewCounterparty, AlertFailed, AlertNonceGap:
+	default:
+		return fmt.Errorf("unknown alert condition %q", c.Type)
+	}
+	return nil
+}
+
+// validateAlertRules check every rule is named once and has valid conditions
+func validateAlertRules(rules []AlertRule) error {
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return errors.New("alert rule name is required")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicated alert rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if len(rule.Conditions) == 0 {
+			return fmt.Errorf("alert rule %q has no condition", rule.Name)
+		}
+		for _, c := range rule.Conditions {
+			if err := c.Validate(); err != nil {
+				return fmt.Errorf("alert rule %q: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchAlertRules names of the rules matching facts
+func MatchAlertRules(rules []AlertRule, facts AlertFacts) []string {
+	var names []string
+	for _, rule := range rules {
+		if rule.Match(facts) {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+// AlertRulesUse whether a condition of conditionType is in rules, for the facts which are costly to gather
+func AlertRulesUse(rules []AlertRule, conditionType AlertConditionType) bool {
+	for _, rule := range rules {
+		for _, c := range rule.Conditions {
+			if c.Type == conditionType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testContract = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+
+func TestMatchAlertRules(t *testing.T) {
+	rules := []AlertRule{
+		{Name: "whale", Conditions: []AlertCondition{{Type: AlertValueAbove, Value: "1000000000000000000"}}},
+		{Name: "new payee", Conditions: []AlertCondition{{Type: AlertNewCounterparty}, {Type: AlertValueAbove, Value: "0"}}},
+		{Name: "usdc transfer", Conditions: []AlertCondition{{Type: AlertContract, Contracts: []string{testContract}, Method: "0xa9059cbb"}}},
+		{Name: "failed", Conditions: []AlertCondition{{Type: AlertFailed}}},
+		{Name: "nonce gap", Conditions: []AlertCondition{{Type: AlertNonceGap}}},
+	}
+	tests := []struct {
+		name  string
+		facts AlertFacts
+		want  []string
+	}{
+		{name: "nothing interesting", facts: AlertFacts{Transaction: Transaction{Value: "10"}}},
+		{name: "value above", facts: AlertFacts{Transaction: Transaction{Value: "2000000000000000000"}}, want: []string{"whale"}},
+		{name: "value equal", facts: AlertFacts{Transaction: Transaction{Value: "1000000000000000000"}}},
+		{
+			name:  "every condition of a rule",
+			facts: AlertFacts{Transaction: Transaction{Value: "5"}, NewCounterparty: true},
+			want:  []string{"new payee"},
+		},
+		{name: "new counterparty without value", facts: AlertFacts{Transaction: Transaction{Value: "0"}, NewCounterparty: true}},
+		{
+			name:  "contract method",
+			facts: AlertFacts{Transaction: Transaction{To: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Value: "0"}, Input: "0xA9059CBB0000"},
+			want:  []string{"usdc transfer"},
+		},
+		{name: "other method", facts: AlertFacts{Transaction: Transaction{To: testContract, Value: "0"}, Input: "0x095ea7b3"}},
+		{name: "failed and gap", facts: AlertFacts{Failed: true, NonceGap: 2}, want: []string{"failed", "nonce gap"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, MatchAlertRules(rules, tt.facts))
+		})
+	}
+
+	require.True(t, AlertRulesUse(rules, AlertFailed))
+	require.False(t, AlertRulesUse(rules[:3], AlertFailed))
+}
+
+func TestAlertRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []AlertRule
+		wantErr string
+	}{
+		{
+			name: "valid",
+			rules: []AlertRule{
+				{Name: "a", Conditions: []AlertCondition{{Type: AlertValueAbove, Value: "1"}, {Type: AlertNewCounterparty}}},
+				{Name: "b", Conditions: []AlertCondition{{Type: AlertContract, Contracts: []string{testContract}}}},
+			},
+		},
+		{name: "no name", rules: []AlertRule{{Conditions: []AlertCondition{{Type: AlertFailed}}}}, wantErr: "alert rule name is required"},
+		{
+			name:    "duplicated name",
+			rules:   []AlertRule{{Name: "a", Conditions: []AlertCondition{{Type: AlertFailed}}}, {Name: "a", Conditions: []AlertCondition{{Type: AlertNonceGap}}}},
+			wantErr: `duplicated alert rule "a"`,
+		},
+		{name: "no condition", rules: []AlertRule{{Name: "a"}}, wantErr: `alert rule "a" has no condition`},
+		{
+			name:    "unknown condition",
+			rules:   []AlertRule{{Name: "a", Conditions: []AlertCondition{{Type: "gas_above"}}}},
+			wantErr: `alert rule "a": unknown alert condition "gas_above"`,
+		},
+		{
+			name:    "value not in wei",
+			rules:   []AlertRule{{Name: "a", Conditions: []AlertCondition{{Type: AlertValueAbove, Value: "1.5"}}}},
+			wantErr: `alert rule "a": value_above condition need a value in wei`,
+		},
+		{
+			name:    "invalid contract",
+			rules:   []AlertRule{{Name: "a", Conditions: []AlertCondition{{Type: AlertContract, Contracts: []string{"0x123"}}}}},
+			wantErr: `alert rule "a": contract condition 0x123: invalid address, expected 0x followed by 40 hex characters`,
+		},
+		{
+			name:    "invalid method",
+			rules:   []AlertRule{{Name: "a", Conditions: []AlertCondition{{Type: AlertContract, Contracts: []string{testContract}, Method: "transfer"}}}},
+			wantErr: `alert rule "a": contract condition method must be a 4 bytes selector`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NotificationPreferences{Rules: tt.rules}.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
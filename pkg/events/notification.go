@@ -0,0 +1,84 @@
+// Package events defines the notification events delivered to subscribers together with the
+// subscription, transaction and alert types they carry. They are the contract shared by the
+// parser and the notifiers, internal/models aliases them
+package events
+
+import "time"
+
+// NotificationEventVersion version of the notification event model, bumped on breaking changes
+// so external consumers can tell the formats apart
+const NotificationEventVersion = "1"
+
+// ChainEthereum the only chain parsed for now
+const ChainEthereum = "ethereum"
+
+// NotificationEventType what happened to the subscribed address
+type NotificationEventType string
+
+const (
+	// EventIncoming a txn sent to the address
+	EventIncoming NotificationEventType = "incoming"
+	// EventOutgoing a txn sent by the address
+	EventOutgoing NotificationEventType = "outgoing"
+	// EventTokenTransfer an ERC-20 transfer or transferFrom call sent by or to the address
+	EventTokenTransfer NotificationEventType = "token_transfer"
+	// EventReorged the block holding a notified txn was replaced on the canonical chain
+	EventReorged NotificationEventType = "reorged"
+	// EventConfirmed a notified txn reached the confirmation depth
+	EventConfirmed NotificationEventType = "confirmed"
+	// EventDigest several events of a subscription sent together, see Digest
+	EventDigest NotificationEventType = "digest"
+)
+
+// NotificationEventTypes every event type, in the order they are documented
+var NotificationEventTypes = []NotificationEventType{
+	EventIncoming, EventOutgoing, EventTokenTransfer, EventReorged, EventConfirmed, EventDigest,
+}
+
+// NotificationEvent sent to the owner of a subscription, every notifier receive the same event
+type NotificationEvent struct {
+	// ID stay the same for every delivery of the event so receivers can drop duplicates
+	ID      string
+	Version string
+	Type    NotificationEventType
+	Chain   string
+	// Address the subscribed address, lower case
+	Address      string
+	Subscription Subscription
+	Transaction  Transaction
+	// Confirmations of the txn when the event was created
+	Confirmations int64
+	// Reorg only set for reorged events
+	Reorg *Reorg
+	// Digest only set for digest events, which have no Transaction
+	Digest *Digest
+	// Alerts names of the alert rules of the subscription the txn matched
+	Alerts []string
+	// OccurredAt time of the block holding the txn
+	OccurredAt time.Time
+	CreatedAt  time.Time
+}
+
+// Tenant the tenant owning the subscription the event was created for
+func (e NotificationEvent) Tenant() string {
+	return e.Subscription.Tenant
+}
+
+// Digest summary of the events of a subscription batched in a single notification
+type Digest struct {
+	// Mode why the events were batched: DigestInterval, DigestDaily or DigestThrottled
+	Mode DigestMode
+	// From and To creation time of the first and the last event
+	From  time.Time
+	To    time.Time
+	Count int
+	// Counts events by type
+	Counts map[NotificationEventType]int
+	// TotalIn and TotalOut wei received and sent by the address, in base 10
+	TotalIn  string
+	TotalOut string
+	// Top the txns moving the most value, largest first
+	Top []Transaction
+	// EventIDs the events in the digest, oldest first
+	EventIDs []string
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// DefaultTenant tenant of subscriptions created without one
+const DefaultTenant = "default"
+
+// Subscription an address watched by the parser together with who owns it,
+// the same address can be subscribed by several tenants
+type Subscription struct {
+	Address string
+	// Tenant isolate subscriptions and their txn between API users
+	Tenant string
+	Label  string
+	// Owner team or tenant the address belongs to
+	Owner     string
+	Tags      []string
+	CreatedAt time.Time
+	// StartBlock txn in blocks before it are ignored, zero means from the next parsed block
+	StartBlock    int64
+	Notifications NotificationPreferences
+}
+
+// NotificationPreferences how the owner of a subscription want to be notified
+type NotificationPreferences struct {
+	// Muted txn are still stored but no notification is sent
+	Muted      bool
+	WebhookURL string
+	Emails     []string
+	// Routes channels the events are sent to, the default routes of the server when empty
+	Routes []NotificationRoute
+	Digest DigestPreferences
+	// Throttle notifications sent per period, the events over the limit are sent together in a digest
+	Throttle Throttle
+	// Rules only the txns raising an alert are notified when set
+	Rules []AlertRule
+}
+
+// Validate check the routes only use known channels and event types, the emails, the digest and throttle
+// settings and the alert rules
+func (p NotificationPreferences) Validate() error {
+	if err := validateWebhookURL(p.WebhookURL); err != nil {
+		return err
+	}
+	if err := validateAlertRules(p.Rules); err != nil {
+		return err
+	}
+	for _, email := range p.Emails {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			return fmt.Errorf("invalid email %q", email)
+		}
+	}
+	switch p.Digest.Mode {
+	case "", DigestImmediate:
+	case DigestInterval:
+		if p.Digest.Interval < time.Minute {
+			return errors.New("digest interval must be at least a minute")
+		}
+	case DigestDaily:
+		if p.Digest.DailyAt < 0 || p.Digest.DailyAt >= 24*time.Hour {
+			return errors.New("digest daily time must be within a day")
+		}
+	default:
+		return fmt.Errorf("unknown digest mode %q", p.Digest.Mode)
+	}
+	if p.Throttle.Limit < 0 || (p.Throttle.Limit > 0 && p.Throttle.Period < time.Minute) {
+		return errors.New("throttle limit must be positive and its period at least a minute")
+	}
+	for _, route := range p.Routes {
+		if !slices.Contains(NotificationChannels, route.Channel) {
+			return fmt.Errorf("unknown notification channel %q", route.Channel)
+		}
+		for _, eventType := range route.EventTypes {
+			if !slices.Contains(NotificationEventTypes, eventType) {
+				return fmt.Errorf("unknown notification event type %q", eventType)
+			}
+		}
+	}
+	return nil
+}
+
+// validateWebhookURL only accept absolute https urls, the webhook notifier also refuse to connect
+// to internal addresses whatever the host resolve to
+func validateWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || !u.IsAbs() || u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("webhook url must be an absolute https url")
+	}
+	return nil
+}
+
+// NotificationChannel a way of delivering events, configured on the server
+type NotificationChannel string
+
+const (
+	ChannelWebhook NotificationChannel = "webhook"
+	ChannelEmail   NotificationChannel = "email"
+	ChannelSlack   NotificationChannel = "slack"
+	ChannelDiscord NotificationChannel = "discord"
+	ChannelKafka   NotificationChannel = "kafka"
+	ChannelNATS    NotificationChannel = "nats"
+	ChannelConsole NotificationChannel = "console"
+)
+
+// NotificationChannels every channel a route can use
+var NotificationChannels = []NotificationChannel{
+	ChannelWebhook, ChannelEmail, ChannelSlack, ChannelDiscord, ChannelKafka, ChannelNATS, ChannelConsole,
+}
+
+// NotificationRoute send the events of EventTypes to Channel, every type when empty
+type NotificationRoute struct {
+	Channel    NotificationChannel
+	EventTypes []NotificationEventType
+}
+
+// Match tell if the events of eventType take the route
+func (r NotificationRoute) Match(eventType NotificationEventType) bool {
+	return len(r.EventTypes) == 0 || slices.Contains(r.EventTypes, eventType)
+}
+
+// DigestMode how often the events of a subscription are sent
+type DigestMode string
+
+const (
+	// DigestImmediate send every event on its own, the default
+	DigestImmediate DigestMode = "immediate"
+	// DigestInterval send the events together every Interval
+	DigestInterval DigestMode = "interval"
+	// DigestDaily send a summary of the events once a day at DailyAt
+	DigestDaily DigestMode = "daily"
+	// DigestThrottled the events over the throttle limit, only used in digests
+	DigestThrottled DigestMode = "throttled"
+)
+
+// DigestPreferences when the events of a subscription are sent
+type DigestPreferences struct {
+	Mode DigestMode
+	// Interval between two digests with DigestInterval, windows are aligned on UTC midnight
+	Interval time.Duration
+	// DailyAt time after UTC midnight the daily digest is sent
+	DailyAt time.Duration
+}
+
+// DeliverAt when an event created at t is sent, the end of its digest window
+func (d DigestPreferences) DeliverAt(t time.Time) time.Time {
+	switch d.Mode {
+	case DigestInterval:
+		if d.Interval > 0 {
+			return t.UTC().Truncate(d.Interval).Add(d.Interval)
+		}
+	case DigestDaily:
+		at := t.UTC().Truncate(24 * time.Hour).Add(d.DailyAt)
+		if !at.After(t) {
+			at = at.Add(24 * time.Hour)
+		}
+		return at
+	}
+	return t
+}
+
+// Batched tell if the events wait for a digest instead of being sent on their own
+func (d DigestPreferences) Batched() bool {
+	return d.Mode == DigestInterval || d.Mode == DigestDaily
+}
+
+// Throttle at most Limit notifications per Period, no limit when Limit is zero
+type Throttle struct {
+	Limit  int
+	Period time.Duration
+}
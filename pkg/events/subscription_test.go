@@ -0,0 +1,84 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestPreferences_DeliverAt(t *testing.T) {
+	created := time.Date(2024, 1, 2, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		name   string
+		digest DigestPreferences
+		want   time.Time
+	}{
+		{name: "immediate", digest: DigestPreferences{}, want: created},
+		{
+			name:   "interval",
+			digest: DigestPreferences{Mode: DigestInterval, Interval: 15 * time.Minute},
+			want:   time.Date(2024, 1, 2, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily later today",
+			digest: DigestPreferences{Mode: DigestDaily, DailyAt: 18 * time.Hour},
+			want:   time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily tomorrow",
+			digest: DigestPreferences{Mode: DigestDaily, DailyAt: 9 * time.Hour},
+			want:   time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.digest.DeliverAt(created))
+		})
+	}
+}
+
+func TestNotificationPreferences_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefs   NotificationPreferences
+		wantErr bool
+	}{
+		{name: "empty", prefs: NotificationPreferences{}},
+		{
+			name: "routes and digest",
+			prefs: NotificationPreferences{
+				Routes:   []NotificationRoute{{Channel: ChannelEmail, EventTypes: []NotificationEventType{EventDigest}}},
+				Digest:   DigestPreferences{Mode: DigestDaily, DailyAt: 9 * time.Hour},
+				Throttle: Throttle{Limit: 10, Period: time.Hour},
+			},
+		},
+		{name: "unknown channel", prefs: NotificationPreferences{Routes: []NotificationRoute{{Channel: "pager"}}}, wantErr: true},
+		{
+			name:    "unknown event type",
+			prefs:   NotificationPreferences{Routes: []NotificationRoute{{Channel: ChannelEmail, EventTypes: []NotificationEventType{"pending"}}}},
+			wantErr: true,
+		},
+		{name: "unknown digest mode", prefs: NotificationPreferences{Digest: DigestPreferences{Mode: DigestThrottled}}, wantErr: true},
+		{name: "interval too short", prefs: NotificationPreferences{Digest: DigestPreferences{Mode: DigestInterval}}, wantErr: true},
+		{name: "daily out of day", prefs: NotificationPreferences{Digest: DigestPreferences{Mode: DigestDaily, DailyAt: 25 * time.Hour}}, wantErr: true},
+		{name: "throttle without period", prefs: NotificationPreferences{Throttle: Throttle{Limit: 5}}, wantErr: true},
+		{name: "webhook url", prefs: NotificationPreferences{WebhookURL: "https://example.com/hook"}},
+		{name: "plain http webhook", prefs: NotificationPreferences{WebhookURL: "http://example.com/hook"}, wantErr: true},
+		{name: "relative webhook", prefs: NotificationPreferences{WebhookURL: "/hook"}, wantErr: true},
+		{name: "emails", prefs: NotificationPreferences{Emails: []string{"alice@example.com", "bob@example.org"}}},
+		{name: "invalid email", prefs: NotificationPreferences{Emails: []string{"alice@example.com", "bob"}}, wantErr: true},
+		{name: "email with display name", prefs: NotificationPreferences{Emails: []string{"Alice <alice@example.com>"}}, wantErr: true},
+		{name: "webhook without host", prefs: NotificationPreferences{WebhookURL: "https:///hook"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prefs.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package events
+
+type Transaction struct {
+	Hash        string
+	From        string
+	To          string
+	Value       string
+	BlockNumber string
+	Timestamp   string
+	// TokenTransfer set when the txn is an ERC-20 transfer or transferFrom call
+	TokenTransfer *TokenTransfer `json:",omitempty"`
+	// Nonce of the sender
+	Nonce int64
+	// Alerts raised by the txn for the tenants subscribing with alert rules, kept for its confirmed and
+	// reorged events. They are internal to the parser, use Public before returning the txn
+	Alerts map[string][]string `json:",omitempty"`
+}
+
+// Public the txn without the alerts of the tenants
+func (tx Transaction) Public() Transaction {
+	tx.Alerts = nil
+	return tx
+}
+
+// TokenTransfer an ERC-20 transfer decoded from the input of a txn
+type TokenTransfer struct {
+	// Token contract called by the txn
+	Token string
+	From  string
+	To    string
+	// Amount in the smallest unit of the token, base 10
+	Amount string
+}
+
+// Reorg a processed block replaced by another one on the canonical chain
+type Reorg struct {
+	BlockNumber int64
+	OldHash     string
+	NewHash     string
+}
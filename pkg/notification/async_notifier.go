@@ -0,0 +1,380 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// OverflowPolicy what an async notifier do with an event when the queue of its address is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock wait until the queue has room, the default
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drop the oldest event of the queue to make room
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowSpill write the event to a file of SpillDir, it is delivered once the queue is empty
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+var (
+	// ErrNotifierClosed returned when an event is given to a notifier which is shut down
+	ErrNotifierClosed = errors.New("notifier is shut down")
+	// ErrEventDropped given to OnError for the events dropped from a full queue
+	ErrEventDropped = errors.New("event dropped from a full queue")
+)
+
+// AsyncConfig of an async notifier, zero values use the defaults
+type AsyncConfig struct {
+	// Workers delivering events concurrently, 4 by default. The events of an address are always
+	// delivered by the same worker, in the order they were queued
+	Workers int
+	// QueueSize events waiting per worker, 256 by default
+	QueueSize int
+	// Overflow OverflowBlock by default
+	Overflow OverflowPolicy
+	// SpillDir where the events are spilled with OverflowSpill, events left in it by a previous run
+	// are delivered first. Keep the same number of workers between runs
+	SpillDir string
+	// DrainTimeout how long Shutdown keep delivering the queued events, 30s by default. The events left
+	// are spilled with OverflowSpill and given to OnError otherwise
+	DrainTimeout time.Duration
+	// OnError receive the events whose delivery failed or which were dropped, they are only logged without it
+	OnError func(event events.NotificationEvent, err error)
+	// OnDelivered receive the events delivered by the next notifier
+	OnDelivered func(event events.NotificationEvent)
+}
+
+func (c AsyncConfig) withDefaults() AsyncConfig {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 256
+	}
+	if c.Overflow == "" {
+		c.Overflow = OverflowBlock
+	}
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = 30 * time.Second
+	}
+	return c
+}
+
+// AsyncMetrics of an async notifier, the counters are cumulative since it started
+type AsyncMetrics struct {
+	// Queued events waiting in memory
+	Queued int
+	// Spilled events waiting on disk
+	Spilled   int
+	Delivered int64
+	Failed    int64
+	Dropped   int64
+}
+
+// AsyncNotifier a Notifier queuing events to deliver them in the background
+type AsyncNotifier interface {
+	Notifier
+	// Shutdown stop accepting events, deliver the queued ones and shut down the wrapped notifier
+	Shutdown()
+	Metrics() AsyncMetrics
+}
+
+type asyncNotifier struct {
+	next   Notifier
+	config AsyncConfig
+	shards []*asyncShard
+	log    *zap.Logger
+	// ctx of the deliveries, canceled when the drain timeout is reached
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	metrics AsyncMetrics
+}
+
+// asyncShard the queue of one worker
+type asyncShard struct {
+	mu    sync.Mutex
+	queue []events.NotificationEvent
+	// spill hold the events which overflowed, they are newer than the ones in queue
+	spill *spillQueue
+	// ready signaled when an event is queued, room when one is taken
+	ready chan struct{}
+	room  chan struct{}
+	// closed once the notifier is shut down and the queue collected
+	closed bool
+}
+
+// NewAsyncNotifier wrap next so Notify only queue the event, which is delivered by a pool of workers.
+// Notify return an error only when the event could not be queued, delivery errors are given to OnError
+func NewAsyncNotifier(next Notifier, config AsyncConfig) (AsyncNotifier, error) {
+	config = config.withDefaults()
+	switch config.Overflow {
+	case OverflowBlock, OverflowDropOldest:
+	case OverflowSpill:
+		if config.SpillDir == "" {
+			return nil, errors.New("spill dir is required to spill events")
+		}
+		if err := os.MkdirAll(config.SpillDir, 0o700); err != nil {
+			return nil, fmt.Errorf("create spill dir: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %q", config.Overflow)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &asyncNotifier{
+		next:   next,
+		config: config,
+		log:    logger.GetLogger().With(zap.String("notifier", "async")),
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+	}
+	for i := range config.Workers {
+		shard := &asyncShard{ready: make(chan struct{}, 1), room: make(chan struct{}, 1)}
+		if config.Overflow == OverflowSpill {
+			spill, err := openSpillQueue(filepath.Join(config.SpillDir, fmt.Sprintf("spill-%d.jsonl", i)))
+			if err != nil {
+				n.closeSpills()
+				cancel()
+				return nil, err
+			}
+			shard.spill = spill
+		}
+		n.shards = append(n.shards, shard)
+	}
+	for _, shard := range n.shards {
+		n.wg.Add(1)
+		go n.work(shard)
+	}
+	return n, nil
+}
+
+// Notify queue the event, with OverflowBlock it wait for room until ctx is done
+func (n *asyncNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	s := n.shard(event.Address)
+	for {
+		select {
+		case <-n.stop:
+			return ErrNotifierClosed
+		default:
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return ErrNotifierClosed
+		}
+		switch {
+		// once events are spilled the new ones follow them, so they are delivered in order
+		case s.spill != nil && s.spill.len() > 0,
+			len(s.queue) >= n.config.QueueSize && n.config.Overflow == OverflowSpill:
+			err := s.spill.push(event)
+			s.mu.Unlock()
+			signal(s.ready)
+			return err
+		case len(s.queue) < n.config.QueueSize:
+			s.queue = append(s.queue, event)
+			// wake the next producer waiting for room
+			if len(s.queue) < n.config.QueueSize {
+				signal(s.room)
+			}
+			s.mu.Unlock()
+			signal(s.ready)
+			return nil
+		case n.config.Overflow == OverflowDropOldest:
+			dropped := s.queue[0]
+			s.queue = append(s.queue[1:], event)
+			s.mu.Unlock()
+			signal(s.ready)
+			n.count(func(m *AsyncMetrics) { m.Dropped++ })
+			n.log.Warn("Queue full, dropped oldest event", zap.String("event_id", dropped.ID))
+			n.fail(dropped, ErrEventDropped)
+			return nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.room:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.stop:
+			return ErrNotifierClosed
+		}
+	}
+}
+
+// shard of the worker delivering the events of address
+func (n *asyncNotifier) shard(address string) *asyncShard {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(address)))
+	return n.shards[h.Sum32()%uint32(len(n.shards))]
+}
+
+// work deliver the events of s until shutdown, the queued events are drained first
+func (n *asyncNotifier) work(s *asyncShard) {
+	defer n.wg.Done()
+	for n.ctx.Err() == nil {
+		event, ok, err := n.take(s)
+		if err != nil {
+			n.log.Error("Failed to read spilled event", zap.Error(err))
+			continue
+		}
+		if ok {
+			signal(s.room)
+			n.deliver(s, event)
+			continue
+		}
+		select {
+		case <-s.ready:
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+// take the oldest event of s, the spilled events are only read once the queue is empty
+// and not while shutting down, they are kept for the next run
+func (n *asyncNotifier) take(s *asyncShard) (events.NotificationEvent, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) > 0 {
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		return event, true, nil
+	}
+	select {
+	case <-n.stop:
+		return events.NotificationEvent{}, false, nil
+	default:
+	}
+	if s.spill == nil || s.spill.len() == 0 {
+		return events.NotificationEvent{}, false, nil
+	}
+	event, err := s.spill.pop()
+	return event, err == nil, err
+}
+
+func (n *asyncNotifier) deliver(s *asyncShard, event events.NotificationEvent) {
+	err := n.next.Notify(n.ctx, event)
+	switch {
+	case err == nil:
+		n.count(func(m *AsyncMetrics) { m.Delivered++ })
+		if n.config.OnDelivered != nil {
+			n.config.OnDelivered(event)
+		}
+	case errors.Is(err, context.Canceled) && n.ctx.Err() != nil:
+		// aborted by the drain timeout, kept like the events still queued
+		s.mu.Lock()
+		s.queue = append([]events.NotificationEvent{event}, s.queue...)
+		s.mu.Unlock()
+	default:
+		n.count(func(m *AsyncMetrics) { m.Failed++ })
+		n.fail(event, err)
+	}
+}
+
+func (n *asyncNotifier) fail(event events.NotificationEvent, err error) {
+	if n.config.OnError != nil {
+		n.config.OnError(event, err)
+		return
+	}
+	n.log.Error("Notification failed", zap.String("event_id", event.ID), zap.Error(err))
+}
+
+// Shutdown stop accepting events and deliver the queued ones until the drain timeout
+func (n *asyncNotifier) Shutdown() {
+	n.once.Do(func() {
+		n.log.Info("Draining notification queue", zap.Int("queued", n.Metrics().Queued))
+		close(n.stop)
+		done := make(chan struct{})
+		go func() {
+			n.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(n.config.DrainTimeout):
+			n.log.Warn("Drain timeout reached, aborting deliveries")
+			n.cancel()
+			<-done
+		}
+		n.cancel()
+
+		for _, s := range n.shards {
+			s.mu.Lock()
+			left := s.queue
+			s.queue, s.closed = nil, true
+			if s.spill != nil && len(left) > 0 {
+				if err := s.spill.prepend(left); err != nil {
+					n.log.Error("Failed to spill queued events", zap.Error(err))
+				} else {
+					left = nil
+				}
+			}
+			s.mu.Unlock()
+			for _, event := range left {
+				n.fail(event, ErrNotifierClosed)
+			}
+		}
+		n.closeSpills()
+
+		if s, ok := n.next.(interface{ Shutdown() }); ok {
+			s.Shutdown()
+		}
+	})
+}
+
+func (n *asyncNotifier) closeSpills() {
+	for _, s := range n.shards {
+		if s.spill != nil {
+			s.spill.close()
+		}
+	}
+}
+
+// Metrics return a copy of the counters with the current queue sizes
+func (n *asyncNotifier) Metrics() AsyncMetrics {
+	n.mu.Lock()
+	metrics := n.metrics
+	n.mu.Unlock()
+	for _, s := range n.shards {
+		s.mu.Lock()
+		metrics.Queued += len(s.queue)
+		if s.spill != nil {
+			metrics.Spilled += s.spill.len()
+		}
+		s.mu.Unlock()
+	}
+	return metrics
+}
+
+func (n *asyncNotifier) count(update func(*AsyncMetrics)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	update(&n.metrics)
+}
+
+// signal ch without blocking, a pending signal is enough to wake its receiver
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
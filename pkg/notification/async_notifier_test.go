@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// gatedNotifier record the delivered events by address, deliveries wait until the gate is opened
+type gatedNotifier struct {
+	gate chan struct{}
+	err  error
+
+	mu        sync.Mutex
+	delivered map[string][]string
+	shutdown  bool
+}
+
+func newGatedNotifier(open bool) *gatedNotifier {
+	g := &gatedNotifier{gate: make(chan struct{}), delivered: map[string][]string{}}
+	if open {
+		close(g.gate)
+	}
+	return g
+}
+
+func (g *gatedNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	select {
+	case <-g.gate:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if g.err != nil {
+		return g.err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.delivered[event.Address] = append(g.delivered[event.Address], event.ID)
+	return nil
+}
+
+func (g *gatedNotifier) Shutdown() {
+	g.shutdown = true
+}
+
+func (g *gatedNotifier) byAddress() map[string][]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	result := map[string][]string{}
+	for address, ids := range g.delivered {
+		result[address] = append([]string(nil), ids...)
+	}
+	return result
+}
+
+func asyncEvent(address string, i int) events.NotificationEvent {
+	event := chatTestEvent()
+	event.ID = fmt.Sprintf("evt_%d", i)
+	event.Address = address
+	return event
+}
+
+// notifyAll queue count events spread over addresses and return the ids expected per address
+func notifyAll(t *testing.T, n Notifier, addresses []string, count int) map[string][]string {
+	want := map[string][]string{}
+	for i := range count {
+		event := asyncEvent(addresses[i%len(addresses)], i)
+		require.NoError(t, n.Notify(context.Background(), event))
+		want[event.Address] = append(want[event.Address], event.ID)
+	}
+	return want
+}
+
+var asyncAddresses = []string{"0xaa", "0xbb", "0xcc", "0xdd", "0xee"}
+
+func TestNewAsyncNotifier(t *testing.T) {
+	_, err := NewAsyncNotifier(newGatedNotifier(true), AsyncConfig{Overflow: OverflowSpill})
+	require.Error(t, err)
+	_, err = NewAsyncNotifier(newGatedNotifier(true), AsyncConfig{Overflow: "discard"})
+	require.Error(t, err)
+}
+
+func Test_asyncNotifier_Notify(t *testing.T) {
+	next := newGatedNotifier(true)
+	n, err := NewAsyncNotifier(next, AsyncConfig{Workers: 3, QueueSize: 4})
+	require.NoError(t, err)
+
+	want := notifyAll(t, n, asyncAddresses, 100)
+	n.Shutdown()
+	// the events of an address are delivered in order
+	require.Equal(t, want, next.byAddress())
+	require.Equal(t, AsyncMetrics{Delivered: 100}, n.Metrics())
+	require.True(t, next.shutdown)
+
+	require.ErrorIs(t, n.Notify(context.Background(), asyncEvent("0xaa", 0)), ErrNotifierClosed)
+}
+
+func Test_asyncNotifier_Notify_block(t *testing.T) {
+	next := newGatedNotifier(false)
+	n, err := NewAsyncNotifier(next, AsyncConfig{Workers: 1, QueueSize: 2})
+	require.NoError(t, err)
+	defer n.Shutdown()
+
+	// one event is being delivered and two are queued
+	notifyAll(t, n, asyncAddresses, 1)
+	require.Eventually(t, func() bool { return n.Metrics().Queued == 0 }, time.Second, time.Millisecond)
+	notifyAll(t, n, asyncAddresses, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, n.Notify(ctx, asyncEvent("0xaa", 3)), context.DeadlineExceeded)
+
+	// room is made as soon as the notifier move on
+	queued := make(chan error)
+	go func() { queued <- n.Notify(context.Background(), asyncEvent("0xaa", 3)) }()
+	close(next.gate)
+	require.NoError(t, <-queued)
+}
+
+func Test_asyncNotifier_Notify_dropOldest(t *testing.T) {
+	next := newGatedNotifier(false)
+	var dropped []string
+	n, err := NewAsyncNotifier(next, AsyncConfig{
+		Workers:   1,
+		QueueSize: 2,
+		Overflow:  OverflowDropOldest,
+		OnError: func(event events.NotificationEvent, err error) {
+			require.ErrorIs(t, err, ErrEventDropped)
+			dropped = append(dropped, event.ID)
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Notify(context.Background(), asyncEvent("0xaa", 0)))
+	require.Eventually(t, func() bool { return n.Metrics().Queued == 0 }, time.Second, time.Millisecond)
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, n.Notify(context.Background(), asyncEvent("0xaa", i)))
+	}
+	require.Equal(t, []string{"evt_1", "evt_2"}, dropped)
+
+	close(next.gate)
+	n.Shutdown()
+	require.Equal(t, map[string][]string{"0xaa": {"evt_0", "evt_3", "evt_4"}}, next.byAddress())
+	require.Equal(t, int64(2), n.Metrics().Dropped)
+}
+
+func Test_asyncNotifier_Notify_spill(t *testing.T) {
+	next := newGatedNotifier(false)
+	n, err := NewAsyncNotifier(next, AsyncConfig{Workers: 2, QueueSize: 2, Overflow: OverflowSpill, SpillDir: t.TempDir()})
+	require.NoError(t, err)
+
+	want := notifyAll(t, n, asyncAddresses, 30)
+	require.Positive(t, n.Metrics().Spilled)
+
+	close(next.gate)
+	require.Eventually(t, func() bool { return n.Metrics().Delivered == 30 }, 5*time.Second, time.Millisecond)
+	n.Shutdown()
+	require.Equal(t, want, next.byAddress())
+	require.Zero(t, n.Metrics().Spilled)
+}
+
+func Test_asyncNotifier_Shutdown_spill(t *testing.T) {
+	dir := t.TempDir()
+	stuck := newGatedNotifier(false)
+	n, err := NewAsyncNotifier(stuck, AsyncConfig{
+		Workers: 2, QueueSize: 2, Overflow: OverflowSpill, SpillDir: dir, DrainTimeout: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	want := notifyAll(t, n, asyncAddresses, 20)
+
+	// nothing could be delivered before the drain timeout, every event is kept on disk
+	n.Shutdown()
+	require.Empty(t, stuck.byAddress())
+	require.Equal(t, 20, n.Metrics().Spilled)
+
+	next := newGatedNotifier(true)
+	n, err = NewAsyncNotifier(next, AsyncConfig{Workers: 2, QueueSize: 2, Overflow: OverflowSpill, SpillDir: dir})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return n.Metrics().Delivered == 20 }, 5*time.Second, time.Millisecond)
+	n.Shutdown()
+	require.Equal(t, want, next.byAddress())
+}
+
+func Test_asyncNotifier_Shutdown_timeout(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		failed []error
+	)
+	n, err := NewAsyncNotifier(newGatedNotifier(false), AsyncConfig{
+		Workers:      1,
+		DrainTimeout: 20 * time.Millisecond,
+		OnError: func(_ events.NotificationEvent, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, err)
+		},
+	})
+	require.NoError(t, err)
+	notifyAll(t, n, asyncAddresses, 3)
+
+	n.Shutdown()
+	require.Len(t, failed, 3)
+	for _, err := range failed {
+		require.ErrorIs(t, err, ErrNotifierClosed)
+	}
+}
+
+func Test_asyncNotifier_deliver_error(t *testing.T) {
+	errDown := errors.New("down")
+	next := newGatedNotifier(true)
+	next.err = errDown
+	var failed []string
+	n, err := NewAsyncNotifier(next, AsyncConfig{
+		Workers: 1,
+		OnError: func(event events.NotificationEvent, err error) {
+			require.ErrorIs(t, err, errDown)
+			failed = append(failed, event.ID)
+		},
+	})
+	require.NoError(t, err)
+
+	notifyAll(t, n, asyncAddresses, 2)
+	n.Shutdown()
+	require.Equal(t, []string{"evt_0", "evt_1"}, failed)
+	require.Equal(t, int64(2), n.Metrics().Failed)
+}
+
+func Test_asyncNotifier_deliver_delivered(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	n, err := NewAsyncNotifier(newGatedNotifier(true), AsyncConfig{
+		Workers: 1,
+		OnDelivered: func(event events.NotificationEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			delivered = append(delivered, event.ID)
+		},
+	})
+	require.NoError(t, err)
+
+	notifyAll(t, n, asyncAddresses, 2)
+	n.Shutdown()
+	require.Equal(t, []string{"evt_0", "evt_1"}, delivered)
+}
@@ -0,0 +1,93 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+const (
+	// EventTypeHeader header of the broker messages holding the event type
+	EventTypeHeader = "X-TxParser-Event-Type"
+	// EventVersionHeader header of the broker messages holding the payload version
+	EventVersionHeader = "X-TxParser-Event-Version"
+)
+
+// ErrBrokerPublish returned when the broker did not acknowledge an event
+var ErrBrokerPublish = errors.New("broker publish failed")
+
+// PartitionKey what the events published to a broker are partitioned by,
+// events with the same key are delivered in order
+type PartitionKey string
+
+const (
+	// PartitionByAddress keep the events of an address together, the default
+	PartitionByAddress PartitionKey = "address"
+	// PartitionByTenant keep the events of a tenant together
+	PartitionByTenant PartitionKey = "tenant"
+	// PartitionNone spread the events without ordering
+	PartitionNone PartitionKey = "none"
+)
+
+// BrokerNotifier a Notifier publishing events to a message broker
+type BrokerNotifier interface {
+	Notifier
+	// Shutdown close the connection to the broker
+	Shutdown()
+}
+
+// brokerMessage an event encoded for a broker
+type brokerMessage struct {
+	payload EventPayload
+	body    []byte
+	// key the event is partitioned by, empty with PartitionNone
+	key     string
+	headers map[string]string
+}
+
+func newBrokerMessage(event events.NotificationEvent, partitionBy PartitionKey) (brokerMessage, error) {
+	payload := NewEventPayload(event)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return brokerMessage{}, fmt.Errorf("encode event: %w", err)
+	}
+	m := brokerMessage{
+		payload: payload,
+		body:    body,
+		headers: map[string]string{
+			"Content-Type":     "application/json",
+			EventIDHeader:      payload.ID,
+			EventTypeHeader:    payload.Type,
+			EventVersionHeader: payload.Version,
+		},
+	}
+	switch partitionBy {
+	case PartitionByTenant:
+		m.key = event.Tenant()
+	case PartitionNone:
+	default:
+		m.key = strings.ToLower(event.Address)
+	}
+	return m, nil
+}
+
+// destination expand the {type}, {tenant} and {address} of tmpl, the values are passed to escape
+// so they can not break the topic or subject syntax
+func (m brokerMessage) destination(tmpl string, escape func(string) string) string {
+	return strings.NewReplacer(
+		"{type}", escape(m.payload.Type),
+		"{tenant}", escape(m.payload.Subscription.Tenant),
+		"{address}", escape(strings.ToLower(m.payload.Address)),
+	).Replace(tmpl)
+}
+
+func validPartitionKey(key PartitionKey) error {
+	switch key {
+	case "", PartitionByAddress, PartitionByTenant, PartitionNone:
+		return nil
+	}
+	return fmt.Errorf("unknown partition key %q", key)
+}
@@ -0,0 +1,280 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const defaultExplorerURL = "https://etherscan.io/tx/{hash}"
+
+var (
+	// ErrChatDelivery returned when a Slack or Discord webhook refused a message
+	ErrChatDelivery = errors.New("chat delivery failed")
+	// ErrRateLimited returned when the webhook ask to wait longer than allowed before retrying
+	ErrRateLimited = errors.New("chat webhook rate limited")
+)
+
+// chatNotifier post events rendered by render to an incoming webhook of a chat service,
+// waiting as asked by its rate-limit responses
+type chatNotifier struct {
+	name        string
+	url         string
+	render      func(chatEvent) any
+	client      *http.Client
+	explorerURL string
+	// maxAttempts posts of a message when rate limited, waits longer than maxWait are not honored
+	maxAttempts int
+	maxWait     time.Duration
+	log         *zap.Logger
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu sync.Mutex
+	// blockedUntil no message is posted before, set from the rate-limit headers
+	blockedUntil time.Time
+}
+
+func newChatNotifier(name, url string, render func(chatEvent) any, opts []ChatOption) *chatNotifier {
+	n := &chatNotifier{
+		name:        name,
+		url:         url,
+		render:      render,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		explorerURL: defaultExplorerURL,
+		maxAttempts: 3,
+		maxWait:     time.Minute,
+		log:         logger.GetLogger().With(zap.String("notifier", name)),
+		now:         time.Now,
+		sleep:       sleepContext,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	if n.maxAttempts < 1 {
+		n.maxAttempts = 1
+	}
+	return n
+}
+
+// Notify post the event rendered as a message, nothing is sent without a webhook url
+func (n *chatNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	if n.url == "" {
+		return nil
+	}
+	body, err := json.Marshal(n.render(n.chatEvent(NewEventPayload(event))))
+	if err != nil {
+		return fmt.Errorf("encode %s message: %w", n.name, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := n.waitRateLimit(ctx); err != nil {
+			return err
+		}
+		retryAfter, err := n.post(ctx, body)
+		if !errors.Is(err, ErrRateLimited) {
+			return err
+		}
+		if attempt == n.maxAttempts || retryAfter > n.maxWait {
+			return fmt.Errorf("%w: retry after %s", ErrRateLimited, retryAfter)
+		}
+		n.log.Warn("Rate limited, waiting", zap.String("event_id", event.ID), zap.Duration("retry_after", retryAfter))
+		n.block(retryAfter)
+	}
+}
+
+// post send body once and return how long to wait when rate limited
+func (n *chatNotifier) post(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tx-parser-"+n.name+"/"+events.NotificationEventVersion)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrChatDelivery, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	// discord tell when the bucket is empty before it is exceeded
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if resetAfter, ok := parseSeconds(resp.Header.Get("X-RateLimit-Reset-After")); ok {
+			n.block(resetAfter)
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return retryAfter(resp.Header, respBody), ErrRateLimited
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, fmt.Errorf("%w: unexpected status %s: %s", ErrChatDelivery, resp.Status, bytes.TrimSpace(respBody))
+	}
+	return 0, nil
+}
+
+// retryAfter read the wait of a 429, from the Retry-After header (slack) or the retry_after field (discord)
+func retryAfter(header http.Header, body []byte) time.Duration {
+	var payload struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.RetryAfter > 0 {
+		return time.Duration(payload.RetryAfter * float64(time.Second))
+	}
+	if d, ok := parseSeconds(header.Get("Retry-After")); ok {
+		return d
+	}
+	return time.Second
+}
+
+func parseSeconds(value string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+func (n *chatNotifier) block(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if until := n.now().Add(d); until.After(n.blockedUntil) {
+		n.blockedUntil = until
+	}
+}
+
+// waitRateLimit wait until the webhook accept messages again
+func (n *chatNotifier) waitRateLimit(ctx context.Context) error {
+	n.mu.Lock()
+	wait := n.blockedUntil.Sub(n.now())
+	n.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	if wait > n.maxWait {
+		return fmt.Errorf("%w: retry after %s", ErrRateLimited, wait)
+	}
+	return n.sleep(ctx, wait)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chatEvent what the chat messages show of an event
+type chatEvent struct {
+	EventPayload
+	Title string
+	// Name label of the subscription, or its address
+	Name      string
+	Direction string
+	Amount    string
+	// Link to the txn on the explorer
+	Link string
+	// Top the largest txns of a digest event
+	Top []chatTxn
+}
+
+// chatTxn a txn listed in a digest message
+type chatTxn struct {
+	Hash   string
+	Amount string
+	Link   string
+}
+
+var chatTitles = map[string]string{
+	string(events.EventIncoming):      "Incoming transaction",
+	string(events.EventOutgoing):      "Outgoing transaction",
+	string(events.EventTokenTransfer): "Token transfer",
+	string(events.EventReorged):       "Transaction reorged",
+	string(events.EventConfirmed):     "Transaction confirmed",
+	string(events.EventDigest):        "Notification digest",
+}
+
+func (n *chatNotifier) chatEvent(payload EventPayload) chatEvent {
+	e := chatEvent{
+		EventPayload: payload,
+		Title:        chatTitles[payload.Type],
+		Name:         payload.Subscription.Label,
+		Direction:    direction(payload),
+		Amount:       formatWei(payload.Transaction.Value),
+		Link:         strings.ReplaceAll(n.explorerURL, "{hash}", payload.Transaction.Hash),
+	}
+	if e.Title == "" {
+		e.Title = payload.Type
+	}
+	if e.Name == "" {
+		e.Name = payload.Address
+	}
+	if payload.Digest != nil {
+		e.Direction, e.Amount, e.Link = "", "", ""
+		for _, txn := range payload.Digest.Top {
+			e.Top = append(e.Top, chatTxn{
+				Hash:   txn.Hash,
+				Amount: formatWei(txn.Value),
+				Link:   strings.ReplaceAll(n.explorerURL, "{hash}", txn.Hash),
+			})
+		}
+	}
+	return e
+}
+
+// digestPeriod the time covered by a digest
+func digestPeriod(digest *EventDigest) string {
+	const layout = "2006-01-02 15:04 UTC"
+	return digest.From.Format(layout) + " - " + digest.To.Format(layout)
+}
+
+// direction of the txn seen from the subscribed address
+func direction(payload EventPayload) string {
+	from := strings.EqualFold(payload.Transaction.From, payload.Address)
+	to := strings.EqualFold(payload.Transaction.To, payload.Address)
+	switch {
+	case from && to:
+		return "self"
+	case from:
+		return "outgoing"
+	default:
+		return "incoming"
+	}
+}
+
+var weiPerEther = big.NewInt(1_000_000_000_000_000_000)
+
+// formatWei format a base 10 wei value in ETH without trailing zeros
+func formatWei(value string) string {
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return value + " wei"
+	}
+	whole, frac := new(big.Int).QuoRem(wei, weiPerEther, new(big.Int))
+	if frac.Sign() == 0 {
+		return whole.String() + " ETH"
+	}
+	decimals := strings.TrimRight(fmt.Sprintf("%018s", frac.String()), "0")
+	return whole.String() + "." + decimals + " ETH"
+}
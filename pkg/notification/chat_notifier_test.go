@@ -0,0 +1,264 @@
+package notification
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// chatResponse what the chat server answer to one post
+type chatResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// chatServer answer with responses in order, the last one is repeated
+func chatServer(t *testing.T, responses ...chatResponse) (*httptest.Server, func() []receivedWebhook) {
+	var (
+		mu       sync.Mutex
+		received []receivedWebhook
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		resp := responses[min(len(received), len(responses))-1]
+		mu.Unlock()
+		for k, v := range resp.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []receivedWebhook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedWebhook(nil), received...)
+	}
+}
+
+// newTestChatNotifier a notifier recording its waits instead of sleeping, the clock move with them
+func newTestChatNotifier(url string, opts ...ChatOption) (*chatNotifier, *[]time.Duration) {
+	var waits []time.Duration
+	now := time.Unix(1700000000, 0)
+	n := newChatNotifier("test", url, func(e chatEvent) any { return e.EventPayload }, opts)
+	n.now = func() time.Time { return now }
+	n.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	return n, &waits
+}
+
+func chatTestEvent() events.NotificationEvent {
+	return events.NotificationEvent{
+		ID:      "evt_1",
+		Version: events.NotificationEventVersion,
+		Type:    events.EventIncoming,
+		Chain:   events.ChainEthereum,
+		Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		Subscription: events.Subscription{
+			Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			Tenant:  events.DefaultTenant,
+			Label:   "cold wallet",
+		},
+		Transaction: events.Transaction{
+			Hash:        "0xabc",
+			From:        "0x1111111111111111111111111111111111111111",
+			To:          "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			Value:       "1500000000000000000",
+			BlockNumber: "16",
+		},
+		Confirmations: 1,
+		OccurredAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func chatTestDigest() events.NotificationEvent {
+	event := chatTestEvent()
+	event.Type = events.EventDigest
+	event.Transaction = events.Transaction{}
+	event.Digest = &events.Digest{
+		Mode:     events.DigestInterval,
+		From:     time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 1, 2, 3, 14, 0, 0, time.UTC),
+		Count:    3,
+		Counts:   map[events.NotificationEventType]int{events.EventIncoming: 2, events.EventOutgoing: 1},
+		TotalIn:  "2000000000000000000",
+		TotalOut: "500000000000000000",
+		Top:      []events.Transaction{{Hash: "0xa", Value: "1500000000000000000"}, {Hash: "0xb", Value: "500000000000000000"}},
+		EventIDs: []string{"evt_a", "evt_b", "evt_c"},
+	}
+	return event
+}
+
+func Test_chatNotifier_Notify(t *testing.T) {
+	ok := chatResponse{status: http.StatusNoContent}
+
+	tests := []struct {
+		name      string
+		responses []chatResponse
+		opts      []ChatOption
+		wantErr   error
+		wantPosts int
+		wantWaits []time.Duration
+	}{
+		{
+			name:      "delivered",
+			responses: []chatResponse{ok},
+			wantPosts: 1,
+		},
+		{
+			name: "slack retry after header",
+			responses: []chatResponse{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "2"}},
+				ok,
+			},
+			wantPosts: 2,
+			wantWaits: []time.Duration{2 * time.Second},
+		},
+		{
+			name: "discord retry after body",
+			responses: []chatResponse{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"},
+					body: `{"message": "You are being rate limited.", "retry_after": 0.25, "global": false}`},
+				ok,
+			},
+			wantPosts: 2,
+			wantWaits: []time.Duration{250 * time.Millisecond},
+		},
+		{
+			name: "still rate limited",
+			responses: []chatResponse{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"}},
+			},
+			opts:      []ChatOption{WithRateLimitRetry(2, time.Minute)},
+			wantErr:   ErrRateLimited,
+			wantPosts: 2,
+			wantWaits: []time.Duration{time.Second},
+		},
+		{
+			name: "wait too long",
+			responses: []chatResponse{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3600"}},
+			},
+			wantErr:   ErrRateLimited,
+			wantPosts: 1,
+		},
+		{
+			name:      "rejected",
+			responses: []chatResponse{{status: http.StatusBadRequest, body: "invalid_blocks"}},
+			wantErr:   ErrChatDelivery,
+			wantPosts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, received := chatServer(t, tt.responses...)
+			n, waits := newTestChatNotifier(srv.URL, tt.opts...)
+
+			err := n.Notify(context.Background(), chatTestEvent())
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, received(), tt.wantPosts)
+			require.Equal(t, tt.wantWaits, *waits)
+			for _, req := range received() {
+				require.Equal(t, "application/json", req.header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func Test_chatNotifier_Notify_bucket(t *testing.T) {
+	// the last request of the bucket delay the next message
+	srv, received := chatServer(t, chatResponse{status: http.StatusNoContent, header: map[string]string{
+		"X-RateLimit-Remaining":   "0",
+		"X-RateLimit-Reset-After": "1.5",
+	}})
+	n, waits := newTestChatNotifier(srv.URL)
+
+	require.NoError(t, n.Notify(context.Background(), chatTestEvent()))
+	require.Empty(t, *waits)
+	require.NoError(t, n.Notify(context.Background(), chatTestEvent()))
+	require.Equal(t, []time.Duration{1500 * time.Millisecond}, *waits)
+	require.Len(t, received(), 2)
+}
+
+func Test_chatNotifier_Notify_noURL(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	require.NoError(t, n.Notify(context.Background(), chatTestEvent()))
+}
+
+func Test_chatNotifier_chatEvent(t *testing.T) {
+	n, _ := newTestChatNotifier("", WithExplorerURL("https://sepolia.etherscan.io/tx/{hash}"))
+	event := NewEventPayload(chatTestEvent())
+	got := n.chatEvent(event)
+	require.Equal(t, "Incoming transaction", got.Title)
+	require.Equal(t, "cold wallet", got.Name)
+	require.Equal(t, "incoming", got.Direction)
+	require.Equal(t, "1.5 ETH", got.Amount)
+	require.Equal(t, "https://sepolia.etherscan.io/tx/0xabc", got.Link)
+
+	// without label the address is shown
+	event.Subscription.Label = ""
+	require.Equal(t, event.Address, n.chatEvent(event).Name)
+}
+
+func Test_chatNotifier_chatEvent_digest(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	got := n.chatEvent(NewEventPayload(chatTestDigest()))
+	require.Equal(t, "Notification digest", got.Title)
+	require.Empty(t, got.Amount)
+	require.Empty(t, got.Link)
+	require.Equal(t, []chatTxn{
+		{Hash: "0xa", Amount: "1.5 ETH", Link: "https://etherscan.io/tx/0xa"},
+		{Hash: "0xb", Amount: "0.5 ETH", Link: "https://etherscan.io/tx/0xb"},
+	}, got.Top)
+}
+
+func Test_direction(t *testing.T) {
+	const addr = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{name: "incoming", from: "0x1111111111111111111111111111111111111111", to: addr, want: "incoming"},
+		{name: "outgoing", from: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", to: "0x1111111111111111111111111111111111111111", want: "outgoing"},
+		{name: "self", from: addr, to: addr, want: "self"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := direction(EventPayload{Address: addr, Transaction: EventTransaction{From: tt.from, To: tt.to}})
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_formatWei(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "0", want: "0 ETH"},
+		{value: "1000000000000000000", want: "1 ETH"},
+		{value: "1500000000000000000", want: "1.5 ETH"},
+		{value: "1", want: "0.000000000000000001 ETH"},
+		{value: "123456789000000000000000", want: "123456.789 ETH"},
+		{value: "not a number", want: "not a number wei"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			require.Equal(t, tt.want, formatWei(tt.value))
+		})
+	}
+}
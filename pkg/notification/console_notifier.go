@@ -1,7 +1,10 @@
 package notification
 
 import (
+	"context"
 	"log"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
 )
 
 type consoleNotifier struct{}
@@ -10,7 +13,13 @@ func NewConsoleNotifier() Notifier {
 	return &consoleNotifier{}
 }
 
-func (n *consoleNotifier) Notify(address string, message string, payload interface{}) error {
-	log.Printf("\n[ConsoleNotifier] Notification for %s: %s\nTransaction: %+v\n", address, message, payload)
+func (n *consoleNotifier) Notify(_ context.Context, event events.NotificationEvent) error {
+	if d := event.Digest; d != nil {
+		log.Printf("\n[ConsoleNotifier] %s digest %s for %s (tenant %s)\n%d events %v, in %s wei, out %s wei\n",
+			d.Mode, event.ID, event.Address, event.Tenant(), d.Count, d.Counts, d.TotalIn, d.TotalOut)
+		return nil
+	}
+	log.Printf("\n[ConsoleNotifier] %s event %s for %s (tenant %s)\nTransaction: %+v\n",
+		event.Type, event.ID, event.Address, event.Tenant(), event.Transaction)
 	return nil
 }
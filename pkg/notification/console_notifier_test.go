@@ -1,9 +1,11 @@
 package notification
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
 )
 
 func TestNewConsoleNotifier(t *testing.T) {
@@ -12,58 +14,52 @@ func TestNewConsoleNotifier(t *testing.T) {
 }
 
 func Test_consoleNotifier_Notify(t *testing.T) {
-	type args struct {
-		address string
-		message string
-		payload interface{}
-	}
 	tests := []struct {
 		name    string
-		n       *consoleNotifier
-		args    args
+		event   events.NotificationEvent
 		wantErr bool
 	}{
 		{
-			name: "successful notification with string payload",
-			n:    &consoleNotifier{},
-			args: args{
-				address: "0x123",
-				message: "Test message",
-				payload: "string payload",
+			name: "successful notification of a transaction",
+			event: events.NotificationEvent{
+				ID:           "evt_1",
+				Type:         events.EventIncoming,
+				Address:      "0x123",
+				Subscription: events.Subscription{Address: "0x123", Tenant: events.DefaultTenant},
+				Transaction:  events.Transaction{Hash: "0xabc", To: "0x123"},
 			},
 			wantErr: false,
 		},
 		{
-			name: "successful notification with struct payload",
-			n:    &consoleNotifier{},
-			args: args{
-				address: "0x123",
-				message: "Test message",
-				payload: struct {
-					Key   string
-					Value int
-				}{
-					Key:   "test",
-					Value: 123,
-				},
+			name: "successful notification of a reorg",
+			event: events.NotificationEvent{
+				ID:      "evt_2",
+				Type:    events.EventReorged,
+				Address: "0x123",
+				Reorg:   &events.Reorg{BlockNumber: 10, OldHash: "0x1", NewHash: "0x2"},
 			},
 			wantErr: false,
 		},
 		{
-			name: "successful notification with nil payload",
-			n:    &consoleNotifier{},
-			args: args{
-				address: "0x123",
-				message: "Test message",
-				payload: nil,
+			name: "successful notification of a digest",
+			event: events.NotificationEvent{
+				ID:      "evt_3",
+				Type:    events.EventDigest,
+				Address: "0x123",
+				Digest:  &events.Digest{Mode: events.DigestDaily, Count: 2, TotalIn: "1", TotalOut: "0"},
 			},
 			wantErr: false,
 		},
+		{
+			name:    "successful notification with empty event",
+			event:   events.NotificationEvent{},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			n := &consoleNotifier{}
-			if err := n.Notify(tt.args.address, tt.args.message, tt.args.payload); (err != nil) != tt.wantErr {
+			if err := n.Notify(context.Background(), tt.event); (err != nil) != tt.wantErr {
 				t.Errorf("consoleNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
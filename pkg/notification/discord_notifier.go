@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// discordMessage an execute webhook request with a single embed
+type discordMessage struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+	// AllowedMentions empty so a label can not ping @everyone
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Footer      discordFooter  `json:"footer"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+var discordColors = map[string]int{
+	string(events.EventIncoming):      0x2ecc71,
+	string(events.EventOutgoing):      0xe67e22,
+	string(events.EventTokenTransfer): 0x3498db,
+	string(events.EventReorged):       0xe74c3c,
+	string(events.EventConfirmed):     0x95a5a6,
+	string(events.EventDigest):        0x9b59b6,
+}
+
+// NewDiscordNotifier create a notifier posting events as embeds to a Discord webhook
+func NewDiscordNotifier(webhookURL string, opts ...ChatOption) Notifier {
+	return newChatNotifier("discord", webhookURL, renderDiscord, opts)
+}
+
+func renderDiscord(e chatEvent) any {
+	if e.Digest != nil {
+		return renderDiscordDigest(e)
+	}
+	fields := []discordField{
+		{Name: "Amount", Value: e.Amount, Inline: true},
+		{Name: "Direction", Value: e.Direction, Inline: true},
+		{Name: "Block", Value: e.Transaction.BlockNumber, Inline: true},
+		{Name: "From", Value: "`" + e.Transaction.From + "`"},
+		{Name: "To", Value: "`" + e.Transaction.To + "`"},
+	}
+	if e.Reorg != nil {
+		fields = append(fields, discordField{Name: "Replaced block", Value: fmt.Sprintf("%d `%s`", e.Reorg.BlockNumber, e.Reorg.OldHash)})
+	}
+	if e.Type == string(events.EventConfirmed) {
+		fields = append(fields, discordField{Name: "Confirmations", Value: fmt.Sprint(e.Confirmations), Inline: true})
+	}
+	if len(e.Alerts) > 0 {
+		fields = append(fields, discordField{Name: "Alerts", Value: strings.Join(e.Alerts, ", ")})
+	}
+
+	embed := discordEmbed{
+		Title:       e.Title,
+		URL:         e.Link,
+		Description: fmt.Sprintf("**%s** · %s", e.Name, e.Amount),
+		Color:       discordColors[e.Type],
+		Fields:      fields,
+		Footer:      discordFooter{Text: "event " + e.ID},
+	}
+	if !e.OccurredAt.IsZero() {
+		embed.Timestamp = e.OccurredAt.Format(time.RFC3339)
+	}
+	return discordMessage{
+		Username:        "tx-parser",
+		Embeds:          []discordEmbed{embed},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	}
+}
+
+func renderDiscordDigest(e chatEvent) any {
+	fields := []discordField{
+		{Name: "Received", Value: formatWei(e.Digest.TotalIn), Inline: true},
+		{Name: "Sent", Value: formatWei(e.Digest.TotalOut), Inline: true},
+		{Name: "Period", Value: digestPeriod(e.Digest)},
+	}
+	if len(e.Top) > 0 {
+		lines := make([]string, 0, len(e.Top))
+		for _, txn := range e.Top {
+			lines = append(lines, fmt.Sprintf("[%s](%s) %s", txn.Hash, txn.Link, txn.Amount))
+		}
+		fields = append(fields, discordField{Name: "Top transactions", Value: strings.Join(lines, "\n")})
+	}
+	return discordMessage{
+		Username: "tx-parser",
+		Embeds: []discordEmbed{{
+			Title:       e.Title,
+			Description: fmt.Sprintf("**%s** · %d notifications", e.Name, e.Digest.Count),
+			Color:       discordColors[e.Type],
+			Fields:      fields,
+			Footer:      discordFooter{Text: "event " + e.ID},
+			Timestamp:   e.Digest.To.Format(time.RFC3339),
+		}},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	}
+}
@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+func TestNewDiscordNotifier(t *testing.T) {
+	srv, received := chatServer(t, chatResponse{status: http.StatusNoContent})
+	n := NewDiscordNotifier(srv.URL, WithExplorerURL("https://explorer.test/tx/{hash}"))
+
+	require.NoError(t, n.Notify(context.Background(), chatTestEvent()))
+
+	reqs := received()
+	require.Len(t, reqs, 1)
+	// mentions must stay disabled, an empty list and not a missing field
+	require.Contains(t, string(reqs[0].body), `"allowed_mentions":{"parse":[]}`)
+	var got discordMessage
+	require.NoError(t, json.Unmarshal(reqs[0].body, &got))
+	require.Equal(t, discordMessage{
+		Username: "tx-parser",
+		Embeds: []discordEmbed{{
+			Title:       "Incoming transaction",
+			URL:         "https://explorer.test/tx/0xabc",
+			Description: "**cold wallet** · 1.5 ETH",
+			Color:       0x2ecc71,
+			Fields: []discordField{
+				{Name: "Amount", Value: "1.5 ETH", Inline: true},
+				{Name: "Direction", Value: "incoming", Inline: true},
+				{Name: "Block", Value: "16", Inline: true},
+				{Name: "From", Value: "`0x1111111111111111111111111111111111111111`"},
+				{Name: "To", Value: "`0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed`"},
+			},
+			Footer:    discordFooter{Text: "event evt_1"},
+			Timestamp: "2024-01-02T03:04:05Z",
+		}},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	}, got)
+}
+
+func Test_renderDiscord_confirmed(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	event := chatTestEvent()
+	event.Type = events.EventConfirmed
+	event.Confirmations = 12
+
+	got := renderDiscord(n.chatEvent(NewEventPayload(event))).(discordMessage)
+	require.Equal(t, "Transaction confirmed", got.Embeds[0].Title)
+	require.Equal(t, 0x95a5a6, got.Embeds[0].Color)
+	require.Contains(t, got.Embeds[0].Fields, discordField{Name: "Confirmations", Value: "12", Inline: true})
+}
+
+func Test_renderDiscord_alerts(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	event := chatTestEvent()
+	event.Alerts = []string{"whale", "failed"}
+
+	got := renderDiscord(n.chatEvent(NewEventPayload(event))).(discordMessage)
+	require.Contains(t, got.Embeds[0].Fields, discordField{Name: "Alerts", Value: "whale, failed"})
+}
+
+func Test_renderDiscord_digest(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	got := renderDiscord(n.chatEvent(NewEventPayload(chatTestDigest()))).(discordMessage)
+	embed := got.Embeds[0]
+	require.Equal(t, "Notification digest", embed.Title)
+	require.Equal(t, "**cold wallet** · 3 notifications", embed.Description)
+	require.Equal(t, 0x9b59b6, embed.Color)
+	require.Equal(t, "2024-01-02T03:14:00Z", embed.Timestamp)
+	require.Contains(t, embed.Fields, discordField{Name: "Received", Value: "2 ETH", Inline: true})
+	require.Contains(t, embed.Fields, discordField{
+		Name:  "Top transactions",
+		Value: "[0xa](https://etherscan.io/tx/0xa) 1.5 ETH\n[0xb](https://etherscan.io/tx/0xb) 0.5 ETH",
+	})
+}
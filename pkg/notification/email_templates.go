@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// EmailTemplate sources of an email, Subject and Text are text/template and HTML an html/template.
+// An email is rendered with an EventPayload, the summary of a digest event is in .Digest. Empty fields
+// use the default template, HTML may be set to "-" to send text only
+type EmailTemplate struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+const subjectName = `{{with .Subscription.Label}}{{.}}{{else}}{{.Address}}{{end}}`
+
+var defaultSubjects = map[events.NotificationEventType]string{
+	events.EventIncoming:      "[tx-parser] Incoming transaction to " + subjectName,
+	events.EventOutgoing:      "[tx-parser] Outgoing transaction from " + subjectName,
+	events.EventTokenTransfer: "[tx-parser] Token transfer on " + subjectName,
+	events.EventReorged:       "[tx-parser] Transaction reorged on " + subjectName,
+	events.EventConfirmed:     "[tx-parser] Transaction confirmed on " + subjectName,
+	events.EventDigest:        "[tx-parser] {{.Digest.Count}} notifications on " + subjectName,
+}
+
+const defaultEventText = `{{.Type}} event on {{.Address}}{{with .Subscription.Label}} ({{.}}){{end}}
+
+Transaction:   {{.Transaction.Hash}}
+From:          {{.Transaction.From}}
+To:            {{.Transaction.To}}
+Value:         {{.Transaction.Value}} wei
+Block:         {{.Transaction.BlockNumber}}
+Confirmations: {{.Confirmations}}
+{{with .Reorg}}Reorg:         block {{.BlockNumber}} {{.OldHash}} replaced by {{.NewHash}}
+{{end}}{{with .Alerts}}Alerts:        {{range $i, $name := .}}{{if $i}}, {{end}}{{$name}}{{end}}
+{{end}}
+Event {{.ID}} of tenant {{.Subscription.Tenant}}
+`
+
+const defaultEventHTML = `<p><b>{{.Type}}</b> event on <code>{{.Address}}</code>{{with .Subscription.Label}} ({{.}}){{end}}</p>
+<table>
+<tr><td>Transaction</td><td><code>{{.Transaction.Hash}}</code></td></tr>
+<tr><td>From</td><td><code>{{.Transaction.From}}</code></td></tr>
+<tr><td>To</td><td><code>{{.Transaction.To}}</code></td></tr>
+<tr><td>Value</td><td>{{.Transaction.Value}} wei</td></tr>
+<tr><td>Block</td><td>{{.Transaction.BlockNumber}}</td></tr>
+<tr><td>Confirmations</td><td>{{.Confirmations}}</td></tr>
+{{with .Reorg}}<tr><td>Reorg</td><td>block {{.BlockNumber}} <code>{{.OldHash}}</code> replaced by <code>{{.NewHash}}</code></td></tr>
+{{end}}{{with .Alerts}}<tr><td>Alerts</td><td>{{range $i, $name := .}}{{if $i}}, {{end}}{{$name}}{{end}}</td></tr>
+{{end}}</table>
+<p><small>Event {{.ID}} of tenant {{.Subscription.Tenant}}</small></p>
+`
+
+const defaultDigestEventText = `{{.Digest.Count}} notifications on {{.Address}}{{with .Subscription.Label}} ({{.}}){{end}}
+from {{.Digest.From.Format "2006-01-02 15:04:05 UTC"}} to {{.Digest.To.Format "2006-01-02 15:04:05 UTC"}}
+{{range $type, $count := .Digest.Counts}}
+{{printf "%-15s" (print $type ":")}}{{$count}}
+{{- end}}
+
+Received:      {{.Digest.TotalIn}} wei
+Sent:          {{.Digest.TotalOut}} wei
+{{with .Digest.Top}}
+Top transactions:
+{{range .}}- {{.Hash}}: {{.Value}} wei from {{.From}} to {{.To}}
+{{end}}{{end}}
+Event {{.ID}} of tenant {{.Subscription.Tenant}}
+`
+
+const defaultDigestEventHTML = `<p><b>{{.Digest.Count}}</b> notifications on <code>{{.Address}}</code>{{with .Subscription.Label}} ({{.}}){{end}}
+from {{.Digest.From.Format "2006-01-02 15:04:05 UTC"}} to {{.Digest.To.Format "2006-01-02 15:04:05 UTC"}}</p>
+<table>
+{{range $type, $count := .Digest.Counts}}<tr><td>{{$type}}</td><td>{{$count}}</td></tr>
+{{end}}<tr><td>Received</td><td>{{.Digest.TotalIn}} wei</td></tr>
+<tr><td>Sent</td><td>{{.Digest.TotalOut}} wei</td></tr>
+</table>
+{{with .Digest.Top}}<table>
+<tr><th>Transaction</th><th>From</th><th>To</th><th>Value (wei)</th></tr>
+{{range .}}<tr><td><code>{{.Hash}}</code></td><td><code>{{.From}}</code></td><td><code>{{.To}}</code></td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}<p><small>Event {{.ID}} of tenant {{.Subscription.Tenant}}</small></p>
+`
+
+func defaultEventTemplate(eventType events.NotificationEventType) EmailTemplate {
+	subject, ok := defaultSubjects[eventType]
+	if !ok {
+		subject = "[tx-parser] {{.Type}} event on " + subjectName
+	}
+	if eventType == events.EventDigest {
+		return EmailTemplate{Subject: subject, Text: defaultDigestEventText, HTML: defaultDigestEventHTML}
+	}
+	return EmailTemplate{Subject: subject, Text: defaultEventText, HTML: defaultEventHTML}
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	// html nil when the email is text only
+	html *htmltemplate.Template
+}
+
+// parseEmailTemplate parse tmpl, its empty fields are taken from defaults
+func parseEmailTemplate(name string, tmpl, defaults EmailTemplate) (*emailTemplate, error) {
+	if tmpl.Subject == "" {
+		tmpl.Subject = defaults.Subject
+	}
+	if tmpl.Text == "" {
+		tmpl.Text = defaults.Text
+	}
+	if tmpl.HTML == "" {
+		tmpl.HTML = defaults.HTML
+	}
+
+	var (
+		t   emailTemplate
+		err error
+	)
+	if t.subject, err = texttemplate.New(name + " subject").Option("missingkey=error").Parse(tmpl.Subject); err != nil {
+		return nil, fmt.Errorf("parse %s email template: %w", name, err)
+	}
+	if t.text, err = texttemplate.New(name + " text").Option("missingkey=error").Parse(tmpl.Text); err != nil {
+		return nil, fmt.Errorf("parse %s email template: %w", name, err)
+	}
+	if tmpl.HTML != "-" {
+		if t.html, err = htmltemplate.New(name + " html").Option("missingkey=error").Parse(tmpl.HTML); err != nil {
+			return nil, fmt.Errorf("parse %s email template: %w", name, err)
+		}
+	}
+	return &t, nil
+}
+
+// render execute the template with data, html is empty for a text only template
+func (t *emailTemplate) render(data any) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	subject = buf.String()
+
+	buf.Reset()
+	if err := t.text.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.Execute(&buf, data); err != nil {
+			return "", "", "", err
+		}
+		html = buf.String()
+	}
+	return subject, text, html, nil
+}
@@ -0,0 +1,137 @@
+package notification
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// EventSchema JSON schema of EventPayload, the format every notifier send events in
+//
+//go:embed event.schema.json
+var EventSchema []byte
+
+// EventPayload JSON representation of a events.NotificationEvent for external consumers,
+// addresses are EIP-55 checksummed
+type EventPayload struct {
+	Version       string            `json:"version"`
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Chain         string            `json:"chain"`
+	Address       string            `json:"address"`
+	Subscription  EventSubscription `json:"subscription"`
+	Transaction   EventTransaction  `json:"transaction"`
+	Confirmations int64             `json:"confirmations"`
+	Reorg         *EventReorg       `json:"reorg,omitempty"`
+	Digest        *EventDigest      `json:"digest,omitempty"`
+	Alerts        []string          `json:"alerts,omitempty"`
+	OccurredAt    time.Time         `json:"occurred_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// EventSubscription the subscription the event was created for
+type EventSubscription struct {
+	Address string   `json:"address"`
+	Tenant  string   `json:"tenant"`
+	Label   string   `json:"label,omitempty"`
+	Owner   string   `json:"owner,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// EventTransaction the txn the event is about
+type EventTransaction struct {
+	Hash        string `json:"hash"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	BlockNumber string `json:"block_number"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// EventReorg the block replacement of a reorged event
+type EventReorg struct {
+	BlockNumber int64  `json:"block_number"`
+	OldHash     string `json:"old_hash"`
+	NewHash     string `json:"new_hash"`
+}
+
+// EventDigest the events batched in a digest event
+type EventDigest struct {
+	Mode     string             `json:"mode"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Count    int                `json:"count"`
+	Counts   map[string]int     `json:"counts"`
+	TotalIn  string             `json:"total_in"`
+	TotalOut string             `json:"total_out"`
+	Top      []EventTransaction `json:"top"`
+	EventIDs []string           `json:"event_ids"`
+}
+
+// NewEventPayload convert event to the format sent to external consumers
+func NewEventPayload(event events.NotificationEvent) EventPayload {
+	sub, txn := event.Subscription, event.Transaction.Checksummed()
+	payload := EventPayload{
+		Version: event.Version,
+		ID:      event.ID,
+		Type:    string(event.Type),
+		Chain:   event.Chain,
+		Address: events.ChecksumAddress(event.Address),
+		Subscription: EventSubscription{
+			Address: events.ChecksumAddress(sub.Address),
+			Tenant:  sub.Tenant,
+			Label:   sub.Label,
+			Owner:   sub.Owner,
+			Tags:    sub.Tags,
+		},
+		Transaction:   newEventTransaction(txn),
+		Confirmations: event.Confirmations,
+		Alerts:        event.Alerts,
+		OccurredAt:    event.OccurredAt.UTC(),
+		CreatedAt:     event.CreatedAt.UTC(),
+	}
+	if event.Reorg != nil {
+		payload.Reorg = &EventReorg{
+			BlockNumber: event.Reorg.BlockNumber,
+			OldHash:     event.Reorg.OldHash,
+			NewHash:     event.Reorg.NewHash,
+		}
+	}
+	if event.Digest != nil {
+		payload.Digest = newEventDigest(*event.Digest)
+	}
+	return payload
+}
+
+func newEventTransaction(txn events.Transaction) EventTransaction {
+	return EventTransaction{
+		Hash:        txn.Hash,
+		From:        txn.From,
+		To:          txn.To,
+		Value:       txn.Value,
+		BlockNumber: txn.BlockNumber,
+		Timestamp:   txn.Timestamp,
+	}
+}
+
+func newEventDigest(digest events.Digest) *EventDigest {
+	result := &EventDigest{
+		Mode:     string(digest.Mode),
+		From:     digest.From.UTC(),
+		To:       digest.To.UTC(),
+		Count:    digest.Count,
+		Counts:   make(map[string]int, len(digest.Counts)),
+		TotalIn:  digest.TotalIn,
+		TotalOut: digest.TotalOut,
+		Top:      make([]EventTransaction, 0, len(digest.Top)),
+		EventIDs: digest.EventIDs,
+	}
+	for eventType, count := range digest.Counts {
+		result.Counts[string(eventType)] = count
+	}
+	for _, txn := range digest.Top {
+		result.Top = append(result.Top, newEventTransaction(txn.Checksummed()))
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+type schemaObject struct {
+	Required   []string                `json:"required"`
+	Properties map[string]schemaObject `json:"properties"`
+	Enum       []string                `json:"enum"`
+	Const      string                  `json:"const"`
+}
+
+// jsonFields the json names of the fields of t and the ones which are always written
+func jsonFields(t reflect.Type) (fields, required []string) {
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+		if opts != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	return fields, required
+}
+
+func TestEventSchema(t *testing.T) {
+	var schema schemaObject
+	require.NoError(t, json.Unmarshal(EventSchema, &schema))
+
+	// the schema must describe exactly what is sent
+	for _, tt := range []struct {
+		name   string
+		schema schemaObject
+		typ    reflect.Type
+	}{
+		{name: "event", schema: schema, typ: reflect.TypeOf(EventPayload{})},
+		{name: "subscription", schema: schema.Properties["subscription"], typ: reflect.TypeOf(EventSubscription{})},
+		{name: "transaction", schema: schema.Properties["transaction"], typ: reflect.TypeOf(EventTransaction{})},
+		{name: "reorg", schema: schema.Properties["reorg"], typ: reflect.TypeOf(EventReorg{})},
+		{name: "digest", schema: schema.Properties["digest"], typ: reflect.TypeOf(EventDigest{})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, required := jsonFields(tt.typ)
+			properties := make([]string, 0, len(tt.schema.Properties))
+			for name := range tt.schema.Properties {
+				properties = append(properties, name)
+			}
+			require.ElementsMatch(t, fields, properties)
+			require.ElementsMatch(t, required, tt.schema.Required)
+		})
+	}
+
+	types := make([]string, 0, len(events.NotificationEventTypes))
+	for _, eventType := range events.NotificationEventTypes {
+		types = append(types, string(eventType))
+	}
+	require.Equal(t, types, schema.Properties["type"].Enum)
+	require.Equal(t, events.NotificationEventVersion, schema.Properties["version"].Const)
+}
+
+func TestNewEventPayload(t *testing.T) {
+	const (
+		addr    = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		addrSum = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+7", 7*3600))
+	got := NewEventPayload(events.NotificationEvent{
+		ID:            "evt_1",
+		Version:       events.NotificationEventVersion,
+		Type:          events.EventReorged,
+		Chain:         events.ChainEthereum,
+		Address:       addr,
+		Subscription:  events.Subscription{Address: addr, Tenant: "a", Owner: "treasury", Tags: []string{"cold"}},
+		Transaction:   events.Transaction{Hash: "0xabc", From: "0x1111111111111111111111111111111111111111", To: addr},
+		Confirmations: 3,
+		Reorg:         &events.Reorg{BlockNumber: 10, OldHash: "0x1", NewHash: "0x2"},
+		Alerts:        []string{"whale"},
+		OccurredAt:    at,
+		CreatedAt:     at,
+	})
+	require.Equal(t, EventPayload{
+		Version:       "1",
+		ID:            "evt_1",
+		Type:          "reorged",
+		Chain:         "ethereum",
+		Address:       addrSum,
+		Subscription:  EventSubscription{Address: addrSum, Tenant: "a", Owner: "treasury", Tags: []string{"cold"}},
+		Transaction:   EventTransaction{Hash: "0xabc", From: "0x1111111111111111111111111111111111111111", To: addrSum},
+		Confirmations: 3,
+		Reorg:         &EventReorg{BlockNumber: 10, OldHash: "0x1", NewHash: "0x2"},
+		Alerts:        []string{"whale"},
+		OccurredAt:    at.UTC(),
+		CreatedAt:     at.UTC(),
+	}, got)
+}
+
+func TestNewEventPayload_digest(t *testing.T) {
+	const (
+		addr    = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		addrSum = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+7", 7*3600))
+	got := NewEventPayload(events.NotificationEvent{
+		ID:      "evt_1",
+		Version: events.NotificationEventVersion,
+		Type:    events.EventDigest,
+		Address: addr,
+		Digest: &events.Digest{
+			Mode:     events.DigestDaily,
+			From:     at,
+			To:       at,
+			Count:    2,
+			Counts:   map[events.NotificationEventType]int{events.EventIncoming: 2},
+			TotalIn:  "3",
+			TotalOut: "0",
+			Top:      []events.Transaction{{Hash: "0xb", To: addr, Value: "2"}, {Hash: "0xa", To: addr, Value: "1"}},
+			EventIDs: []string{"evt_a", "evt_b"},
+		},
+	})
+	require.Equal(t, &EventDigest{
+		Mode:     "daily",
+		From:     at.UTC(),
+		To:       at.UTC(),
+		Count:    2,
+		Counts:   map[string]int{"incoming": 2},
+		TotalIn:  "3",
+		TotalOut: "0",
+		Top:      []EventTransaction{{Hash: "0xb", To: addrSum, Value: "2"}, {Hash: "0xa", To: addrSum, Value: "1"}},
+		EventIDs: []string{"evt_a", "evt_b"},
+	}, got.Digest)
+	require.Equal(t, EventTransaction{}, got.Transaction)
+}
@@ -0,0 +1,116 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const defaultKafkaTopic = "tx-parser.events"
+
+// KafkaConfig of a Kafka notifier, Brokers is required
+type KafkaConfig struct {
+	Brokers []string
+	// Topic the events are produced to, {type} and {tenant} are replaced by those of the event
+	// e.g. tx-parser.{type}. tx-parser.events by default
+	Topic string
+	// PartitionBy the record key, PartitionByAddress by default. Keys are hashed like the java client
+	// so consumers in other languages agree on the partition
+	PartitionBy PartitionKey
+	// Timeout of the delivery of one event including the producer retries, 30s by default
+	Timeout time.Duration
+	// ClientOptions extra franz-go options e.g. TLS or SASL, applied after the defaults
+	ClientOptions []kgo.Opt
+}
+
+type kafkaNotifier struct {
+	client      *kgo.Client
+	topic       string
+	partitionBy PartitionKey
+	log         *zap.Logger
+}
+
+// NewKafkaNotifier create a notifier producing events as JSON records. The producer is idempotent and
+// wait for all in-sync replicas, a record is only acknowledged once and a failed delivery is returned
+// so the outbox retry it
+func NewKafkaNotifier(config KafkaConfig) (BrokerNotifier, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("kafka brokers are required")
+	}
+	if err := validPartitionKey(config.PartitionBy); err != nil {
+		return nil, err
+	}
+	if config.Topic == "" {
+		config.Topic = defaultKafkaTopic
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ClientID("tx-parser"),
+		// idempotent writes are the franz-go default, they require acks from all in-sync replicas
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.RecordDeliveryTimeout(config.Timeout),
+	}
+	client, err := kgo.NewClient(append(opts, config.ClientOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka client: %w", err)
+	}
+	return &kafkaNotifier{
+		client:      client,
+		topic:       config.Topic,
+		partitionBy: config.PartitionBy,
+		log:         logger.GetLogger().With(zap.String("notifier", "kafka")),
+	}, nil
+}
+
+// Notify produce the event and wait until it is acknowledged
+func (n *kafkaNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	msg, err := newBrokerMessage(event, n.partitionBy)
+	if err != nil {
+		return err
+	}
+	record := &kgo.Record{
+		Topic: msg.destination(n.topic, kafkaTopicEscape),
+		Value: msg.body,
+	}
+	if msg.key != "" {
+		record.Key = []byte(msg.key)
+	}
+	for key, value := range msg.headers {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+	}
+
+	if err := n.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("%w: kafka topic %s: %w", ErrBrokerPublish, record.Topic, err)
+	}
+	n.log.Debug("Event produced", zap.String("event_id", event.ID), zap.String("topic", record.Topic),
+		zap.Int32("partition", record.Partition), zap.Int64("offset", record.Offset))
+	return nil
+}
+
+// Shutdown close the client, every event was already acknowledged
+func (n *kafkaNotifier) Shutdown() {
+	n.client.Close()
+}
+
+// kafkaTopicEscape replace the characters not allowed in a topic name
+func kafkaTopicEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
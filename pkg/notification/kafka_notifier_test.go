@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+func kafkaCluster(t *testing.T, topics ...string) []string {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(4, topics...))
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster.ListenAddrs()
+}
+
+// consumeKafka read count records of topic from the start
+func consumeKafka(t *testing.T, brokers []string, topic string, count int) []*kgo.Record {
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) < count {
+		fetches := client.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func newTestKafkaNotifier(t *testing.T, config KafkaConfig) BrokerNotifier {
+	n, err := NewKafkaNotifier(config)
+	require.NoError(t, err)
+	t.Cleanup(n.Shutdown)
+	return n
+}
+
+func TestNewKafkaNotifier(t *testing.T) {
+	_, err := NewKafkaNotifier(KafkaConfig{})
+	require.Error(t, err)
+	_, err = NewKafkaNotifier(KafkaConfig{Brokers: []string{"localhost:9092"}, PartitionBy: "txn"})
+	require.Error(t, err)
+}
+
+func Test_kafkaNotifier_Notify(t *testing.T) {
+	brokers := kafkaCluster(t, defaultKafkaTopic)
+	n := newTestKafkaNotifier(t, KafkaConfig{Brokers: brokers})
+
+	event := chatTestEvent()
+	other := chatTestEvent()
+	other.ID = "evt_2"
+	other.Type = events.EventConfirmed
+	require.NoError(t, n.Notify(context.Background(), event))
+	require.NoError(t, n.Notify(context.Background(), other))
+
+	records := consumeKafka(t, brokers, defaultKafkaTopic, 2)
+	require.Len(t, records, 2)
+	// keyed by address, the events of an address share a partition
+	require.Equal(t, records[0].Partition, records[1].Partition)
+
+	record := records[0]
+	require.Equal(t, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", string(record.Key))
+	headers := map[string]string{}
+	for _, h := range record.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	require.Equal(t, map[string]string{
+		"Content-Type":     "application/json",
+		EventIDHeader:      "evt_1",
+		EventTypeHeader:    "incoming",
+		EventVersionHeader: events.NotificationEventVersion,
+	}, headers)
+
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(record.Value, &payload))
+	require.Equal(t, NewEventPayload(event), payload)
+}
+
+func Test_kafkaNotifier_Notify_partitionBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		partitionBy PartitionKey
+		wantKey     []byte
+	}{
+		{name: "tenant", partitionBy: PartitionByTenant, wantKey: []byte(events.DefaultTenant)},
+		{name: "none", partitionBy: PartitionNone, wantKey: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brokers := kafkaCluster(t, "tx-parser.incoming")
+			n := newTestKafkaNotifier(t, KafkaConfig{Brokers: brokers, Topic: "tx-parser.{type}", PartitionBy: tt.partitionBy})
+
+			require.NoError(t, n.Notify(context.Background(), chatTestEvent()))
+			records := consumeKafka(t, brokers, "tx-parser.incoming", 1)
+			require.Equal(t, tt.wantKey, records[0].Key)
+		})
+	}
+}
+
+func Test_kafkaNotifier_Notify_error(t *testing.T) {
+	// the topic does not exist and the cluster does not create it
+	brokers := kafkaCluster(t, defaultKafkaTopic)
+	n := newTestKafkaNotifier(t, KafkaConfig{Brokers: brokers, Topic: "missing", Timeout: 2 * time.Second})
+
+	err := n.Notify(context.Background(), chatTestEvent())
+	require.ErrorIs(t, err, ErrBrokerPublish)
+}
+
+func Test_kafkaNotifier_Notify_canceled(t *testing.T) {
+	brokers := kafkaCluster(t, defaultKafkaTopic)
+	n := newTestKafkaNotifier(t, KafkaConfig{Brokers: brokers})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the dispatcher tell a shutdown from a failed delivery with the context error
+	err := n.Notify(ctx, chatTestEvent())
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_kafkaTopicEscape(t *testing.T) {
+	require.Equal(t, "tx-parser.acme_corp_", kafkaTopicEscape("tx-parser.acme corp/"))
+}
@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const defaultNATSSubject = "tx-parser.events.{type}.{address}"
+
+// NATSConfig of a NATS notifier, URL is required
+type NATSConfig struct {
+	URL string
+	// Subject the events are published to, {type}, {tenant} and {address} are replaced by those of the event.
+	// tx-parser.events.{type}.{address} by default, a subject mapping with partition() on the address token
+	// split the events by address
+	Subject string
+	// JetStream publish to the stream capturing Subject and wait for its ack. The event id is sent as
+	// Nats-Msg-Id so the stream drop the events delivered twice within its duplicate window
+	JetStream bool
+	// Timeout of one publish, 10s by default
+	Timeout time.Duration
+	// Options extra connection options e.g. credentials or TLS
+	Options []nats.Option
+}
+
+type natsNotifier struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+	timeout time.Duration
+	log     *zap.Logger
+}
+
+// NewNATSNotifier connect to the NATS server and create a notifier publishing events as JSON messages,
+// a publish not confirmed by the server is returned so the outbox retry it
+func NewNATSNotifier(config NATSConfig) (BrokerNotifier, error) {
+	if config.URL == "" {
+		return nil, errors.New("nats url is required")
+	}
+	if config.Subject == "" {
+		config.Subject = defaultNATSSubject
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	log := logger.GetLogger().With(zap.String("notifier", "nats"))
+	opts := []nats.Option{
+		nats.Name("tx-parser"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			log.Warn("Disconnected from NATS", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Info("Reconnected to NATS", zap.String("url", conn.ConnectedUrlRedacted()))
+		}),
+	}
+	conn, err := nats.Connect(config.URL, append(opts, config.Options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+	n := &natsNotifier{conn: conn, subject: config.Subject, timeout: config.Timeout, log: log}
+	if config.JetStream {
+		if n.js, err = jetstream.New(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("create jetstream context: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Notify publish the event and wait until the server, or the stream with JetStream, confirm it
+func (n *natsNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	msg, err := newBrokerMessage(event, PartitionNone)
+	if err != nil {
+		return err
+	}
+	natsMsg := nats.NewMsg(msg.destination(n.subject, natsTokenEscape))
+	natsMsg.Data = msg.body
+	for key, value := range msg.headers {
+		natsMsg.Header.Set(key, value)
+	}
+	natsMsg.Header.Set(nats.MsgIdHdr, event.ID)
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	if n.js != nil {
+		ack, err := n.js.PublishMsg(ctx, natsMsg)
+		if err != nil {
+			return fmt.Errorf("%w: nats subject %s: %w", ErrBrokerPublish, natsMsg.Subject, err)
+		}
+		n.log.Debug("Event published", zap.String("event_id", event.ID), zap.String("stream", ack.Stream),
+			zap.Uint64("sequence", ack.Sequence), zap.Bool("duplicate", ack.Duplicate))
+		return nil
+	}
+
+	if err := n.conn.PublishMsg(natsMsg); err != nil {
+		return fmt.Errorf("%w: nats subject %s: %w", ErrBrokerPublish, natsMsg.Subject, err)
+	}
+	// the server answer the flush once it processed every message published before
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("%w: nats subject %s: %w", ErrBrokerPublish, natsMsg.Subject, err)
+	}
+	n.log.Debug("Event published", zap.String("event_id", event.ID), zap.String("subject", natsMsg.Subject))
+	return nil
+}
+
+// Shutdown close the connection, every event was already confirmed
+func (n *natsNotifier) Shutdown() {
+	n.conn.Close()
+}
+
+// natsTokenEscape replace what would split a subject token or act as a wildcard
+func natsTokenEscape(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
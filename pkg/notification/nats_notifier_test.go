@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// natsServer start an embedded server with JetStream on a random port
+func natsServer(t *testing.T) *server.Server {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func newTestNATSNotifier(t *testing.T, config NATSConfig) BrokerNotifier {
+	n, err := NewNATSNotifier(config)
+	require.NoError(t, err)
+	t.Cleanup(n.Shutdown)
+	return n
+}
+
+func TestNewNATSNotifier(t *testing.T) {
+	_, err := NewNATSNotifier(NATSConfig{})
+	require.Error(t, err)
+	_, err = NewNATSNotifier(NATSConfig{URL: "nats://127.0.0.1:1", Options: []nats.Option{nats.Timeout(100 * time.Millisecond)}})
+	require.Error(t, err)
+}
+
+func Test_natsNotifier_Notify(t *testing.T) {
+	srv := natsServer(t)
+	sub, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer sub.Close()
+	messages, err := sub.SubscribeSync("tx-parser.events.>")
+	require.NoError(t, err)
+	require.NoError(t, sub.Flush())
+
+	n := newTestNATSNotifier(t, NATSConfig{URL: srv.ClientURL()})
+	event := chatTestEvent()
+	require.NoError(t, n.Notify(context.Background(), event))
+
+	msg, err := messages.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "tx-parser.events.incoming.0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", msg.Subject)
+	require.Equal(t, "evt_1", msg.Header.Get(nats.MsgIdHdr))
+	require.Equal(t, "evt_1", msg.Header.Get(EventIDHeader))
+	require.Equal(t, "incoming", msg.Header.Get(EventTypeHeader))
+	require.Equal(t, events.NotificationEventVersion, msg.Header.Get(EventVersionHeader))
+
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(msg.Data, &payload))
+	require.Equal(t, NewEventPayload(event), payload)
+}
+
+func Test_natsNotifier_Notify_jetStream(t *testing.T) {
+	srv := natsServer(t)
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	ctx := context.Background()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}})
+	require.NoError(t, err)
+
+	n := newTestNATSNotifier(t, NATSConfig{URL: srv.ClientURL(), Subject: "events.{tenant}.{type}", JetStream: true})
+	event := chatTestEvent()
+	require.NoError(t, n.Notify(ctx, event))
+	// a redelivery of the outbox is dropped by the stream
+	require.NoError(t, n.Notify(ctx, event))
+	event.ID = "evt_2"
+	require.NoError(t, n.Notify(ctx, event))
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), info.State.Msgs)
+	msg, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "events.default.incoming", msg.Subject)
+}
+
+func Test_natsNotifier_Notify_error(t *testing.T) {
+	t.Run("no stream", func(t *testing.T) {
+		srv := natsServer(t)
+		n := newTestNATSNotifier(t, NATSConfig{URL: srv.ClientURL(), JetStream: true, Timeout: time.Second})
+		err := n.Notify(context.Background(), chatTestEvent())
+		require.ErrorIs(t, err, ErrBrokerPublish)
+	})
+
+	t.Run("server down", func(t *testing.T) {
+		srv := natsServer(t)
+		n := newTestNATSNotifier(t, NATSConfig{URL: srv.ClientURL(), Timeout: 500 * time.Millisecond})
+		srv.Shutdown()
+		err := n.Notify(context.Background(), chatTestEvent())
+		require.ErrorIs(t, err, ErrBrokerPublish)
+	})
+}
+
+func Test_natsTokenEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "acme", want: "acme"},
+		{in: "acme.corp", want: "acme_corp"},
+		{in: "a*b>c d", want: "a_b_c_d"},
+		{in: "", want: "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.want, natsTokenEscape(tt.in))
+		})
+	}
+}
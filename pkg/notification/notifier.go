@@ -1,7 +1,14 @@
 package notification
 
+import (
+	"context"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
 // Notifier interface of our notification service
 type Notifier interface {
-	// Notify send notification to subscriber
-	Notify(address string, message string, payload interface{}) error
+	// Notify deliver event to the owner of its subscription, an error means the delivery
+	// failed and may be retried with the same event
+	Notify(ctx context.Context, event events.NotificationEvent) error
 }
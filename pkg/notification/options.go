@@ -0,0 +1,104 @@
+package notification
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// WebhookOption configure a webhook notifier
+type WebhookOption func(*webhookNotifier)
+
+// WithHTTPClient use client to POST events
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(n *webhookNotifier) {
+		n.client = client
+	}
+}
+
+// WithRetry set how many times a delivery is attempted and the backoff between attempts,
+// the backoff double after every failure up to maxBackoff
+func WithRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) WebhookOption {
+	return func(n *webhookNotifier) {
+		n.maxAttempts = maxAttempts
+		n.initialBackoff = initialBackoff
+		n.maxBackoff = maxBackoff
+	}
+}
+
+// WithDefaultURL deliver events of subscriptions without a webhook url to url
+func WithDefaultURL(url string) WebhookOption {
+	return func(n *webhookNotifier) {
+		n.defaultURL = url
+	}
+}
+
+// WithPrivateNetworks allow webhooks to loopback, private and link-local addresses, which are refused
+// by default. It has no effect together with WithHTTPClient
+func WithPrivateNetworks() WebhookOption {
+	return func(n *webhookNotifier) {
+		n.allowPrivate = true
+	}
+}
+
+// WithAttemptHistory keep the last size delivery attempts
+func WithAttemptHistory(size int) WebhookOption {
+	return func(n *webhookNotifier) {
+		n.historySize = size
+	}
+}
+
+// SMTPOption configure an SMTP notifier
+type SMTPOption func(*smtpNotifier)
+
+// WithEmailTemplate render the emails of eventType with tmpl, its empty fields keep the default
+func WithEmailTemplate(eventType events.NotificationEventType, tmpl EmailTemplate) SMTPOption {
+	return func(n *smtpNotifier) {
+		n.sources[eventType] = tmpl
+	}
+}
+
+// ChatOption configure a Slack or Discord notifier
+type ChatOption func(*chatNotifier)
+
+// WithChatHTTPClient use client to post messages
+func WithChatHTTPClient(client *http.Client) ChatOption {
+	return func(n *chatNotifier) {
+		n.client = client
+	}
+}
+
+// WithExplorerURL link the txns to url, where {hash} is replaced by the txn hash.
+// https://etherscan.io/tx/{hash} by default
+func WithExplorerURL(url string) ChatOption {
+	return func(n *chatNotifier) {
+		n.explorerURL = url
+	}
+}
+
+// WithRateLimitRetry post a rate limited message up to maxAttempts times, waiting as asked by the
+// webhook unless it is longer than maxWait
+func WithRateLimitRetry(maxAttempts int, maxWait time.Duration) ChatOption {
+	return func(n *chatNotifier) {
+		n.maxAttempts = maxAttempts
+		n.maxWait = maxWait
+	}
+}
+
+// RoutingOption configure a routing notifier
+type RoutingOption func(*routingNotifier)
+
+// WithDefaultRoutes route the events of subscriptions without routes, to every channel by default
+func WithDefaultRoutes(routes ...events.NotificationRoute) RoutingOption {
+	return func(n *routingNotifier) {
+		n.defaultRoutes = routes
+	}
+}
+
+// WithChannelTimeout give up the delivery to a channel after timeout, 30s by default
+func WithChannelTimeout(timeout time.Duration) RoutingOption {
+	return func(n *routingNotifier) {
+		n.timeout = timeout
+	}
+}
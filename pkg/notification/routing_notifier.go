@@ -0,0 +1,179 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maxPartialDeliveries events delivered to some of their channels only which are remembered,
+// the oldest are forgotten first
+const maxPartialDeliveries = 10000
+
+// RoutingNotifier a Notifier sending each event to the channels its subscription route it to
+type RoutingNotifier interface {
+	Notifier
+	// Shutdown shut down the channels, sending what they still batch
+	Shutdown()
+}
+
+type routingNotifier struct {
+	channels map[events.NotificationChannel]Notifier
+	// defaultRoutes of the subscriptions without routes, every channel by default
+	defaultRoutes []events.NotificationRoute
+	// timeout of the delivery to one channel, so a stuck channel does not hold the others back for long
+	timeout time.Duration
+	log     *zap.Logger
+
+	mu sync.Mutex
+	// delivered channels of the events which failed on others, a retry of the event skip them
+	delivered map[string][]events.NotificationChannel
+	// order the events were added to delivered in
+	order []string
+}
+
+// NewRoutingNotifier create a notifier fanning events out to channels. Each channel is delivered
+// concurrently with its own timeout and a failure of one does not stop the others: the error is
+// returned so the outbox retry the event, and the retry only go to the channels which failed
+func NewRoutingNotifier(channels map[events.NotificationChannel]Notifier, opts ...RoutingOption) (RoutingNotifier, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("at least one notification channel is required")
+	}
+	n := &routingNotifier{
+		channels:  channels,
+		timeout:   30 * time.Second,
+		log:       logger.GetLogger().With(zap.String("notifier", "routing")),
+		delivered: make(map[string][]events.NotificationChannel),
+	}
+	for channel := range channels {
+		n.defaultRoutes = append(n.defaultRoutes, events.NotificationRoute{Channel: channel})
+	}
+	slices.SortFunc(n.defaultRoutes, func(a, b events.NotificationRoute) int {
+		return slices.Index(events.NotificationChannels, a.Channel) - slices.Index(events.NotificationChannels, b.Channel)
+	})
+	for _, opt := range opts {
+		opt(n)
+	}
+	for _, route := range n.defaultRoutes {
+		if _, ok := channels[route.Channel]; !ok {
+			return nil, fmt.Errorf("default route to unconfigured channel %q", route.Channel)
+		}
+	}
+	return n, nil
+}
+
+// Notify deliver the event to the channels of its routes and wait for all of them
+func (n *routingNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	channels := n.route(event)
+	delivered := n.deliveredChannels(event.ID)
+
+	errs := make([]error, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		if slices.Contains(delivered, channel) {
+			continue
+		}
+		notifier, ok := n.channels[channel]
+		if !ok {
+			n.log.Warn("Notification channel not configured, skipping it",
+				zap.String("event_id", event.ID), zap.String("channel", string(channel)))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = n.deliver(ctx, channel, notifier, event)
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			delivered = append(delivered, channels[i])
+			continue
+		}
+		n.log.Warn("Notification channel failed", zap.String("event_id", event.ID),
+			zap.String("channel", string(channels[i])), zap.Error(err))
+		failed = append(failed, err)
+	}
+	n.setDelivered(event.ID, delivered, len(failed) == 0)
+	return errors.Join(failed...)
+}
+
+// route the channels event is sent to, in the order of the routes
+func (n *routingNotifier) route(event events.NotificationEvent) []events.NotificationChannel {
+	routes := event.Subscription.Notifications.Routes
+	if len(routes) == 0 {
+		routes = n.defaultRoutes
+	}
+	var channels []events.NotificationChannel
+	for _, route := range routes {
+		if route.Match(event.Type) && !slices.Contains(channels, route.Channel) {
+			channels = append(channels, route.Channel)
+		}
+	}
+	return channels
+}
+
+func (n *routingNotifier) deliver(ctx context.Context, channel events.NotificationChannel, notifier Notifier,
+	event events.NotificationEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s channel: panic: %v", channel, r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	if err := notifier.Notify(ctx, event); err != nil {
+		return fmt.Errorf("%s channel: %w", channel, err)
+	}
+	return nil
+}
+
+func (n *routingNotifier) deliveredChannels(eventID string) []events.NotificationChannel {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.delivered[eventID])
+}
+
+// setDelivered remember the channels which received the event until every channel did
+func (n *routingNotifier) setDelivered(eventID string, channels []events.NotificationChannel, complete bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if complete {
+		delete(n.delivered, eventID)
+		return
+	}
+	if _, ok := n.delivered[eventID]; !ok {
+		n.order = append(n.order, eventID)
+	}
+	n.delivered[eventID] = channels
+	for len(n.delivered) > maxPartialDeliveries {
+		delete(n.delivered, n.order[0])
+		n.order = n.order[1:]
+	}
+	// drop the events which completed since, so order does not grow past the map
+	if len(n.order) > 2*maxPartialDeliveries {
+		n.order = slices.DeleteFunc(n.order, func(id string) bool {
+			_, ok := n.delivered[id]
+			return !ok
+		})
+	}
+}
+
+// Shutdown shut down the channels which batch events or hold connections
+func (n *routingNotifier) Shutdown() {
+	for _, notifier := range n.channels {
+		if s, ok := notifier.(interface{ Shutdown() }); ok {
+			s.Shutdown()
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// fakeNotifier record the events it is given and answer with notify
+type fakeNotifier struct {
+	mu       sync.Mutex
+	events   []string
+	notify   func(ctx context.Context) error
+	shutdown bool
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	f.mu.Lock()
+	f.events = append(f.events, event.ID)
+	f.mu.Unlock()
+	if f.notify != nil {
+		return f.notify(ctx)
+	}
+	return nil
+}
+
+func (f *fakeNotifier) Shutdown() {
+	f.shutdown = true
+}
+
+func (f *fakeNotifier) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.events
+}
+
+func routedEvent(eventType events.NotificationEventType, routes ...events.NotificationRoute) events.NotificationEvent {
+	event := chatTestEvent()
+	event.Type = eventType
+	event.Subscription.Notifications.Routes = routes
+	return event
+}
+
+func TestNewRoutingNotifier(t *testing.T) {
+	_, err := NewRoutingNotifier(nil)
+	require.Error(t, err)
+
+	_, err = NewRoutingNotifier(map[events.NotificationChannel]Notifier{events.ChannelWebhook: &fakeNotifier{}},
+		WithDefaultRoutes(events.NotificationRoute{Channel: events.ChannelEmail}))
+	require.Error(t, err)
+}
+
+func Test_routingNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []RoutingOption
+		event events.NotificationEvent
+		want  map[events.NotificationChannel]int
+	}{
+		{
+			name:  "default routes to every channel",
+			event: routedEvent(events.EventIncoming),
+			want:  map[events.NotificationChannel]int{events.ChannelWebhook: 1, events.ChannelEmail: 1, events.ChannelKafka: 1},
+		},
+		{
+			name: "configured default routes",
+			opts: []RoutingOption{WithDefaultRoutes(
+				events.NotificationRoute{Channel: events.ChannelKafka},
+				events.NotificationRoute{Channel: events.ChannelEmail, EventTypes: []events.NotificationEventType{events.EventConfirmed}},
+			)},
+			event: routedEvent(events.EventIncoming),
+			want:  map[events.NotificationChannel]int{events.ChannelKafka: 1},
+		},
+		{
+			name: "subscription routes by event type",
+			event: routedEvent(events.EventReorged,
+				events.NotificationRoute{Channel: events.ChannelWebhook},
+				events.NotificationRoute{Channel: events.ChannelEmail, EventTypes: []events.NotificationEventType{events.EventReorged}},
+				events.NotificationRoute{Channel: events.ChannelKafka, EventTypes: []events.NotificationEventType{events.EventIncoming}},
+			),
+			want: map[events.NotificationChannel]int{events.ChannelWebhook: 1, events.ChannelEmail: 1},
+		},
+		{
+			name: "channel routed twice is sent once",
+			event: routedEvent(events.EventIncoming,
+				events.NotificationRoute{Channel: events.ChannelEmail},
+				events.NotificationRoute{Channel: events.ChannelEmail, EventTypes: []events.NotificationEventType{events.EventIncoming}},
+			),
+			want: map[events.NotificationChannel]int{events.ChannelEmail: 1},
+		},
+		{
+			name:  "unconfigured channel is skipped",
+			event: routedEvent(events.EventIncoming, events.NotificationRoute{Channel: events.ChannelSlack}),
+			want:  map[events.NotificationChannel]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := map[events.NotificationChannel]*fakeNotifier{
+				events.ChannelWebhook: {}, events.ChannelEmail: {}, events.ChannelKafka: {},
+			}
+			channels := map[events.NotificationChannel]Notifier{}
+			for name, f := range fakes {
+				channels[name] = f
+			}
+			n, err := NewRoutingNotifier(channels, tt.opts...)
+			require.NoError(t, err)
+
+			require.NoError(t, n.Notify(context.Background(), tt.event))
+			got := map[events.NotificationChannel]int{}
+			for name, f := range fakes {
+				if len(f.received()) > 0 {
+					got[name] = len(f.received())
+				}
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_routingNotifier_Notify_isolation(t *testing.T) {
+	errDown := errors.New("down")
+	failing := true
+	webhook := &fakeNotifier{notify: func(context.Context) error {
+		if failing {
+			return errDown
+		}
+		return nil
+	}}
+	email := &fakeNotifier{}
+	// a stuck channel is given up after the timeout
+	kafka := &fakeNotifier{notify: func(ctx context.Context) error {
+		if failing {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	n, err := NewRoutingNotifier(map[events.NotificationChannel]Notifier{
+		events.ChannelWebhook: webhook, events.ChannelEmail: email, events.ChannelKafka: kafka,
+	}, WithChannelTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	event := routedEvent(events.EventIncoming)
+	err = n.Notify(context.Background(), event)
+	require.ErrorIs(t, err, errDown)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, []string{"evt_1"}, email.received())
+
+	// the retry only go to the channels which failed
+	failing = false
+	require.NoError(t, n.Notify(context.Background(), event))
+	require.Equal(t, []string{"evt_1"}, email.received())
+	require.Equal(t, []string{"evt_1", "evt_1"}, webhook.received())
+	require.Equal(t, []string{"evt_1", "evt_1"}, kafka.received())
+
+	// once delivered everywhere the event is forgotten, a replay send it again
+	require.NoError(t, n.Notify(context.Background(), event))
+	require.Equal(t, []string{"evt_1", "evt_1"}, email.received())
+}
+
+func Test_routingNotifier_Notify_panic(t *testing.T) {
+	email := &fakeNotifier{}
+	n, err := NewRoutingNotifier(map[events.NotificationChannel]Notifier{
+		events.ChannelWebhook: &fakeNotifier{notify: func(context.Context) error { panic("boom") }},
+		events.ChannelEmail:   email,
+	})
+	require.NoError(t, err)
+
+	err = n.Notify(context.Background(), routedEvent(events.EventIncoming))
+	require.ErrorContains(t, err, "webhook channel: panic: boom")
+	require.Len(t, email.received(), 1)
+}
+
+func Test_routingNotifier_Notify_canceled(t *testing.T) {
+	n, err := NewRoutingNotifier(map[events.NotificationChannel]Notifier{
+		events.ChannelWebhook: &fakeNotifier{notify: func(ctx context.Context) error { return ctx.Err() }},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the dispatcher tell a shutdown from a failed delivery with the context error
+	require.ErrorIs(t, n.Notify(ctx, routedEvent(events.EventIncoming)), context.Canceled)
+}
+
+func Test_routingNotifier_setDelivered(t *testing.T) {
+	n := &routingNotifier{delivered: map[string][]events.NotificationChannel{}}
+	for i := range maxPartialDeliveries + 5 {
+		n.setDelivered(string(rune(i)), []events.NotificationChannel{events.ChannelEmail}, false)
+	}
+	require.Len(t, n.delivered, maxPartialDeliveries)
+	require.Empty(t, n.deliveredChannels(string(rune(0))))
+	require.Equal(t, []events.NotificationChannel{events.ChannelEmail}, n.deliveredChannels(string(rune(5))))
+}
+
+func Test_routingNotifier_Shutdown(t *testing.T) {
+	email := &fakeNotifier{}
+	n, err := NewRoutingNotifier(map[events.NotificationChannel]Notifier{
+		events.ChannelEmail:   email,
+		events.ChannelConsole: NewConsoleNotifier(),
+	})
+	require.NoError(t, err)
+	n.Shutdown()
+	require.True(t, email.shutdown)
+}
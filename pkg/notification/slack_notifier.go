@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// slackMessage a Block Kit message of an incoming webhook, Text is shown in notifications
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// NewSlackNotifier create a notifier posting events as Block Kit messages to a Slack incoming webhook
+func NewSlackNotifier(webhookURL string, opts ...ChatOption) Notifier {
+	return newChatNotifier("slack", webhookURL, renderSlack, opts)
+}
+
+func slackField(name, value string) slackText {
+	return slackText{Type: "mrkdwn", Text: "*" + name + "*\n" + value}
+}
+
+func renderSlack(e chatEvent) any {
+	if e.Digest != nil {
+		return renderSlackDigest(e)
+	}
+	field := slackField
+	fields := []slackText{
+		field("Subscription", slackEscape(e.Name)),
+		field("Amount", e.Amount),
+		field("Direction", e.Direction),
+		field("Block", e.Transaction.BlockNumber),
+		field("From", "`"+e.Transaction.From+"`"),
+		field("To", "`"+e.Transaction.To+"`"),
+	}
+	if e.Reorg != nil {
+		fields = append(fields, field("Replaced block", fmt.Sprintf("%d `%s`", e.Reorg.BlockNumber, e.Reorg.OldHash)))
+	}
+	if e.Type == string(events.EventConfirmed) {
+		fields = append(fields, field("Confirmations", fmt.Sprint(e.Confirmations)))
+	}
+	if len(e.Alerts) > 0 {
+		fields = append(fields, field("Alerts", slackEscape(strings.Join(e.Alerts, ", "))))
+	}
+
+	return slackMessage{
+		Text: fmt.Sprintf("%s on %s: %s", e.Title, slackEscape(e.Name), e.Amount),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: e.Title}},
+			{Type: "section", Fields: fields},
+			{Type: "context", Elements: []slackText{{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("<%s|%s> · event %s", e.Link, e.Transaction.Hash, e.ID),
+			}}},
+		},
+	}
+}
+
+func renderSlackDigest(e chatEvent) any {
+	fields := []slackText{
+		slackField("Subscription", slackEscape(e.Name)),
+		slackField("Notifications", fmt.Sprint(e.Digest.Count)),
+		slackField("Received", formatWei(e.Digest.TotalIn)),
+		slackField("Sent", formatWei(e.Digest.TotalOut)),
+		slackField("Period", digestPeriod(e.Digest)),
+	}
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: e.Title}},
+		{Type: "section", Fields: fields},
+	}
+	if len(e.Top) > 0 {
+		lines := []string{"*Top transactions*"}
+		for _, txn := range e.Top {
+			lines = append(lines, fmt.Sprintf("<%s|%s> %s", txn.Link, txn.Hash, txn.Amount))
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}})
+	}
+	blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "event " + e.ID}}})
+	return slackMessage{
+		Text:   fmt.Sprintf("%d notifications on %s", e.Digest.Count, slackEscape(e.Name)),
+		Blocks: blocks,
+	}
+}
+
+// slackEscape escape the control characters of mrkdwn so a label can not inject links or mentions
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
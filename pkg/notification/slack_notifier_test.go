@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+func TestNewSlackNotifier(t *testing.T) {
+	srv, received := chatServer(t, chatResponse{status: http.StatusOK, body: "ok"})
+	n := NewSlackNotifier(srv.URL)
+
+	event := chatTestEvent()
+	event.Subscription.Label = "<!channel> cold & hot"
+	require.NoError(t, n.Notify(context.Background(), event))
+
+	reqs := received()
+	require.Len(t, reqs, 1)
+	var got slackMessage
+	require.NoError(t, json.Unmarshal(reqs[0].body, &got))
+	require.Equal(t, slackMessage{
+		Text: "Incoming transaction on &lt;!channel&gt; cold &amp; hot: 1.5 ETH",
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: "Incoming transaction"}},
+			{Type: "section", Fields: []slackText{
+				{Type: "mrkdwn", Text: "*Subscription*\n&lt;!channel&gt; cold &amp; hot"},
+				{Type: "mrkdwn", Text: "*Amount*\n1.5 ETH"},
+				{Type: "mrkdwn", Text: "*Direction*\nincoming"},
+				{Type: "mrkdwn", Text: "*Block*\n16"},
+				{Type: "mrkdwn", Text: "*From*\n`0x1111111111111111111111111111111111111111`"},
+				{Type: "mrkdwn", Text: "*To*\n`0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed`"},
+			}},
+			{Type: "context", Elements: []slackText{
+				{Type: "mrkdwn", Text: "<https://etherscan.io/tx/0xabc|0xabc> · event evt_1"},
+			}},
+		},
+	}, got)
+}
+
+func Test_renderSlack_reorged(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	event := chatTestEvent()
+	event.Type = events.EventReorged
+	event.Reorg = &events.Reorg{BlockNumber: 16, OldHash: "0xa16", NewHash: "0xb16"}
+
+	got := renderSlack(n.chatEvent(NewEventPayload(event))).(slackMessage)
+	require.Equal(t, "Transaction reorged", got.Blocks[0].Text.Text)
+	require.Contains(t, got.Blocks[1].Fields, slackText{Type: "mrkdwn", Text: "*Replaced block*\n16 `0xa16`"})
+}
+
+func Test_renderSlack_alerts(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	event := chatTestEvent()
+	event.Alerts = []string{"whale", "new <payee>"}
+
+	got := renderSlack(n.chatEvent(NewEventPayload(event))).(slackMessage)
+	require.Contains(t, got.Blocks[1].Fields, slackText{Type: "mrkdwn", Text: "*Alerts*\nwhale, new &lt;payee&gt;"})
+}
+
+func Test_renderSlack_digest(t *testing.T) {
+	n, _ := newTestChatNotifier("")
+	got := renderSlack(n.chatEvent(NewEventPayload(chatTestDigest()))).(slackMessage)
+	require.Equal(t, "3 notifications on cold wallet", got.Text)
+	require.Equal(t, "Notification digest", got.Blocks[0].Text.Text)
+	require.Equal(t, []slackText{
+		{Type: "mrkdwn", Text: "*Subscription*\ncold wallet"},
+		{Type: "mrkdwn", Text: "*Notifications*\n3"},
+		{Type: "mrkdwn", Text: "*Received*\n2 ETH"},
+		{Type: "mrkdwn", Text: "*Sent*\n0.5 ETH"},
+		{Type: "mrkdwn", Text: "*Period*\n2024-01-02 03:00 UTC - 2024-01-02 03:14 UTC"},
+	}, got.Blocks[1].Fields)
+	require.Equal(t, "*Top transactions*\n<https://etherscan.io/tx/0xa|0xa> 1.5 ETH\n<https://etherscan.io/tx/0xb|0xb> 0.5 ETH",
+		got.Blocks[2].Text.Text)
+	require.Equal(t, "event evt_1", got.Blocks[3].Elements[0].Text)
+}
@@ -0,0 +1,317 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SMTPSecurity how the connection to the SMTP server is secured
+type SMTPSecurity string
+
+const (
+	// SMTPStartTLS upgrade the connection with STARTTLS, the server must support it
+	SMTPStartTLS SMTPSecurity = "starttls"
+	// SMTPImplicitTLS connect over TLS, usually on port 465
+	SMTPImplicitTLS SMTPSecurity = "tls"
+	// SMTPNone no encryption, only meant for a local relay
+	SMTPNone SMTPSecurity = "none"
+)
+
+// ErrEmailDelivery returned when an email could not be handed to the SMTP server
+var ErrEmailDelivery = errors.New("email delivery failed")
+
+// SMTPConfig of an SMTP notifier, Host and From are required
+type SMTPConfig struct {
+	Host string
+	// Port 587 by default, 465 with SMTPImplicitTLS
+	Port int
+	// Username and Password used for PLAIN auth, no auth when Username is empty
+	Username string
+	Password string
+	From     string
+	// Security SMTPStartTLS by default
+	Security SMTPSecurity
+	// TLSConfig used by STARTTLS and implicit TLS, ServerName default to Host
+	TLSConfig *tls.Config
+	// DefaultRecipients receive the events of subscriptions without emails
+	DefaultRecipients []string
+	// Timeout of one SMTP session, 30s by default
+	Timeout time.Duration
+}
+
+type smtpNotifier struct {
+	config SMTPConfig
+	// sources set by the options, parsed once the notifier is configured
+	sources map[events.NotificationEventType]EmailTemplate
+	// templates by event type, the empty type is used for unknown types
+	templates map[events.NotificationEventType]*emailTemplate
+	log       *zap.Logger
+}
+
+// NewSMTPNotifier create a notifier emailing events to the recipients of their subscription through
+// the SMTP server of config. Bursts are batched by the digest and throttle of the subscriptions
+func NewSMTPNotifier(config SMTPConfig, opts ...SMTPOption) (Notifier, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, errors.New("smtp host and from address are required")
+	}
+	if config.Security == "" {
+		config.Security = SMTPStartTLS
+	}
+	switch config.Security {
+	case SMTPStartTLS, SMTPImplicitTLS, SMTPNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp security %q", config.Security)
+	}
+	if config.Port == 0 {
+		config.Port = 587
+		if config.Security == SMTPImplicitTLS {
+			config.Port = 465
+		}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	n := &smtpNotifier{
+		config:    config,
+		sources:   make(map[events.NotificationEventType]EmailTemplate),
+		templates: make(map[events.NotificationEventType]*emailTemplate),
+		log:       logger.GetLogger().With(zap.String("notifier", "smtp")),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	for _, eventType := range append(slices.Clone(events.NotificationEventTypes), "") {
+		t, err := parseEmailTemplate(string(eventType), n.sources[eventType], defaultEventTemplate(eventType))
+		if err != nil {
+			return nil, err
+		}
+		n.templates[eventType] = t
+	}
+	return n, nil
+}
+
+// Notify email the event to the recipients of its subscription, nothing is sent when there are none
+func (n *smtpNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	recipients := n.recipients(event)
+	if len(recipients) == 0 {
+		return nil
+	}
+	return n.sendEvent(ctx, recipients, NewEventPayload(event))
+}
+
+// recipients of event, sorted and without duplicates
+func (n *smtpNotifier) recipients(event events.NotificationEvent) []string {
+	emails := event.Subscription.Notifications.Emails
+	if len(emails) == 0 {
+		emails = n.config.DefaultRecipients
+	}
+	recipients := make([]string, 0, len(emails))
+	for _, email := range emails {
+		if email = strings.TrimSpace(email); email != "" {
+			recipients = append(recipients, email)
+		}
+	}
+	slices.Sort(recipients)
+	return slices.Compact(recipients)
+}
+
+func (n *smtpNotifier) sendEvent(ctx context.Context, recipients []string, payload EventPayload) error {
+	tmpl, ok := n.templates[events.NotificationEventType(payload.Type)]
+	if !ok {
+		tmpl = n.templates[""]
+	}
+	subject, text, html, err := tmpl.render(payload)
+	if err != nil {
+		return fmt.Errorf("render %s email: %w", payload.Type, err)
+	}
+	return n.send(ctx, recipients, subject, text, html, payload.ID)
+}
+
+func (n *smtpNotifier) send(ctx context.Context, recipients []string, subject, text, html, eventID string) error {
+	msg, err := n.message(recipients, subject, text, html, eventID)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	if err := n.sendMail(ctx, recipients, msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrEmailDelivery, err)
+	}
+	return nil
+}
+
+// sendMail run one SMTP session delivering msg to recipients
+func (n *smtpNotifier) sendMail(ctx context.Context, recipients []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	dialer := &net.Dialer{}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if n.config.Security == SMTPImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: n.tlsConfig()}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	// smtp.Client has no context support, closing the connection abort the session
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.config.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(n.tlsConfig()); err != nil {
+			return err
+		}
+	}
+	if n.config.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.config.From); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (n *smtpNotifier) tlsConfig() *tls.Config {
+	config := &tls.Config{}
+	if n.config.TLSConfig != nil {
+		config = n.config.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = n.config.Host
+	}
+	return config
+}
+
+// message build a MIME email, multipart/alternative when there is an html body
+func (n *smtpNotifier) message(recipients []string, subject, text, html, eventID string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", n.config.From)
+	header.Set("To", strings.Join(recipients, ", "))
+	// the subject may come from a label, it must stay a single header line
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject), " ")))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-Id", "<"+messageID(eventID)+"@tx-parser>")
+	header.Set("Mime-Version", "1.0")
+	if eventID != "" {
+		header.Set(EventIDHeader, eventID)
+	}
+
+	if html == "" {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s: %s\r\n", key, header.Get(key))
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID the event id for the email of an event, random for a digest
+func messageID(eventID string) string {
+	if eventID != "" {
+		return eventID
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "digest_" + hex.EncodeToString(b)
+}
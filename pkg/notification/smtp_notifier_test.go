@@ -0,0 +1,489 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// receivedEmail a message accepted by the smtp stand-in
+type receivedEmail struct {
+	from string
+	to   []string
+	// tls whether the session was encrypted when the message was sent
+	tls  bool
+	auth string
+	msg  *mail.Message
+	body []byte
+}
+
+// smtpServer a minimal SMTP server accepting every message, enough for net/smtp
+type smtpServer struct {
+	t        *testing.T
+	addr     string
+	tls      *tls.Config
+	implicit bool
+	// password required for PLAIN auth when set
+	password string
+	received chan receivedEmail
+}
+
+func newSMTPServer(t *testing.T, tlsConfig *tls.Config, implicit bool, password string) *smtpServer {
+	var (
+		lis net.Listener
+		err error
+	)
+	if implicit {
+		lis, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		lis, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	s := &smtpServer{t: t, addr: lis.Addr().String(), tls: tlsConfig, implicit: implicit, password: password,
+		received: make(chan receivedEmail, 100)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	encrypted := s.implicit
+	var email receivedEmail
+	tp.PrintfLine("220 localhost ESMTP stand-in")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			ext := []string{"250-localhost", "250-8BITMIME"}
+			if s.tls != nil && !encrypted {
+				ext = append(ext, "250-STARTTLS")
+			}
+			ext = append(ext, "250 AUTH PLAIN")
+			tp.PrintfLine("%s", strings.Join(ext, "\r\n"))
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, encrypted = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			raw, _ := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(raw), "\x00")
+			if len(parts) != 3 || parts[2] != s.password {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			email.auth = parts[1]
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			email.from = strings.Trim(from, "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			email.to = append(email.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			if s.password != "" && email.auth == "" {
+				tp.PrintfLine("530 authentication required")
+				continue
+			}
+			tp.PrintfLine("354 go ahead")
+			// line endings are turned to \n
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				tp.PrintfLine("554 invalid message")
+				continue
+			}
+			email.body, _ = io.ReadAll(msg.Body)
+			email.msg, email.tls = msg, encrypted
+			s.received <- email
+			email = receivedEmail{auth: email.auth}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// next wait for the next message accepted by the server
+func (s *smtpServer) next() receivedEmail {
+	s.t.Helper()
+	select {
+	case email := <-s.received:
+		return email
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no email received")
+		return receivedEmail{}
+	}
+}
+
+func (s *smtpServer) requireNothing() {
+	s.t.Helper()
+	select {
+	case email := <-s.received:
+		s.t.Fatalf("unexpected email %q", email.msg.Header.Get("Subject"))
+	default:
+	}
+}
+
+func (s *smtpServer) config(security SMTPSecurity) SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.addr)
+	config := SMTPConfig{Host: host, From: "alerts@tx-parser.test", Security: security}
+	config.Port, _ = net.LookupPort("tcp", port)
+	return config
+}
+
+// selfSignedTLS a server config for 127.0.0.1 and a client config trusting it
+func selfSignedTLS(t *testing.T) (server, client *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: pool}
+}
+
+func emailEvent(id string, eventType events.NotificationEventType, emails ...string) events.NotificationEvent {
+	return events.NotificationEvent{
+		ID:      id,
+		Version: events.NotificationEventVersion,
+		Type:    eventType,
+		Chain:   events.ChainEthereum,
+		Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		Subscription: events.Subscription{
+			Address:       "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			Tenant:        events.DefaultTenant,
+			Label:         "cold wallet",
+			Notifications: events.NotificationPreferences{Emails: emails},
+		},
+		Transaction:   events.Transaction{Hash: "0x" + id, From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", Value: "1000", BlockNumber: "16"},
+		Confirmations: 1,
+	}
+}
+
+// parts the text and html bodies of email
+func parts(t *testing.T, email receivedEmail) (text, html string) {
+	mediaType, params, err := mime.ParseMediaType(email.msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	if mediaType == "text/plain" {
+		return decodeQP(t, email.body), ""
+	}
+	require.Equal(t, "multipart/alternative", mediaType)
+	r := multipart.NewReader(strings.NewReader(string(email.body)), params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return text, html
+		}
+		require.NoError(t, err)
+		// the reader decode quoted-printable parts itself
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			text = string(body)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			html = string(body)
+		}
+	}
+}
+
+func decodeQP(t *testing.T, body []byte) string {
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(body))))
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func subject(t *testing.T, email receivedEmail) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(email.msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestNewSMTPNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SMTPConfig
+		opts    []SMTPOption
+		wantErr bool
+	}{
+		{name: "valid", config: SMTPConfig{Host: "localhost", From: "a@b.c"}},
+		{name: "missing host", config: SMTPConfig{From: "a@b.c"}, wantErr: true},
+		{name: "missing from", config: SMTPConfig{Host: "localhost"}, wantErr: true},
+		{name: "unknown security", config: SMTPConfig{Host: "localhost", From: "a@b.c", Security: "ssl"}, wantErr: true},
+		{
+			name:    "invalid template",
+			config:  SMTPConfig{Host: "localhost", From: "a@b.c"},
+			opts:    []SMTPOption{WithEmailTemplate(events.EventIncoming, EmailTemplate{Subject: "{{.Type"})},
+			wantErr: true,
+		},
+		{
+			name:    "invalid digest template",
+			config:  SMTPConfig{Host: "localhost", From: "a@b.c"},
+			opts:    []SMTPOption{WithEmailTemplate(events.EventDigest, EmailTemplate{HTML: "{{range}}"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSMTPNotifier(tt.config, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			n := got.(*smtpNotifier)
+			require.Equal(t, 587, n.config.Port)
+			require.Equal(t, SMTPStartTLS, n.config.Security)
+		})
+	}
+}
+
+func Test_smtpNotifier_Notify(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+
+	tests := []struct {
+		name     string
+		security SMTPSecurity
+		implicit bool
+		password string
+		wantTLS  bool
+	}{
+		{name: "starttls with auth", security: SMTPStartTLS, password: "s3cret", wantTLS: true},
+		{name: "implicit tls", security: SMTPImplicitTLS, implicit: true, wantTLS: true},
+		{name: "plain local relay", security: SMTPNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, serverTLS, tt.implicit, tt.password)
+			config := srv.config(tt.security)
+			config.TLSConfig = clientTLS
+			if tt.password != "" {
+				config.Username, config.Password = "ops", tt.password
+			}
+			n, err := NewSMTPNotifier(config)
+			require.NoError(t, err)
+
+			event := emailEvent("1", events.EventIncoming, "ops@example.com", " oncall@example.com", "ops@example.com")
+			require.NoError(t, n.Notify(context.Background(), event))
+
+			email := srv.next()
+			require.Equal(t, tt.wantTLS, email.tls)
+			require.Equal(t, "alerts@tx-parser.test", email.from)
+			require.Equal(t, []string{"oncall@example.com", "ops@example.com"}, email.to)
+			if tt.password != "" {
+				require.Equal(t, "ops", email.auth)
+			}
+			require.Equal(t, "[tx-parser] Incoming transaction to cold wallet", subject(t, email))
+			require.Equal(t, "1", email.msg.Header.Get(EventIDHeader))
+			require.Equal(t, "<1@tx-parser>", email.msg.Header.Get("Message-Id"))
+
+			text, html := parts(t, email)
+			require.Contains(t, text, "Transaction:   0x1\n")
+			require.Contains(t, text, "From:          0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+			require.Contains(t, html, "<code>0x1</code>")
+		})
+	}
+}
+
+func Test_smtpNotifier_Notify_errors(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+
+	t.Run("no recipients", func(t *testing.T) {
+		srv := newSMTPServer(t, nil, false, "")
+		n, err := NewSMTPNotifier(srv.config(SMTPNone))
+		require.NoError(t, err)
+		require.NoError(t, n.Notify(context.Background(), emailEvent("1", events.EventIncoming)))
+		srv.requireNothing()
+	})
+
+	t.Run("default recipients", func(t *testing.T) {
+		srv := newSMTPServer(t, nil, false, "")
+		config := srv.config(SMTPNone)
+		config.DefaultRecipients = []string{"ops@example.com"}
+		n, err := NewSMTPNotifier(config)
+		require.NoError(t, err)
+		require.NoError(t, n.Notify(context.Background(), emailEvent("1", events.EventIncoming)))
+		require.Equal(t, []string{"ops@example.com"}, srv.next().to)
+	})
+
+	t.Run("starttls not supported", func(t *testing.T) {
+		srv := newSMTPServer(t, nil, false, "")
+		n, err := NewSMTPNotifier(srv.config(SMTPStartTLS))
+		require.NoError(t, err)
+		err = n.Notify(context.Background(), emailEvent("1", events.EventIncoming, "ops@example.com"))
+		require.ErrorIs(t, err, ErrEmailDelivery)
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		srv := newSMTPServer(t, serverTLS, false, "")
+		n, err := NewSMTPNotifier(srv.config(SMTPStartTLS))
+		require.NoError(t, err)
+		err = n.Notify(context.Background(), emailEvent("1", events.EventIncoming, "ops@example.com"))
+		require.ErrorIs(t, err, ErrEmailDelivery)
+		srv.requireNothing()
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		srv := newSMTPServer(t, serverTLS, false, "s3cret")
+		config := srv.config(SMTPStartTLS)
+		config.TLSConfig = clientTLS
+		config.Username, config.Password = "ops", "wrong"
+		n, err := NewSMTPNotifier(config)
+		require.NoError(t, err)
+		err = n.Notify(context.Background(), emailEvent("1", events.EventIncoming, "ops@example.com"))
+		require.ErrorIs(t, err, ErrEmailDelivery)
+	})
+
+	t.Run("server down", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		host, port, _ := net.SplitHostPort(lis.Addr().String())
+		lis.Close()
+		config := SMTPConfig{Host: host, From: "alerts@tx-parser.test", Security: SMTPNone}
+		config.Port, _ = net.LookupPort("tcp", port)
+		n, err := NewSMTPNotifier(config)
+		require.NoError(t, err)
+		err = n.Notify(context.Background(), emailEvent("1", events.EventIncoming, "ops@example.com"))
+		require.ErrorIs(t, err, ErrEmailDelivery)
+	})
+}
+
+func Test_smtpNotifier_Notify_templates(t *testing.T) {
+	srv := newSMTPServer(t, nil, false, "")
+	n, err := NewSMTPNotifier(srv.config(SMTPNone),
+		WithEmailTemplate(events.EventReorged, EmailTemplate{
+			Subject: "REORG {{.Transaction.Hash}} at {{.Reorg.BlockNumber}}",
+			Text:    "{{.Reorg.OldHash}} -> {{.Reorg.NewHash}}\n",
+			HTML:    "-",
+		}),
+		WithEmailTemplate(events.EventConfirmed, EmailTemplate{Subject: "confirmed {{.Confirmations}}"}))
+	require.NoError(t, err)
+
+	event := emailEvent("1", events.EventReorged, "ops@example.com")
+	event.Reorg = &events.Reorg{BlockNumber: 16, OldHash: "0xa", NewHash: "0xb"}
+	require.NoError(t, n.Notify(context.Background(), event))
+	email := srv.next()
+	require.Equal(t, "REORG 0x1 at 16", subject(t, email))
+	text, html := parts(t, email)
+	require.Equal(t, "0xa -> 0xb\n", text)
+	require.Empty(t, html)
+
+	// the fields left empty keep the default
+	event = emailEvent("2", events.EventConfirmed, "ops@example.com")
+	event.Confirmations = 12
+	event.Alerts = []string{"whale", "failed"}
+	require.NoError(t, n.Notify(context.Background(), event))
+	email = srv.next()
+	require.Equal(t, "confirmed 12", subject(t, email))
+	text, html = parts(t, email)
+	require.Contains(t, text, "Confirmations: 12")
+	require.Contains(t, text, "Alerts:        whale, failed")
+	require.Contains(t, html, "<tr><td>Alerts</td><td>whale, failed</td></tr>")
+
+	// a label can not inject headers
+	event = emailEvent("3", events.EventIncoming, "ops@example.com")
+	event.Subscription.Label = "cold\r\nBcc: attacker@example.com"
+	require.NoError(t, n.Notify(context.Background(), event))
+	email = srv.next()
+	require.Empty(t, email.msg.Header.Get("Bcc"))
+	require.Equal(t, "[tx-parser] Incoming transaction to cold Bcc: attacker@example.com", subject(t, email))
+}
+
+func Test_smtpNotifier_Notify_digestEvent(t *testing.T) {
+	srv := newSMTPServer(t, nil, false, "")
+	n, err := NewSMTPNotifier(srv.config(SMTPNone))
+	require.NoError(t, err)
+
+	event := emailEvent("1", events.EventDigest, "ops@example.com")
+	event.Transaction = events.Transaction{}
+	event.Digest = &events.Digest{
+		Mode:     events.DigestDaily,
+		From:     time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 1, 2, 20, 0, 0, 0, time.UTC),
+		Count:    2,
+		Counts:   map[events.NotificationEventType]int{events.EventIncoming: 2},
+		TotalIn:  "3000",
+		TotalOut: "0",
+		Top:      []events.Transaction{{Hash: "0xb", Value: "2000"}, {Hash: "0xa", Value: "1000"}},
+	}
+	require.NoError(t, n.Notify(context.Background(), event))
+	email := srv.next()
+	require.Equal(t, "[tx-parser] 2 notifications on cold wallet", subject(t, email))
+	text, html := parts(t, email)
+	require.Contains(t, text, "incoming:      2\n")
+	require.Contains(t, text, "Received:      3000 wei\n")
+	require.Contains(t, text, "- 0xb: 2000 wei")
+	require.Contains(t, html, "<td><code>0xa</code></td>")
+}
+
+func Test_smtpNotifier_message(t *testing.T) {
+	n := &smtpNotifier{config: SMTPConfig{From: "alerts@tx-parser.test"}}
+	msg, err := n.message([]string{"a@example.com", "b@example.com"}, "Héllo", "line=1\n", "", "evt_1")
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(msg))))
+	require.NoError(t, err)
+	require.Equal(t, "a@example.com, b@example.com", parsed.Header.Get("To"))
+	require.Equal(t, "=?utf-8?q?H=C3=A9llo?=", parsed.Header.Get("Subject"))
+	require.Equal(t, "1.0", parsed.Header.Get("Mime-Version"))
+	body, err := io.ReadAll(parsed.Body)
+	require.NoError(t, err)
+	require.Equal(t, "line=3D1\r\n", string(body))
+}
@@ -0,0 +1,140 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+// spillQueue a FIFO of events in a JSON lines file, the file is truncated once every event was read.
+// It is not safe for concurrent use
+type spillQueue struct {
+	w *os.File
+	r *os.File
+	// reader over r, positioned at the oldest event not read yet
+	reader  *bufio.Reader
+	pending int
+}
+
+// openSpillQueue open the queue stored at path, the events left by a previous run are read first
+func openSpillQueue(path string) (*spillQueue, error) {
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open spill file: %w", err)
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("open spill file: %w", err)
+	}
+	q := &spillQueue{w: w, r: r, reader: bufio.NewReader(r)}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		q.close()
+		return nil, fmt.Errorf("read spill file: %w", err)
+	}
+	// end a line cut by a crash so the next event is not appended to it, it is skipped when read
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := w.Write([]byte("\n")); err != nil {
+			q.close()
+			return nil, fmt.Errorf("write spill file: %w", err)
+		}
+		data = append(data, '\n')
+	}
+	q.pending = bytes.Count(data, []byte("\n"))
+	if err := q.rewind(); err != nil {
+		q.close()
+		return nil, err
+	}
+	if q.pending == 0 {
+		return q, q.reset()
+	}
+	return q, nil
+}
+
+func (q *spillQueue) len() int {
+	return q.pending
+}
+
+func (q *spillQueue) push(event events.NotificationEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode spilled event: %w", err)
+	}
+	if _, err := q.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+	q.pending++
+	return nil
+}
+
+// pop read the oldest event, the queue must not be empty. Lines which can not be decoded are skipped
+func (q *spillQueue) pop() (events.NotificationEvent, error) {
+	for {
+		line, err := q.reader.ReadBytes('\n')
+		if err != nil {
+			// the file does not hold what was counted, start over
+			q.pending = 0
+			return events.NotificationEvent{}, errors.Join(fmt.Errorf("read spill file: %w", err), q.reset())
+		}
+		q.pending--
+		var event events.NotificationEvent
+		decodeErr := json.Unmarshal(line, &event)
+		if q.pending == 0 {
+			if err := q.reset(); err != nil {
+				return event, err
+			}
+		}
+		if decodeErr == nil {
+			return event, nil
+		}
+		if q.pending == 0 {
+			return event, fmt.Errorf("decode spilled event: %w", decodeErr)
+		}
+	}
+}
+
+// prepend put batch before the events in the queue
+func (q *spillQueue) prepend(batch []events.NotificationEvent) error {
+	var rest []events.NotificationEvent
+	for q.pending > 0 {
+		event, err := q.pop()
+		if err != nil {
+			continue
+		}
+		rest = append(rest, event)
+	}
+	for _, event := range append(batch, rest...) {
+		if err := q.push(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset empty the file once every event was read
+func (q *spillQueue) reset() error {
+	if err := q.w.Truncate(0); err != nil {
+		return fmt.Errorf("truncate spill file: %w", err)
+	}
+	return q.rewind()
+}
+
+func (q *spillQueue) rewind() error {
+	if _, err := q.r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek spill file: %w", err)
+	}
+	q.reader.Reset(q.r)
+	return nil
+}
+
+func (q *spillQueue) close() error {
+	q.r.Close()
+	return q.w.Close()
+}
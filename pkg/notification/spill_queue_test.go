@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+func popAll(t *testing.T, q *spillQueue) []string {
+	var ids []string
+	for q.len() > 0 {
+		event, err := q.pop()
+		require.NoError(t, err)
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func Test_spillQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := openSpillQueue(path)
+	require.NoError(t, err)
+
+	for i := range 3 {
+		require.NoError(t, q.push(asyncEvent("0xaa", i)))
+	}
+	event, err := q.pop()
+	require.NoError(t, err)
+	require.Equal(t, asyncEvent("0xaa", 0), event)
+
+	require.NoError(t, q.prepend([]events.NotificationEvent{asyncEvent("0xaa", 9)}))
+	require.Equal(t, []string{"evt_9", "evt_1", "evt_2"}, popAll(t, q))
+
+	// the file is emptied once read
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+
+	require.NoError(t, q.push(asyncEvent("0xaa", 4)))
+	require.Equal(t, []string{"evt_4"}, popAll(t, q))
+	require.NoError(t, q.close())
+}
+
+func Test_spillQueue_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := openSpillQueue(path)
+	require.NoError(t, err)
+	require.NoError(t, q.push(asyncEvent("0xaa", 0)))
+	require.NoError(t, q.push(asyncEvent("0xaa", 1)))
+	require.NoError(t, q.close())
+
+	// a crash cut the last line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"ID":"evt_`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	q, err = openSpillQueue(path)
+	require.NoError(t, err)
+	defer q.close()
+	require.NoError(t, q.push(asyncEvent("0xaa", 2)))
+	require.Equal(t, 4, q.len())
+	require.Equal(t, []string{"evt_0", "evt_1", "evt_2"}, popAll(t, q))
+}
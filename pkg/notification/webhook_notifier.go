@@ -0,0 +1,277 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vdhieu/tx-parser/pkg/events"
+	"github.com/vdhieu/tx-parser/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// SignatureHeader hold "sha256=" followed by the hex HMAC of "<timestamp>.<body>"
+	SignatureHeader = "X-TxParser-Signature"
+	// TimestampHeader unix seconds the event was signed at
+	TimestampHeader = "X-TxParser-Timestamp"
+	// EventIDHeader stay the same between retries so receivers can drop duplicates
+	EventIDHeader = "X-TxParser-Event-Id"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	// ErrForbiddenAddress returned when a webhook url resolve to a loopback, private or link-local address
+	ErrForbiddenAddress = errors.New("webhook address is not public")
+	// ErrWebhookDelivery returned when every attempt of a delivery failed
+	ErrWebhookDelivery = errors.New("webhook delivery failed")
+	// ErrInvalidSignature returned by VerifySignature
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// DeliveryAttempt one POST of an event to a webhook
+type DeliveryAttempt struct {
+	EventID string
+	URL     string
+	Attempt int
+	// StatusCode zero when no response was received
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	At         time.Time
+}
+
+// WebhookNotifier a Notifier POSTing events to the webhook url of the subscriptions
+type WebhookNotifier interface {
+	Notifier
+	// Attempts return the recorded attempts of an event, or all of them when eventID is empty, oldest first
+	Attempts(eventID string) []DeliveryAttempt
+}
+
+type webhookNotifier struct {
+	secret         []byte
+	client         *http.Client
+	defaultURL     string
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	historySize    int
+	allowPrivate   bool
+	log            *zap.Logger
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+
+	mu       sync.Mutex
+	attempts []DeliveryAttempt
+}
+
+// NewWebhookNotifier create a notifier signing every body with secret
+func NewWebhookNotifier(secret string, opts ...WebhookOption) WebhookNotifier {
+	n := &webhookNotifier{
+		secret:         []byte(secret),
+		maxAttempts:    5,
+		initialBackoff: time.Second,
+		maxBackoff:     30 * time.Second,
+		historySize:    1000,
+		log:            logger.GetLogger().With(zap.String("notifier", "webhook")),
+		now:            time.Now,
+		sleep:          sleepContext,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	if n.client == nil {
+		n.client = newWebhookClient(n.allowPrivate)
+	}
+	if n.maxAttempts < 1 {
+		n.maxAttempts = 1
+	}
+	return n
+}
+
+// Notify POST the event as an EventPayload to the webhook url of the subscription and retry until
+// it is accepted, nothing is sent when there is no url to deliver to
+func (n *webhookNotifier) Notify(ctx context.Context, event events.NotificationEvent) error {
+	url := event.Subscription.Notifications.WebhookURL
+	if url == "" {
+		url = n.defaultURL
+	}
+	if url == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(NewEventPayload(event))
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+
+	return n.deliver(ctx, event.ID, url, body)
+}
+
+func (n *webhookNotifier) deliver(ctx context.Context, id, url string, body []byte) error {
+	log := n.log.With(zap.String("event_id", id), zap.String("url", url))
+	backoff := n.initialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := n.sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff *= 2
+			if backoff > n.maxBackoff {
+				backoff = n.maxBackoff
+			}
+		}
+
+		record := n.post(ctx, id, url, body)
+		record.Attempt = attempt
+		n.record(record)
+		if record.Error == "" {
+			log.Debug("webhook delivered", zap.Int("attempt", attempt))
+			return nil
+		}
+		lastErr = errors.New(record.Error)
+		log.Warn("webhook attempt failed", zap.Int("attempt", attempt), zap.String("error", record.Error))
+	}
+	return fmt.Errorf("%w after %d attempts: %v", ErrWebhookDelivery, n.maxAttempts, lastErr)
+}
+
+// post send a signed body once, a non 2xx response is a failed attempt
+func (n *webhookNotifier) post(ctx context.Context, id, url string, body []byte) DeliveryAttempt {
+	start := n.now()
+	attempt := DeliveryAttempt{EventID: id, URL: url, At: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tx-parser-webhook/"+events.NotificationEventVersion)
+	req.Header.Set(EventIDHeader, id)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(n.secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	attempt.Duration = n.now().Sub(start)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	// drain so the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+// newWebhookClient a client refusing to connect to internal addresses unless allowPrivate, so a tenant
+// can not make the server call its own network. The resolved address is checked when dialing, a name
+// resolving to a public address when subscribing can not be switched to an internal one later
+func newWebhookClient(allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		// a proxy would be dialed instead of the webhook, its address is not the one to check
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   publicAddressOnly,
+		}).DialContext
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// publicAddressOnly dialer control rejecting loopback, private, link-local and unspecified addresses
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() ||
+		cgnat.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// cgnat shared address space of carrier grade NATs, not reachable from the internet either
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+func (n *webhookNotifier) record(attempt DeliveryAttempt) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.attempts = append(n.attempts, attempt)
+	if over := len(n.attempts) - n.historySize; over > 0 {
+		n.attempts = append(n.attempts[:0], n.attempts[over:]...)
+	}
+}
+
+func (n *webhookNotifier) Attempts(eventID string) []DeliveryAttempt {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var attempts []DeliveryAttempt
+	for _, attempt := range n.attempts {
+		if eventID == "" || attempt.EventID == eventID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts
+}
+
+// Sign return the signature header value of body signed at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature check a received webhook, the timestamp must not be older than tolerance
+// so a captured request can not be replayed later. A zero tolerance skip the timestamp check
+func VerifySignature(secret []byte, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vdhieu/tx-parser/pkg/events"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer answer with statuses in order, the last one is repeated
+func webhookServer(t *testing.T, statuses ...int) (*httptest.Server, func() []receivedWebhook) {
+	var (
+		mu       sync.Mutex
+		received []receivedWebhook
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		status := statuses[min(len(received), len(statuses))-1]
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []receivedWebhook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedWebhook(nil), received...)
+	}
+}
+
+func newTestWebhookNotifier(opts ...WebhookOption) (*webhookNotifier, *[]time.Duration) {
+	var sleeps []time.Duration
+	// the test servers listen on loopback
+	n := NewWebhookNotifier("s3cret", append([]WebhookOption{WithPrivateNetworks()}, opts...)...).(*webhookNotifier)
+	n.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return n, &sleeps
+}
+
+func Test_webhookNotifier_Notify(t *testing.T) {
+	const (
+		addr    = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		addrSum = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	)
+	event := func(url string) events.NotificationEvent {
+		return events.NotificationEvent{
+			ID:      "evt_1",
+			Version: events.NotificationEventVersion,
+			Type:    events.EventIncoming,
+			Chain:   events.ChainEthereum,
+			Address: addr,
+			Subscription: events.Subscription{
+				Address:       addr,
+				Tenant:        events.DefaultTenant,
+				Label:         "deposit",
+				Notifications: events.NotificationPreferences{WebhookURL: url},
+			},
+			Transaction:   events.Transaction{Hash: "0xabc", From: addr, To: addr, Value: "1", BlockNumber: "16"},
+			Confirmations: 1,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      error
+		wantAttempts []int
+		wantSleeps   []time.Duration
+	}{
+		{
+			name:         "delivered",
+			statuses:     []int{http.StatusNoContent},
+			wantAttempts: []int{http.StatusNoContent},
+		},
+		{
+			name:         "retry until accepted",
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			wantAttempts: []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "attempts exhausted",
+			statuses:     []int{http.StatusBadGateway},
+			wantErr:      ErrWebhookDelivery,
+			wantAttempts: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, received := webhookServer(t, tt.statuses...)
+			n, sleeps := newTestWebhookNotifier(WithRetry(4, time.Second, 3*time.Second))
+
+			err := n.Notify(context.Background(), event(srv.URL))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantSleeps, *sleeps)
+
+			reqs := received()
+			require.Len(t, reqs, len(tt.wantAttempts))
+			for _, req := range reqs {
+				// the same event is sent again, signed every time
+				require.Equal(t, "evt_1", req.header.Get(EventIDHeader))
+				require.Equal(t, "application/json", req.header.Get("Content-Type"))
+				require.NoError(t, VerifySignature([]byte("s3cret"), req.header.Get(TimestampHeader),
+					req.header.Get(SignatureHeader), req.body, time.Minute))
+			}
+
+			var payload EventPayload
+			require.NoError(t, json.Unmarshal(reqs[0].body, &payload))
+			require.Equal(t, EventPayload{
+				Version: "1",
+				ID:      "evt_1",
+				Type:    "incoming",
+				Chain:   "ethereum",
+				Address: addrSum,
+				Subscription: EventSubscription{
+					Address: addrSum,
+					Tenant:  events.DefaultTenant,
+					Label:   "deposit",
+				},
+				Transaction:   EventTransaction{Hash: "0xabc", From: addrSum, To: addrSum, Value: "1", BlockNumber: "16"},
+				Confirmations: 1,
+			}, payload)
+
+			attempts := n.Attempts("evt_1")
+			require.Len(t, attempts, len(tt.wantAttempts))
+			for i, attempt := range attempts {
+				require.Equal(t, i+1, attempt.Attempt)
+				require.Equal(t, srv.URL, attempt.URL)
+				require.Equal(t, tt.wantAttempts[i], attempt.StatusCode)
+				require.Equal(t, attempt.StatusCode >= 300, attempt.Error != "")
+			}
+		})
+	}
+}
+
+func Test_webhookNotifier_Notify_url(t *testing.T) {
+	withURL := func(url string) events.NotificationEvent {
+		return events.NotificationEvent{
+			ID:           "evt_1",
+			Subscription: events.Subscription{Notifications: events.NotificationPreferences{WebhookURL: url}},
+		}
+	}
+
+	t.Run("no url", func(t *testing.T) {
+		n, _ := newTestWebhookNotifier()
+		require.NoError(t, n.Notify(context.Background(), withURL("")))
+		require.Empty(t, n.Attempts(""))
+	})
+
+	t.Run("default url", func(t *testing.T) {
+		srv, received := webhookServer(t, http.StatusOK)
+		n, _ := newTestWebhookNotifier(WithDefaultURL(srv.URL))
+		require.NoError(t, n.Notify(context.Background(), withURL("")))
+		require.Len(t, received(), 1)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		srv, _ := webhookServer(t, http.StatusOK)
+		srv.Close()
+		n, _ := newTestWebhookNotifier(WithRetry(2, time.Millisecond, time.Millisecond))
+		err := n.Notify(context.Background(), withURL(srv.URL))
+		require.ErrorIs(t, err, ErrWebhookDelivery)
+
+		attempts := n.Attempts("")
+		require.Len(t, attempts, 2)
+		require.Zero(t, attempts[1].StatusCode)
+		require.NotEmpty(t, attempts[1].Error)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		srv, received := webhookServer(t, http.StatusInternalServerError)
+		n, _ := newTestWebhookNotifier()
+		ctx, cancel := context.WithCancel(context.Background())
+		n.sleep = sleepContext
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		err := n.Notify(ctx, withURL(srv.URL))
+		require.ErrorIs(t, err, context.Canceled)
+		// the backoff is interrupted and the attempt after it is not sent
+		require.Less(t, time.Since(start), n.initialBackoff)
+		require.Len(t, received(), 1)
+	})
+
+	t.Run("internal address", func(t *testing.T) {
+		srv, received := webhookServer(t, http.StatusOK)
+		n := NewWebhookNotifier("s3cret", WithRetry(1, 0, 0)).(*webhookNotifier)
+		err := n.Notify(context.Background(), withURL(srv.URL))
+		require.ErrorIs(t, err, ErrWebhookDelivery)
+		require.Contains(t, n.Attempts("")[0].Error, ErrForbiddenAddress.Error())
+		require.Empty(t, received())
+	})
+}
+
+func Test_publicAddressOnly(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"93.184.215.14:443":    true,
+		"[2606:4700::1]:443":   true,
+		"127.0.0.1:443":        false,
+		"10.1.2.3:443":         false,
+		"192.168.1.1:443":      false,
+		"169.254.169.254:80":   false,
+		"100.64.0.1:443":       false,
+		"0.0.0.0:443":          false,
+		"[::1]:443":            false,
+		"[fe80::1]:443":        false,
+		"[fd00::1]:443":        false,
+		"[::ffff:10.0.0.1]:80": false,
+	} {
+		err := publicAddressOnly("tcp", address, nil)
+		if allowed {
+			require.NoError(t, err, address)
+		} else {
+			require.ErrorIs(t, err, ErrForbiddenAddress, address)
+		}
+	}
+}
+
+func Test_webhookNotifier_Attempts_history(t *testing.T) {
+	n, _ := newTestWebhookNotifier(WithAttemptHistory(2))
+	for i := 1; i <= 3; i++ {
+		n.record(DeliveryAttempt{EventID: strconv.Itoa(i)})
+	}
+	attempts := n.Attempts("")
+	require.Len(t, attempts, 2)
+	require.Equal(t, "2", attempts[0].EventID)
+	require.Equal(t, "3", attempts[1].EventID)
+	require.Empty(t, n.Attempts("1"))
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"id":"evt_1"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		tolerance time.Duration
+		wantErr   bool
+	}{
+		{name: "valid", timestamp: now, signature: Sign(secret, now, body), body: body, tolerance: time.Minute},
+		{name: "tampered body", timestamp: now, signature: Sign(secret, now, body), body: []byte(`{"id":"evt_2"}`), tolerance: time.Minute, wantErr: true},
+		{name: "other secret", timestamp: now, signature: Sign([]byte("other"), now, body), body: body, tolerance: time.Minute, wantErr: true},
+		{name: "changed timestamp", timestamp: old, signature: Sign(secret, now, body), body: body, wantErr: true},
+		{name: "too old", timestamp: old, signature: Sign(secret, old, body), body: body, tolerance: time.Minute, wantErr: true},
+		{name: "old without tolerance", timestamp: old, signature: Sign(secret, old, body), body: body},
+		{name: "missing prefix", timestamp: now, signature: Sign(secret, now, body)[len(signaturePrefix):], body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(secret, tt.timestamp, tt.signature, tt.body, tt.tolerance)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSignature)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,1440 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: txparser/v1/txparser.proto
+
+package txparserv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Value         string                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	BlockNumber   int64                  `protobuf:"varint,5,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Transaction) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Transaction) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Transaction) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Transaction) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Transaction) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *Transaction) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type NotificationPreferences struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Muted      bool                   `protobuf:"varint,1,opt,name=muted,proto3" json:"muted,omitempty"`
+	WebhookUrl string                 `protobuf:"bytes,2,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	Emails     []string               `protobuf:"bytes,3,rep,name=emails,proto3" json:"emails,omitempty"`
+	// channels the events are sent to, the server default routes when empty
+	Routes []*NotificationRoute `protobuf:"bytes,4,rep,name=routes,proto3" json:"routes,omitempty"`
+	// batch the events in summaries, every event is sent on its own when unset
+	Digest *Digest `protobuf:"bytes,5,opt,name=digest,proto3" json:"digest,omitempty"`
+	// limit the notifications sent, the events over the limit are sent in a digest
+	Throttle *Throttle `protobuf:"bytes,6,opt,name=throttle,proto3" json:"throttle,omitempty"`
+	// only the txns raising an alert are notified when set
+	Rules         []*AlertRule `protobuf:"bytes,7,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationPreferences) Reset() {
+	*x = NotificationPreferences{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationPreferences) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationPreferences) ProtoMessage() {}
+
+func (x *NotificationPreferences) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationPreferences.ProtoReflect.Descriptor instead.
+func (*NotificationPreferences) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *NotificationPreferences) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+func (x *NotificationPreferences) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+func (x *NotificationPreferences) GetEmails() []string {
+	if x != nil {
+		return x.Emails
+	}
+	return nil
+}
+
+func (x *NotificationPreferences) GetRoutes() []*NotificationRoute {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *NotificationPreferences) GetDigest() *Digest {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *NotificationPreferences) GetThrottle() *Throttle {
+	if x != nil {
+		return x.Throttle
+	}
+	return nil
+}
+
+func (x *NotificationPreferences) GetRules() []*AlertRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type AlertRule struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// unique per subscription, the events list the rules which raised them
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// every condition must match the txn
+	Conditions    []*AlertCondition `protobuf:"bytes,2,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlertRule) Reset() {
+	*x = AlertRule{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlertRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertRule) ProtoMessage() {}
+
+func (x *AlertRule) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertRule.ProtoReflect.Descriptor instead.
+func (*AlertRule) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AlertRule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AlertRule) GetConditions() []*AlertCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+type AlertCondition struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// value_above, new_counterparty, contract, failed or nonce_gap
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// threshold of value_above in wei, base 10
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// addresses called, for contract
+	Contracts []string `protobuf:"bytes,3,rep,name=contracts,proto3" json:"contracts,omitempty"`
+	// 4 bytes selector of the call, for contract. Any call when empty
+	Method        string `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlertCondition) Reset() {
+	*x = AlertCondition{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlertCondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertCondition) ProtoMessage() {}
+
+func (x *AlertCondition) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertCondition.ProtoReflect.Descriptor instead.
+func (*AlertCondition) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AlertCondition) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AlertCondition) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *AlertCondition) GetContracts() []string {
+	if x != nil {
+		return x.Contracts
+	}
+	return nil
+}
+
+func (x *AlertCondition) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+type Digest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// immediate, interval or daily
+	Mode string `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	// length of the windows of the interval mode
+	IntervalMinutes int32 `protobuf:"varint,2,opt,name=interval_minutes,json=intervalMinutes,proto3" json:"interval_minutes,omitempty"`
+	// UTC time of the daily digest, HH:MM
+	DailyAt       string `protobuf:"bytes,3,opt,name=daily_at,json=dailyAt,proto3" json:"daily_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Digest) Reset() {
+	*x = Digest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Digest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Digest) ProtoMessage() {}
+
+func (x *Digest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Digest.ProtoReflect.Descriptor instead.
+func (*Digest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Digest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Digest) GetIntervalMinutes() int32 {
+	if x != nil {
+		return x.IntervalMinutes
+	}
+	return 0
+}
+
+func (x *Digest) GetDailyAt() string {
+	if x != nil {
+		return x.DailyAt
+	}
+	return ""
+}
+
+type Throttle struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// notifications sent per period
+	Limit         int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	PeriodMinutes int32 `protobuf:"varint,2,opt,name=period_minutes,json=periodMinutes,proto3" json:"period_minutes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Throttle) Reset() {
+	*x = Throttle{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Throttle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Throttle) ProtoMessage() {}
+
+func (x *Throttle) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Throttle.ProtoReflect.Descriptor instead.
+func (*Throttle) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Throttle) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Throttle) GetPeriodMinutes() int32 {
+	if x != nil {
+		return x.PeriodMinutes
+	}
+	return 0
+}
+
+type NotificationRoute struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// webhook, email, slack, discord, kafka, nats or console
+	Channel string `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	// only these event types take the route, every type when empty
+	EventTypes    []string `protobuf:"bytes,2,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationRoute) Reset() {
+	*x = NotificationRoute{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationRoute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationRoute) ProtoMessage() {}
+
+func (x *NotificationRoute) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationRoute.ProtoReflect.Descriptor instead.
+func (*NotificationRoute) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *NotificationRoute) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *NotificationRoute) GetEventTypes() []string {
+	if x != nil {
+		return x.EventTypes
+	}
+	return nil
+}
+
+type Subscription struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Address       string                   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Tenant        string                   `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Label         string                   `protobuf:"bytes,3,opt,name=label,proto3" json:"label,omitempty"`
+	Owner         string                   `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+	Tags          []string                 `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	CreatedAt     int64                    `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartBlock    int64                    `protobuf:"varint,7,opt,name=start_block,json=startBlock,proto3" json:"start_block,omitempty"`
+	Notifications *NotificationPreferences `protobuf:"bytes,8,opt,name=notifications,proto3" json:"notifications,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subscription) Reset() {
+	*x = Subscription{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subscription) ProtoMessage() {}
+
+func (x *Subscription) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subscription.ProtoReflect.Descriptor instead.
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Subscription) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Subscription) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *Subscription) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Subscription) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Subscription) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Subscription) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Subscription) GetStartBlock() int64 {
+	if x != nil {
+		return x.StartBlock
+	}
+	return 0
+}
+
+func (x *Subscription) GetNotifications() *NotificationPreferences {
+	if x != nil {
+		return x.Notifications
+	}
+	return nil
+}
+
+type GetCurrentBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentBlockRequest) Reset() {
+	*x = GetCurrentBlockRequest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentBlockRequest) ProtoMessage() {}
+
+func (x *GetCurrentBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentBlockRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentBlockRequest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{8}
+}
+
+type GetCurrentBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Block         int64                  `protobuf:"varint,1,opt,name=block,proto3" json:"block,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentBlockResponse) Reset() {
+	*x = GetCurrentBlockResponse{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentBlockResponse) ProtoMessage() {}
+
+func (x *GetCurrentBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentBlockResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentBlockResponse) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetCurrentBlockResponse) GetBlock() int64 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Address       string                   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Label         string                   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Owner         string                   `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	Tags          []string                 `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	StartBlock    int64                    `protobuf:"varint,5,opt,name=start_block,json=startBlock,proto3" json:"start_block,omitempty"`
+	Notifications *NotificationPreferences `protobuf:"bytes,6,opt,name=notifications,proto3" json:"notifications,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SubscribeRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetStartBlock() int64 {
+	if x != nil {
+		return x.StartBlock
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetNotifications() *NotificationPreferences {
+	if x != nil {
+		return x.Notifications
+	}
+	return nil
+}
+
+type SubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeResponse) Reset() {
+	*x = SubscribeResponse{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeResponse) ProtoMessage() {}
+
+func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{11}
+}
+
+type UnsubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeRequest) Reset() {
+	*x = UnsubscribeRequest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeRequest) ProtoMessage() {}
+
+func (x *UnsubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeRequest.ProtoReflect.Descriptor instead.
+func (*UnsubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UnsubscribeRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type UnsubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeResponse) Reset() {
+	*x = UnsubscribeResponse{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeResponse) ProtoMessage() {}
+
+func (x *UnsubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeResponse.ProtoReflect.Descriptor instead.
+func (*UnsubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{13}
+}
+
+type GetTransactionsRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// offset of the first transaction, in insertion order
+	Offset int32 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// maximum number of transactions, 0 return everything after offset
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionsRequest) Reset() {
+	*x = GetTransactionsRequest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionsRequest) ProtoMessage() {}
+
+func (x *GetTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetTransactionsRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *GetTransactionsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetTransactionsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetTransactionsResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Transactions []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	// total number of stored transactions of the address
+	Total         int32 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionsResponse) Reset() {
+	*x = GetTransactionsResponse{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionsResponse) ProtoMessage() {}
+
+func (x *GetTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*GetTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *GetTransactionsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type WatchTransactionsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Addresses []string               `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	// id of the last event received by a previous call
+	LastEventId   string `protobuf:"bytes,2,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTransactionsRequest) Reset() {
+	*x = WatchTransactionsRequest{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTransactionsRequest) ProtoMessage() {}
+
+func (x *WatchTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*WatchTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WatchTransactionsRequest) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *WatchTransactionsRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type TransactionEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id to resume from with last_event_id
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// the watched addresses the transaction involves
+	Addresses     []string     `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Transaction   *Transaction `protobuf:"bytes,3,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionEvent) Reset() {
+	*x = TransactionEvent{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionEvent) ProtoMessage() {}
+
+func (x *TransactionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionEvent.ProtoReflect.Descriptor instead.
+func (*TransactionEvent) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TransactionEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *TransactionEvent) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+type ReorgEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BlockNumber   int64                  `protobuf:"varint,1,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	OldHash       string                 `protobuf:"bytes,2,opt,name=old_hash,json=oldHash,proto3" json:"old_hash,omitempty"`
+	NewHash       string                 `protobuf:"bytes,3,opt,name=new_hash,json=newHash,proto3" json:"new_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReorgEvent) Reset() {
+	*x = ReorgEvent{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReorgEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReorgEvent) ProtoMessage() {}
+
+func (x *ReorgEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReorgEvent.ProtoReflect.Descriptor instead.
+func (*ReorgEvent) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ReorgEvent) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *ReorgEvent) GetOldHash() string {
+	if x != nil {
+		return x.OldHash
+	}
+	return ""
+}
+
+func (x *ReorgEvent) GetNewHash() string {
+	if x != nil {
+		return x.NewHash
+	}
+	return ""
+}
+
+type WatchTransactionsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*WatchTransactionsResponse_Transaction
+	//	*WatchTransactionsResponse_Reorg
+	Event         isWatchTransactionsResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTransactionsResponse) Reset() {
+	*x = WatchTransactionsResponse{}
+	mi := &file_txparser_v1_txparser_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTransactionsResponse) ProtoMessage() {}
+
+func (x *WatchTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_txparser_v1_txparser_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*WatchTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_txparser_v1_txparser_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchTransactionsResponse) GetEvent() isWatchTransactionsResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *WatchTransactionsResponse) GetTransaction() *TransactionEvent {
+	if x != nil {
+		if x, ok := x.Event.(*WatchTransactionsResponse_Transaction); ok {
+			return x.Transaction
+		}
+	}
+	return nil
+}
+
+func (x *WatchTransactionsResponse) GetReorg() *ReorgEvent {
+	if x != nil {
+		if x, ok := x.Event.(*WatchTransactionsResponse_Reorg); ok {
+			return x.Reorg
+		}
+	}
+	return nil
+}
+
+type isWatchTransactionsResponse_Event interface {
+	isWatchTransactionsResponse_Event()
+}
+
+type WatchTransactionsResponse_Transaction struct {
+	Transaction *TransactionEvent `protobuf:"bytes,1,opt,name=transaction,proto3,oneof"`
+}
+
+type WatchTransactionsResponse_Reorg struct {
+	Reorg *ReorgEvent `protobuf:"bytes,2,opt,name=reorg,proto3,oneof"`
+}
+
+func (*WatchTransactionsResponse_Transaction) isWatchTransactionsResponse_Event() {}
+
+func (*WatchTransactionsResponse_Reorg) isWatchTransactionsResponse_Event() {}
+
+var File_txparser_v1_txparser_proto protoreflect.FileDescriptor
+
+const file_txparser_v1_txparser_proto_rawDesc = "" +
+	"\n" +
+	"\x1atxparser/v1/txparser.proto\x12\vtxparser.v1\"\x9c\x01\n" +
+	"\vTransaction\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12!\n" +
+	"\fblock_number\x18\x05 \x01(\x03R\vblockNumber\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\"\xae\x02\n" +
+	"\x17NotificationPreferences\x12\x14\n" +
+	"\x05muted\x18\x01 \x01(\bR\x05muted\x12\x1f\n" +
+	"\vwebhook_url\x18\x02 \x01(\tR\n" +
+	"webhookUrl\x12\x16\n" +
+	"\x06emails\x18\x03 \x03(\tR\x06emails\x126\n" +
+	"\x06routes\x18\x04 \x03(\v2\x1e.txparser.v1.NotificationRouteR\x06routes\x12+\n" +
+	"\x06digest\x18\x05 \x01(\v2\x13.txparser.v1.DigestR\x06digest\x121\n" +
+	"\bthrottle\x18\x06 \x01(\v2\x15.txparser.v1.ThrottleR\bthrottle\x12,\n" +
+	"\x05rules\x18\a \x03(\v2\x16.txparser.v1.AlertRuleR\x05rules\"\\\n" +
+	"\tAlertRule\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12;\n" +
+	"\n" +
+	"conditions\x18\x02 \x03(\v2\x1b.txparser.v1.AlertConditionR\n" +
+	"conditions\"p\n" +
+	"\x0eAlertCondition\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x1c\n" +
+	"\tcontracts\x18\x03 \x03(\tR\tcontracts\x12\x16\n" +
+	"\x06method\x18\x04 \x01(\tR\x06method\"b\n" +
+	"\x06Digest\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12)\n" +
+	"\x10interval_minutes\x18\x02 \x01(\x05R\x0fintervalMinutes\x12\x19\n" +
+	"\bdaily_at\x18\x03 \x01(\tR\adailyAt\"G\n" +
+	"\bThrottle\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12%\n" +
+	"\x0eperiod_minutes\x18\x02 \x01(\x05R\rperiodMinutes\"N\n" +
+	"\x11NotificationRoute\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12\x1f\n" +
+	"\vevent_types\x18\x02 \x03(\tR\n" +
+	"eventTypes\"\x8c\x02\n" +
+	"\fSubscription\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\x12\x14\n" +
+	"\x05label\x18\x03 \x01(\tR\x05label\x12\x14\n" +
+	"\x05owner\x18\x04 \x01(\tR\x05owner\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\x03R\tcreatedAt\x12\x1f\n" +
+	"\vstart_block\x18\a \x01(\x03R\n" +
+	"startBlock\x12J\n" +
+	"\rnotifications\x18\b \x01(\v2$.txparser.v1.NotificationPreferencesR\rnotifications\"\x18\n" +
+	"\x16GetCurrentBlockRequest\"/\n" +
+	"\x17GetCurrentBlockResponse\x12\x14\n" +
+	"\x05block\x18\x01 \x01(\x03R\x05block\"\xd9\x01\n" +
+	"\x10SubscribeRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12\x14\n" +
+	"\x05owner\x18\x03 \x01(\tR\x05owner\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x12\x1f\n" +
+	"\vstart_block\x18\x05 \x01(\x03R\n" +
+	"startBlock\x12J\n" +
+	"\rnotifications\x18\x06 \x01(\v2$.txparser.v1.NotificationPreferencesR\rnotifications\"\x13\n" +
+	"\x11SubscribeResponse\".\n" +
+	"\x12UnsubscribeRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x15\n" +
+	"\x13UnsubscribeResponse\"`\n" +
+	"\x16GetTransactionsRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"m\n" +
+	"\x17GetTransactionsResponse\x12<\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x18.txparser.v1.TransactionR\ftransactions\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"\\\n" +
+	"\x18WatchTransactionsRequest\x12\x1c\n" +
+	"\taddresses\x18\x01 \x03(\tR\taddresses\x12\"\n" +
+	"\rlast_event_id\x18\x02 \x01(\tR\vlastEventId\"|\n" +
+	"\x10TransactionEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1c\n" +
+	"\taddresses\x18\x02 \x03(\tR\taddresses\x12:\n" +
+	"\vtransaction\x18\x03 \x01(\v2\x18.txparser.v1.TransactionR\vtransaction\"e\n" +
+	"\n" +
+	"ReorgEvent\x12!\n" +
+	"\fblock_number\x18\x01 \x01(\x03R\vblockNumber\x12\x19\n" +
+	"\bold_hash\x18\x02 \x01(\tR\aoldHash\x12\x19\n" +
+	"\bnew_hash\x18\x03 \x01(\tR\anewHash\"\x98\x01\n" +
+	"\x19WatchTransactionsResponse\x12A\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x1d.txparser.v1.TransactionEventH\x00R\vtransaction\x12/\n" +
+	"\x05reorg\x18\x02 \x01(\v2\x17.txparser.v1.ReorgEventH\x00R\x05reorgB\a\n" +
+	"\x05event2\xd1\x03\n" +
+	"\x0fTxParserService\x12\\\n" +
+	"\x0fGetCurrentBlock\x12#.txparser.v1.GetCurrentBlockRequest\x1a$.txparser.v1.GetCurrentBlockResponse\x12J\n" +
+	"\tSubscribe\x12\x1d.txparser.v1.SubscribeRequest\x1a\x1e.txparser.v1.SubscribeResponse\x12P\n" +
+	"\vUnsubscribe\x12\x1f.txparser.v1.UnsubscribeRequest\x1a .txparser.v1.UnsubscribeResponse\x12\\\n" +
+	"\x0fGetTransactions\x12#.txparser.v1.GetTransactionsRequest\x1a$.txparser.v1.GetTransactionsResponse\x12d\n" +
+	"\x11WatchTransactions\x12%.txparser.v1.WatchTransactionsRequest\x1a&.txparser.v1.WatchTransactionsResponse0\x01B;Z9github.com/vdhieu/tx-parser/pkg/pb/txparser/v1;txparserv1b\x06proto3"
+
+var (
+	file_txparser_v1_txparser_proto_rawDescOnce sync.Once
+	file_txparser_v1_txparser_proto_rawDescData []byte
+)
+
+func file_txparser_v1_txparser_proto_rawDescGZIP() []byte {
+	file_txparser_v1_txparser_proto_rawDescOnce.Do(func() {
+		file_txparser_v1_txparser_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_txparser_v1_txparser_proto_rawDesc), len(file_txparser_v1_txparser_proto_rawDesc)))
+	})
+	return file_txparser_v1_txparser_proto_rawDescData
+}
+
+var file_txparser_v1_txparser_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_txparser_v1_txparser_proto_goTypes = []any{
+	(*Transaction)(nil),               // 0: txparser.v1.Transaction
+	(*NotificationPreferences)(nil),   // 1: txparser.v1.NotificationPreferences
+	(*AlertRule)(nil),                 // 2: txparser.v1.AlertRule
+	(*AlertCondition)(nil),            // 3: txparser.v1.AlertCondition
+	(*Digest)(nil),                    // 4: txparser.v1.Digest
+	(*Throttle)(nil),                  // 5: txparser.v1.Throttle
+	(*NotificationRoute)(nil),         // 6: txparser.v1.NotificationRoute
+	(*Subscription)(nil),              // 7: txparser.v1.Subscription
+	(*GetCurrentBlockRequest)(nil),    // 8: txparser.v1.GetCurrentBlockRequest
+	(*GetCurrentBlockResponse)(nil),   // 9: txparser.v1.GetCurrentBlockResponse
+	(*SubscribeRequest)(nil),          // 10: txparser.v1.SubscribeRequest
+	(*SubscribeResponse)(nil),         // 11: txparser.v1.SubscribeResponse
+	(*UnsubscribeRequest)(nil),        // 12: txparser.v1.UnsubscribeRequest
+	(*UnsubscribeResponse)(nil),       // 13: txparser.v1.UnsubscribeResponse
+	(*GetTransactionsRequest)(nil),    // 14: txparser.v1.GetTransactionsRequest
+	(*GetTransactionsResponse)(nil),   // 15: txparser.v1.GetTransactionsResponse
+	(*WatchTransactionsRequest)(nil),  // 16: txparser.v1.WatchTransactionsRequest
+	(*TransactionEvent)(nil),          // 17: txparser.v1.TransactionEvent
+	(*ReorgEvent)(nil),                // 18: txparser.v1.ReorgEvent
+	(*WatchTransactionsResponse)(nil), // 19: txparser.v1.WatchTransactionsResponse
+}
+var file_txparser_v1_txparser_proto_depIdxs = []int32{
+	6,  // 0: txparser.v1.NotificationPreferences.routes:type_name -> txparser.v1.NotificationRoute
+	4,  // 1: txparser.v1.NotificationPreferences.digest:type_name -> txparser.v1.Digest
+	5,  // 2: txparser.v1.NotificationPreferences.throttle:type_name -> txparser.v1.Throttle
+	2,  // 3: txparser.v1.NotificationPreferences.rules:type_name -> txparser.v1.AlertRule
+	3,  // 4: txparser.v1.AlertRule.conditions:type_name -> txparser.v1.AlertCondition
+	1,  // 5: txparser.v1.Subscription.notifications:type_name -> txparser.v1.NotificationPreferences
+	1,  // 6: txparser.v1.SubscribeRequest.notifications:type_name -> txparser.v1.NotificationPreferences
+	0,  // 7: txparser.v1.GetTransactionsResponse.transactions:type_name -> txparser.v1.Transaction
+	0,  // 8: txparser.v1.TransactionEvent.transaction:type_name -> txparser.v1.Transaction
+	17, // 9: txparser.v1.WatchTransactionsResponse.transaction:type_name -> txparser.v1.TransactionEvent
+	18, // 10: txparser.v1.WatchTransactionsResponse.reorg:type_name -> txparser.v1.ReorgEvent
+	8,  // 11: txparser.v1.TxParserService.GetCurrentBlock:input_type -> txparser.v1.GetCurrentBlockRequest
+	10, // 12: txparser.v1.TxParserService.Subscribe:input_type -> txparser.v1.SubscribeRequest
+	12, // 13: txparser.v1.TxParserService.Unsubscribe:input_type -> txparser.v1.UnsubscribeRequest
+	14, // 14: txparser.v1.TxParserService.GetTransactions:input_type -> txparser.v1.GetTransactionsRequest
+	16, // 15: txparser.v1.TxParserService.WatchTransactions:input_type -> txparser.v1.WatchTransactionsRequest
+	9,  // 16: txparser.v1.TxParserService.GetCurrentBlock:output_type -> txparser.v1.GetCurrentBlockResponse
+	11, // 17: txparser.v1.TxParserService.Subscribe:output_type -> txparser.v1.SubscribeResponse
+	13, // 18: txparser.v1.TxParserService.Unsubscribe:output_type -> txparser.v1.UnsubscribeResponse
+	15, // 19: txparser.v1.TxParserService.GetTransactions:output_type -> txparser.v1.GetTransactionsResponse
+	19, // 20: txparser.v1.TxParserService.WatchTransactions:output_type -> txparser.v1.WatchTransactionsResponse
+	16, // [16:21] is the sub-list for method output_type
+	11, // [11:16] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_txparser_v1_txparser_proto_init() }
+func file_txparser_v1_txparser_proto_init() {
+	if File_txparser_v1_txparser_proto != nil {
+		return
+	}
+	file_txparser_v1_txparser_proto_msgTypes[19].OneofWrappers = []any{
+		(*WatchTransactionsResponse_Transaction)(nil),
+		(*WatchTransactionsResponse_Reorg)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_txparser_v1_txparser_proto_rawDesc), len(file_txparser_v1_txparser_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_txparser_v1_txparser_proto_goTypes,
+		DependencyIndexes: file_txparser_v1_txparser_proto_depIdxs,
+		MessageInfos:      file_txparser_v1_txparser_proto_msgTypes,
+	}.Build()
+	File_txparser_v1_txparser_proto = out.File
+	file_txparser_v1_txparser_proto_goTypes = nil
+	file_txparser_v1_txparser_proto_depIdxs = nil
+}
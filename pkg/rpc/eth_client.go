@@ -148,6 +148,31 @@ func (c *ethClient) GetBlockByNumber(blockNum int64) (Block, error) {
 	return block, nil
 }
 
+func (c *ethClient) GetTransactionReceipt(hash string) (TransactionReceipt, error) {
+	response, err := c.call("eth_getTransactionReceipt", []interface{}{hash})
+	if err != nil {
+		return TransactionReceipt{}, err
+	}
+	if response.Result == nil {
+		return TransactionReceipt{}, fmt.Errorf("receipt of %s not found", hash)
+	}
+
+	receiptData, ok := response.Result.(map[string]interface{})
+	if !ok {
+		return TransactionReceipt{}, fmt.Errorf("invalid receipt format")
+	}
+
+	receipt := TransactionReceipt{}
+	if statusStr, ok := receiptData["status"].(string); ok {
+		receipt.Status, _ = hexToInt64(statusStr)
+	}
+	if numStr, ok := receiptData["blockNumber"].(string); ok {
+		receipt.BlockNumber, _ = hexToInt64(numStr)
+	}
+	receipt.BlockHash, _ = receiptData["blockHash"].(string)
+	return receipt, nil
+}
+
 // Helper function to convert hex string to int64
 func hexToInt64(hex string) (int64, error) {
 	if hex == "" {
@@ -202,6 +202,51 @@ func Test_ethClient_GetBlockByNumber(t *testing.T) {
 	}
 }
 
+func Test_ethClient_GetTransactionReceipt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		require.NoError(t, err)
+		require.Equal(t, "eth_getTransactionReceipt", req.Method)
+		require.Len(t, req.Params, 1)
+
+		response := RPCResponse{JsonRPC: "2.0", ID: req.ID}
+		switch req.Params[0].(string) {
+		case "0xfailed":
+			response.Result = map[string]interface{}{"status": "0x0", "blockNumber": "0x10", "blockHash": "0xb16"}
+		case "0xpending":
+			response.Result = nil
+		default:
+			response.Result = map[string]interface{}{"status": "0x1", "blockNumber": "0x10", "blockHash": "0xb16"}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		hash    string
+		want    TransactionReceipt
+		wantErr bool
+	}{
+		{name: "successful txn", hash: "0xok", want: TransactionReceipt{Status: 1, BlockHash: "0xb16", BlockNumber: 16}},
+		{name: "reverted txn", hash: "0xfailed", want: TransactionReceipt{Status: 0, BlockHash: "0xb16", BlockNumber: 16}},
+		{name: "unknown txn", hash: "0xpending", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ethClient{endpoint: server.URL, client: &http.Client{}}
+			got, err := c.GetTransactionReceipt(tt.hash)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func Test_hexToInt64(t *testing.T) {
 	type args struct {
 		hex string
@@ -10,4 +10,6 @@ type ethClient struct {
 type Client interface {
 	GetLatestBlockNumber() (int64, error)
 	GetBlockByNumber(blockNum int64) (Block, error)
+	// GetTransactionReceipt return the receipt of a mined txn, Status is 1 on success and 0 when reverted
+	GetTransactionReceipt(hash string) (TransactionReceipt, error)
 }